@@ -0,0 +1,17 @@
+// Package checkpointsvc stores the position of the crawls, so that they can be resumed
+package checkpointsvc
+
+import "github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+
+// New returns a new checkpoint service backed by mgo
+func New(db *database.DB, ms ...Middleware) Service {
+	return NewWithStore(NewStore(db, database.Checkpoints), ms...)
+}
+
+// NewWithStore returns a new checkpoint service backed by the store of any storage driver
+func NewWithStore(store Store, ms ...Middleware) Service {
+	model := NewModel(store)
+	service := NewService(model)
+	service = Decorate(service, ms...)
+	return service
+}
@@ -0,0 +1,66 @@
+package checkpointsvc
+
+import (
+	"context"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Logging adds logging capabilities to the service
+func Logging(l *logger.Logger) Middleware {
+	return func(s Service) Service {
+		return &loggingMiddleware{
+			service: s,
+			logger:  l,
+		}
+	}
+}
+
+type loggingMiddleware struct {
+	logger  *logger.Logger
+	service Service
+}
+
+func (m *loggingMiddleware) Find(ctx context.Context, key string) (cp *Checkpoint, ok bool) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("Find"),
+			logger.Duration(start),
+			zap.String("key", key))
+
+		L.Info("find checkpoint",
+			zap.Bool("exists", ok))
+	}(time.Now())
+
+	return m.service.Find(ctx, key)
+}
+
+func (m *loggingMiddleware) Save(ctx context.Context, cp Checkpoint) (err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("Save"),
+			logger.Duration(start),
+			zap.String("key", cp.Key),
+			zap.String("query", cp.Query),
+			zap.String("cursor", cp.Cursor))
+
+		logger.Maybe(L, "save checkpoint", err)
+	}(time.Now())
+
+	return m.service.Save(ctx, cp)
+}
+
+func (m *loggingMiddleware) Delete(ctx context.Context, key string) (err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("Delete"),
+			logger.Duration(start),
+			zap.String("key", key))
+
+		logger.Maybe(L, "delete checkpoint", err)
+	}(time.Now())
+
+	return m.service.Delete(ctx, key)
+}
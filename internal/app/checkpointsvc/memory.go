@@ -0,0 +1,97 @@
+package checkpointsvc
+
+import (
+	"sync"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/kv"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// memoryStore keeps the checkpoints in memory, for the tests and the demo mode. With a
+// bucket, the checkpoints are written through to it as well
+type memoryStore struct {
+	sync.RWMutex
+	checkpoints map[string]Checkpoint
+	bucket      kv.Bucket
+}
+
+// NewMemoryStore returns an empty store that keeps the checkpoints in memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		checkpoints: make(map[string]Checkpoint),
+	}
+}
+
+// NewKVStore returns a store that keeps the checkpoints in memory and in the bucket, with
+// the checkpoints already in the bucket
+func NewKVStore(b kv.Bucket) (Store, error) {
+	s := &memoryStore{
+		checkpoints: make(map[string]Checkpoint),
+		bucket:      b,
+	}
+	if err := b.ForEach(func(key string, value []byte) error {
+		var cp Checkpoint
+		if err := bson.Unmarshal(value, &cp); err != nil {
+			return err
+		}
+		s.checkpoints[key] = cp
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryStore) Init() error {
+	return nil
+}
+
+func (s *memoryStore) FindOne(key string) (*Checkpoint, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	cp, ok := s.checkpoints[key]
+	if !ok {
+		return nil, apperr.ErrNotFound
+	}
+	return &cp, nil
+}
+
+func (s *memoryStore) Upsert(cp Checkpoint) error {
+	s.Lock()
+	defer s.Unlock()
+
+	createdAt := moment.NewUTCDate()
+	if prev, ok := s.checkpoints[cp.Key]; ok {
+		if cp.Fence.Stale(prev.Fence) {
+			return fence.ErrStale
+		}
+		createdAt = prev.CreatedAt
+		// A write without a lease keeps the lease of the last write
+		if cp.Fence.IsZero() {
+			cp.Fence = prev.Fence
+		}
+	}
+	cp.CreatedAt = createdAt
+	cp.UpdatedAt = moment.NewUTCDate()
+	if err := kv.Save(s.bucket, map[string]interface{}{cp.Key: cp}); err != nil {
+		return err
+	}
+	s.checkpoints[cp.Key] = cp
+	return nil
+}
+
+func (s *memoryStore) Delete(key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := kv.Delete(s.bucket, key); err != nil {
+		return err
+	}
+	delete(s.checkpoints, key)
+	return nil
+}
@@ -0,0 +1,59 @@
+package checkpointsvc
+
+import (
+	"log"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+)
+
+var (
+	ErrInvalidKey = apperr.Validation("key is required")
+)
+
+type (
+	// Model contains the validation for the checkpoints
+	Model interface {
+		Init() error
+		FindOne(key string) (*Checkpoint, error)
+		Save(cp Checkpoint) error
+		Delete(key string) error
+	}
+
+	model struct {
+		store Store
+	}
+)
+
+// NewModel returns a new model with the store
+func NewModel(store Store) Model {
+	m := model{store: store}
+	if err := m.Init(); err != nil {
+		log.Fatal(err)
+	}
+	return &m
+}
+
+func (m *model) Init() error {
+	return m.store.Init()
+}
+
+func (m *model) FindOne(key string) (*Checkpoint, error) {
+	if key == "" {
+		return nil, ErrInvalidKey
+	}
+	return m.store.FindOne(key)
+}
+
+func (m *model) Save(cp Checkpoint) error {
+	if cp.Key == "" {
+		return ErrInvalidKey
+	}
+	return m.store.Upsert(cp)
+}
+
+func (m *model) Delete(key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+	return m.store.Delete(key)
+}
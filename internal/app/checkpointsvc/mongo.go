@@ -0,0 +1,82 @@
+package checkpointsvc
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoStore is the store on the official mongo driver, with the same documents and indexes
+// as the mgo store
+type mongoStore struct {
+	db         *database.Mongo
+	collection string
+}
+
+// NewMongoStore returns a new checkpoint store on the official mongo driver
+func NewMongoStore(db *database.Mongo, collection string) Store {
+	return &mongoStore{
+		db:         db,
+		collection: collection,
+	}
+}
+
+func (s *mongoStore) Init() error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	_, err := s.db.Collection(s.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    database.Sort("key"),
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (s *mongoStore) FindOne(key string) (*Checkpoint, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	var cp Checkpoint
+	err := s.db.Collection(s.collection).FindOne(ctx, bson.M{"key": key}).Decode(&cp)
+	if err == mongo.ErrNoDocuments {
+		return nil, apperr.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+func (s *mongoStore) Upsert(cp Checkpoint) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	query, set := byKey(cp)
+	_, err := s.db.Collection(s.collection).UpdateOne(ctx,
+		query,
+		bson.M{
+			"$set": set,
+			"$setOnInsert": bson.M{
+				"createdAt": moment.NewUTCDate(),
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return fence.ErrStale
+	}
+	return err
+}
+
+func (s *mongoStore) Delete(key string) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	_, err := s.db.Collection(s.collection).DeleteOne(ctx, bson.M{"key": key})
+	return err
+}
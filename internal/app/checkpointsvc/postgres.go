@@ -0,0 +1,60 @@
+package checkpointsvc
+
+import (
+	"fmt"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+)
+
+// postgresStore keeps the checkpoints as jsonb documents in a table keyed by the key, with
+// the fields of the mongo documents
+type postgresStore struct {
+	db    *database.Postgres
+	table string
+}
+
+// NewPostgresStore returns a new checkpoint store on postgres
+func NewPostgresStore(db *database.Postgres, table string) Store {
+	return &postgresStore{
+		db:    db,
+		table: table,
+	}
+}
+
+func (s *postgresStore) Init() error {
+	return s.db.CreateTable(s.table)
+}
+
+func (s *postgresStore) FindOne(key string) (*Checkpoint, error) {
+	var cp Checkpoint
+	if err := s.db.FindOne(&cp, fmt.Sprintf(`SELECT doc FROM %s WHERE id = $1`, s.table), key); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// Upsert replaces the checkpoint. Under a lease, the checkpoint last written under a newer
+// token of the lease is kept, and fence.ErrStale is returned
+func (s *postgresStore) Upsert(cp Checkpoint) error {
+	return s.db.Upsert(s.table, cp.Fence, database.Doc{
+		ID: cp.Key,
+		Set: map[string]interface{}{
+			"key":       cp.Key,
+			"query":     cp.Query,
+			"start":     cp.Start,
+			"end":       cp.End,
+			"until":     cp.Until,
+			"cursor":    cp.Cursor,
+			"updatedAt": moment.NewUTCDate(),
+		},
+		SetOnInsert: map[string]interface{}{
+			"createdAt": moment.NewUTCDate(),
+		},
+	})
+}
+
+func (s *postgresStore) Delete(key string) error {
+	_, err := s.db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, s.table), key)
+	return err
+}
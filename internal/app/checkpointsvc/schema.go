@@ -0,0 +1,18 @@
+package checkpointsvc
+
+import "github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+
+// Checkpoint represents the position of a crawl after the last page that is stored.
+// Start and End is the window that is searched, which may be part of a larger crawl
+// that ends at Until. Fence is the lease of the job that wrote the checkpoint last
+type Checkpoint struct {
+	Key       string      `json:"key,omitempty" bson:"key,omitempty"`
+	Query     string      `json:"query,omitempty" bson:"query,omitempty"`
+	Start     string      `json:"start,omitempty" bson:"start,omitempty"`
+	End       string      `json:"end,omitempty" bson:"end,omitempty"`
+	Until     string      `json:"until,omitempty" bson:"until,omitempty"`
+	Cursor    string      `json:"cursor,omitempty" bson:"cursor,omitempty"`
+	CreatedAt string      `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	UpdatedAt string      `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	Fence     fence.Fence `json:"-" bson:"fence,omitempty"`
+}
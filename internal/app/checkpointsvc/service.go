@@ -0,0 +1,45 @@
+package checkpointsvc
+
+import (
+	"context"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+)
+
+type (
+	// Service represents the checkpoint service
+	Service interface {
+		Find(ctx context.Context, key string) (*Checkpoint, bool)
+		Save(ctx context.Context, cp Checkpoint) error
+		Delete(ctx context.Context, key string) error
+	}
+
+	service struct {
+		model Model
+	}
+)
+
+// NewService returns a new service
+func NewService(m Model) Service {
+	return &service{m}
+}
+
+// Find returns the checkpoint for the key, and a boolean to indicate if it exists
+func (s *service) Find(ctx context.Context, key string) (*Checkpoint, bool) {
+	cp, err := s.model.FindOne(key)
+	if err != nil || cp == nil {
+		return nil, false
+	}
+	return cp, true
+}
+
+// Save stores the checkpoint under the lease of the job in the context, if any, so that the
+// checkpoint of a job whose lease has been taken over does not overwrite the newer one
+func (s *service) Save(ctx context.Context, cp Checkpoint) error {
+	cp.Fence = fence.FromContext(ctx)
+	return s.model.Save(cp)
+}
+
+func (s *service) Delete(ctx context.Context, key string) error {
+	return s.model.Delete(key)
+}
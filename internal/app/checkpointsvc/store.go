@@ -0,0 +1,104 @@
+package checkpointsvc
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type (
+	// Store represents the interface for the checkpoint store
+	Store interface {
+		Init() error
+		FindOne(key string) (*Checkpoint, error)
+		Upsert(cp Checkpoint) error
+		Delete(key string) error
+	}
+
+	store struct {
+		db         *database.DB
+		collection string
+	}
+)
+
+// NewStore returns a new checkpoint store
+func NewStore(db *database.DB, collection string) Store {
+	return &store{
+		db:         db,
+		collection: collection,
+	}
+}
+
+func (s *store) Init() error {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	return c.EnsureIndex(mgo.Index{
+		Key:    []string{"key"},
+		Unique: true,
+	})
+}
+
+func (s *store) FindOne(key string) (*Checkpoint, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	var cp Checkpoint
+	if err := c.Find(bson.M{"key": key}).One(&cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+func (s *store) Upsert(cp Checkpoint) error {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	query, set := byKey(cp)
+	_, err := c.Upsert(
+		query,
+		bson.M{
+			"$set": set,
+			"$setOnInsert": bson.M{
+				"createdAt": moment.NewUTCDate(),
+			},
+		},
+	)
+	if mgo.IsDup(err) {
+		return fence.ErrStale
+	}
+	return err
+}
+
+func (s *store) Delete(key string) error {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	err := c.Remove(bson.M{"key": key})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// byKey returns the query and the fields of the upsert of the checkpoint. Under a lease, the
+// checkpoint last written under a newer token of the lease is not matched
+func byKey(cp Checkpoint) (query, set bson.M) {
+	query = bson.M{"key": cp.Key}
+	set = bson.M{
+		"query":     cp.Query,
+		"start":     cp.Start,
+		"end":       cp.End,
+		"until":     cp.Until,
+		"cursor":    cp.Cursor,
+		"updatedAt": moment.NewUTCDate(),
+	}
+	if !cp.Fence.IsZero() {
+		query["$or"] = database.ByFence(cp.Fence)
+		set["fence"] = cp.Fence
+	}
+	return query, set
+}
@@ -0,0 +1,49 @@
+package checkpointsvc
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+)
+
+// Tracing decorates the service with tracing capabilities
+func Tracing() Middleware {
+	return func(s Service) Service {
+		return &tracingMiddleware{
+			service: s,
+		}
+	}
+}
+
+type tracingMiddleware struct {
+	service Service
+}
+
+func (m *tracingMiddleware) Find(ctx context.Context, key string) (*Checkpoint, bool) {
+	ctx, span := trace.StartSpan(ctx, "Find")
+	defer span.End()
+
+	span.AddAttributes(trace.StringAttribute("key", key))
+
+	return m.service.Find(ctx, key)
+}
+
+func (m *tracingMiddleware) Save(ctx context.Context, cp Checkpoint) error {
+	ctx, span := trace.StartSpan(ctx, "Save")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("key", cp.Key),
+		trace.StringAttribute("cursor", cp.Cursor))
+
+	return m.service.Save(ctx, cp)
+}
+
+func (m *tracingMiddleware) Delete(ctx context.Context, key string) error {
+	ctx, span := trace.StartSpan(ctx, "Delete")
+	defer span.End()
+
+	span.AddAttributes(trace.StringAttribute("key", key))
+
+	return m.service.Delete(ctx, key)
+}
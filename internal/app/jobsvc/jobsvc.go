@@ -0,0 +1,18 @@
+// Package jobsvc stores the history of the runs of the cron jobs, from which the health
+// of each job is reported
+package jobsvc
+
+import "github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+
+// New returns a new job service backed by mgo
+func New(db *database.DB, ms ...Middleware) Service {
+	return NewWithStore(NewStore(db, database.JobRuns), ms...)
+}
+
+// NewWithStore returns a new job service backed by the store of any storage driver
+func NewWithStore(store Store, ms ...Middleware) Service {
+	model := NewModel(store)
+	service := NewService(model)
+	service = Decorate(service, ms...)
+	return service
+}
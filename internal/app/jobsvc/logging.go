@@ -0,0 +1,73 @@
+package jobsvc
+
+import (
+	"context"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Logging adds logging capabilities to the service
+func Logging(l *logger.Logger) Middleware {
+	return func(s Service) Service {
+		return &loggingMiddleware{
+			service: s,
+			logger:  l,
+		}
+	}
+}
+
+type loggingMiddleware struct {
+	logger  *logger.Logger
+	service Service
+}
+
+func (m *loggingMiddleware) Save(ctx context.Context, run Run) (err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("Save"),
+			logger.Duration(start),
+			zap.String("name", run.Name),
+			zap.String("status", run.Status),
+			zap.Int64("durationMs", run.DurationMs),
+			zap.Int("tasks", len(run.Tasks)))
+
+		logger.Maybe(L, "save run", err)
+	}(time.Now())
+
+	return m.service.Save(ctx, run)
+}
+
+func (m *loggingMiddleware) FindAll(ctx context.Context, name string, limit int) (res []Run, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("FindAll"),
+			logger.Duration(start),
+			zap.String("name", name),
+			zap.Int("limit", limit),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "find all runs", err)
+	}(time.Now())
+
+	return m.service.FindAll(ctx, name, limit)
+}
+
+func (m *loggingMiddleware) Health(ctx context.Context, name string, threshold int) (res *Health, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("Health"),
+			logger.Duration(start),
+			zap.String("name", name),
+			zap.Int("threshold", threshold))
+
+		if res != nil {
+			L = L.With(zap.String("status", res.Status),
+				zap.Int("consecutiveFailures", res.ConsecutiveFailures))
+		}
+		logger.Maybe(L, "get job health", err)
+	}(time.Now())
+
+	return m.service.Health(ctx, name, threshold)
+}
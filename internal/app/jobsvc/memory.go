@@ -0,0 +1,101 @@
+package jobsvc
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/kv"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// maxMemoryRuns is the number of runs kept for each job, in place of the retention of the
+// mongo store
+const maxMemoryRuns = 100
+
+// memoryStore keeps the runs in memory, for the tests and the demo mode. With a bucket, the
+// runs kept are written through to it as well
+type memoryStore struct {
+	sync.RWMutex
+	runs   map[string][]Run
+	bucket kv.Bucket
+}
+
+// NewMemoryStore returns an empty store that keeps the most recent runs of each job in memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		runs: make(map[string][]Run),
+	}
+}
+
+// NewKVStore returns a store that keeps the most recent runs of each job in memory and in
+// the bucket, with the runs already in the bucket
+func NewKVStore(b kv.Bucket) (Store, error) {
+	s := &memoryStore{
+		runs:   make(map[string][]Run),
+		bucket: b,
+	}
+	if err := b.ForEach(func(key string, value []byte) error {
+		var run Run
+		if err := bson.Unmarshal(value, &run); err != nil {
+			return err
+		}
+		s.runs[run.Name] = append(s.runs[run.Name], run)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for _, runs := range s.runs {
+		sort.SliceStable(runs, func(i, j int) bool {
+			return runs[i].StartedAt.After(runs[j].StartedAt)
+		})
+	}
+	return s, nil
+}
+
+func (s *memoryStore) Init() error {
+	return nil
+}
+
+func (s *memoryStore) Insert(run Run) error {
+	s.Lock()
+	defer s.Unlock()
+
+	// The runs are kept with the most recent first. Runs that end out of order, e.g. when
+	// triggered at start, are placed by their start time
+	runs := s.runs[run.Name]
+	i := 0
+	for i < len(runs) && runs[i].StartedAt.After(run.StartedAt) {
+		i++
+	}
+	if i >= maxMemoryRuns {
+		return nil
+	}
+	if err := kv.Save(s.bucket, map[string]interface{}{run.ID: run}); err != nil {
+		return err
+	}
+	runs = append(runs, Run{})
+	copy(runs[i+1:], runs[i:])
+	runs[i] = run
+	if len(runs) > maxMemoryRuns {
+		if err := kv.Delete(s.bucket, runs[maxMemoryRuns].ID); err != nil {
+			return err
+		}
+		runs = runs[:maxMemoryRuns]
+	}
+	s.runs[run.Name] = runs
+	return nil
+}
+
+func (s *memoryStore) FindAll(name string, limit int) ([]Run, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	runs := s.runs[name]
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	res := make([]Run, len(runs))
+	copy(res, runs)
+	return res, nil
+}
@@ -0,0 +1,112 @@
+package jobsvc
+
+import (
+	"log"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+)
+
+var (
+	ErrInvalidName      = apperr.Validation("name is required")
+	ErrInvalidThreshold = apperr.Validation("threshold must be between 1 and 100")
+)
+
+const (
+	// defaultPageSize is the number of runs returned when the limit is not provided
+	defaultPageSize = 20
+
+	// MaxHealthRuns is the number of recent runs that the health is computed from, and so
+	// the largest threshold of consecutive failures
+	MaxHealthRuns = 100
+)
+
+type (
+	// Model contains the validation for the runs, and computes the health of the jobs
+	Model interface {
+		Init() error
+		Save(run Run) error
+		FindAll(name string, limit int) ([]Run, error)
+		Health(name string, threshold int) (*Health, error)
+	}
+
+	model struct {
+		store Store
+	}
+)
+
+// NewModel returns a new model with the store
+func NewModel(store Store) Model {
+	m := model{store: store}
+	if err := m.Init(); err != nil {
+		log.Fatal(err)
+	}
+	return &m
+}
+
+func (m *model) Init() error {
+	return m.store.Init()
+}
+
+func (m *model) Save(run Run) error {
+	if run.Name == "" {
+		return ErrInvalidName
+	}
+	return m.store.Insert(run)
+}
+
+func (m *model) FindAll(name string, limit int) ([]Run, error) {
+	if name == "" {
+		return nil, ErrInvalidName
+	}
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	return m.store.FindAll(name, setLimit(limit))
+}
+
+// Health counts the runs that failed since the last run that succeeded. The job is degraded
+// once the count reaches the threshold, and its health is unknown until it has run. The runs
+// skipped because the lock was held are not recorded, and do not count toward the health
+func (m *model) Health(name string, threshold int) (*Health, error) {
+	if name == "" {
+		return nil, ErrInvalidName
+	}
+	if threshold <= 0 || threshold > MaxHealthRuns {
+		return nil, ErrInvalidThreshold
+	}
+	runs, err := m.store.FindAll(name, MaxHealthRuns)
+	if err != nil {
+		return nil, err
+	}
+	h := Health{
+		Name:   name,
+		Status: HealthUnknown,
+	}
+	if len(runs) == 0 {
+		return &h, nil
+	}
+	h.LastRun = &runs[0]
+	for _, run := range runs {
+		if run.Status == StatusSucceeded {
+			h.LastSucceededAt = run.StartedAt.UTC().Format(time.RFC3339)
+			break
+		}
+		h.ConsecutiveFailures++
+	}
+	h.Status = HealthOK
+	if h.ConsecutiveFailures >= threshold {
+		h.Status = HealthDegraded
+	}
+	return &h, nil
+}
+
+func setLimit(limit int) int {
+	if limit < 0 {
+		return 10
+	}
+	if limit > 100 {
+		return 100
+	}
+	return limit
+}
@@ -0,0 +1,65 @@
+package jobsvc
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoStore is the store on the official mongo driver, with the same documents and indexes
+// as the mgo store
+type mongoStore struct {
+	db         *database.Mongo
+	collection string
+}
+
+// NewMongoStore returns a new run store on the official mongo driver
+func NewMongoStore(db *database.Mongo, collection string) Store {
+	return &mongoStore{
+		db:         db,
+		collection: collection,
+	}
+}
+
+func (s *mongoStore) Init() error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	_, err := s.db.Collection(s.collection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: database.Sort("name", "-startedAt")},
+		{
+			Keys:    database.Sort("startedAt"),
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())),
+		},
+	})
+	return err
+}
+
+func (s *mongoStore) Insert(run Run) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	_, err := s.db.Collection(s.collection).InsertOne(ctx, run)
+	return err
+}
+
+// FindAll returns the runs of the job, with the most recent first
+func (s *mongoStore) FindAll(name string, limit int) ([]Run, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	cur, err := s.db.Collection(s.collection).Find(ctx,
+		bson.M{"name": name},
+		options.Find().SetSort(database.Sort("-startedAt")).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var runs []Run
+	if err := cur.All(ctx, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -0,0 +1,62 @@
+package jobsvc
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+)
+
+// postgresStore keeps the runs as jsonb documents, with the name and the start of the run in
+// columns of their own to sort and expire the runs by
+type postgresStore struct {
+	db    *database.Postgres
+	table string
+}
+
+// NewPostgresStore returns a new run store on postgres
+func NewPostgresStore(db *database.Postgres, table string) Store {
+	return &postgresStore{
+		db:    db,
+		table: table,
+	}
+}
+
+func (s *postgresStore) Init() error {
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS %[1]s (id text PRIMARY KEY, name text NOT NULL, started_at timestamptz NOT NULL, doc jsonb NOT NULL)`,
+		`CREATE INDEX IF NOT EXISTS %[1]s_name ON %[1]s (name, started_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS %[1]s_started_at ON %[1]s (started_at)`,
+	} {
+		if _, err := s.db.Exec(fmt.Sprintf(stmt, s.table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Insert inserts the run, and removes the runs older than the retention, which postgres
+// does not expire by itself
+func (s *postgresStore) Insert(run Run) error {
+	doc, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(fmt.Sprintf(`INSERT INTO %s (id, name, started_at, doc) VALUES ($1, $2, $3, $4)`, s.table),
+		run.ID, run.Name, run.StartedAt, string(doc)); err != nil {
+		return err
+	}
+	_, err = s.db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE started_at < $1`, s.table), time.Now().Add(-retention))
+	return err
+}
+
+// FindAll returns the runs of the job, with the most recent first
+func (s *postgresStore) FindAll(name string, limit int) ([]Run, error) {
+	var runs []Run
+	if err := s.db.Find(&runs, fmt.Sprintf(`SELECT doc FROM %s WHERE name = $1 ORDER BY started_at DESC%s`,
+		s.table, database.Limit(limit)), name); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -0,0 +1,57 @@
+package jobsvc
+
+import (
+	"context"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/cronjob"
+	"github.com/rs/xid"
+)
+
+// Recorder saves the outcome of the runs of the cron jobs in the history
+type Recorder struct {
+	service Service
+	holder  string
+}
+
+// NewRecorder returns a recorder that saves the runs as run by the holder, which identifies the instance
+func NewRecorder(s Service, holder string) *Recorder {
+	return &Recorder{
+		service: s,
+		holder:  holder,
+	}
+}
+
+// Record saves the run. The error is logged by the service, since there is no one to return it to
+func (r *Recorder) Record(ctx context.Context, res cronjob.Result) {
+	run := Run{
+		ID:         xid.New().String(),
+		Name:       res.Name,
+		Holder:     r.holder,
+		StartedAt:  res.StartedAt.UTC(),
+		EndedAt:    res.EndedAt.UTC(),
+		DurationMs: milliseconds(res.EndedAt.Sub(res.StartedAt)),
+		Status:     StatusSucceeded,
+	}
+	if res.Err != nil {
+		run.Status = StatusFailed
+		run.Error = res.Err.Error()
+	}
+	for _, t := range res.Tasks {
+		task := Task{
+			Name:       t.Name,
+			DurationMs: milliseconds(t.EndedAt.Sub(t.StartedAt)),
+			Status:     StatusSucceeded,
+		}
+		if t.Err != nil {
+			task.Status = StatusFailed
+			task.Error = t.Err.Error()
+		}
+		run.Tasks = append(run.Tasks, task)
+	}
+	r.service.Save(ctx, run)
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
@@ -0,0 +1,46 @@
+package jobsvc
+
+import "time"
+
+// The outcome of a run or a task
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// The health of a job, which is degraded once the last runs have failed one after another
+const (
+	HealthUnknown  = "unknown"
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// Run represents a run of a job on the instance in the holder
+type Run struct {
+	ID         string    `json:"id" bson:"id"`
+	Name       string    `json:"name" bson:"name"`
+	Holder     string    `json:"holder,omitempty" bson:"holder,omitempty"`
+	StartedAt  time.Time `json:"startedAt" bson:"startedAt"`
+	EndedAt    time.Time `json:"endedAt" bson:"endedAt"`
+	DurationMs int64     `json:"durationMs" bson:"durationMs"`
+	Status     string    `json:"status" bson:"status"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	Tasks      []Task    `json:"tasks,omitempty" bson:"tasks,omitempty"`
+}
+
+// Task represents the outcome of a part of the run
+type Task struct {
+	Name       string `json:"name" bson:"name"`
+	DurationMs int64  `json:"durationMs" bson:"durationMs"`
+	Status     string `json:"status" bson:"status"`
+	Error      string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// Health represents the health of a job, from its last runs
+type Health struct {
+	Name                string `json:"name"`
+	Status              string `json:"status"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	LastRun             *Run   `json:"lastRun,omitempty"`
+	LastSucceededAt     string `json:"lastSucceededAt,omitempty"`
+}
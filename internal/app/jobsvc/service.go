@@ -0,0 +1,36 @@
+package jobsvc
+
+import "context"
+
+type (
+	// Service represents the job service
+	Service interface {
+		Save(ctx context.Context, run Run) error
+		FindAll(ctx context.Context, name string, limit int) ([]Run, error)
+		Health(ctx context.Context, name string, threshold int) (*Health, error)
+	}
+
+	service struct {
+		model Model
+	}
+)
+
+// NewService returns a new service
+func NewService(m Model) Service {
+	return &service{m}
+}
+
+func (s *service) Save(ctx context.Context, run Run) error {
+	return s.model.Save(run)
+}
+
+// FindAll returns the runs of the job, with the most recent first
+func (s *service) FindAll(ctx context.Context, name string, limit int) ([]Run, error) {
+	return s.model.FindAll(name, limit)
+}
+
+// Health returns the health of the job, which is degraded after the threshold of
+// consecutive failures
+func (s *service) Health(ctx context.Context, name string, threshold int) (*Health, error) {
+	return s.model.Health(name, threshold)
+}
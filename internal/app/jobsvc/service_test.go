@@ -0,0 +1,55 @@
+package jobsvc_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/jobsvc"
+)
+
+func TestHealth(t *testing.T) {
+	ctx := context.Background()
+	svc := jobsvc.NewWithStore(jobsvc.NewMemoryStore())
+
+	// The job succeeded once, then failed in every run since
+	now := time.Now().UTC()
+	for i := 0; i <= jobsvc.MaxHealthRuns; i++ {
+		status := jobsvc.StatusFailed
+		if i == 0 {
+			status = jobsvc.StatusSucceeded
+		}
+		if err := svc.Save(ctx, jobsvc.Run{
+			ID:        fmt.Sprintf("run-%d", i),
+			Name:      "job",
+			StartedAt: now.Add(time.Duration(i) * time.Minute),
+			Status:    status,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		threshold int
+		want      string
+		err       error
+	}{
+		{0, "", jobsvc.ErrInvalidThreshold},
+		{3, jobsvc.HealthDegraded, nil},
+		{jobsvc.MaxHealthRuns, jobsvc.HealthDegraded, nil},
+		{jobsvc.MaxHealthRuns + 1, "", jobsvc.ErrInvalidThreshold},
+	}
+	for _, tt := range tests {
+		h, err := svc.Health(ctx, "job", tt.threshold)
+		if err != tt.err {
+			t.Fatalf("threshold %d: want error %v, got %v", tt.threshold, tt.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		if h.Status != tt.want || h.ConsecutiveFailures != jobsvc.MaxHealthRuns {
+			t.Fatalf("threshold %d: want %s after %d failures, got %+v", tt.threshold, tt.want, jobsvc.MaxHealthRuns, h)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package jobsvc
+
+import (
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// retention is how long the runs are kept, after which Mongo removes them
+const retention = 30 * 24 * time.Hour
+
+type (
+	// Store represents the interface for the run store
+	Store interface {
+		Init() error
+		Insert(run Run) error
+		FindAll(name string, limit int) ([]Run, error)
+	}
+
+	store struct {
+		db         *database.DB
+		collection string
+	}
+)
+
+// NewStore returns a new run store
+func NewStore(db *database.DB, collection string) Store {
+	return &store{
+		db:         db,
+		collection: collection,
+	}
+}
+
+func (s *store) Init() error {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	if err := c.EnsureIndex(mgo.Index{
+		Key: []string{"name", "-startedAt"},
+	}); err != nil {
+		return err
+	}
+	return c.EnsureIndex(mgo.Index{
+		Key:         []string{"startedAt"},
+		ExpireAfter: retention,
+	})
+}
+
+func (s *store) Insert(run Run) error {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	return c.Insert(run)
+}
+
+// FindAll returns the runs of the job, with the most recent first
+func (s *store) FindAll(name string, limit int) ([]Run, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	var runs []Run
+	if err := c.Find(bson.M{"name": name}).
+		Sort("-startedAt").
+		Limit(limit).
+		All(&runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -0,0 +1,53 @@
+package jobsvc
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+)
+
+// Tracing decorates the service with tracing capabilities
+func Tracing() Middleware {
+	return func(s Service) Service {
+		return &tracingMiddleware{
+			service: s,
+		}
+	}
+}
+
+type tracingMiddleware struct {
+	service Service
+}
+
+func (m *tracingMiddleware) Save(ctx context.Context, run Run) error {
+	ctx, span := trace.StartSpan(ctx, "Save")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", run.Name),
+		trace.StringAttribute("status", run.Status))
+
+	return m.service.Save(ctx, run)
+}
+
+func (m *tracingMiddleware) FindAll(ctx context.Context, name string, limit int) ([]Run, error) {
+	ctx, span := trace.StartSpan(ctx, "FindAll")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", name),
+		trace.Int64Attribute("limit", int64(limit)))
+
+	return m.service.FindAll(ctx, name, limit)
+}
+
+func (m *tracingMiddleware) Health(ctx context.Context, name string, threshold int) (*Health, error) {
+	ctx, span := trace.StartSpan(ctx, "Health")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", name),
+		trace.Int64Attribute("threshold", int64(threshold)))
+
+	return m.service.Health(ctx, name, threshold)
+}
@@ -0,0 +1,89 @@
+package locksvc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+)
+
+// Locker holds the lease of a job while it runs, and renews it in the background so that
+// a job that runs for longer than the ttl keeps it
+type Locker struct {
+	service Service
+	holder  string
+	ttl     time.Duration
+}
+
+// NewLocker returns a locker that acquires the leases for the holder, which identifies the instance
+func NewLocker(s Service, holder string, ttl time.Duration) *Locker {
+	return &Locker{
+		service: s,
+		holder:  holder,
+		ttl:     ttl,
+	}
+}
+
+// DefaultHolder returns the hostname and the process id, which is unique across the
+// replicas even when they run on the same host
+func DefaultHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// Holder returns the name of the instance that the leases are acquired for
+func (l *Locker) Holder() string {
+	return l.holder
+}
+
+// Lock acquires the lease with the name, and renews it every third of the ttl. The context
+// returned is cancelled when the lease is lost, either because it has been acquired by
+// another instance, or because it could not be renewed before it expired. It carries the
+// fencing token of the lease, which the stores check to reject the writes made after the
+// lease is taken over. Unlock stops the renewal and releases the lease
+func (l *Locker) Lock(ctx context.Context, name string) (context.Context, func(), error) {
+	lease, err := l.service.Acquire(ctx, name, l.holder, l.ttl)
+	if err != nil {
+		return nil, nil, err
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(fence.NewContext(ctx, fence.Fence{Name: name, Token: lease.Token}))
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			res, err := l.service.Renew(ctx, *lease, l.ttl)
+			switch {
+			case err == nil:
+				lease = res
+			case err == ErrLockLost, !lease.Held():
+				cancel()
+				return
+			}
+		}
+	}()
+
+	unlock := func() {
+		close(done)
+		<-stopped
+		cancel()
+		l.service.Release(parent, *lease)
+	}
+	return ctx, unlock, nil
+}
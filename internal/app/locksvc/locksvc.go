@@ -0,0 +1,18 @@
+// Package locksvc stores the leases on the cron jobs, so that a job runs on one instance
+// at a time when several replicas are deployed
+package locksvc
+
+import "github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+
+// New returns a new lock service backed by mgo
+func New(db *database.DB, ms ...Middleware) Service {
+	return NewWithStore(NewStore(db, database.Locks), ms...)
+}
+
+// NewWithStore returns a new lock service backed by the store of any storage driver
+func NewWithStore(store Store, ms ...Middleware) Service {
+	model := NewModel(store)
+	service := NewService(model)
+	service = Decorate(service, ms...)
+	return service
+}
@@ -0,0 +1,90 @@
+package locksvc
+
+import (
+	"context"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Logging adds logging capabilities to the service
+func Logging(l *logger.Logger) Middleware {
+	return func(s Service) Service {
+		return &loggingMiddleware{
+			service: s,
+			logger:  l,
+		}
+	}
+}
+
+type loggingMiddleware struct {
+	logger  *logger.Logger
+	service Service
+}
+
+func (m *loggingMiddleware) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (res *Lease, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("Acquire"),
+			logger.Duration(start),
+			zap.String("name", name),
+			zap.String("holder", holder),
+			zap.Duration("ttl", ttl))
+
+		// A lease held elsewhere is expected whenever the replicas race for the job
+		if err == ErrLockHeld {
+			L.Info("acquire lock", zap.Bool("held", true))
+			return
+		}
+		if res != nil {
+			L = L.With(zap.Int64("token", res.Token))
+		}
+		logger.Maybe(L, "acquire lock", err)
+	}(time.Now())
+
+	return m.service.Acquire(ctx, name, holder, ttl)
+}
+
+func (m *loggingMiddleware) Renew(ctx context.Context, lease Lease, ttl time.Duration) (res *Lease, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("Renew"),
+			logger.Duration(start),
+			zap.String("name", lease.Name),
+			zap.String("holder", lease.Holder),
+			zap.Int64("token", lease.Token))
+
+		logger.Maybe(L, "renew lock", err)
+	}(time.Now())
+
+	return m.service.Renew(ctx, lease, ttl)
+}
+
+func (m *loggingMiddleware) Release(ctx context.Context, lease Lease) (err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("Release"),
+			logger.Duration(start),
+			zap.String("name", lease.Name),
+			zap.String("holder", lease.Holder),
+			zap.Int64("token", lease.Token))
+
+		logger.Maybe(L, "release lock", err)
+	}(time.Now())
+
+	return m.service.Release(ctx, lease)
+}
+
+func (m *loggingMiddleware) FindAll(ctx context.Context) (res []Lease, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("FindAll"),
+			logger.Duration(start),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "find all locks", err)
+	}(time.Now())
+
+	return m.service.FindAll(ctx)
+}
@@ -0,0 +1,126 @@
+package locksvc
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/kv"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// memoryStore keeps the leases in memory, for the tests and the demo mode, where the
+// instances that share the store run in the same process. With a bucket, the leases are
+// written through to it as well
+type memoryStore struct {
+	sync.Mutex
+	leases map[string]Lease
+	bucket kv.Bucket
+}
+
+// NewMemoryStore returns an empty store that keeps the leases in memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		leases: make(map[string]Lease),
+	}
+}
+
+// NewKVStore returns a store that keeps the leases in memory and in the bucket, with the
+// leases already in the bucket, so that the tokens keep increasing across restarts
+func NewKVStore(b kv.Bucket) (Store, error) {
+	s := &memoryStore{
+		leases: make(map[string]Lease),
+		bucket: b,
+	}
+	if err := b.ForEach(func(key string, value []byte) error {
+		var lease Lease
+		if err := bson.Unmarshal(value, &lease); err != nil {
+			return err
+		}
+		s.leases[key] = lease
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryStore) Init() error {
+	return nil
+}
+
+func (s *memoryStore) Acquire(name, holder string, ttl time.Duration) (*Lease, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now().UTC()
+	lease := s.leases[name]
+	if lease.Held() {
+		return nil, ErrLockHeld
+	}
+	lease = Lease{
+		Name:       name,
+		Holder:     holder,
+		Token:      lease.Token + 1,
+		AcquiredAt: now,
+		RenewedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	if err := s.save(lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+func (s *memoryStore) Renew(lease Lease, ttl time.Duration) (*Lease, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	curr, ok := s.leases[lease.Name]
+	if !ok || curr.Holder != lease.Holder || curr.Token != lease.Token {
+		return nil, ErrLockLost
+	}
+	now := time.Now().UTC()
+	curr.RenewedAt = now
+	curr.ExpiresAt = now.Add(ttl)
+	if err := s.save(curr); err != nil {
+		return nil, err
+	}
+	return &curr, nil
+}
+
+func (s *memoryStore) Release(lease Lease) error {
+	s.Lock()
+	defer s.Unlock()
+
+	curr, ok := s.leases[lease.Name]
+	if !ok || curr.Holder != lease.Holder || curr.Token != lease.Token {
+		return nil
+	}
+	curr.ExpiresAt = time.Now().UTC()
+	return s.save(curr)
+}
+
+func (s *memoryStore) FindAll() ([]Lease, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	leases := make([]Lease, 0, len(s.leases))
+	for _, lease := range s.leases {
+		leases = append(leases, lease)
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].Name < leases[j].Name
+	})
+	return leases, nil
+}
+
+// save writes the lease through to the bucket, then keeps it in memory
+func (s *memoryStore) save(lease Lease) error {
+	if err := kv.Save(s.bucket, map[string]interface{}{lease.Name: lease}); err != nil {
+		return err
+	}
+	s.leases[lease.Name] = lease
+	return nil
+}
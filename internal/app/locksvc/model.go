@@ -0,0 +1,88 @@
+package locksvc
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+)
+
+// minTTL is the shortest lease allowed, so that the lease outlives the call to renew it
+const minTTL = time.Second
+
+var (
+	ErrInvalidName   = apperr.Validation("name is required")
+	ErrInvalidHolder = apperr.Validation("holder is required")
+	ErrInvalidTTL    = apperr.Validation("ttl must be at least one second")
+
+	// ErrLockHeld is returned when the lease is held by another instance, or by an earlier
+	// run that has not finished
+	ErrLockHeld = errors.New("lock is held")
+
+	// ErrLockLost is returned when the lease has been acquired by another instance after
+	// it expired
+	ErrLockLost = errors.New("lock is lost")
+)
+
+type (
+	// Model contains the validation for the leases
+	Model interface {
+		Init() error
+		Acquire(name, holder string, ttl time.Duration) (*Lease, error)
+		Renew(lease Lease, ttl time.Duration) (*Lease, error)
+		Release(lease Lease) error
+		FindAll() ([]Lease, error)
+	}
+
+	model struct {
+		store Store
+	}
+)
+
+// NewModel returns a new model with the store
+func NewModel(store Store) Model {
+	m := model{store: store}
+	if err := m.Init(); err != nil {
+		log.Fatal(err)
+	}
+	return &m
+}
+
+func (m *model) Init() error {
+	return m.store.Init()
+}
+
+func (m *model) Acquire(name, holder string, ttl time.Duration) (*Lease, error) {
+	if name == "" {
+		return nil, ErrInvalidName
+	}
+	if holder == "" {
+		return nil, ErrInvalidHolder
+	}
+	if ttl < minTTL {
+		return nil, ErrInvalidTTL
+	}
+	return m.store.Acquire(name, holder, ttl)
+}
+
+func (m *model) Renew(lease Lease, ttl time.Duration) (*Lease, error) {
+	if lease.Name == "" {
+		return nil, ErrInvalidName
+	}
+	if ttl < minTTL {
+		return nil, ErrInvalidTTL
+	}
+	return m.store.Renew(lease, ttl)
+}
+
+func (m *model) Release(lease Lease) error {
+	if lease.Name == "" {
+		return ErrInvalidName
+	}
+	return m.store.Release(lease)
+}
+
+func (m *model) FindAll() ([]Lease, error) {
+	return m.store.FindAll()
+}
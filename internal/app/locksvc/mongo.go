@@ -0,0 +1,137 @@
+package locksvc
+
+import (
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoStore is the store on the official mongo driver, with the same documents and indexes
+// as the mgo store
+type mongoStore struct {
+	db         *database.Mongo
+	collection string
+}
+
+// NewMongoStore returns a new lease store on the official mongo driver
+func NewMongoStore(db *database.Mongo, collection string) Store {
+	return &mongoStore{
+		db:         db,
+		collection: collection,
+	}
+}
+
+func (s *mongoStore) Init() error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	_, err := s.db.Collection(s.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    database.Sort("name"),
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Acquire takes the lease if it has expired, or creates it if it does not exist. While the
+// lease is held, the upsert conflicts with the existing lease on the unique name instead
+func (s *mongoStore) Acquire(name, holder string, ttl time.Duration) (*Lease, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	var lease Lease
+	err := s.db.Collection(s.collection).FindOneAndUpdate(ctx,
+		bson.M{
+			"name":      name,
+			"expiresAt": bson.M{"$lte": now},
+		},
+		bson.M{
+			"$set": bson.M{
+				"holder":     holder,
+				"acquiredAt": now,
+				"renewedAt":  now,
+				"expiresAt":  now.Add(ttl),
+			},
+			"$inc": bson.M{"token": 1},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&lease)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrLockHeld
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// Renew extends the lease, as long as it has not been acquired by anyone else since
+func (s *mongoStore) Renew(lease Lease, ttl time.Duration) (*Lease, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	var res Lease
+	err := s.db.Collection(s.collection).FindOneAndUpdate(ctx,
+		bson.M{
+			"name":   lease.Name,
+			"holder": lease.Holder,
+			"token":  lease.Token,
+		},
+		bson.M{
+			"$set": bson.M{
+				"renewedAt": now,
+				"expiresAt": now.Add(ttl),
+			},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&res)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrLockLost
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Release expires the lease. The lease is kept rather than removed, so that the token
+// keeps increasing across the holders
+func (s *mongoStore) Release(lease Lease) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	_, err := s.db.Collection(s.collection).UpdateOne(ctx,
+		bson.M{
+			"name":   lease.Name,
+			"holder": lease.Holder,
+			"token":  lease.Token,
+		},
+		bson.M{
+			"$set": bson.M{
+				"expiresAt": time.Now().UTC().Truncate(time.Millisecond),
+			},
+		},
+	)
+	return err
+}
+
+func (s *mongoStore) FindAll() ([]Lease, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	cur, err := s.db.Collection(s.collection).Find(ctx, bson.M{},
+		options.Find().SetSort(database.Sort("name")))
+	if err != nil {
+		return nil, err
+	}
+	var leases []Lease
+	if err := cur.All(ctx, &leases); err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
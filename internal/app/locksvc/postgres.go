@@ -0,0 +1,116 @@
+package locksvc
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+)
+
+// leaseColumns are the columns of the table, in the order of the fields of the lease
+const leaseColumns = `name, holder, token, acquired_at, renewed_at, expires_at`
+
+// postgresStore keeps the leases in a table of their own, with a column for each field, so
+// that the expiry is compared as a timestamp
+type postgresStore struct {
+	db    *database.Postgres
+	table string
+}
+
+// NewPostgresStore returns a new lease store on postgres
+func NewPostgresStore(db *database.Postgres, table string) Store {
+	return &postgresStore{
+		db:    db,
+		table: table,
+	}
+}
+
+func (s *postgresStore) Init() error {
+	_, err := s.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		name text PRIMARY KEY,
+		holder text NOT NULL,
+		token bigint NOT NULL,
+		acquired_at timestamptz NOT NULL,
+		renewed_at timestamptz NOT NULL,
+		expires_at timestamptz NOT NULL
+	)`, s.table))
+	return err
+}
+
+// Acquire takes the lease if it has expired, or creates it if it does not exist. While the
+// lease is held, the conflicting insert updates nothing instead
+func (s *postgresStore) Acquire(name, holder string, ttl time.Duration) (*Lease, error) {
+	// Postgres keeps the timestamps in microseconds
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	lease, err := s.queryRow(fmt.Sprintf(`INSERT INTO %s AS l (%s) VALUES ($1, $2, 1, $3, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET
+			holder = EXCLUDED.holder,
+			token = l.token + 1,
+			acquired_at = EXCLUDED.acquired_at,
+			renewed_at = EXCLUDED.renewed_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE l.expires_at <= $3
+		RETURNING %[2]s`, s.table, leaseColumns), name, holder, now, now.Add(ttl))
+	if err == sql.ErrNoRows {
+		return nil, ErrLockHeld
+	}
+	return lease, err
+}
+
+// Renew extends the lease, as long as it has not been acquired by anyone else since
+func (s *postgresStore) Renew(lease Lease, ttl time.Duration) (*Lease, error) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	res, err := s.queryRow(fmt.Sprintf(`UPDATE %s SET renewed_at = $1, expires_at = $2
+		WHERE name = $3 AND holder = $4 AND token = $5
+		RETURNING %s`, s.table, leaseColumns), now, now.Add(ttl), lease.Name, lease.Holder, lease.Token)
+	if err == sql.ErrNoRows {
+		return nil, ErrLockLost
+	}
+	return res, err
+}
+
+// Release expires the lease. The lease is kept rather than removed, so that the token
+// keeps increasing across the holders
+func (s *postgresStore) Release(lease Lease) error {
+	_, err := s.db.Exec(fmt.Sprintf(`UPDATE %s SET expires_at = $1 WHERE name = $2 AND holder = $3 AND token = $4`, s.table),
+		time.Now().UTC().Truncate(time.Microsecond), lease.Name, lease.Holder, lease.Token)
+	return err
+}
+
+func (s *postgresStore) FindAll() ([]Lease, error) {
+	var leases []Lease
+	err := s.db.Rows(func(rows *sql.Rows) error {
+		lease, err := scanLease(rows)
+		if err != nil {
+			return err
+		}
+		leases = append(leases, *lease)
+		return nil
+	}, fmt.Sprintf(`SELECT %s FROM %s ORDER BY name COLLATE "C"`, leaseColumns, s.table))
+	return leases, err
+}
+
+// queryRow returns the lease of the row returned by the statement, or sql.ErrNoRows
+func (s *postgresStore) queryRow(query string, args ...interface{}) (*Lease, error) {
+	var lease *Lease
+	err := sql.ErrNoRows
+	if qerr := s.db.Rows(func(rows *sql.Rows) error {
+		lease, err = scanLease(rows)
+		return err
+	}, query, args...); qerr != nil {
+		return nil, qerr
+	}
+	return lease, err
+}
+
+func scanLease(rows *sql.Rows) (*Lease, error) {
+	var lease Lease
+	if err := rows.Scan(&lease.Name, &lease.Holder, &lease.Token, &lease.AcquiredAt, &lease.RenewedAt, &lease.ExpiresAt); err != nil {
+		return nil, err
+	}
+	lease.AcquiredAt = lease.AcquiredAt.UTC()
+	lease.RenewedAt = lease.RenewedAt.UTC()
+	lease.ExpiresAt = lease.ExpiresAt.UTC()
+	return &lease, nil
+}
@@ -0,0 +1,20 @@
+package locksvc
+
+import "time"
+
+// Lease represents the lock on a job, held by an instance until it expires or is released.
+// Token is the fencing token, which increases every time the lease is acquired, so that a
+// holder whose lease has been taken over can tell from the token that it no longer holds it
+type Lease struct {
+	Name       string    `json:"name" bson:"name"`
+	Holder     string    `json:"holder" bson:"holder"`
+	Token      int64     `json:"token" bson:"token"`
+	AcquiredAt time.Time `json:"acquiredAt" bson:"acquiredAt"`
+	RenewedAt  time.Time `json:"renewedAt" bson:"renewedAt"`
+	ExpiresAt  time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+// Held returns true if the lease has neither expired nor been released
+func (l Lease) Held() bool {
+	return time.Now().Before(l.ExpiresAt)
+}
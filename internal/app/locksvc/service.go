@@ -0,0 +1,47 @@
+package locksvc
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// Service represents the lock service
+	Service interface {
+		Acquire(ctx context.Context, name, holder string, ttl time.Duration) (*Lease, error)
+		Renew(ctx context.Context, lease Lease, ttl time.Duration) (*Lease, error)
+		Release(ctx context.Context, lease Lease) error
+		FindAll(ctx context.Context) ([]Lease, error)
+	}
+
+	service struct {
+		model Model
+	}
+)
+
+// NewService returns a new service
+func NewService(m Model) Service {
+	return &service{m}
+}
+
+// Acquire takes the lease with the name for the ttl, unless it is held. ErrLockHeld is
+// returned when it is held, even by the same holder
+func (s *service) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (*Lease, error) {
+	return s.model.Acquire(name, holder, ttl)
+}
+
+// Renew extends the lease by the ttl from now. ErrLockLost is returned when the lease has
+// been acquired by another holder since, and the holder should stop what it is doing
+func (s *service) Renew(ctx context.Context, lease Lease, ttl time.Duration) (*Lease, error) {
+	return s.model.Renew(lease, ttl)
+}
+
+// Release gives up the lease, so that the next holder does not have to wait for it to expire
+func (s *service) Release(ctx context.Context, lease Lease) error {
+	return s.model.Release(lease)
+}
+
+// FindAll returns the leases by name, including the ones that have expired
+func (s *service) FindAll(ctx context.Context) ([]Lease, error) {
+	return s.model.FindAll()
+}
@@ -0,0 +1,139 @@
+package locksvc
+
+import (
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type (
+	// Store represents the interface for the lease store
+	Store interface {
+		Init() error
+		Acquire(name, holder string, ttl time.Duration) (*Lease, error)
+		Renew(lease Lease, ttl time.Duration) (*Lease, error)
+		Release(lease Lease) error
+		FindAll() ([]Lease, error)
+	}
+
+	store struct {
+		db         *database.DB
+		collection string
+	}
+)
+
+// NewStore returns a new lease store
+func NewStore(db *database.DB, collection string) Store {
+	return &store{
+		db:         db,
+		collection: collection,
+	}
+}
+
+func (s *store) Init() error {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	return c.EnsureIndex(mgo.Index{
+		Key:    []string{"name"},
+		Unique: true,
+	})
+}
+
+// Acquire takes the lease if it has expired, or creates it if it does not exist. While the
+// lease is held, the upsert conflicts with the existing lease on the unique name instead
+func (s *store) Acquire(name, holder string, ttl time.Duration) (*Lease, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	// Mongo keeps the dates in milliseconds
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	var lease Lease
+	_, err := c.Find(bson.M{
+		"name":      name,
+		"expiresAt": bson.M{"$lte": now},
+	}).Apply(mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{
+				"holder":     holder,
+				"acquiredAt": now,
+				"renewedAt":  now,
+				"expiresAt":  now.Add(ttl),
+			},
+			"$inc": bson.M{"token": 1},
+		},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &lease)
+	if mgo.IsDup(err) {
+		return nil, ErrLockHeld
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// Renew extends the lease, as long as it has not been acquired by anyone else since
+func (s *store) Renew(lease Lease, ttl time.Duration) (*Lease, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	var res Lease
+	_, err := c.Find(bson.M{
+		"name":   lease.Name,
+		"holder": lease.Holder,
+		"token":  lease.Token,
+	}).Apply(mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{
+				"renewedAt": now,
+				"expiresAt": now.Add(ttl),
+			},
+		},
+		ReturnNew: true,
+	}, &res)
+	if err == mgo.ErrNotFound {
+		return nil, ErrLockLost
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Release expires the lease. The lease is kept rather than removed, so that the token
+// keeps increasing across the holders
+func (s *store) Release(lease Lease) error {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	err := c.Update(bson.M{
+		"name":   lease.Name,
+		"holder": lease.Holder,
+		"token":  lease.Token,
+	}, bson.M{
+		"$set": bson.M{
+			"expiresAt": time.Now().UTC().Truncate(time.Millisecond),
+		},
+	})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (s *store) FindAll() ([]Lease, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	var leases []Lease
+	if err := c.Find(nil).Sort("name").All(&leases); err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
@@ -0,0 +1,61 @@
+package locksvc
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+// Tracing decorates the service with tracing capabilities
+func Tracing() Middleware {
+	return func(s Service) Service {
+		return &tracingMiddleware{
+			service: s,
+		}
+	}
+}
+
+type tracingMiddleware struct {
+	service Service
+}
+
+func (m *tracingMiddleware) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (*Lease, error) {
+	ctx, span := trace.StartSpan(ctx, "Acquire")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", name),
+		trace.StringAttribute("holder", holder))
+
+	return m.service.Acquire(ctx, name, holder, ttl)
+}
+
+func (m *tracingMiddleware) Renew(ctx context.Context, lease Lease, ttl time.Duration) (*Lease, error) {
+	ctx, span := trace.StartSpan(ctx, "Renew")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", lease.Name),
+		trace.Int64Attribute("token", lease.Token))
+
+	return m.service.Renew(ctx, lease, ttl)
+}
+
+func (m *tracingMiddleware) Release(ctx context.Context, lease Lease) error {
+	ctx, span := trace.StartSpan(ctx, "Release")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("name", lease.Name),
+		trace.Int64Attribute("token", lease.Token))
+
+	return m.service.Release(ctx, lease)
+}
+
+func (m *tracingMiddleware) FindAll(ctx context.Context) ([]Lease, error) {
+	ctx, span := trace.StartSpan(ctx, "FindAll")
+	defer span.End()
+
+	return m.service.FindAll(ctx)
+}
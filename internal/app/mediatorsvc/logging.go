@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/logger"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/region"
 
 	"go.uber.org/zap"
 )
@@ -24,19 +25,20 @@ type loggingMiddleware struct {
 	logger  *logger.Logger
 }
 
-func (m *loggingMiddleware) FetchUsers(ctx context.Context, location string, months int, perPage int) (err error) {
+func (m *loggingMiddleware) FetchUsers(ctx context.Context, r region.Region, months int, perPage int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("FetchUsers"),
 			logger.Duration(start),
-			zap.String("location", location),
+			zap.String("region", r.Name),
+			zap.Strings("locations", r.Locations),
 			zap.Int("months", months),
 			zap.Int("perPage", perPage))
 
 		logger.Maybe(L, "fetch users", err)
 	}(time.Now())
 
-	return m.service.FetchUsers(ctx, location, months, perPage)
+	return m.service.FetchUsers(ctx, r, months, perPage)
 }
 
 func (m *loggingMiddleware) FetchRepos(ctx context.Context, userPerPage, repoPerPage int, reset bool) (err error) {
@@ -53,118 +55,140 @@ func (m *loggingMiddleware) FetchRepos(ctx context.Context, userPerPage, repoPer
 	return m.service.FetchRepos(ctx, userPerPage, repoPerPage, reset)
 }
 
-func (m *loggingMiddleware) UpdateUserCount(ctx context.Context) (err error) {
+func (m *loggingMiddleware) RefreshRepos(ctx context.Context, perPage int) (err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("RefreshRepos"),
+			logger.Duration(start),
+			zap.Int("perPage", perPage))
+
+		logger.Maybe(L, "refresh repos", err)
+	}(time.Now())
+
+	return m.service.RefreshRepos(ctx, perPage)
+}
+
+func (m *loggingMiddleware) UpdateUserCount(ctx context.Context, region string) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateUserCount"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "update user count", err)
 	}(time.Now())
 
-	return m.service.UpdateUserCount(ctx)
+	return m.service.UpdateUserCount(ctx, region)
 }
 
-func (m *loggingMiddleware) UpdateRepoCount(ctx context.Context) (err error) {
+func (m *loggingMiddleware) UpdateRepoCount(ctx context.Context, region string) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateRepoCount"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "update repo count", err)
 	}(time.Now())
 
-	return m.service.UpdateRepoCount(ctx)
+	return m.service.UpdateRepoCount(ctx, region)
 }
 
-func (m *loggingMiddleware) UpdateReposMostRecent(ctx context.Context, perPage int) (err error) {
+func (m *loggingMiddleware) UpdateReposMostRecent(ctx context.Context, region string, perPage int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateReposMostRecent"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "update repos most recent", err)
 	}(time.Now())
 
-	return m.service.UpdateReposMostRecent(ctx, perPage)
+	return m.service.UpdateReposMostRecent(ctx, region, perPage)
 }
 
-func (m *loggingMiddleware) UpdateRepoCountByUser(ctx context.Context, perPage int) (err error) {
+func (m *loggingMiddleware) UpdateRepoCountByUser(ctx context.Context, region string, perPage int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateRepoCountByUser"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("perPage", perPage))
 
 		logger.Maybe(L, "update repo count by user", err)
 	}(time.Now())
 
-	return m.service.UpdateRepoCountByUser(ctx, perPage)
+	return m.service.UpdateRepoCountByUser(ctx, region, perPage)
 }
 
-func (m *loggingMiddleware) UpdateReposMostStars(ctx context.Context, perPage int) (err error) {
+func (m *loggingMiddleware) UpdateReposMostStars(ctx context.Context, region string, perPage int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateReposMostStars"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("perPage", perPage))
 
 		logger.Maybe(L, "update repos most stars", err)
 	}(time.Now())
 
-	return m.service.UpdateReposMostStars(ctx, perPage)
+	return m.service.UpdateReposMostStars(ctx, region, perPage)
 }
 
-func (m *loggingMiddleware) UpdateReposMostForks(ctx context.Context, perPage int) (err error) {
+func (m *loggingMiddleware) UpdateReposMostForks(ctx context.Context, region string, perPage int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateReposMostForks"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("perPage", perPage))
 
 		logger.Maybe(L, "update repos most forks", err)
 	}(time.Now())
 
-	return m.service.UpdateReposMostForks(ctx, perPage)
+	return m.service.UpdateReposMostForks(ctx, region, perPage)
 }
 
-func (m *loggingMiddleware) UpdateLanguagesMostPopular(ctx context.Context, perPage int) (err error) {
+func (m *loggingMiddleware) UpdateLanguagesMostPopular(ctx context.Context, region string, perPage int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateLanguagesMostPopular"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("perPage", perPage))
 
 		logger.Maybe(L, "update languages most popular", err)
 	}(time.Now())
 
-	return m.service.UpdateLanguagesMostPopular(ctx, perPage)
+	return m.service.UpdateLanguagesMostPopular(ctx, region, perPage)
 }
 
-func (m *loggingMiddleware) UpdateMostRecentReposByLanguage(ctx context.Context, perPage int) (err error) {
+func (m *loggingMiddleware) UpdateMostRecentReposByLanguage(ctx context.Context, region string, perPage int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateMostRecentReposByLanguage"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("perPage", perPage))
 
 		logger.Maybe(L, "update most recent repos by language", err)
 	}(time.Now())
 
-	return m.service.UpdateMostRecentReposByLanguage(ctx, perPage)
+	return m.service.UpdateMostRecentReposByLanguage(ctx, region, perPage)
 }
 
-func (m *loggingMiddleware) UpdateReposByLanguage(ctx context.Context, perPage int) (err error) {
+func (m *loggingMiddleware) UpdateReposByLanguage(ctx context.Context, region string, perPage int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateReposByLanguage"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("perPage", perPage))
 
 		logger.Maybe(L, "update repos by language", err)
 	}(time.Now())
 
-	return m.service.UpdateReposByLanguage(ctx, perPage)
+	return m.service.UpdateReposByLanguage(ctx, region, perPage)
 }
 
 func (m *loggingMiddleware) UpdateProfile(ctx context.Context, numWorkers int) (err error) {
@@ -180,38 +204,54 @@ func (m *loggingMiddleware) UpdateProfile(ctx context.Context, numWorkers int) (
 	return m.service.UpdateProfile(ctx, numWorkers)
 }
 
-func (m *loggingMiddleware) UpdateMatches(ctx context.Context) (err error) {
+func (m *loggingMiddleware) UpdateMatches(ctx context.Context, numWorkers int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateMatches"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.Int("numWorkers", numWorkers))
 
 		logger.Maybe(L, "update matches", err)
 	}(time.Now())
 
-	return m.service.UpdateMatches(ctx)
+	return m.service.UpdateMatches(ctx, numWorkers)
+}
+
+func (m *loggingMiddleware) UpdateSimilarRepos(ctx context.Context, numWorkers int) (err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("UpdateSimilarRepos"),
+			logger.Duration(start),
+			zap.Int("numWorkers", numWorkers))
+
+		logger.Maybe(L, "update similar repos", err)
+	}(time.Now())
+
+	return m.service.UpdateSimilarRepos(ctx, numWorkers)
 }
 
-func (m *loggingMiddleware) UpdateUsersByCompany(ctx context.Context, min, max int) (err error) {
+func (m *loggingMiddleware) UpdateUsersByCompany(ctx context.Context, region string, min, max int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateUsersByCompany"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "update users by company", err)
 	}(time.Now())
 
-	return m.service.UpdateUsersByCompany(ctx, min, max)
+	return m.service.UpdateUsersByCompany(ctx, region, min, max)
 }
 
-func (m *loggingMiddleware) UpdateCompanyCount(ctx context.Context) (err error) {
+func (m *loggingMiddleware) UpdateCompanyCount(ctx context.Context, region string) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("UpdateCompanyCount"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "update company count", err)
 	}(time.Now())
 
-	return m.service.UpdateCompanyCount(ctx)
+	return m.service.UpdateCompanyCount(ctx, region)
 }
@@ -0,0 +1,62 @@
+package mediatorsvc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/recsys"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+)
+
+func newLanguageUser(login string, languages ...schema.LanguageCount) usersvc.User {
+	var user usersvc.User
+	user.Login = login
+	user.Languages = languages
+	return user
+}
+
+func TestToProfileLanguages(t *testing.T) {
+	// aisyah and hafiz mostly write Go, though aisyah has more Python repos. nurul has the
+	// same repos as aisyah, but mostly writes Python
+	users := []usersvc.User{
+		newLanguageUser("aisyah",
+			schema.LanguageCount{Name: "Go", Count: 1, Bytes: 900000},
+			schema.LanguageCount{Name: "Python", Count: 3, Bytes: 100000}),
+		newLanguageUser("hafiz",
+			schema.LanguageCount{Name: "Go", Count: 3, Bytes: 90000},
+			schema.LanguageCount{Name: "Python", Count: 1, Bytes: 10000}),
+		newLanguageUser("nurul",
+			schema.LanguageCount{Name: "Go", Count: 1, Bytes: 100000},
+			schema.LanguageCount{Name: "Python", Count: 3, Bytes: 900000}),
+		newLanguageUser("wei",
+			schema.LanguageCount{Name: "Rust", Count: 2, Bytes: 50000}),
+	}
+	profiles := make([]recsys.Profile, len(users))
+	for i, user := range users {
+		profiles[i] = toProfile(user)
+	}
+	if got, want := profiles[0].Terms["languages"], profiles[1].Terms["languages"]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want the same weights for the same shares of bytes, got %v and %v", got, want)
+	}
+
+	r := recsys.NewTFIDF(recsys.Weights{"languages": 1})
+	r.Fit(profiles)
+	matches := recsys.TopK(r, profiles[0], profiles, 2)
+	if got, want := []string{matches[0].ID, matches[1].ID}, []string{"hafiz", "nurul"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want the users matched by their bytes %v, got %v", want, got)
+	}
+
+	// Without the bytes, the languages are weighted by the repos
+	for i := range users {
+		for j := range users[i].Languages {
+			users[i].Languages[j].Bytes = 0
+		}
+		profiles[i] = toProfile(users[i])
+	}
+	r.Fit(profiles)
+	matches = recsys.TopK(r, profiles[0], profiles, 2)
+	if got, want := []string{matches[0].ID, matches[1].ID}, []string{"nurul", "hafiz"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want the users matched by their repos %v, got %v", want, got)
+	}
+}
@@ -8,41 +8,52 @@ import (
 	"sync"
 	"time"
 
+	"github.com/alextanhongpin/go-github-scraper/internal/app/checkpointsvc"
 	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
 	"github.com/alextanhongpin/go-github-scraper/internal/app/statsvc"
 	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/bow"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
-	"github.com/alextanhongpin/go-github-scraper/internal/pkg/heapsort"
-	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/recsys"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/region"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 )
 
 type (
 	// Service represents the methods the mediator service must implement
 	Service interface {
-		FetchUsers(ctx context.Context, location string, months int, perPage int) error
+		FetchUsers(ctx context.Context, r region.Region, months int, perPage int) error
 		FetchRepos(ctx context.Context, userPerPage, repoPerPage int, reset bool) error
-		UpdateUserCount(ctx context.Context) error
-		UpdateRepoCount(ctx context.Context) error
-		UpdateReposMostRecent(ctx context.Context, perPage int) error
-		UpdateRepoCountByUser(ctx context.Context, perPage int) error
-		UpdateReposMostStars(ctx context.Context, perPage int) error
-		UpdateReposMostForks(ctx context.Context, perPage int) error
-		UpdateLanguagesMostPopular(ctx context.Context, perPage int) error
-		UpdateMostRecentReposByLanguage(ctx context.Context, perPage int) error
-		UpdateReposByLanguage(ctx context.Context, perPage int) error
+		RefreshRepos(ctx context.Context, perPage int) error
+		UpdateUserCount(ctx context.Context, region string) error
+		UpdateRepoCount(ctx context.Context, region string) error
+		UpdateReposMostRecent(ctx context.Context, region string, perPage int) error
+		UpdateRepoCountByUser(ctx context.Context, region string, perPage int) error
+		UpdateReposMostStars(ctx context.Context, region string, perPage int) error
+		UpdateReposMostForks(ctx context.Context, region string, perPage int) error
+		UpdateLanguagesMostPopular(ctx context.Context, region string, perPage int) error
+		UpdateMostRecentReposByLanguage(ctx context.Context, region string, perPage int) error
+		UpdateReposByLanguage(ctx context.Context, region string, perPage int) error
 		UpdateProfile(ctx context.Context, numWorkers int) error
-		UpdateMatches(ctx context.Context) error
-		UpdateUsersByCompany(ctx context.Context, min, max int) error
-		UpdateCompanyCount(ctx context.Context) error
+		UpdateMatches(ctx context.Context, numWorkers int) error
+		UpdateSimilarRepos(ctx context.Context, numWorkers int) error
+		UpdateUsersByCompany(ctx context.Context, region string, min, max int) error
+		UpdateCompanyCount(ctx context.Context, region string) error
 	}
 
 	// Mediator holds the services in used
 	Mediator struct {
-		Github github.Service
-		Stat   statsvc.Service
-		Repo   reposvc.Service
-		User   usersvc.Service
+		Checkpoint checkpointsvc.Service
+		Github     github.Service
+		Stat       statsvc.Service
+		Repo       reposvc.Service
+		User       usersvc.Service
+
+		// Recommender computes the similarity of the users for the matches
+		Recommender recsys.Recommender
+
+		// RepoRecommender computes the similarity of the repos
+		RepoRecommender recsys.Recommender
 	}
 
 	service struct {
@@ -67,39 +78,152 @@ func makeEndDate(start string, months int) string {
 	return t2.Format("2006-01-02")
 }
 
-func (s *service) FetchUsers(ctx context.Context, location string, months int, perPage int) error {
-	start, _ := s.User.FindLastCreated(ctx)
-	end := makeEndDate(start, months)
-
-	users, err := s.Github.FetchUsersCursor(ctx, location, start, end, perPage)
+// nextDate returns the day after the date in the format YYYY-MM-DD
+func nextDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
 	if err != nil {
+		return date
+	}
+	return t.AddDate(0, 0, 1).Format("2006-01-02")
+}
+
+// usersCheckpoint returns the checkpoint key for the users crawl of the location
+func usersCheckpoint(location string) string {
+	return "users:" + location
+}
+
+// reposCheckpoint returns the checkpoint key for the repos crawl of the user
+func reposCheckpoint(login string) string {
+	return "repos:" + login
+}
+
+// FetchUsers crawls the users in every location of the region. A location that fails
+// is retried from its checkpoint in the next run, and does not hold back the rest
+func (s *service) FetchUsers(ctx context.Context, r region.Region, months int, perPage int) error {
+	var lastErr error
+	for _, location := range r.Locations {
+		if err := s.fetchUsers(ctx, r.Name, location, months, perPage); err != nil {
+			if isFatal(err) {
+				return err
+			}
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// fetchUsers crawls the users in the location page by page, storing each page together with
+// the checkpoint as soon as it arrives. A crawl that did not complete is resumed from the checkpoint
+func (s *service) fetchUsers(ctx context.Context, region, location string, months int, perPage int) error {
+	key := usersCheckpoint(location)
+
+	var start, end, until, cursor string
+	if cp, ok := s.Checkpoint.Find(ctx, key); ok {
+		start, end, until, cursor = cp.Start, cp.End, cp.Until, cp.Cursor
+	} else {
+		start, _ = s.User.FindLastCreated(ctx, location)
+		end = makeEndDate(start, months)
+		until = end
+	}
+
+	save := func(users []github.User, c github.Cursor) error {
+		// Stop between the pages once the lease of the job is lost
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.User.BulkUpsert(ctx, users, region, location); err != nil {
+			return err
+		}
+		return s.Checkpoint.Save(ctx, checkpointsvc.Checkpoint{
+			Key:    key,
+			Query:  c.Query,
+			Start:  c.Start,
+			End:    c.End,
+			Until:  until,
+			Cursor: c.After,
+		})
+	}
+
+	if err := s.Github.FetchUsersCursor(ctx, location, start, end, cursor, perPage, save); err != nil {
 		return err
 	}
 
-	return s.User.BulkUpsert(ctx, users)
+	// The window that is resumed may be part of a larger crawl, continue with the rest of it
+	if end < until {
+		if err := s.Github.FetchUsersCursor(ctx, location, nextDate(end), until, "", perPage, save); err != nil {
+			return err
+		}
+	}
+
+	return s.Checkpoint.Delete(ctx, key)
 }
 
+// FetchRepos crawls the repos of the users that are least recently fetched, with the most recently
+// pushed first, until the repos that are pushed before the last run. Unless reset, in which case all
+// the repos are fetched again. Each page is stored together with the checkpoint as soon as it arrives,
+// and a crawl that did not complete for a user is resumed from the checkpoint
 func (s *service) FetchRepos(ctx context.Context, userPerPage, repoPerPage int, reset bool) error {
+	// Each user takes at least a call, so only start as much work as the budget allows
+	if budget := s.Github.RateLimit(ctx); budget.Known() && time.Now().Before(budget.ResetAt) {
+		userPerPage = take(userPerPage, budget.Remaining)
+		if userPerPage <= 0 {
+			return nil
+		}
+	}
+
 	users, err := s.User.FindLastFetched(ctx, userPerPage)
 	if err != nil {
 		return err
 	}
 
+	var lastErr error
 	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		login := user.Login
 		if login == "" {
 			continue
 		}
+		key := reposCheckpoint(login)
+
+		var since, cursor string
+		if cp, ok := s.Checkpoint.Find(ctx, key); ok {
+			since, cursor = cp.Start, cp.Cursor
+		} else if !reset {
+			since, _ = s.Repo.LastPushedBy(ctx, login)
+		}
 
-		start, _ := s.Repo.LastCreatedBy(ctx, login)
-		end := moment.NewCurrentFormattedDate()
+		err := s.Github.FetchReposCursor(ctx, login, since, cursor, repoPerPage, func(repos []github.Repo, c github.Cursor) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := s.Repo.BulkUpsert(ctx, repos, user.Region); err != nil {
+				return err
+			}
+			return s.Checkpoint.Save(ctx, checkpointsvc.Checkpoint{
+				Key:    key,
+				Query:  c.Query,
+				Start:  c.Start,
+				Cursor: c.After,
+			})
+		})
 
-		repos, err := s.Github.FetchReposCursor(ctx, login, start, end, repoPerPage)
+		// A user that is renamed or deleted has no repos to fetch
+		if isNotFound(err) {
+			err = nil
+		}
+
+		// Leave fetchedAt untouched for the user, so that it will be retried in the next run
 		if err != nil {
-			return err
+			if isFatal(err) {
+				return err
+			}
+			lastErr = err
+			continue
 		}
 
-		if err = s.Repo.BulkUpsert(ctx, repos); err != nil {
+		if err = s.Checkpoint.Delete(ctx, key); err != nil {
 			return err
 		}
 
@@ -107,89 +231,143 @@ func (s *service) FetchRepos(ctx context.Context, userPerPage, repoPerPage int,
 			return err
 		}
 	}
-	return nil
+	return lastErr
+}
+
+// RefreshRepos refreshes the counters, such as the stars and forks, of the repos that are least
+// recently refreshed. New repos are fetched by FetchRepos, but the counters of the repos that are
+// not pushed to keep changing, and are only picked up here
+func (s *service) RefreshRepos(ctx context.Context, perPage int) error {
+	if budget := s.Github.RateLimit(ctx); budget.Exhausted() {
+		return nil
+	}
+
+	repos, err := s.Repo.FindStale(ctx, perPage)
+	if err != nil {
+		return err
+	}
+	// Nothing is stale, e.g. before the first repos are fetched
+	if len(repos) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(repos))
+	for i, repo := range repos {
+		ids[i] = repo.NodeID
+	}
+
+	refreshed, err := s.Github.FetchReposByID(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	return s.Repo.RefreshCounters(ctx, ids, refreshed)
+}
+
+// isNotFound checks if the pagination failed because the resource does not exist
+func isNotFound(err error) bool {
+	perr, ok := err.(*github.PartialError)
+	if !ok {
+		return false
+	}
+	_, ok = perr.Err.(*github.NotFoundError)
+	return ok
+}
+
+// isFatal checks if the pagination error will affect the remaining users too,
+// in which case there is no point continuing
+func isFatal(err error) bool {
+	perr, ok := err.(*github.PartialError)
+	if !ok {
+		return true
+	}
+	switch perr.Err.(type) {
+	case *github.AuthError, *github.RateLimitError:
+		return true
+	}
+	return perr.Err == context.Canceled || perr.Err == context.DeadlineExceeded
 }
 
 // UpdateUserCount updates the analytic type `user_count`
-func (s *service) UpdateUserCount(ctx context.Context) error {
-	count, err := s.User.Count(ctx)
+func (s *service) UpdateUserCount(ctx context.Context, region string) error {
+	count, err := s.User.Count(ctx, region)
 	if err != nil {
 		return err
 	}
 
-	return s.Stat.PostUserCount(ctx, count)
+	return s.Stat.PostUserCount(ctx, region, count)
 }
 
 // UpdateRepoCount updates the analytic type `repo_count`
-func (s *service) UpdateRepoCount(ctx context.Context) error {
-	count, err := s.Repo.Count(ctx)
+func (s *service) UpdateRepoCount(ctx context.Context, region string) error {
+	count, err := s.Repo.Count(ctx, region)
 	if err != nil {
 		return err
 	}
 
-	return s.Stat.PostRepoCount(ctx, count)
+	return s.Stat.PostRepoCount(ctx, region, count)
 }
 
 // UpdateReposMostRecent updates the analytic type `repos_most_recent`
-func (s *service) UpdateReposMostRecent(ctx context.Context, perPage int) error {
-	repos, err := s.Repo.MostRecent(ctx, perPage)
+func (s *service) UpdateReposMostRecent(ctx context.Context, region string, perPage int) error {
+	repos, err := s.Repo.MostRecent(ctx, region, perPage)
 	if err != nil {
 		return err
 	}
 
-	return s.Stat.PostReposMostRecent(ctx, repos)
+	return s.Stat.PostReposMostRecent(ctx, region, repos)
 }
 
 // UpdateRepoCountByUser updates the analytic type `repo_count_by_user`
-func (s *service) UpdateRepoCountByUser(ctx context.Context, perPage int) error {
-	users, err := s.Repo.RepoCountByUser(ctx, perPage)
+func (s *service) UpdateRepoCountByUser(ctx context.Context, region string, perPage int) error {
+	users, err := s.Repo.RepoCountByUser(ctx, region, perPage)
 	if err != nil {
 		return err
 	}
 
-	return s.Stat.PostRepoCountByUser(ctx, users)
+	return s.Stat.PostRepoCountByUser(ctx, region, users)
 }
 
 // UpdateReposMostStars updates the analytic type `repos_most_stars`
-func (s *service) UpdateReposMostStars(ctx context.Context, perPage int) error {
-	repos, err := s.Repo.MostStars(ctx, perPage)
+func (s *service) UpdateReposMostStars(ctx context.Context, region string, perPage int) error {
+	repos, err := s.Repo.MostStars(ctx, region, perPage)
 	if err != nil {
 		return err
 	}
 
-	return s.Stat.PostReposMostStars(ctx, repos)
+	return s.Stat.PostReposMostStars(ctx, region, repos)
 }
 
 // UpdateReposMostForks updates the analytic type `repos_most_forks`
-func (s *service) UpdateReposMostForks(ctx context.Context, perPage int) error {
-	repos, err := s.Repo.MostForks(ctx, perPage)
+func (s *service) UpdateReposMostForks(ctx context.Context, region string, perPage int) error {
+	repos, err := s.Repo.MostForks(ctx, region, perPage)
 	if err != nil {
 		return err
 	}
 
-	return s.Stat.PostReposMostForks(ctx, repos)
+	return s.Stat.PostReposMostForks(ctx, region, repos)
 }
 
 // UpdateLanguagesMostPopular updates the analytic type `languages_most_popular`
-func (s *service) UpdateLanguagesMostPopular(ctx context.Context, perPage int) error {
-	languages, err := s.Repo.MostPopularLanguage(ctx, perPage)
+func (s *service) UpdateLanguagesMostPopular(ctx context.Context, region string, perPage int) error {
+	languages, err := s.Repo.MostPopularLanguage(ctx, region, perPage)
 	if err != nil {
 		return err
 	}
 
-	return s.Stat.PostMostPopularLanguage(ctx, languages)
+	return s.Stat.PostMostPopularLanguage(ctx, region, languages)
 }
 
 // UpdateMostRecentReposByLanguage updates the analytic type `repos_most_recent_by_language`
-func (s *service) UpdateMostRecentReposByLanguage(ctx context.Context, perPage int) error {
-	languages, err := s.Repo.MostPopularLanguage(ctx, perPage)
+func (s *service) UpdateMostRecentReposByLanguage(ctx context.Context, region string, perPage int) error {
+	languages, err := s.Repo.MostPopularLanguage(ctx, region, perPage)
 	if err != nil {
 		return err
 	}
 
 	var repos []schema.RepoLanguage
 	for _, lang := range languages {
-		r, err := s.Repo.MostRecentReposByLanguage(ctx, lang.Name, perPage)
+		r, err := s.Repo.MostRecentReposByLanguage(ctx, region, lang.Name, perPage)
 		if err != nil {
 			return err
 		}
@@ -199,19 +377,19 @@ func (s *service) UpdateMostRecentReposByLanguage(ctx context.Context, perPage i
 		})
 	}
 
-	return s.Stat.PostMostRecentReposByLanguage(ctx, repos)
+	return s.Stat.PostMostRecentReposByLanguage(ctx, region, repos)
 }
 
 // UpdateReposByLanguage updates the analytic type `repos_by_language`
-func (s *service) UpdateReposByLanguage(ctx context.Context, perPage int) error {
-	languages, err := s.Repo.MostPopularLanguage(ctx, perPage)
+func (s *service) UpdateReposByLanguage(ctx context.Context, region string, perPage int) error {
+	languages, err := s.Repo.MostPopularLanguage(ctx, region, perPage)
 	if err != nil {
 		return err
 	}
 
 	var users []schema.UserCountByLanguage
 	for _, lang := range languages {
-		user, err := s.Repo.ReposByLanguage(ctx, lang.Name, perPage)
+		user, err := s.Repo.ReposByLanguage(ctx, region, lang.Name, perPage)
 		if err != nil {
 			return err
 		}
@@ -222,7 +400,7 @@ func (s *service) UpdateReposByLanguage(ctx context.Context, perPage int) error
 		})
 	}
 
-	return s.Stat.PostReposByLanguage(ctx, users)
+	return s.Stat.PostReposByLanguage(ctx, region, users)
 }
 
 func (s *service) UpdateProfile(ctx context.Context, numWorkers int) error {
@@ -231,6 +409,12 @@ func (s *service) UpdateProfile(ctx context.Context, numWorkers int) error {
 		return err
 	}
 
+	// The corpus is built once and only read by the workers
+	corpus, err := s.Repo.Corpus(ctx)
+	if err != nil {
+		return err
+	}
+
 	toStream := func(ctx context.Context, args ...string) <-chan string {
 		c := make(chan string)
 		go func() {
@@ -255,7 +439,7 @@ func (s *service) UpdateProfile(ctx context.Context, numWorkers int) error {
 		multiplex := func(in <-chan string) {
 			defer wg.Done()
 			for i := range in {
-				p, err := s.Repo.GetProfile(ctx, i)
+				p, err := s.Repo.GetProfile(ctx, i, corpus)
 				if err != nil {
 					continue
 				}
@@ -286,52 +470,179 @@ func (s *service) UpdateProfile(ctx context.Context, numWorkers int) error {
 	for p := range fanIn(ctx, numWorkers, toStream(ctx, logins...)) {
 		profiles = append(profiles, p)
 	}
-
+	// The profiles are incomplete when the stream is cut short by the lease lost
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return s.User.BulkUpdate(ctx, profiles)
 }
 
-func (s *service) UpdateMatches(ctx context.Context) error {
+// UpdateMatches recommends the most similar users to each user with repos. Each user is
+// only scored against the candidates that share a language or keyword, on a pool of
+// workers, and the matches are written in batches as they are computed
+func (s *service) UpdateMatches(ctx context.Context, numWorkers int) error {
+	var (
+		maxMatches = 20
+		perBatch   = 500
+	)
 
-	maxMatches := 20
-	var users []usersvc.User
 	users, err := s.User.WithRepos(ctx, 0)
 	if err != nil {
 		return err
 	}
 
-	for i := 0; i < len(users); i++ {
-		p1 := users[i]
-		var matches []schema.User
+	profiles := make([]recsys.Profile, len(users))
+	avatars := make(map[string]string, len(users))
+	for i, user := range users {
+		profiles[i] = toProfile(user)
+		avatars[user.Login] = user.AvatarURL
+	}
+	users = nil
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batch := make([]usersvc.User, 0, perBatch)
+	for res := range matchProfiles(ctx, s.Recommender, profiles, numWorkers, maxMatches) {
+		var user usersvc.User
+		user.Login = res.profile.ID
+		for _, m := range res.matches {
+			user.Matches = append(user.Matches, schema.User{
+				Login:     m.ID,
+				AvatarURL: avatars[m.ID],
+				Score:     m.Score,
+			})
+		}
 
-		for j := 0; j < len(users); j++ {
-			p2 := users[j]
-			if i == j {
-				continue
+		batch = append(batch, user)
+		if len(batch) < perBatch {
+			continue
+		}
+		if err := s.User.BulkUpdateMatches(ctx, batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.User.BulkUpdateMatches(ctx, batch)
+}
+
+// UpdateSimilarRepos finds the most similar repos to each repo that is not a fork, based on
+// the keywords of the description, the topics and the languages. The terms shared with
+// each similar repo are kept as the reasons of the match
+func (s *service) UpdateSimilarRepos(ctx context.Context, numWorkers int) error {
+	var (
+		maxSimilar = 20
+		perBatch   = 500
+	)
+
+	repos, err := s.Repo.FindOriginals(ctx)
+	if err != nil {
+		return err
+	}
+
+	profiles := make([]recsys.Profile, len(repos))
+	byName := make(map[string]int, len(repos))
+	for i, repo := range repos {
+		profiles[i] = toRepoProfile(repo)
+		byName[repo.NameWithOwner] = i
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batch := make([]reposvc.SimilarRepos, 0, perBatch)
+	for res := range matchProfiles(ctx, s.RepoRecommender, profiles, numWorkers, maxSimilar) {
+		similar := reposvc.SimilarRepos{NameWithOwner: res.profile.ID}
+		for _, m := range res.matches {
+			i := byName[m.ID]
+			similar.Similar = append(similar.Similar, schema.SimilarRepo{
+				NameWithOwner: repos[i].NameWithOwner,
+				Description:   repos[i].Description,
+				Stargazers:    repos[i].Stargazers,
+				Score:         m.Score,
+				Reasons:       recsys.SharedTerms(res.profile, profiles[i]),
+			})
+		}
+
+		batch = append(batch, similar)
+		if len(batch) < perBatch {
+			continue
+		}
+		if err := s.Repo.BulkUpsertSimilar(ctx, batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Repo.BulkUpsertSimilar(ctx, batch)
+}
+
+// profileMatches represents the matches computed for a profile
+type profileMatches struct {
+	profile recsys.Profile
+	matches []recsys.Match
+}
+
+// matchProfiles fits the recommender on the profiles, and scores each profile against the
+// candidates from an inverted index on a pool of workers. The matches are streamed as they
+// are computed, and the stream is closed once every profile is scored or the context is done
+func matchProfiles(ctx context.Context, r recsys.Recommender, profiles []recsys.Profile, numWorkers, maxMatches int) <-chan profileMatches {
+	maxCandidates := 500
+
+	r.Fit(profiles)
+	index := recsys.NewIndex(profiles)
+
+	toStream := func(ctx context.Context, profiles []recsys.Profile) <-chan recsys.Profile {
+		c := make(chan recsys.Profile)
+		go func() {
+			defer close(c)
+			for _, p := range profiles {
+				select {
+				case <-ctx.Done():
+					return
+				case c <- p:
+				}
 			}
+		}()
+		return c
+	}
+
+	fanIn := func(ctx context.Context, numWorkers int, in <-chan recsys.Profile) <-chan profileMatches {
+		c := make(chan profileMatches)
 
-			score := recsys(p1, p2)
+		var wg sync.WaitGroup
+		wg.Add(numWorkers)
 
-			if len(matches) > maxMatches {
-				if score > matches[0].Score {
-					matches = append(matches[1:], schema.User{
-						Login:     p2.Login,
-						AvatarURL: p2.AvatarURL,
-						Score:     score,
-					})
-					heapsort.Sort(matches)
+		multiplex := func(in <-chan recsys.Profile) {
+			defer wg.Done()
+			for p := range in {
+				candidates := index.Candidates(p, maxCandidates)
+				select {
+				case <-ctx.Done():
+					return
+				case c <- profileMatches{p, recsys.TopK(r, p, candidates, maxMatches)}:
 				}
-			} else {
-				matches = append(matches, schema.User{
-					Login:     p2.Login,
-					AvatarURL: p2.AvatarURL,
-					Score:     score,
-				})
 			}
 		}
-		users[i].Profile.Matches = matches[:take(len(matches), maxMatches)]
+
+		for i := 0; i < numWorkers; i++ {
+			go multiplex(in)
+		}
+
+		go func() {
+			defer close(c)
+			wg.Wait()
+		}()
+
+		return c
 	}
 
-	return s.User.BulkUpdate(ctx, users)
+	return fanIn(ctx, numWorkers, toStream(ctx, profiles))
 }
 
 func take(curr, max int) int {
@@ -341,10 +652,6 @@ func take(curr, max int) int {
 	return max
 }
 
-func square(a, b int64) float64 {
-	return math.Pow(float64(a-b), 2.0)
-}
-
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -352,80 +659,107 @@ func max(a, b int) int {
 	return b
 }
 
-func recsys(user1, user2 usersvc.User) (score float64) {
-	sumSquares := square(user1.Repositories, user2.Repositories) +
-		square(user1.Gists, user2.Gists) +
-		square(user1.Followers, user2.Followers) +
-		square(user1.Following, user2.Following) +
-		square(user1.Profile.Watchers, user2.Profile.Watchers) +
-		square(user1.Profile.Stargazers, user2.Profile.Stargazers) +
-		square(user1.Profile.Forks, user2.Profile.Forks)
-
-	mapAllLang := make(map[string]int64)
-	mapLangUser1 := make(map[string]int64)
-	mapLangUser2 := make(map[string]int64)
-
-	for _, l := range user1.Languages {
-		mapAllLang[l.Name]++
-		mapLangUser1[l.Name] = int64(l.Count)
-	}
-
-	for _, l := range user2.Languages {
-		mapAllLang[l.Name]++
-		mapLangUser2[l.Name] = int64(l.Count)
-	}
-
-	for k, v := range mapAllLang {
-		if v == 2 {
-			sumSquares += square(mapLangUser1[k], mapLangUser2[k])
+// toRepoProfile returns the features of the repo used to find the similar repos
+func toRepoProfile(repo schema.Repo) recsys.Profile {
+	keywords := make(map[string]float64)
+	for _, word := range bow.Parse(repo.Description) {
+		if word != "" {
+			keywords[word]++
 		}
 	}
-
-	mapKeywords := make(map[string]int64)
-	mapKeywordUser1 := make(map[string]int64)
-	mapKeywordUser2 := make(map[string]int64)
-
-	for _, k := range user1.Keywords {
-		mapKeywords[k.ID]++
-		mapKeywordUser1[k.ID]++
+	topics := make(map[string]float64)
+	for _, t := range repo.Topics {
+		topics[t] = 1
 	}
-
-	for _, k := range user2.Languages {
-		mapKeywords[k.Name]++
-		mapKeywordUser2[k.Name]++
+	languages := make(map[string]float64)
+	for _, l := range repo.Languages {
+		languages[l] = 1
 	}
+	return recsys.Profile{
+		ID: repo.NameWithOwner,
+		Terms: map[string]map[string]float64{
+			"keywords":  keywords,
+			"topics":    topics,
+			"languages": languages,
+		},
+	}
+}
 
-	for k, v := range mapKeywords {
-		if v == 2 {
-			sumSquares += square(mapKeywordUser1[k], mapKeywordUser2[k])
+// toProfile returns the features of the user used for the recommendations
+func toProfile(user usersvc.User) recsys.Profile {
+	// The languages are weighted by the share of the bytes the user wrote in them, or by the
+	// share of the repos in the profiles computed before the bytes were stored. The shares
+	// are percentages of at least one, as the term frequency is sublinear and must not be
+	// negative
+	var bytes, count float64
+	for _, l := range user.Languages {
+		bytes += float64(l.Bytes)
+		count += float64(l.Count)
+	}
+	languages := make(map[string]float64)
+	for _, l := range user.Languages {
+		var share float64
+		switch {
+		case bytes > 0:
+			share = float64(l.Bytes) / bytes
+		case count > 0:
+			share = float64(l.Count) / count
 		}
+		if share <= 0 {
+			continue
+		}
+		languages[l.Name] = math.Max(100*share, 1)
+	}
+	// The keywords are weighted by their tf-idf, or by their count in the profiles computed
+	// before the scores were stored
+	keywords := make(map[string]float64)
+	for _, k := range user.Keywords {
+		keywords[k.ID] = k.Score
+		if k.Score == 0 {
+			keywords[k.ID] = float64(k.Value)
+		}
+	}
+	return recsys.Profile{
+		ID: user.Login,
+		Terms: map[string]map[string]float64{
+			"languages": languages,
+			"keywords":  keywords,
+		},
+		Numeric: map[string]float64{
+			"followers":  float64(user.Followers),
+			"following":  float64(user.Following),
+			"repos":      float64(user.Repositories),
+			"gists":      float64(user.Gists),
+			"stargazers": float64(user.Profile.Stargazers),
+			"forks":      float64(user.Profile.Forks),
+			"watchers":   float64(user.Profile.Watchers),
+		},
 	}
-	return 1 / (1 + math.Sqrt(sumSquares))
 }
 
-func (s *service) UpdateCompanyCount(ctx context.Context) error {
+func (s *service) UpdateCompanyCount(ctx context.Context, region string) error {
 	var res []string
-	res, err := s.User.DistinctCompany(ctx)
+	res, err := s.User.DistinctCompany(ctx, region)
 	if err != nil {
 		return err
 	}
 
-	return s.Stat.PostCompanyCount(ctx, len(res))
+	return s.Stat.PostCompanyCount(ctx, region, len(res))
 }
 
-func (s *service) UpdateUsersByCompany(ctx context.Context, min, max int) error {
-	companies, err := s.User.AggregateCompany(ctx, min, max)
+func (s *service) UpdateUsersByCompany(ctx context.Context, region string, min, max int) error {
+	companies, err := s.User.AggregateCompany(ctx, region, min, max)
 	if err != nil {
 		return err
 	}
 
 	for i := 0; i < len(companies); i++ {
-		res, err := s.User.FindByCompany(ctx, companies[i].Company)
+		res, err := s.User.FindByCompany(ctx, region, companies[i].Company)
 		if err != nil {
 			continue
 		}
 		companies[i].Users = res
 	}
 
-	return s.Stat.PostUsersByCompany(ctx, companies)
+	return s.Stat.PostUsersByCompany(ctx, region, companies)
 }
@@ -0,0 +1,198 @@
+package mediatorsvc_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/checkpointsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/mediatorsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/statsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github/githubtest"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/recsys"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/region"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+)
+
+var malaysia = region.Region{
+	Name:      "malaysia",
+	Locations: []string{"Malaysia"},
+}
+
+func newUser(login, location, created string) github.User {
+	t, _ := time.Parse("2006-01-02", created)
+	return github.User{Login: login, Location: location, CreatedAt: t}
+}
+
+func newRepo(login, name string, stars int64, pushed string, fork bool) github.Repo {
+	t, _ := time.Parse("2006-01-02", pushed)
+	return github.Repo{
+		ID:            "node-" + login + "-" + name,
+		Name:          name,
+		NameWithOwner: login + "/" + name,
+		CreatedAt:     t,
+		UpdatedAt:     t,
+		PushedAt:      t,
+		IsFork:        fork,
+		Owner:         github.Owner{Login: login},
+		Stargazers:    github.Stargazers{TotalCount: stars},
+	}
+}
+
+var fixtures = githubtest.Fixtures{
+	Users: []github.User{
+		newUser("aisyah", "Kuala Lumpur, Malaysia", "2018-01-02"),
+		newUser("hafiz", "Penang, Malaysia", "2018-02-03"),
+		newUser("nurul", "Malaysia", "2018-03-04"),
+		newUser("wei", "Singapore", "2018-01-05"),
+	},
+	Repos: []github.Repo{
+		newRepo("aisyah", "raft-kv", 120, "2018-06-01", false),
+		newRepo("aisyah", "dotfiles", 2, "2018-05-01", false),
+		newRepo("aisyah", "linux", 0, "2018-07-01", true),
+		newRepo("hafiz", "ml-notes", 40, "2018-04-01", false),
+		newRepo("wei", "sg-bus", 10, "2018-04-01", false),
+	},
+}
+
+// newServer starts the fake Github with the fixtures, and returns the client of it
+func newServer(t *testing.T, fixtures githubtest.Fixtures) github.Service {
+	t.Helper()
+	srv := githubtest.NewServer(&fixtures)
+	t.Cleanup(srv.Close)
+	return github.New(srv.Client(), []string{"token"}, srv.Endpoint())
+}
+
+// newMediator returns the mediator on the memory stores
+func newMediator(api github.Service) mediatorsvc.Mediator {
+	return mediatorsvc.Mediator{
+		Checkpoint:      checkpointsvc.NewWithStore(checkpointsvc.NewMemoryStore()),
+		Stat:            statsvc.NewWithStore(statsvc.NewMemoryStore()),
+		Github:          api,
+		Repo:            reposvc.NewWithStore(reposvc.NewMemoryStore()),
+		User:            usersvc.NewWithStore(usersvc.NewMemoryStore()),
+		Recommender:     recsys.NewTFIDF(recsys.DefaultWeights),
+		RepoRecommender: recsys.NewTFIDF(recsys.DefaultRepoWeights),
+	}
+}
+
+func TestFetchUsers(t *testing.T) {
+	ctx := context.Background()
+	m := newMediator(newServer(t, fixtures))
+	svc := mediatorsvc.NewService(m)
+
+	if err := svc.FetchUsers(ctx, malaysia, 200, 2); err != nil {
+		t.Fatal(err)
+	}
+	page, err := m.User.List(ctx, usersvc.Filter{Region: "malaysia", Sort: "createdAt"}, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := userLogins(page.Users), []string{"aisyah", "hafiz", "nurul"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want the users in Malaysia %v, got %v", want, got)
+	}
+	if _, ok := m.Checkpoint.Find(ctx, "users:Malaysia"); ok {
+		t.Fatal("want the checkpoint deleted once the crawl completes")
+	}
+
+	// The next run starts from the last user created, and upserts the users found again
+	if err := svc.FetchUsers(ctx, malaysia, 200, 2); err != nil {
+		t.Fatal(err)
+	}
+	count, err := m.User.Count(ctx, "malaysia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("want 3 users after the second run, got %d", count)
+	}
+}
+
+func TestFetchRepos(t *testing.T) {
+	ctx := context.Background()
+	m := newMediator(newServer(t, fixtures))
+	svc := mediatorsvc.NewService(m)
+
+	if err := svc.FetchUsers(ctx, malaysia, 200, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.FetchRepos(ctx, 10, 1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The forks are not fetched, and nurul has no repos
+	page, err := m.Repo.List(ctx, reposvc.Filter{Region: "malaysia", Sort: "-stars"}, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := repoNames(page.Repos), []string{"aisyah/raft-kv", "hafiz/ml-notes", "aisyah/dotfiles"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	for _, login := range []string{"aisyah", "hafiz", "nurul"} {
+		if _, ok := m.Checkpoint.Find(ctx, "repos:"+login); ok {
+			t.Fatalf("want the checkpoint of %s deleted once the crawl completes", login)
+		}
+	}
+}
+
+func TestRefreshRepos(t *testing.T) {
+	ctx := context.Background()
+	m := newMediator(newServer(t, fixtures))
+	svc := mediatorsvc.NewService(m)
+
+	if err := svc.FetchUsers(ctx, malaysia, 200, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.FetchRepos(ctx, 10, 10, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Since then, raft-kv gained stars and dotfiles was deleted
+	refreshed := githubtest.Fixtures{
+		Users: fixtures.Users,
+		Repos: []github.Repo{
+			newRepo("aisyah", "raft-kv", 150, "2018-06-01", false),
+			newRepo("hafiz", "ml-notes", 40, "2018-04-01", false),
+		},
+	}
+	m.Github = newServer(t, refreshed)
+	svc = mediatorsvc.NewService(m)
+	if err := svc.RefreshRepos(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := m.Repo.FindOne(ctx, "aisyah", "raft-kv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Stargazers != 150 {
+		t.Fatalf("want the stars refreshed, got %d", repo.Stargazers)
+	}
+	deleted, err := m.Repo.FindOne(ctx, "aisyah", "dotfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.Stargazers != 2 {
+		t.Fatalf("want the counters of the deleted repo kept, got %d", deleted.Stargazers)
+	}
+}
+
+func userLogins(users []usersvc.User) []string {
+	res := make([]string, len(users))
+	for i, u := range users {
+		res[i] = u.Login
+	}
+	return res
+}
+
+func repoNames(repos []schema.Repo) []string {
+	res := make([]string, len(repos))
+	for i, r := range repos {
+		res[i] = r.NameWithOwner
+	}
+	return res
+}
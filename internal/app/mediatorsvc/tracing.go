@@ -2,6 +2,9 @@ package mediatorsvc
 
 import (
 	"context"
+	"strings"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/region"
 
 	"go.opencensus.io/trace"
 )
@@ -19,16 +22,17 @@ type tracingMiddleware struct {
 	service Service
 }
 
-func (m *tracingMiddleware) FetchUsers(ctx context.Context, location string, months int, perPage int) error {
+func (m *tracingMiddleware) FetchUsers(ctx context.Context, r region.Region, months int, perPage int) error {
 	ctx, span := trace.StartSpan(ctx, "FetchUsers")
 	defer span.End()
 
 	span.AddAttributes(
-		trace.StringAttribute("location", location),
+		trace.StringAttribute("region", r.Name),
+		trace.StringAttribute("locations", strings.Join(r.Locations, ",")),
 		trace.Int64Attribute("months", int64(months)),
 		trace.Int64Attribute("perPage", int64(perPage)))
 
-	return m.service.FetchUsers(ctx, location, months, perPage)
+	return m.service.FetchUsers(ctx, r, months, perPage)
 }
 
 func (m *tracingMiddleware) FetchRepos(ctx context.Context, userPerPage, repoPerPage int, reset bool) error {
@@ -43,81 +47,108 @@ func (m *tracingMiddleware) FetchRepos(ctx context.Context, userPerPage, repoPer
 	return m.service.FetchRepos(ctx, userPerPage, repoPerPage, reset)
 }
 
-func (m *tracingMiddleware) UpdateUserCount(ctx context.Context) error {
+func (m *tracingMiddleware) RefreshRepos(ctx context.Context, perPage int) error {
+	ctx, span := trace.StartSpan(ctx, "RefreshRepos")
+	defer span.End()
+
+	span.AddAttributes(trace.Int64Attribute("perPage", int64(perPage)))
+
+	return m.service.RefreshRepos(ctx, perPage)
+}
+
+func (m *tracingMiddleware) UpdateUserCount(ctx context.Context, region string) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateUserCount")
 	defer span.End()
 
-	return m.service.UpdateUserCount(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.UpdateUserCount(ctx, region)
 }
 
-func (m *tracingMiddleware) UpdateRepoCount(ctx context.Context) error {
+func (m *tracingMiddleware) UpdateRepoCount(ctx context.Context, region string) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateRepoCount")
 	defer span.End()
 
-	return m.service.UpdateRepoCount(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.UpdateRepoCount(ctx, region)
 }
 
-func (m *tracingMiddleware) UpdateReposMostRecent(ctx context.Context, perPage int) error {
+func (m *tracingMiddleware) UpdateReposMostRecent(ctx context.Context, region string, perPage int) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateReposMostRecent")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("perPage", int64(perPage)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("perPage", int64(perPage)))
 
-	return m.service.UpdateReposMostRecent(ctx, perPage)
+	return m.service.UpdateReposMostRecent(ctx, region, perPage)
 }
 
-func (m *tracingMiddleware) UpdateRepoCountByUser(ctx context.Context, perPage int) error {
+func (m *tracingMiddleware) UpdateRepoCountByUser(ctx context.Context, region string, perPage int) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateRepoCountByUser")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("perPage", int64(perPage)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("perPage", int64(perPage)))
 
-	return m.service.UpdateRepoCountByUser(ctx, perPage)
+	return m.service.UpdateRepoCountByUser(ctx, region, perPage)
 }
 
-func (m *tracingMiddleware) UpdateReposMostStars(ctx context.Context, perPage int) error {
+func (m *tracingMiddleware) UpdateReposMostStars(ctx context.Context, region string, perPage int) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateReposMostStars")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("perPage", int64(perPage)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("perPage", int64(perPage)))
 
-	return m.service.UpdateReposMostStars(ctx, perPage)
+	return m.service.UpdateReposMostStars(ctx, region, perPage)
 }
 
-func (m *tracingMiddleware) UpdateReposMostForks(ctx context.Context, perPage int) error {
+func (m *tracingMiddleware) UpdateReposMostForks(ctx context.Context, region string, perPage int) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateReposMostForks")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("perPage", int64(perPage)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("perPage", int64(perPage)))
 
-	return m.service.UpdateReposMostForks(ctx, perPage)
+	return m.service.UpdateReposMostForks(ctx, region, perPage)
 }
 
-func (m *tracingMiddleware) UpdateLanguagesMostPopular(ctx context.Context, perPage int) error {
+func (m *tracingMiddleware) UpdateLanguagesMostPopular(ctx context.Context, region string, perPage int) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateLanguagesMostPopular")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("perPage", int64(perPage)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("perPage", int64(perPage)))
 
-	return m.service.UpdateLanguagesMostPopular(ctx, perPage)
+	return m.service.UpdateLanguagesMostPopular(ctx, region, perPage)
 }
 
-func (m *tracingMiddleware) UpdateMostRecentReposByLanguage(ctx context.Context, perPage int) error {
+func (m *tracingMiddleware) UpdateMostRecentReposByLanguage(ctx context.Context, region string, perPage int) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateMostRecentReposByLanguage")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("perPage", int64(perPage)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("perPage", int64(perPage)))
 
-	return m.service.UpdateMostRecentReposByLanguage(ctx, perPage)
+	return m.service.UpdateMostRecentReposByLanguage(ctx, region, perPage)
 }
 
-func (m *tracingMiddleware) UpdateReposByLanguage(ctx context.Context, perPage int) error {
+func (m *tracingMiddleware) UpdateReposByLanguage(ctx context.Context, region string, perPage int) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateReposByLanguage")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("perPage", int64(perPage)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("perPage", int64(perPage)))
 
-	return m.service.UpdateReposByLanguage(ctx, perPage)
+	return m.service.UpdateReposByLanguage(ctx, region, perPage)
 }
 
 func (m *tracingMiddleware) UpdateProfile(ctx context.Context, numWorkers int) error {
@@ -129,27 +160,41 @@ func (m *tracingMiddleware) UpdateProfile(ctx context.Context, numWorkers int) e
 	return m.service.UpdateProfile(ctx, numWorkers)
 }
 
-func (m *tracingMiddleware) UpdateMatches(ctx context.Context) error {
+func (m *tracingMiddleware) UpdateMatches(ctx context.Context, numWorkers int) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateMatches")
 	defer span.End()
 
-	return m.service.UpdateMatches(ctx)
+	span.AddAttributes(trace.Int64Attribute("numWorkers", int64(numWorkers)))
+
+	return m.service.UpdateMatches(ctx, numWorkers)
 }
 
-func (m *tracingMiddleware) UpdateUsersByCompany(ctx context.Context, min, max int) error {
+func (m *tracingMiddleware) UpdateSimilarRepos(ctx context.Context, numWorkers int) error {
+	ctx, span := trace.StartSpan(ctx, "UpdateSimilarRepos")
+	defer span.End()
+
+	span.AddAttributes(trace.Int64Attribute("numWorkers", int64(numWorkers)))
+
+	return m.service.UpdateSimilarRepos(ctx, numWorkers)
+}
+
+func (m *tracingMiddleware) UpdateUsersByCompany(ctx context.Context, region string, min, max int) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateUsersByCompany")
 	defer span.End()
 
 	span.AddAttributes(
+		trace.StringAttribute("region", region),
 		trace.Int64Attribute("min", int64(min)),
 		trace.Int64Attribute("max", int64(max)))
 
-	return m.service.UpdateUsersByCompany(ctx, min, max)
+	return m.service.UpdateUsersByCompany(ctx, region, min, max)
 }
 
-func (m *tracingMiddleware) UpdateCompanyCount(ctx context.Context) error {
+func (m *tracingMiddleware) UpdateCompanyCount(ctx context.Context, region string) error {
 	ctx, span := trace.StartSpan(ctx, "UpdateCompanyCount")
 	defer span.End()
 
-	return m.service.UpdateCompanyCount(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.UpdateCompanyCount(ctx, region)
 }
@@ -0,0 +1,122 @@
+package reposvc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+)
+
+// dateLayout is the layout of the date ranges in the filter, YYYY-MM-DD
+const dateLayout = "2006-01-02"
+
+// sortFields maps the sort keys accepted by the api to the fields in the store
+var sortFields = map[string]string{
+	"stars":     "stargazers",
+	"forks":     "forks",
+	"createdAt": "createdAt",
+	"pushedAt":  "pushedAt",
+}
+
+// Filter represents the criteria to list the repos. Zero values are not applied
+type Filter struct {
+	Region      string
+	Language    string
+	Owner       string
+	IsFork      *bool
+	MinStars    int64
+	CreatedFrom string
+	CreatedTo   string
+	PushedFrom  string
+	PushedTo    string
+
+	// Sort is one of stars, forks, createdAt or pushedAt, sorted in ascending order
+	// unless prefixed with "-". Ties are broken by the name with owner
+	Sort string
+}
+
+// Validate checks that the dates, ranges and the sort key of the filter are valid
+func (f Filter) Validate() error {
+	for _, d := range []string{f.CreatedFrom, f.CreatedTo, f.PushedFrom, f.PushedTo} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, d); err != nil {
+			return ErrInvalidDate
+		}
+	}
+	if (f.CreatedFrom != "" && f.CreatedTo != "" && f.CreatedFrom > f.CreatedTo) ||
+		(f.PushedFrom != "" && f.PushedTo != "" && f.PushedFrom > f.PushedTo) ||
+		f.MinStars < 0 {
+		return ErrInvalidRange
+	}
+	if _, _, ok := f.SortField(); !ok {
+		return ErrInvalidSort
+	}
+	return nil
+}
+
+// SortField returns the field in the store to sort by and the direction. The repos are
+// sorted by the most stars when no sort key is provided
+func (f Filter) SortField() (field string, desc bool, ok bool) {
+	key := f.Sort
+	if key == "" {
+		key = "-stars"
+	}
+	if strings.HasPrefix(key, "-") {
+		key, desc = key[1:], true
+	}
+	field, ok = sortFields[key]
+	return field, desc, ok
+}
+
+// Cursor marks the last repo of a page, by the value of the sort field and the name with
+// owner, which is unique
+type Cursor struct {
+	Value         interface{} `json:"v"`
+	NameWithOwner string      `json:"n"`
+}
+
+// Encode returns the cursor as an opaque url-safe string
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses the cursor returned in the previous page
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.NameWithOwner == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// newCursor returns the cursor pointing to the repo, for the field sorted by
+func newCursor(r schema.Repo, field string) Cursor {
+	var value interface{}
+	switch field {
+	case "stargazers":
+		value = r.Stargazers
+	case "forks":
+		value = r.Forks
+	case "createdAt":
+		value = r.CreatedAt
+	case "pushedAt":
+		value = r.PushedAt
+	}
+	return Cursor{Value: value, NameWithOwner: r.NameWithOwner}
+}
+
+// Page represents a page of repos, and the cursor to the next page if there are more repos
+type Page struct {
+	Repos      []schema.Repo `json:"repos"`
+	Count      int           `json:"count"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/bow"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/logger"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
@@ -26,123 +27,159 @@ type loggingMiddleware struct {
 	next   Service
 }
 
-func (l *loggingMiddleware) BulkUpsert(ctx context.Context, repos []github.Repo) (err error) {
+func (l *loggingMiddleware) BulkUpsert(ctx context.Context, repos []github.Repo, region string) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, l.logger,
 			logger.Method("BulkUpsert"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "bulk upsert repos", err)
 	}(time.Now())
-	return l.next.BulkUpsert(ctx, repos)
+	return l.next.BulkUpsert(ctx, repos, region)
 }
 
-func (l *loggingMiddleware) Count(ctx context.Context) (c int, err error) {
+func (l *loggingMiddleware) Count(ctx context.Context, region string) (c int, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, l.logger,
 			logger.Method("Count"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get repo count", err)
 	}(time.Now())
-	return l.next.Count(ctx)
+	return l.next.Count(ctx, region)
 }
 
-func (l *loggingMiddleware) LastCreatedBy(ctx context.Context, login string) (date string, ok bool) {
+func (l *loggingMiddleware) LastPushedBy(ctx context.Context, login string) (date string, ok bool) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, l.logger)
-		L.Info("find last created by user",
-			zap.String("method", "LastCreatedBy"),
+		L.Info("find last pushed by user",
+			zap.String("method", "LastPushedBy"),
 			zap.Duration("took", time.Since(start)),
+			zap.String("login", login),
 			zap.String("date", date),
-			zap.Bool("default", ok))
+			zap.Bool("found", ok))
+	}(time.Now())
+	return l.next.LastPushedBy(ctx, login)
+}
+
+func (l *loggingMiddleware) FindStale(ctx context.Context, limit int) (res []schema.Repo, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, l.logger,
+			logger.Method("FindStale"),
+			logger.Duration(start),
+			zap.Int("limit", limit),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "find stale repos", err)
 	}(time.Now())
-	return l.next.LastCreatedBy(ctx, login)
+	return l.next.FindStale(ctx, limit)
 }
 
-func (l *loggingMiddleware) MostPopularLanguage(ctx context.Context, limit int) (res []schema.LanguageCount, err error) {
+func (l *loggingMiddleware) RefreshCounters(ctx context.Context, ids []string, repos []github.Repo) (err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, l.logger,
+			logger.Method("RefreshCounters"),
+			logger.Duration(start),
+			zap.Int("ids", len(ids)),
+			zap.Int("count", len(repos)))
+
+		logger.Maybe(L, "refresh repo counters", err)
+	}(time.Now())
+	return l.next.RefreshCounters(ctx, ids, repos)
+}
+
+func (l *loggingMiddleware) MostPopularLanguage(ctx context.Context, region string, limit int) (res []schema.LanguageCount, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, l.logger,
 			logger.Method("MostPopularLanguage"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("limit", limit))
 
 		logger.Maybe(L, "get most popular language", err)
 	}(time.Now())
-	return l.next.MostPopularLanguage(ctx, limit)
+	return l.next.MostPopularLanguage(ctx, region, limit)
 }
 
-func (l *loggingMiddleware) MostRecent(ctx context.Context, limit int) (res []schema.Repo, err error) {
+func (l *loggingMiddleware) MostRecent(ctx context.Context, region string, limit int) (res []schema.Repo, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, l.logger,
 			logger.Method("MostRecent"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get most recent repos", err)
 	}(time.Now())
-	return l.next.MostRecent(ctx, limit)
+	return l.next.MostRecent(ctx, region, limit)
 }
 
-func (l *loggingMiddleware) MostRecentReposByLanguage(ctx context.Context, language string, limit int) (res []schema.Repo, err error) {
+func (l *loggingMiddleware) MostRecentReposByLanguage(ctx context.Context, region, language string, limit int) (res []schema.Repo, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, l.logger,
 			logger.Method("MostRecentReposByLanguage"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.String("language", language),
 			zap.Int("limit", limit))
 
 		logger.Maybe(L, "get most recent repos by language", err)
 	}(time.Now())
-	return l.next.MostRecentReposByLanguage(ctx, language, limit)
+	return l.next.MostRecentReposByLanguage(ctx, region, language, limit)
 }
 
-func (l *loggingMiddleware) MostStars(ctx context.Context, limit int) (res []schema.Repo, err error) {
+func (l *loggingMiddleware) MostStars(ctx context.Context, region string, limit int) (res []schema.Repo, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, l.logger,
 			logger.Method("MostStars"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("limit", limit))
 
 		logger.Maybe(L, "get repos with most stars", err)
 	}(time.Now())
-	return l.next.MostStars(ctx, limit)
+	return l.next.MostStars(ctx, region, limit)
 }
 
-func (l *loggingMiddleware) MostForks(ctx context.Context, limit int) (res []schema.Repo, err error) {
+func (l *loggingMiddleware) MostForks(ctx context.Context, region string, limit int) (res []schema.Repo, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, l.logger,
 			logger.Method("MostForks"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("limit", limit))
 
 		logger.Maybe(L, "get repos with most forks", err)
 	}(time.Now())
-	return l.next.MostForks(ctx, limit)
+	return l.next.MostForks(ctx, region, limit)
 }
 
-func (l *loggingMiddleware) RepoCountByUser(ctx context.Context, limit int) (res []schema.UserCount, err error) {
+func (l *loggingMiddleware) RepoCountByUser(ctx context.Context, region string, limit int) (res []schema.UserCount, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, l.logger,
 			logger.Method("RepoCountByUser"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("limit", limit))
 
 		logger.Maybe(L, "get repo count by user", err)
 	}(time.Now())
-	return l.next.RepoCountByUser(ctx, limit)
+	return l.next.RepoCountByUser(ctx, region, limit)
 }
 
-func (l *loggingMiddleware) ReposByLanguage(ctx context.Context, language string, limit int) (res []schema.UserCount, err error) {
+func (l *loggingMiddleware) ReposByLanguage(ctx context.Context, region, language string, limit int) (res []schema.UserCount, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, l.logger,
 			logger.Method("ReposByLanguage"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.String("language", language),
 			zap.Int("limit", limit))
 
 		logger.Maybe(L, "get repos by language", err)
 	}(time.Now())
-	return l.next.ReposByLanguage(ctx, language, limit)
+	return l.next.ReposByLanguage(ctx, region, language, limit)
 }
 
 func (l *loggingMiddleware) Distinct(ctx context.Context, field string) (res []string, err error) {
@@ -157,7 +194,23 @@ func (l *loggingMiddleware) Distinct(ctx context.Context, field string) (res []s
 	return l.next.Distinct(ctx, field)
 }
 
-func (l *loggingMiddleware) GetProfile(ctx context.Context, login string) (p *usersvc.User, err error) {
+func (l *loggingMiddleware) Corpus(ctx context.Context) (corpus *bow.Corpus, err error) {
+	defer func(start time.Time) {
+		var docs int
+		if corpus != nil {
+			docs = corpus.Len()
+		}
+		L := logger.Wrap(ctx, l.logger,
+			logger.Method("Corpus"),
+			logger.Duration(start),
+			zap.Int("documents", docs))
+
+		logger.Maybe(L, "build keyword corpus", err)
+	}(time.Now())
+	return l.next.Corpus(ctx)
+}
+
+func (l *loggingMiddleware) GetProfile(ctx context.Context, login string, corpus *bow.Corpus) (p *usersvc.User, err error) {
 	L := logger.Wrap(ctx, l.logger)
 	defer func(start time.Time) {
 		L.Info("get profile",
@@ -170,5 +223,82 @@ func (l *loggingMiddleware) GetProfile(ctx context.Context, login string) (p *us
 			zap.Int("keywords", len(p.Keywords)),
 			zap.Int("languages", len(p.Languages)))
 	}(time.Now())
-	return l.next.GetProfile(ctx, login)
+	return l.next.GetProfile(ctx, login, corpus)
+}
+
+func (l *loggingMiddleware) FindOne(ctx context.Context, owner, name string) (repo *schema.Repo, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, l.logger,
+			logger.Method("FindOne"),
+			logger.Duration(start),
+			zap.String("owner", owner),
+			zap.String("name", name))
+
+		logger.Maybe(L, "find one repo", err)
+	}(time.Now())
+	return l.next.FindOne(ctx, owner, name)
+}
+
+func (l *loggingMiddleware) List(ctx context.Context, filter Filter, cursor string, limit int) (page *Page, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, l.logger,
+			logger.Method("List"),
+			logger.Duration(start),
+			zap.Any("filter", filter),
+			zap.String("cursor", cursor),
+			zap.Int("limit", limit))
+
+		logger.Maybe(L, "list repos", err)
+	}(time.Now())
+	return l.next.List(ctx, filter, cursor, limit)
+}
+
+func (l *loggingMiddleware) CountBy(ctx context.Context, filter Filter) (count int, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, l.logger,
+			logger.Method("CountBy"),
+			logger.Duration(start),
+			zap.Any("filter", filter))
+
+		logger.Maybe(L, "count repos", err)
+	}(time.Now())
+	return l.next.CountBy(ctx, filter)
+}
+
+func (l *loggingMiddleware) FindOriginals(ctx context.Context) (repos []schema.Repo, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, l.logger,
+			logger.Method("FindOriginals"),
+			logger.Duration(start),
+			zap.Int("count", len(repos)))
+
+		logger.Maybe(L, "find original repos", err)
+	}(time.Now())
+	return l.next.FindOriginals(ctx)
+}
+
+func (l *loggingMiddleware) FindSimilar(ctx context.Context, owner, name string, limit int) (res []schema.SimilarRepo, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, l.logger,
+			logger.Method("FindSimilar"),
+			logger.Duration(start),
+			zap.String("owner", owner),
+			zap.String("name", name),
+			zap.Int("limit", limit))
+
+		logger.Maybe(L, "find similar repos", err)
+	}(time.Now())
+	return l.next.FindSimilar(ctx, owner, name, limit)
+}
+
+func (l *loggingMiddleware) BulkUpsertSimilar(ctx context.Context, similar []SimilarRepos) (err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, l.logger,
+			logger.Method("BulkUpsertSimilar"),
+			logger.Duration(start),
+			zap.Int("count", len(similar)))
+
+		logger.Maybe(L, "bulk upsert similar repos", err)
+	}(time.Now())
+	return l.next.BulkUpsertSimilar(ctx, similar)
 }
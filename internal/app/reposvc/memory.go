@@ -0,0 +1,547 @@
+package reposvc
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/kv"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/order"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// memoryStore keeps the repos in memory, for the tests and the demo mode. The queries and
+// aggregations follow the semantics of the mongo store, where a date field is missing
+// when it is empty, e.g. the push date of a repo that has never been pushed to. With the
+// buckets, the repos and the similar repos are written through to them as well. The fences
+// of the last writes are only kept in memory, since the buckets are not shared with other
+// instances
+type memoryStore struct {
+	sync.RWMutex
+	repos   map[string]schema.Repo
+	similar map[string]SimilarRepos
+
+	repoFences    map[string]fence.Fence
+	similarFences map[string]fence.Fence
+
+	reposBucket   kv.Bucket
+	similarBucket kv.Bucket
+}
+
+// NewMemoryStore returns an empty store that keeps the repos in memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		repos:         make(map[string]schema.Repo),
+		similar:       make(map[string]SimilarRepos),
+		repoFences:    make(map[string]fence.Fence),
+		similarFences: make(map[string]fence.Fence),
+	}
+}
+
+// NewKVStore returns a store that keeps the repos and the similar repos in memory and in
+// their buckets, with the records already in the buckets
+func NewKVStore(repos, similar kv.Bucket) (Store, error) {
+	s := &memoryStore{
+		repos:         make(map[string]schema.Repo),
+		similar:       make(map[string]SimilarRepos),
+		repoFences:    make(map[string]fence.Fence),
+		similarFences: make(map[string]fence.Fence),
+		reposBucket:   repos,
+		similarBucket: similar,
+	}
+	if err := repos.ForEach(func(key string, value []byte) error {
+		var r schema.Repo
+		if err := bson.Unmarshal(value, &r); err != nil {
+			return err
+		}
+		s.repos[key] = r
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := similar.ForEach(func(key string, value []byte) error {
+		var r SimilarRepos
+		if err := bson.Unmarshal(value, &r); err != nil {
+			return err
+		}
+		s.similar[key] = r
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryStore) Init() error {
+	return nil
+}
+
+func (s *memoryStore) Drop() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := kv.Clear(s.reposBucket); err != nil {
+		return err
+	}
+	s.repos = make(map[string]schema.Repo)
+	s.repoFences = make(map[string]fence.Fence)
+	return nil
+}
+
+// BulkUpsert upserts the repos, tagging them with the region of the owner. Under a lease,
+// no repo is written when one was last written under a newer token of the lease, and
+// fence.ErrStale is returned instead
+func (s *memoryStore) BulkUpsert(f fence.Fence, repos []github.Repo, region string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, repo := range repos {
+		if f.Stale(s.repoFences[repo.NameWithOwner]) {
+			return fence.ErrStale
+		}
+	}
+
+	changed := make(map[string]schema.Repo)
+	for _, repo := range repos {
+		r, ok := changed[repo.NameWithOwner]
+		if !ok {
+			r = s.repos[repo.NameWithOwner]
+		}
+		r = setGithubRepo(r, repo)
+		r.Region = region
+		changed[repo.NameWithOwner] = r
+	}
+	return s.save(f, changed)
+}
+
+// FindAll returns the repos of the region sorted by the keys, then by the name with owner.
+// A limit of zero returns all repos
+func (s *memoryStore) FindAll(region string, limit int, sort []string) ([]schema.Repo, error) {
+	repos := s.find(func(r schema.Repo) bool { return inRegion(r, region) })
+	sortRepos(repos, append(append([]string{}, sort...), "nameWithOwner")...)
+	return head(repos, limit), nil
+}
+
+func (s *memoryStore) FindOne(nameWithOwner string) (*schema.Repo, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	repo, ok := s.repos[nameWithOwner]
+	if !ok {
+		return nil, apperr.ErrNotFound
+	}
+	return &repo, nil
+}
+
+func (s *memoryStore) FindPage(filter Filter, after *Cursor, limit int) ([]schema.Repo, error) {
+	field, desc, _ := filter.SortField()
+	repos := s.find(func(r schema.Repo) bool {
+		if !matchFilter(r, filter) {
+			return false
+		}
+		if after == nil {
+			return true
+		}
+		c := order.Compare(repoValue(r, field), after.Value)
+		if desc {
+			c = -c
+		}
+		return c > 0 || (c == 0 && r.NameWithOwner > after.NameWithOwner)
+	})
+
+	key := field
+	if desc {
+		key = "-" + field
+	}
+	sortRepos(repos, key, "nameWithOwner")
+	return head(repos, limit), nil
+}
+
+func (s *memoryStore) CountBy(filter Filter) (int, error) {
+	return len(s.find(func(r schema.Repo) bool { return matchFilter(r, filter) })), nil
+}
+
+func (s *memoryStore) ReposBy(login string) ([]schema.Repo, error) {
+	repos := s.find(func(r schema.Repo) bool { return r.Login == login && !r.IsFork })
+	sortRepos(repos, "nameWithOwner")
+	return repos, nil
+}
+
+func (s *memoryStore) LastPushedBy(login string) (*schema.Repo, error) {
+	repos := s.find(func(r schema.Repo) bool { return r.Login == login && r.PushedAt != "" })
+	if len(repos) == 0 {
+		return &schema.Repo{}, apperr.ErrNotFound
+	}
+	sortRepos(repos, "-pushedAt", "nameWithOwner")
+	return &repos[0], nil
+}
+
+// FindOriginals returns the repos that are not forks, with only the fields used to find
+// the similar repos
+func (s *memoryStore) FindOriginals() ([]schema.Repo, error) {
+	repos := s.find(func(r schema.Repo) bool { return !r.IsFork })
+	sortRepos(repos, "nameWithOwner")
+
+	res := make([]schema.Repo, len(repos))
+	for i, r := range repos {
+		res[i] = schema.Repo{
+			NameWithOwner: r.NameWithOwner,
+			Description:   r.Description,
+			Languages:     r.Languages,
+			Topics:        r.Topics,
+			Stargazers:    r.Stargazers,
+		}
+	}
+	return res, nil
+}
+
+func (s *memoryStore) FindSimilar(nameWithOwner string) (*SimilarRepos, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	res, ok := s.similar[nameWithOwner]
+	if !ok {
+		return nil, apperr.ErrNotFound
+	}
+	return &res, nil
+}
+
+// BulkUpsertSimilar replaces the similar repos of each repo, with the same fencing as
+// BulkUpsert
+func (s *memoryStore) BulkUpsertSimilar(f fence.Fence, similar []SimilarRepos) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, r := range similar {
+		if f.Stale(s.similarFences[r.NameWithOwner]) {
+			return fence.ErrStale
+		}
+	}
+
+	records := make(map[string]interface{})
+	for _, r := range similar {
+		r.UpdatedAt = moment.NewUTCDate()
+		records[r.NameWithOwner] = r
+	}
+	if err := kv.Save(s.similarBucket, records); err != nil {
+		return err
+	}
+	for key, r := range records {
+		s.similar[key] = r.(SimilarRepos)
+		if !f.IsZero() {
+			s.similarFences[key] = f
+		}
+	}
+	return nil
+}
+
+// FindStale returns the repos with a node id, with the counters least recently refreshed
+func (s *memoryStore) FindStale(limit int) ([]schema.Repo, error) {
+	repos := s.find(func(r schema.Repo) bool { return r.NodeID != "" })
+	sortRepos(repos, "countersRefreshedAt", "nameWithOwner")
+	return head(repos, limit), nil
+}
+
+// BulkUpdateCounters updates the counters of the repos refreshed, and marks the repos that
+// no longer exist as refreshed
+func (s *memoryStore) BulkUpdateCounters(ids []string, repos []github.Repo) error {
+	s.Lock()
+	defer s.Unlock()
+
+	byID := make(map[string]github.Repo)
+	for _, repo := range repos {
+		byID[repo.ID] = repo
+	}
+	requested := make(map[string]bool)
+	for _, id := range ids {
+		requested[id] = true
+	}
+
+	changed := make(map[string]schema.Repo)
+	for key, r := range s.repos {
+		if repo, ok := byID[r.NodeID]; ok {
+			r = setCounters(r, repo)
+		} else if requested[r.NodeID] {
+			r.CountersRefreshedAt = moment.NewUTCDate()
+		} else {
+			continue
+		}
+		changed[key] = r
+	}
+	return s.save(fence.Fence{}, changed)
+}
+
+func (s *memoryStore) Count(region string) (int, error) {
+	return len(s.find(func(r schema.Repo) bool { return inRegion(r, region) })), nil
+}
+
+// Languages counts the original repos of the region by language, sorted by the count
+func (s *memoryStore) Languages(region string, limit int) ([]schema.LanguageCount, error) {
+	return countLanguages(s.find(func(r schema.Repo) bool {
+		return inRegion(r, region) && !r.IsFork
+	}), limit), nil
+}
+
+// LanguagesBy counts the original repos of the user by language, sorted by the count
+func (s *memoryStore) LanguagesBy(login string, limit int) ([]schema.LanguageCount, error) {
+	return countLanguages(s.find(func(r schema.Repo) bool {
+		return r.Login == login && !r.IsFork
+	}), limit), nil
+}
+
+// GroupByUser counts the original repos of the region by user, sorted by the count
+func (s *memoryStore) GroupByUser(region string, limit int) ([]schema.UserCount, error) {
+	return countUsers(s.find(func(r schema.Repo) bool {
+		return inRegion(r, region) && !r.IsFork
+	}), limit), nil
+}
+
+// GroupByLanguage counts the original repos of the region in the language by user, sorted
+// by the count
+func (s *memoryStore) GroupByLanguage(region, language string, limit int) ([]schema.UserCount, error) {
+	return countUsers(s.find(func(r schema.Repo) bool {
+		return inRegion(r, region) && !r.IsFork && contains(r.Languages, language)
+	}), limit), nil
+}
+
+// GroupByLanguageSortByMostRecent returns the most recently updated original repos of the
+// region in the language
+func (s *memoryStore) GroupByLanguageSortByMostRecent(region, language string, limit int) ([]schema.Repo, error) {
+	repos := s.find(func(r schema.Repo) bool {
+		return inRegion(r, region) && !r.IsFork && contains(r.Languages, language)
+	})
+	sortRepos(repos, "-updatedAt", "nameWithOwner")
+	return head(repos, limit), nil
+}
+
+// Distinct returns the distinct values of the field, where the values of the array fields
+// are counted separately as in mongo
+func (s *memoryStore) Distinct(field string) ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+	for _, r := range s.find(func(schema.Repo) bool { return true }) {
+		var values []string
+		switch field {
+		case "login":
+			values = []string{r.Login}
+		case "region":
+			values = []string{r.Region}
+		case "languages":
+			values = r.Languages
+		case "topics":
+			values = r.Topics
+		}
+		for _, v := range values {
+			if !seen[v] {
+				seen[v] = true
+				res = append(res, v)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// save writes the repos changed through to the bucket, then keeps them in memory with the
+// fence of the write. A write without a lease keeps the fence of the last write
+func (s *memoryStore) save(f fence.Fence, repos map[string]schema.Repo) error {
+	records := make(map[string]interface{}, len(repos))
+	for key, r := range repos {
+		records[key] = r
+	}
+	if err := kv.Save(s.reposBucket, records); err != nil {
+		return err
+	}
+	for key, r := range repos {
+		s.repos[key] = r
+		if !f.IsZero() {
+			s.repoFences[key] = f
+		}
+	}
+	return nil
+}
+
+// find returns a copy of the repos matching the predicate
+func (s *memoryStore) find(fn func(schema.Repo) bool) []schema.Repo {
+	s.RLock()
+	defer s.RUnlock()
+
+	var repos []schema.Repo
+	for _, r := range s.repos {
+		if fn(r) {
+			repos = append(repos, r)
+		}
+	}
+	return repos
+}
+
+// setGithubRepo sets the fields of the repo fetched from Github, the same fields set by the
+// mongo store. The push date is kept when the repo fetched has none
+func setGithubRepo(r schema.Repo, repo github.Repo) schema.Repo {
+	var languages, topics []string
+	var languageBytes []schema.LanguageSize
+	for _, lang := range repo.Languages.Edges {
+		languages = append(languages, lang.Node.Name)
+		languageBytes = append(languageBytes, schema.LanguageSize{
+			Name:  lang.Node.Name,
+			Bytes: lang.Size,
+		})
+	}
+	for _, node := range repo.RepositoryTopics.Nodes {
+		topics = append(topics, node.Topic.Name)
+	}
+
+	r = setCounters(r, repo)
+	r.NodeID = repo.ID
+	r.Name = repo.Name
+	r.CreatedAt = repo.CreatedAt.UTC().Format(time.RFC3339)
+	r.FetchedAt = moment.NewUTCDate()
+	r.Description = repo.Description
+	r.Languages = languages
+	r.LanguageBytes = languageBytes
+	r.PrimaryLanguage = repo.PrimaryLanguage.Name
+	r.License = repo.LicenseInfo.ID()
+	r.Topics = topics
+	r.HomepageURL = repo.HomepageURL
+	r.IsFork = repo.IsFork
+	r.NameWithOwner = repo.NameWithOwner
+	r.Login = repo.Owner.Login
+	r.AvatarURL = repo.Owner.AvatarURL
+	r.URL = repo.URL
+	return r
+}
+
+// setCounters sets the fields of the repo that change over time
+func setCounters(r schema.Repo, repo github.Repo) schema.Repo {
+	r.UpdatedAt = repo.UpdatedAt.UTC().Format(time.RFC3339)
+	if !repo.PushedAt.IsZero() {
+		r.PushedAt = repo.PushedAt.UTC().Format(time.RFC3339)
+	}
+	r.IsArchived = repo.IsArchived
+	r.DiskUsage = repo.DiskUsage
+	r.Forks = repo.ForkCount
+	r.Stargazers = repo.Stargazers.TotalCount
+	r.Watchers = repo.Watchers.TotalCount
+	r.CountersRefreshedAt = moment.NewUTCDate()
+	return r
+}
+
+// matchFilter is the in memory equivalent of byFilter. The repos without the sort field
+// are left out, where the counters are never missing and a date is missing when it is empty
+func matchFilter(r schema.Repo, f Filter) bool {
+	if !inRegion(r, f.Region) ||
+		(f.Language != "" && !contains(r.Languages, f.Language)) ||
+		(f.Owner != "" && r.Login != f.Owner) ||
+		(f.IsFork != nil && r.IsFork != *f.IsFork) ||
+		r.Stargazers < f.MinStars {
+		return false
+	}
+	if !inRange(r.CreatedAt, f.CreatedFrom, f.CreatedTo) ||
+		!inRange(r.PushedAt, f.PushedFrom, f.PushedTo) {
+		return false
+	}
+	field, _, _ := f.SortField()
+	return !order.Missing(repoValue(r, field))
+}
+
+// repoValue returns the value of the field of the repo, by the name of the field in mongo
+func repoValue(r schema.Repo, field string) interface{} {
+	switch field {
+	case "nameWithOwner":
+		return r.NameWithOwner
+	case "stargazers":
+		return r.Stargazers
+	case "forks":
+		return r.Forks
+	case "createdAt":
+		return r.CreatedAt
+	case "updatedAt":
+		return r.UpdatedAt
+	case "pushedAt":
+		return r.PushedAt
+	case "countersRefreshedAt":
+		return r.CountersRefreshedAt
+	}
+	return nil
+}
+
+// countLanguages counts the repos by language, the most used first
+func countLanguages(repos []schema.Repo, limit int) []schema.LanguageCount {
+	count := make(map[string]int)
+	for _, r := range repos {
+		for _, lang := range r.Languages {
+			count[lang]++
+		}
+	}
+	var res []schema.LanguageCount
+	for _, name := range order.Counts(count, limit) {
+		res = append(res, schema.LanguageCount{Name: name, Count: count[name]})
+	}
+	return res
+}
+
+// countUsers counts the repos by owner, the owner with the most repos first. The avatar is
+// taken from the first repo of the owner, as with $first
+func countUsers(repos []schema.Repo, limit int) []schema.UserCount {
+	sortRepos(repos, "nameWithOwner")
+	count := make(map[string]int)
+	avatars := make(map[string]string)
+	for _, r := range repos {
+		if _, ok := avatars[r.Login]; !ok {
+			avatars[r.Login] = r.AvatarURL
+		}
+		count[r.Login]++
+	}
+	var res []schema.UserCount
+	for _, login := range order.Counts(count, limit) {
+		res = append(res, schema.UserCount{
+			Name:      login,
+			Count:     count[login],
+			AvatarURL: avatars[login],
+		})
+	}
+	return res
+}
+
+func sortRepos(repos []schema.Repo, keys ...string) {
+	sort.SliceStable(repos, order.Less(keys, func(i int, field string) interface{} {
+		return repoValue(repos[i], field)
+	}))
+}
+
+func head(repos []schema.Repo, limit int) []schema.Repo {
+	if limit > 0 && len(repos) > limit {
+		return repos[:limit]
+	}
+	return repos
+}
+
+func inRegion(r schema.Repo, region string) bool {
+	return region == "" || r.Region == region
+}
+
+// inRange returns true if the date falls within the days from and to (inclusive). An empty
+// date is missing, and is never in a range
+func inRange(date, from, to string) bool {
+	if from == "" && to == "" {
+		return true
+	}
+	return date != "" &&
+		(from == "" || date >= from) &&
+		(to == "" || date <= to+"T23:59:59Z")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -8,37 +8,51 @@ package reposvc
 // Model should have all the params required explicitly defined at the arguments
 
 import (
-	"errors"
 	"log"
 
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 )
 
 var (
-	ErrInvalidLanguage = errors.New("language is required")
-	ErrInvalidLogin    = errors.New("login is required")
+	ErrInvalidLanguage = apperr.Validation("language is required")
+	ErrInvalidLogin    = apperr.Validation("login is required")
+	ErrInvalidName     = apperr.Validation("owner and name are required")
+	ErrInvalidDate     = apperr.Validation("date must be in the format YYYY-MM-DD")
+	ErrInvalidRange    = apperr.Validation("date range or min stars provided is invalid")
+	ErrInvalidSort     = apperr.Validation("sort must be one of stars, forks, createdAt or pushedAt")
+	ErrInvalidCursor   = apperr.Validation("cursor provided is invalid")
 )
 
 type (
 	// Model represents the interface for the repo service
 	Model interface {
-		BulkUpsert(repos []github.Repo) error
-		Count() (int, error)
+		BulkUpsert(f fence.Fence, repos []github.Repo, region string) error
+		Count(region string) (int, error)
 		Drop() error
 		Init() error
-		LastCreatedBy(login string) (*schema.Repo, error)
+		BulkUpdateCounters(ids []string, repos []github.Repo) error
+		FindStale(limit int) ([]schema.Repo, error)
+		LastPushedBy(login string) (*schema.Repo, error)
 		LanguageCountByUser(login string, limit int) ([]schema.LanguageCount, error)
-		MostPopularLanguage(limit int) ([]schema.LanguageCount, error)
-		MostRecent(limit int) ([]schema.Repo, error)
-		MostRecentReposByLanguage(language string, limit int) ([]schema.Repo, error)
-		MostStars(limit int) ([]schema.Repo, error)
-		MostForks(limit int) ([]schema.Repo, error)
-		RepoCountByUser(limit int) ([]schema.UserCount, error)
-		ReposByLanguage(language string, limit int) ([]schema.UserCount, error)
+		MostPopularLanguage(region string, limit int) ([]schema.LanguageCount, error)
+		MostRecent(region string, limit int) ([]schema.Repo, error)
+		MostRecentReposByLanguage(region, language string, limit int) ([]schema.Repo, error)
+		MostStars(region string, limit int) ([]schema.Repo, error)
+		MostForks(region string, limit int) ([]schema.Repo, error)
+		RepoCountByUser(region string, limit int) ([]schema.UserCount, error)
+		ReposByLanguage(region, language string, limit int) ([]schema.UserCount, error)
 		Distinct(field string) ([]string, error)
 		// GetProfile(login string) (*usersvc.User, error)
 		ReposBy(login string) ([]schema.Repo, error)
+		FindOne(owner, name string) (*schema.Repo, error)
+		FindOriginals() ([]schema.Repo, error)
+		FindSimilar(owner, name string, limit int) ([]schema.SimilarRepo, error)
+		BulkUpsertSimilar(f fence.Fence, similar []SimilarRepos) error
+		List(filter Filter, cursor string, limit int) (*Page, error)
+		CountBy(filter Filter) (int, error)
 	}
 
 	model struct {
@@ -56,16 +70,99 @@ func NewModel(store Store) Model {
 }
 
 // BulkUpsert inserts a list of docs if they do not exists, or updates them if they exist and values differs
-func (m *model) BulkUpsert(repos []github.Repo) error {
+func (m *model) BulkUpsert(f fence.Fence, repos []github.Repo, region string) error {
 	if len(repos) == 0 {
 		return nil
 	}
-	return m.store.BulkUpsert(repos)
+	return m.store.BulkUpsert(f, repos, region)
 }
 
-// Count returns the total count of the repos
-func (m *model) Count() (int, error) {
-	return m.store.Count()
+// Count returns the total count of the repos in the region, or all regions if empty
+func (m *model) Count(region string) (int, error) {
+	return m.store.Count(region)
+}
+
+// CountBy returns the number of repos matching the filter. The repos of the region are
+// counted as a whole when there is no other filter, including the repos that have no sort field
+func (m *model) CountBy(filter Filter) (int, error) {
+	if filter == (Filter{Region: filter.Region}) {
+		return m.store.Count(filter.Region)
+	}
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+	return m.store.CountBy(filter)
+}
+
+// FindOne returns the repo by the owner and the name
+func (m *model) FindOne(owner, name string) (*schema.Repo, error) {
+	if owner == "" || name == "" {
+		return nil, ErrInvalidName
+	}
+	return m.store.FindOne(owner + "/" + name)
+}
+
+// FindOriginals returns the repos that are not forks
+func (m *model) FindOriginals() ([]schema.Repo, error) {
+	return m.store.FindOriginals()
+}
+
+// FindSimilar returns the top repos that are most similar to the repo
+func (m *model) FindSimilar(owner, name string, limit int) ([]schema.SimilarRepo, error) {
+	if owner == "" || name == "" {
+		return nil, ErrInvalidName
+	}
+	limit = setLimit(limit)
+	res, err := m.store.FindSimilar(owner + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Similar) > limit {
+		return res.Similar[:limit], nil
+	}
+	return res.Similar, nil
+}
+
+// BulkUpsertSimilar replaces the similar repos of each repo
+func (m *model) BulkUpsertSimilar(f fence.Fence, similar []SimilarRepos) error {
+	if len(similar) == 0 {
+		return nil
+	}
+	return m.store.BulkUpsertSimilar(f, similar)
+}
+
+// List returns a page of the repos matching the filter, starting after the cursor
+func (m *model) List(filter Filter, cursor string, limit int) (*Page, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	var after *Cursor
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = c
+	}
+	limit = setLimit(limit)
+
+	// Fetch an extra repo to know if there is a next page
+	repos, err := m.store.FindPage(filter, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	count, err := m.store.CountBy(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := Page{Repos: repos, Count: count}
+	if len(repos) > limit {
+		field, _, _ := filter.SortField()
+		page.Repos = repos[:limit]
+		page.NextCursor = newCursor(page.Repos[limit-1], field).Encode()
+	}
+	return &page, nil
 }
 
 // Drop drops the collection
@@ -79,12 +176,26 @@ func (m *model) Init() error {
 	return m.store.Init()
 }
 
-// LastCreatedBy returns the last created datetime in the format YYYY-MM-DD for a particular user
-func (m *model) LastCreatedBy(login string) (*schema.Repo, error) {
+// LastPushedBy returns the most recently pushed repo for a particular user
+func (m *model) LastPushedBy(login string) (*schema.Repo, error) {
 	if login == "" {
 		return nil, ErrInvalidLogin
 	}
-	return m.store.LastCreatedBy(login)
+	return m.store.LastPushedBy(login)
+}
+
+// FindStale returns a limited results of repos with the least recently refreshed counters
+func (m *model) FindStale(limit int) ([]schema.Repo, error) {
+	limit = setLimit(limit)
+	return m.store.FindStale(limit)
+}
+
+// BulkUpdateCounters updates the counters of the repos refreshed, and marks the rest of the ids as refreshed
+func (m *model) BulkUpdateCounters(ids []string, repos []github.Repo) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return m.store.BulkUpdateCounters(ids, repos)
 }
 
 // LanguageCountByUser returns the top languages for a particular user
@@ -97,51 +208,51 @@ func (m *model) LanguageCountByUser(login string, limit int) ([]schema.LanguageC
 }
 
 // MostPopularLanguage returns the most frequent language based on repo count in descending order
-func (m *model) MostPopularLanguage(limit int) ([]schema.LanguageCount, error) {
+func (m *model) MostPopularLanguage(region string, limit int) ([]schema.LanguageCount, error) {
 	limit = setLimit(limit)
-	return m.store.Languages(limit)
+	return m.store.Languages(region, limit)
 }
 
 // MostRecent returns a limited results of repo that are recently updated
-func (m *model) MostRecent(limit int) ([]schema.Repo, error) {
+func (m *model) MostRecent(region string, limit int) ([]schema.Repo, error) {
 	limit = setLimit(limit)
-	return m.store.FindAll(limit, []string{"-updatedAt"})
+	return m.store.FindAll(region, limit, []string{"-updatedAt"})
 }
 
 // MostRecentReposByLanguage returns the most recent repos that are updated for a given language
-func (m *model) MostRecentReposByLanguage(language string, limit int) ([]schema.Repo, error) {
+func (m *model) MostRecentReposByLanguage(region, language string, limit int) ([]schema.Repo, error) {
 	limit = setLimit(limit)
 	if language == "" {
 		return nil, ErrInvalidLanguage
 	}
-	return m.store.GroupByLanguageSortByMostRecent(language, limit)
+	return m.store.GroupByLanguageSortByMostRecent(region, language, limit)
 }
 
 // MostStars returns a limited results of repos with the most stars
-func (m *model) MostStars(limit int) ([]schema.Repo, error) {
+func (m *model) MostStars(region string, limit int) ([]schema.Repo, error) {
 	limit = setLimit(limit)
-	return m.store.FindAll(limit, []string{"-stargazers"})
+	return m.store.FindAll(region, limit, []string{"-stargazers"})
 }
 
 // MostForks returns a limited results of repos with the most forks
-func (m *model) MostForks(limit int) ([]schema.Repo, error) {
+func (m *model) MostForks(region string, limit int) ([]schema.Repo, error) {
 	limit = setLimit(limit)
-	return m.store.FindAll(limit, []string{"-forks"})
+	return m.store.FindAll(region, limit, []string{"-forks"})
 }
 
 // RepoCountByUser returns the users with most repos sorted in descending order
-func (m *model) RepoCountByUser(limit int) ([]schema.UserCount, error) {
+func (m *model) RepoCountByUser(region string, limit int) ([]schema.UserCount, error) {
 	limit = setLimit(limit)
-	return m.store.GroupByUser(limit)
+	return m.store.GroupByUser(region, limit)
 }
 
 // ReposByLanguage returns the users with the most repo in the particular language
-func (m *model) ReposByLanguage(language string, limit int) ([]schema.UserCount, error) {
+func (m *model) ReposByLanguage(region, language string, limit int) ([]schema.UserCount, error) {
 	limit = setLimit(limit)
 	if language == "" {
 		return nil, ErrInvalidLanguage
 	}
-	return m.store.GroupByLanguage(language, limit)
+	return m.store.GroupByLanguage(region, language, limit)
 }
 
 // Distinct should return the distinct results for a given field
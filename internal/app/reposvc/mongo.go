@@ -0,0 +1,362 @@
+package reposvc
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/partitioner"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// mongoStore is the store on the official mongo driver. The queries and pipelines are the
+// ones of the mgo store, which the driver encodes the same
+type mongoStore struct {
+	db         *database.Mongo
+	collection string
+	similar    string
+}
+
+// NewMongoStore returns a new store on the official mongo driver, with the similar repos
+// kept in a separate collection
+func NewMongoStore(db *database.Mongo, collection, similar string) Store {
+	return &mongoStore{db, collection, similar}
+}
+
+func (s *mongoStore) Init() error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    database.Sort("nameWithOwner"),
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: database.Sort("login", "-pushedAt")},
+		{Keys: database.Sort("countersRefreshedAt")},
+	}
+	// Indexes for the sort fields of the paginated repos, with the name to break ties
+	for _, field := range sortFields {
+		indexes = append(indexes, mongo.IndexModel{
+			Keys: database.Sort("-"+field, "nameWithOwner"),
+		})
+	}
+	if _, err := s.db.Collection(s.collection).Indexes().CreateMany(ctx, indexes); err != nil {
+		return err
+	}
+	_, err := s.db.Collection(s.similar).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    database.Sort("nameWithOwner"),
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (s *mongoStore) Drop() error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	return s.db.Collection(s.collection).Drop(ctx)
+}
+
+// BulkUpsert upserts the repos, tagging them with the region of the owner, with the fencing
+// of the mgo store
+func (s *mongoStore) BulkUpsert(f fence.Fence, repos []github.Repo, region string) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	perBulk := 500
+	partitions, bucket := partitioner.New(perBulk, len(repos))
+
+	for i := 0; i < bucket; i++ {
+		p := partitions[i]
+
+		var models []mongo.WriteModel
+		for _, repo := range repos[p.Start:p.End] {
+			doc := repo.BSON()
+			doc["region"] = region
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(byFence(f, bson.M{"nameWithOwner": repo.NameWithOwner}, doc)).
+				SetUpdate(bson.M{"$set": doc}).
+				SetUpsert(true))
+		}
+		if _, err := s.db.Collection(s.collection).BulkWrite(ctx, models); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fence.ErrStale
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *mongoStore) FindAll(region string, limit int, sort []string) ([]schema.Repo, error) {
+	var repos []schema.Repo
+	err := s.find(s.collection, byRegion(region, bson.M{}), options.Find().
+		SetSort(database.Sort(sort...)).
+		SetLimit(int64(limit)), &repos)
+	return repos, err
+}
+
+func (s *mongoStore) FindOne(nameWithOwner string) (*schema.Repo, error) {
+	var repo schema.Repo
+	if err := s.findOne(s.collection, bson.M{"nameWithOwner": nameWithOwner}, nil, &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// FindPage returns the repos matching the filter, starting after the cursor when provided
+func (s *mongoStore) FindPage(filter Filter, after *Cursor, limit int) ([]schema.Repo, error) {
+	query, sort := byPage(filter, after)
+	var repos []schema.Repo
+	if err := s.find(s.collection, query, options.Find().
+		SetSort(database.Sort(sort...)).
+		SetLimit(int64(limit)), &repos); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// CountBy returns the number of repos matching the filter
+func (s *mongoStore) CountBy(filter Filter) (int, error) {
+	return s.count(byFilter(filter))
+}
+
+func (s *mongoStore) ReposBy(login string) ([]schema.Repo, error) {
+	var repos []schema.Repo
+	if err := s.find(s.collection, bson.M{
+		"login":  login,
+		"isFork": false,
+	}, nil, &repos); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// LastPushedBy returns the repo of the user that is pushed most recently
+func (s *mongoStore) LastPushedBy(login string) (*schema.Repo, error) {
+	var repo schema.Repo
+	err := s.findOne(s.collection, bson.M{
+		"login":    login,
+		"pushedAt": bson.M{"$exists": true},
+	}, options.FindOne().SetSort(database.Sort("-pushedAt")), &repo)
+	return &repo, err
+}
+
+// FindOriginals returns the repos that are not forks, with only the fields used to find
+// the similar repos
+func (s *mongoStore) FindOriginals() ([]schema.Repo, error) {
+	var repos []schema.Repo
+	err := s.find(s.collection, bson.M{"isFork": bson.M{"$ne": true}}, options.Find().
+		SetProjection(bson.M{
+			"nameWithOwner": 1,
+			"description":   1,
+			"languages":     1,
+			"topics":        1,
+			"stargazers":    1,
+		}), &repos)
+	return repos, err
+}
+
+func (s *mongoStore) FindSimilar(nameWithOwner string) (*SimilarRepos, error) {
+	var res SimilarRepos
+	if err := s.findOne(s.similar, bson.M{"nameWithOwner": nameWithOwner}, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// BulkUpsertSimilar replaces the similar repos of each repo, with the fencing of the mgo
+// store
+func (s *mongoStore) BulkUpsertSimilar(f fence.Fence, similar []SimilarRepos) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	perBulk := 500
+	partitions, bucket := partitioner.New(perBulk, len(similar))
+
+	for i := 0; i < bucket; i++ {
+		p := partitions[i]
+
+		var models []mongo.WriteModel
+		for _, r := range similar[p.Start:p.End] {
+			doc := bson.M{
+				"similar":   r.Similar,
+				"updatedAt": moment.NewUTCDate(),
+			}
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(byFence(f, bson.M{"nameWithOwner": r.NameWithOwner}, doc)).
+				SetUpdate(bson.M{"$set": doc}).
+				SetUpsert(true))
+		}
+		if _, err := s.db.Collection(s.similar).BulkWrite(ctx, models); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fence.ErrStale
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// FindStale returns the repos with the counters that are least recently refreshed. Only the repos
+// with a node id can be refreshed, the rest get theirs when the owner's repos are fetched again
+func (s *mongoStore) FindStale(limit int) ([]schema.Repo, error) {
+	var repos []schema.Repo
+	err := s.find(s.collection, bson.M{
+		"nodeId": bson.M{"$exists": true, "$ne": ""},
+	}, options.Find().
+		SetSort(database.Sort("countersRefreshedAt")).
+		SetLimit(int64(limit)), &repos)
+	return repos, err
+}
+
+// BulkUpdateCounters updates the counters of the repos refreshed. The repos that are requested
+// but no longer exist are marked as refreshed too, so that they do not hold up the rest
+func (s *mongoStore) BulkUpdateCounters(ids []string, repos []github.Repo) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	c := s.db.Collection(s.collection)
+	found := make(map[string]bool)
+	var models []mongo.WriteModel
+	for _, repo := range repos {
+		found[repo.ID] = true
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"nodeId": repo.ID}).
+			SetUpdate(bson.M{"$set": repo.CountersBSON()}))
+	}
+	if len(models) > 0 {
+		if _, err := c.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	_, err := c.UpdateMany(ctx,
+		bson.M{"nodeId": bson.M{"$in": missing}},
+		bson.M{"$set": bson.M{"countersRefreshedAt": moment.NewUTCDate()}},
+	)
+	return err
+}
+
+func (s *mongoStore) Count(region string) (int, error) {
+	return s.count(byRegion(region, bson.M{}))
+}
+
+// Languages returns the languages that exists in sorted by frequency
+func (s *mongoStore) Languages(region string, limit int) ([]schema.LanguageCount, error) {
+	var languages []schema.LanguageCount
+	err := s.aggregate(languagesPipeline(byRegion(region, bson.M{
+		"isFork": false,
+	}), limit), &languages)
+	return languages, err
+}
+
+// GroupByUser will return the repo count by user
+func (s *mongoStore) GroupByUser(region string, limit int) ([]schema.UserCount, error) {
+	var users []schema.UserCount
+	err := s.aggregate(usersPipeline(byRegion(region, bson.M{
+		"isFork": false,
+	}), limit), &users)
+	return users, err
+}
+
+// LanguagesBy returns the user's languages count
+func (s *mongoStore) LanguagesBy(login string, limit int) ([]schema.LanguageCount, error) {
+	var languages []schema.LanguageCount
+	err := s.aggregate(languagesPipeline(bson.M{
+		"isFork": false,
+		"login":  login,
+	}, limit), &languages)
+	return languages, err
+}
+
+// GroupByLanguageSortByMostRecent returns the most recent repos by language
+func (s *mongoStore) GroupByLanguageSortByMostRecent(region, language string, limit int) ([]schema.Repo, error) {
+	var repos []schema.Repo
+	err := s.aggregate(mostRecentPipeline(region, language, limit), &repos)
+	return repos, err
+}
+
+// GroupByLanguage returns the users by languages
+func (s *mongoStore) GroupByLanguage(region, language string, limit int) ([]schema.UserCount, error) {
+	var users []schema.UserCount
+	err := s.aggregate(byLanguagePipeline(region, language, limit), &users)
+	return users, err
+}
+
+// Distinct returns the distinct values of the field
+func (s *mongoStore) Distinct(field string) ([]string, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	values, err := s.db.Collection(s.collection).Distinct(ctx, field, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, v := range values {
+		if str, ok := v.(string); ok {
+			res = append(res, str)
+		}
+	}
+	return res, nil
+}
+
+// find decodes the documents of the collection matching the query into res
+func (s *mongoStore) find(collection string, query bson.M, opts *options.FindOptions, res interface{}) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	cur, err := s.db.Collection(collection).Find(ctx, query, opts)
+	if err != nil {
+		return err
+	}
+	return cur.All(ctx, res)
+}
+
+// findOne decodes the first document of the collection matching the query into res
+func (s *mongoStore) findOne(collection string, query bson.M, opts *options.FindOneOptions, res interface{}) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	err := s.db.Collection(collection).FindOne(ctx, query, opts).Decode(res)
+	if err == mongo.ErrNoDocuments {
+		return apperr.ErrNotFound
+	}
+	return err
+}
+
+func (s *mongoStore) count(query bson.M) (int, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	n, err := s.db.Collection(s.collection).CountDocuments(ctx, query)
+	return int(n), err
+}
+
+// aggregate decodes the result of the pipeline on the repos into res
+func (s *mongoStore) aggregate(pipeline []bson.M, res interface{}) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	cur, err := s.db.Collection(s.collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cur.All(ctx, res)
+}
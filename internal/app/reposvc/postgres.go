@@ -0,0 +1,390 @@
+package reposvc
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+)
+
+// isFork is the expression of the fork flag, where the repos stored without it are not forks
+const isFork = `COALESCE((doc->>'isFork')::boolean, false)`
+
+// postgresStore keeps the repos and the similar repos as jsonb documents in their tables,
+// keyed by the name with owner. The documents have the fields of the mongo documents, and
+// the queries follow the semantics of the memory store, where the counters are never
+// missing and a date is missing when it is empty
+type postgresStore struct {
+	db      *database.Postgres
+	table   string
+	similar string
+}
+
+// NewPostgresStore returns a new store on postgres, with the similar repos kept in a
+// separate table
+func NewPostgresStore(db *database.Postgres, table, similar string) Store {
+	return &postgresStore{db, table, similar}
+}
+
+func (s *postgresStore) Init() error {
+	indexes := []string{
+		`USING gin (doc jsonb_path_ops)`,
+		fmt.Sprintf(`((doc->>'login'), %s)`, repoColumn("pushedAt")),
+		`((doc->>'region'))`,
+		fmt.Sprintf(`(%s)`, repoColumn("countersRefreshedAt")),
+	}
+	// Indexes for the sort fields of the paginated repos, with the name to break ties
+	for _, field := range sortFields {
+		indexes = append(indexes, fmt.Sprintf(`(%s, %s)`, repoColumn(field), repoColumn("nameWithOwner")))
+	}
+	if err := s.db.CreateTable(s.table, indexes...); err != nil {
+		return err
+	}
+	return s.db.CreateTable(s.similar)
+}
+
+func (s *postgresStore) Drop() error {
+	_, err := s.db.Exec(fmt.Sprintf(`TRUNCATE %s`, s.table))
+	return err
+}
+
+// BulkUpsert upserts the repos, tagging them with the region of the owner. Under a lease,
+// no repo is written when one was last written under a newer token of the lease, and
+// fence.ErrStale is returned instead
+func (s *postgresStore) BulkUpsert(f fence.Fence, repos []github.Repo, region string) error {
+	docs := make([]database.Doc, len(repos))
+	for i, repo := range repos {
+		set := repo.BSON()
+		set["region"] = region
+		docs[i] = database.Doc{ID: repo.NameWithOwner, Set: set}
+	}
+	return s.db.Upsert(s.table, f, docs...)
+}
+
+func (s *postgresStore) FindAll(region string, limit int, sort []string) ([]schema.Repo, error) {
+	var w database.Where
+	byPostgresRegion(&w, region)
+	return s.find(fmt.Sprintf(`%s%s%s`, w.String(), repoOrder(sort...), database.Limit(limit)), w.Args...)
+}
+
+func (s *postgresStore) FindOne(nameWithOwner string) (*schema.Repo, error) {
+	var repo schema.Repo
+	if err := s.db.FindOne(&repo, fmt.Sprintf(`SELECT doc FROM %s WHERE id = $1`, s.table), nameWithOwner); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// FindPage returns the repos matching the filter, starting after the cursor when provided
+func (s *postgresStore) FindPage(filter Filter, after *Cursor, limit int) ([]schema.Repo, error) {
+	field, desc, _ := filter.SortField()
+	w := byPostgresFilter(filter)
+	if after != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		w.Add(fmt.Sprintf(`%[1]s %[2]s %%[1]s OR (%[1]s = %%[1]s AND %[3]s > %%[2]s)`,
+			repoColumn(field), op, repoColumn("nameWithOwner")), after.Value, after.NameWithOwner)
+	}
+	key := field
+	if desc {
+		key = "-" + field
+	}
+	return s.find(fmt.Sprintf(`%s%s%s`, w.String(), repoOrder(key), database.Limit(limit)), w.Args...)
+}
+
+// CountBy returns the number of repos matching the filter
+func (s *postgresStore) CountBy(filter Filter) (int, error) {
+	w := byPostgresFilter(filter)
+	return s.db.Count(fmt.Sprintf(`SELECT count(*) FROM %s%s`, s.table, w.String()), w.Args...)
+}
+
+func (s *postgresStore) ReposBy(login string) ([]schema.Repo, error) {
+	return s.find(fmt.Sprintf(`WHERE doc->>'login' = $1 AND NOT %s%s`, isFork, repoOrder()), login)
+}
+
+// LastPushedBy returns the repo of the user that is pushed most recently
+func (s *postgresStore) LastPushedBy(login string) (*schema.Repo, error) {
+	repos, err := s.find(fmt.Sprintf(`WHERE doc->>'login' = $1 AND %s IS NOT NULL%s LIMIT 1`,
+		repoColumn("pushedAt"), repoOrder("-pushedAt")), login)
+	if err != nil {
+		return nil, err
+	}
+	if len(repos) == 0 {
+		return &schema.Repo{}, apperr.ErrNotFound
+	}
+	return &repos[0], nil
+}
+
+// FindOriginals returns the repos that are not forks, with only the fields used to find
+// the similar repos
+func (s *postgresStore) FindOriginals() ([]schema.Repo, error) {
+	repos, err := s.find(fmt.Sprintf(`WHERE NOT %s%s`, isFork, repoOrder()))
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range repos {
+		repos[i] = schema.Repo{
+			NameWithOwner: r.NameWithOwner,
+			Description:   r.Description,
+			Languages:     r.Languages,
+			Topics:        r.Topics,
+			Stargazers:    r.Stargazers,
+		}
+	}
+	return repos, nil
+}
+
+func (s *postgresStore) FindSimilar(nameWithOwner string) (*SimilarRepos, error) {
+	var res SimilarRepos
+	if err := s.db.FindOne(&res, fmt.Sprintf(`SELECT doc FROM %s WHERE id = $1`, s.similar), nameWithOwner); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// BulkUpsertSimilar replaces the similar repos of each repo, with the same fencing as
+// BulkUpsert
+func (s *postgresStore) BulkUpsertSimilar(f fence.Fence, similar []SimilarRepos) error {
+	docs := make([]database.Doc, len(similar))
+	for i, r := range similar {
+		docs[i] = database.Doc{
+			ID: r.NameWithOwner,
+			Set: map[string]interface{}{
+				"nameWithOwner": r.NameWithOwner,
+				"similar":       r.Similar,
+				"updatedAt":     moment.NewUTCDate(),
+			},
+		}
+	}
+	return s.db.Upsert(s.similar, f, docs...)
+}
+
+// FindStale returns the repos with a node id, with the counters least recently refreshed
+func (s *postgresStore) FindStale(limit int) ([]schema.Repo, error) {
+	return s.find(fmt.Sprintf(`WHERE %s IS NOT NULL%s%s`,
+		repoColumn("nodeId"), repoOrder("countersRefreshedAt"), database.Limit(limit)))
+}
+
+// BulkUpdateCounters updates the counters of the repos refreshed, and marks the repos that
+// no longer exist as refreshed
+func (s *postgresStore) BulkUpdateCounters(ids []string, repos []github.Repo) error {
+	found := make(map[string]bool)
+	return s.db.Tx(func(tx *sql.Tx) error {
+		for _, repo := range repos {
+			found[repo.ID] = true
+			if err := s.update(tx, repo.ID, repo.CountersBSON()); err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			if found[id] {
+				continue
+			}
+			if err := s.update(tx, id, map[string]interface{}{
+				"countersRefreshedAt": moment.NewUTCDate(),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *postgresStore) Count(region string) (int, error) {
+	var w database.Where
+	byPostgresRegion(&w, region)
+	return s.db.Count(fmt.Sprintf(`SELECT count(*) FROM %s%s`, s.table, w.String()), w.Args...)
+}
+
+// Languages counts the original repos of the region by language, sorted by the count
+func (s *postgresStore) Languages(region string, limit int) ([]schema.LanguageCount, error) {
+	var w database.Where
+	byPostgresRegion(&w, region)
+	w.Add("NOT " + isFork)
+	return s.countLanguages(&w, limit)
+}
+
+// LanguagesBy counts the original repos of the user by language, sorted by the count
+func (s *postgresStore) LanguagesBy(login string, limit int) ([]schema.LanguageCount, error) {
+	var w database.Where
+	w.Add(`doc->>'login' = %s`, login)
+	w.Add("NOT " + isFork)
+	return s.countLanguages(&w, limit)
+}
+
+// GroupByUser counts the original repos of the region by user, sorted by the count
+func (s *postgresStore) GroupByUser(region string, limit int) ([]schema.UserCount, error) {
+	var w database.Where
+	byPostgresRegion(&w, region)
+	w.Add("NOT " + isFork)
+	return s.countUsers(&w, limit)
+}
+
+// GroupByLanguage counts the original repos of the region in the language by user, sorted
+// by the count
+func (s *postgresStore) GroupByLanguage(region, language string, limit int) ([]schema.UserCount, error) {
+	var w database.Where
+	byPostgresRegion(&w, region)
+	w.Add("NOT " + isFork)
+	byPostgresLanguage(&w, language)
+	return s.countUsers(&w, limit)
+}
+
+// GroupByLanguageSortByMostRecent returns the most recently updated original repos of the
+// region in the language
+func (s *postgresStore) GroupByLanguageSortByMostRecent(region, language string, limit int) ([]schema.Repo, error) {
+	var w database.Where
+	byPostgresRegion(&w, region)
+	w.Add("NOT " + isFork)
+	byPostgresLanguage(&w, language)
+	return s.find(fmt.Sprintf(`%s%s%s`, w.String(), repoOrder("-updatedAt"), database.Limit(limit)), w.Args...)
+}
+
+// Distinct returns the distinct values of the field, where the values of the array fields
+// are counted separately as in mongo
+func (s *postgresStore) Distinct(field string) ([]string, error) {
+	value := fmt.Sprintf(`doc->'%s'`, field)
+	var res []string
+	err := s.db.Rows(func(rows *sql.Rows) error {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return err
+		}
+		res = append(res, v)
+		return nil
+	}, fmt.Sprintf(`SELECT DISTINCT d.v FROM %s, jsonb_array_elements_text(CASE jsonb_typeof(%s) WHEN 'string' THEN jsonb_build_array(%[2]s) ELSE %s END) AS d(v)`,
+		s.table, value, database.JSONArray(value)))
+	sort.Strings(res)
+	return res, err
+}
+
+// find returns the repos of the rest of the query, after the select
+func (s *postgresStore) find(query string, args ...interface{}) ([]schema.Repo, error) {
+	var repos []schema.Repo
+	if err := s.db.Find(&repos, fmt.Sprintf(`SELECT doc FROM %s %s`, s.table, query), args...); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// update sets the fields of the repo with the node id, if it exists
+func (s *postgresStore) update(tx *sql.Tx, nodeID string, set map[string]interface{}) error {
+	var w database.Where
+	doc, err := w.JSONArg(set)
+	if err != nil {
+		return err
+	}
+	w.Add(`doc->>'nodeId' = %s`, nodeID)
+	_, err = tx.Exec(fmt.Sprintf(`UPDATE %s SET doc = doc || %s%s`, s.table, doc, w.String()), w.Args...)
+	return err
+}
+
+// countLanguages counts the repos matching the conditions by language, the most used first
+func (s *postgresStore) countLanguages(w *database.Where, limit int) ([]schema.LanguageCount, error) {
+	var res []schema.LanguageCount
+	err := s.db.Rows(func(rows *sql.Rows) error {
+		var l schema.LanguageCount
+		if err := rows.Scan(&l.Name, &l.Count); err != nil {
+			return err
+		}
+		res = append(res, l)
+		return nil
+	}, fmt.Sprintf(`SELECT l.name, count(*) FROM %s, jsonb_array_elements_text(%s) AS l(name)%s GROUP BY l.name ORDER BY count(*) DESC, l.name COLLATE "C"%s`,
+		s.table, database.JSONArray("doc->'languages'"), w.String(), database.Limit(limit)), w.Args...)
+	return res, err
+}
+
+// countUsers counts the repos matching the conditions by owner, the owner with the most repos
+// first. The avatar is taken from the first repo of the owner by name
+func (s *postgresStore) countUsers(w *database.Where, limit int) ([]schema.UserCount, error) {
+	var res []schema.UserCount
+	err := s.db.Rows(func(rows *sql.Rows) error {
+		var u schema.UserCount
+		var avatar sql.NullString
+		if err := rows.Scan(&u.Name, &u.Count, &avatar); err != nil {
+			return err
+		}
+		u.AvatarURL = avatar.String
+		res = append(res, u)
+		return nil
+	}, fmt.Sprintf(`SELECT doc->>'login', count(*), (array_agg(doc->>'avatarUrl' ORDER BY %s))[1] FROM %s%s GROUP BY doc->>'login' ORDER BY count(*) DESC, (doc->>'login') COLLATE "C"%s`,
+		repoColumn("nameWithOwner"), s.table, w.String(), database.Limit(limit)), w.Args...)
+	return res, err
+}
+
+// byPostgresFilter builds the conditions of the filter, the equivalent of byFilter. The
+// repos without the sort field are left out
+func byPostgresFilter(f Filter) *database.Where {
+	var w database.Where
+	byPostgresRegion(&w, f.Region)
+	byPostgresLanguage(&w, f.Language)
+	if f.Owner != "" {
+		w.Add(`doc->>'login' = %s`, f.Owner)
+	}
+	if f.IsFork != nil {
+		w.Add(isFork+" = %s", *f.IsFork)
+	}
+	if f.MinStars > 0 {
+		w.Add(repoColumn("stargazers")+" >= %s", f.MinStars)
+	}
+	byPostgresRange(&w, "createdAt", f.CreatedFrom, f.CreatedTo)
+	byPostgresRange(&w, "pushedAt", f.PushedFrom, f.PushedTo)
+	if field, _, ok := f.SortField(); ok {
+		w.Add(repoColumn(field) + " IS NOT NULL")
+	}
+	return &w
+}
+
+func byPostgresRegion(w *database.Where, region string) {
+	if region != "" {
+		w.Add(`doc->>'region' = %s`, region)
+	}
+}
+
+func byPostgresLanguage(w *database.Where, language string) {
+	if language != "" {
+		w.Add(`doc->'languages' @> jsonb_build_array(%s::text)`, language)
+	}
+}
+
+// byPostgresRange matches the dates of the field that fall within the days from and to
+// (inclusive)
+func byPostgresRange(w *database.Where, field, from, to string) {
+	if from != "" {
+		w.Add(repoColumn(field)+" >= %s", from)
+	}
+	if to != "" {
+		w.Add(repoColumn(field)+" <= %s", to+"T23:59:59Z")
+	}
+}
+
+// repoColumn returns the expression of the field of the repo, by the name of the field in
+// mongo
+func repoColumn(field string) string {
+	switch field {
+	case "nameWithOwner":
+		return `id COLLATE "C"`
+	case "stargazers", "forks", "watchers", "diskUsage":
+		return database.JSONInt(field)
+	}
+	return database.JSONText(field)
+}
+
+// repoOrder returns the order by clause of the sort keys, then of the name with owner
+func repoOrder(keys ...string) string {
+	terms := make([]string, 0, len(keys)+1)
+	for _, key := range append(append([]string{}, keys...), "nameWithOwner") {
+		desc := strings.HasPrefix(key, "-")
+		terms = append(terms, database.OrderBy(repoColumn(strings.TrimPrefix(key, "-")), desc))
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
@@ -8,9 +8,13 @@ import (
 // building each stages of the service - store, model and service,
 // which can be further decorated
 
-// New returns a new service with store
+// New returns a new service with the mgo store
 func New(db *database.DB, middlewares ...Middleware) Service {
-	store := NewStore(db, database.Repos)
+	return NewWithStore(NewStore(db, database.Repos, database.SimilarRepos), middlewares...)
+}
+
+// NewWithStore returns a new service with the store of any storage driver
+func NewWithStore(store Store, middlewares ...Middleware) Service {
 	model := NewModel(store)
 	service := NewService(model)
 
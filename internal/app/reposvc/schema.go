@@ -0,0 +1,11 @@
+package reposvc
+
+import "github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+// SimilarRepos represents the repos that are most similar to the repo, sorted from the most
+// similar
+type SimilarRepos struct {
+	NameWithOwner string               `json:"nameWithOwner" bson:"nameWithOwner"`
+	Similar       []schema.SimilarRepo `json:"similar" bson:"similar"`
+	UpdatedAt     string               `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+}
@@ -10,30 +10,38 @@ package reposvc
 import (
 	"context"
 	"sort"
-	"time"
 
 	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/bow"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
-	"github.com/alextanhongpin/go-github-scraper/internal/pkg/constant"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 )
 
 // Service represents the interface for the repo service
 type (
 	Service interface {
-		BulkUpsert(ctx context.Context, repos []github.Repo) error
-		Count(ctx context.Context) (int, error)
-		LastCreatedBy(ctx context.Context, login string) (string, bool)
-		MostPopularLanguage(ctx context.Context, limit int) ([]schema.LanguageCount, error)
-		MostRecent(ctx context.Context, limit int) ([]schema.Repo, error)
-		MostRecentReposByLanguage(ctx context.Context, language string, limit int) ([]schema.Repo, error)
-		MostStars(ctx context.Context, limit int) ([]schema.Repo, error)
-		MostForks(ctx context.Context, limit int) ([]schema.Repo, error)
-		RepoCountByUser(ctx context.Context, limit int) ([]schema.UserCount, error)
-		ReposByLanguage(ctx context.Context, language string, limit int) ([]schema.UserCount, error)
+		BulkUpsert(ctx context.Context, repos []github.Repo, region string) error
+		Count(ctx context.Context, region string) (int, error)
+		LastPushedBy(ctx context.Context, login string) (string, bool)
+		FindStale(ctx context.Context, limit int) ([]schema.Repo, error)
+		RefreshCounters(ctx context.Context, ids []string, repos []github.Repo) error
+		MostPopularLanguage(ctx context.Context, region string, limit int) ([]schema.LanguageCount, error)
+		MostRecent(ctx context.Context, region string, limit int) ([]schema.Repo, error)
+		MostRecentReposByLanguage(ctx context.Context, region, language string, limit int) ([]schema.Repo, error)
+		MostStars(ctx context.Context, region string, limit int) ([]schema.Repo, error)
+		MostForks(ctx context.Context, region string, limit int) ([]schema.Repo, error)
+		RepoCountByUser(ctx context.Context, region string, limit int) ([]schema.UserCount, error)
+		ReposByLanguage(ctx context.Context, region, language string, limit int) ([]schema.UserCount, error)
 		Distinct(ctx context.Context, login string) ([]string, error)
-		GetProfile(ctx context.Context, login string) (*usersvc.User, error)
+		Corpus(ctx context.Context) (*bow.Corpus, error)
+		GetProfile(ctx context.Context, login string, corpus *bow.Corpus) (*usersvc.User, error)
+		FindOne(ctx context.Context, owner, name string) (*schema.Repo, error)
+		FindOriginals(ctx context.Context) ([]schema.Repo, error)
+		FindSimilar(ctx context.Context, owner, name string, limit int) ([]schema.SimilarRepo, error)
+		BulkUpsertSimilar(ctx context.Context, similar []SimilarRepos) error
+		List(ctx context.Context, filter Filter, cursor string, limit int) (*Page, error)
+		CountBy(ctx context.Context, filter Filter) (int, error)
 	}
 
 	service struct {
@@ -48,63 +56,88 @@ func NewService(model Model) Service {
 	}
 }
 
-func (s *service) BulkUpsert(ctx context.Context, repos []github.Repo) error {
-	return s.model.BulkUpsert(repos)
+func (s *service) BulkUpsert(ctx context.Context, repos []github.Repo, region string) error {
+	return s.model.BulkUpsert(fence.FromContext(ctx), repos, region)
 }
 
-func (s *service) Count(ctx context.Context) (int, error) {
-	return s.model.Count()
+func (s *service) Count(ctx context.Context, region string) (int, error) {
+	return s.model.Count(region)
 }
 
-func (s *service) LastCreatedBy(ctx context.Context, login string) (string, bool) {
-	repo, err := s.model.LastCreatedBy(login)
-	if err != nil || repo == nil {
-		return constant.GithubCreatedAt, false
+// LastPushedBy returns the push date of the user's most recently pushed repo in the RFC3339 format,
+// or false if none of the user's repos have been fetched with the push date
+func (s *service) LastPushedBy(ctx context.Context, login string) (string, bool) {
+	repo, err := s.model.LastPushedBy(login)
+	if err != nil || repo == nil || repo.PushedAt == "" {
+		return "", false
 	}
-	t, err := time.Parse(time.RFC3339, repo.CreatedAt)
-	if err != nil {
-		return constant.GithubCreatedAt, false
-	}
-	// Deduct a day
-	t = t.AddDate(0, -1, 0)
-	return t.Format("2006-01-02"), true
+	return repo.PushedAt, true
+}
+
+// FindStale returns the repos with the least recently refreshed counters
+func (s *service) FindStale(ctx context.Context, limit int) ([]schema.Repo, error) {
+	return s.model.FindStale(limit)
 }
 
-func (s *service) MostPopularLanguage(ctx context.Context, limit int) ([]schema.LanguageCount, error) {
-	return s.model.MostPopularLanguage(limit)
+// RefreshCounters stores the counters of the repos refreshed by id
+func (s *service) RefreshCounters(ctx context.Context, ids []string, repos []github.Repo) error {
+	return s.model.BulkUpdateCounters(ids, repos)
 }
 
-func (s *service) MostRecent(ctx context.Context, limit int) ([]schema.Repo, error) {
-	return s.model.MostRecent(limit)
+func (s *service) MostPopularLanguage(ctx context.Context, region string, limit int) ([]schema.LanguageCount, error) {
+	return s.model.MostPopularLanguage(region, limit)
 }
 
-func (s *service) MostRecentReposByLanguage(ctx context.Context, language string, limit int) ([]schema.Repo, error) {
-	return s.model.MostRecentReposByLanguage(language, limit)
+func (s *service) MostRecent(ctx context.Context, region string, limit int) ([]schema.Repo, error) {
+	return s.model.MostRecent(region, limit)
 }
 
-func (s *service) MostStars(ctx context.Context, limit int) ([]schema.Repo, error) {
-	return s.model.MostStars(limit)
+func (s *service) MostRecentReposByLanguage(ctx context.Context, region, language string, limit int) ([]schema.Repo, error) {
+	return s.model.MostRecentReposByLanguage(region, language, limit)
 }
 
-func (s *service) MostForks(ctx context.Context, limit int) ([]schema.Repo, error) {
-	return s.model.MostForks(limit)
+func (s *service) MostStars(ctx context.Context, region string, limit int) ([]schema.Repo, error) {
+	return s.model.MostStars(region, limit)
 }
 
-func (s *service) RepoCountByUser(ctx context.Context, limit int) ([]schema.UserCount, error) {
-	return s.model.RepoCountByUser(limit)
+func (s *service) MostForks(ctx context.Context, region string, limit int) ([]schema.Repo, error) {
+	return s.model.MostForks(region, limit)
 }
 
-func (s *service) ReposByLanguage(ctx context.Context, language string, limit int) ([]schema.UserCount, error) {
-	return s.model.ReposByLanguage(language, limit)
+func (s *service) RepoCountByUser(ctx context.Context, region string, limit int) ([]schema.UserCount, error) {
+	return s.model.RepoCountByUser(region, limit)
+}
+
+func (s *service) ReposByLanguage(ctx context.Context, region, language string, limit int) ([]schema.UserCount, error) {
+	return s.model.ReposByLanguage(region, language, limit)
 }
 
 func (s *service) Distinct(ctx context.Context, field string) ([]string, error) {
 	return s.model.Distinct(field)
 }
 
-func (s *service) GetProfile(ctx context.Context, login string) (*usersvc.User, error) {
+// Corpus returns the document frequency of the terms in the descriptions of the original
+// repos, which is used to weight the keywords of the profiles
+func (s *service) Corpus(ctx context.Context) (*bow.Corpus, error) {
+	repos, err := s.model.FindOriginals()
+	if err != nil {
+		return nil, err
+	}
+	corpus := bow.NewCorpus(bow.KeywordOptions)
+	for _, repo := range repos {
+		corpus.Add(repo.Description)
+	}
+	return corpus, nil
+}
+
+// GetProfile aggregates the user's repos into a profile. The languages are weighted by the
+// bytes written in each language, rather than the number of repos using it. The keywords
+// are ranked by their tf-idf against the corpus, so that the words specific to the user's
+// repos are ranked above the words found in most descriptions. Without a corpus, the
+// keywords are ranked by their count. Each keyword keeps its stem, which the users are
+// filtered by, and its score, which weights it in the matches
+func (s *service) GetProfile(ctx context.Context, login string, corpus *bow.Corpus) (*usersvc.User, error) {
 	var watchers, stargazers, forks int64
-	var languageList []string
 	var descriptions []string
 
 	var keywords []schema.Keyword
@@ -115,30 +148,54 @@ func (s *service) GetProfile(ctx context.Context, login string) (*usersvc.User,
 		return nil, err
 	}
 
+	languageCount := make(map[string]int)
+	languageBytes := make(map[string]int64)
 	for i := 0; i < len(repos); i++ {
 		repo := repos[i]
 		stargazers += repo.Stargazers
 		watchers += repo.Watchers
 		forks += repo.Forks
-		languageList = append(languageList, repo.Languages...)
+		for _, lang := range repo.Languages {
+			languageCount[lang]++
+		}
+		for _, lang := range repo.LanguageBytes {
+			languageBytes[lang.Name] += lang.Bytes
+		}
 		descriptions = append(descriptions, repo.Description)
 	}
 
-	topKeywords := bow.Top(bow.Parse(descriptions...), 20)
-	for _, k := range topKeywords {
-		keywords = append(keywords, schema.Keyword{ID: k.Key, Value: k.Value})
+	if corpus == nil {
+		corpus = bow.NewCorpus(bow.KeywordOptions)
+	}
+	for _, k := range corpus.Keywords(20, descriptions...) {
+		keywords = append(keywords, schema.Keyword{
+			ID:    k.Key,
+			Text:  k.Text,
+			Value: k.Count,
+			Score: k.Score,
+		})
 	}
-	sort.SliceStable(keywords, func(i, j int) bool {
-		return keywords[i].Value > keywords[j].Value
-	})
 
-	topLanguages := bow.Top(languageList, 20)
-	for _, k := range topLanguages {
-		languages = append(languages, schema.LanguageCount{Name: k.Key, Count: k.Value})
+	for name, count := range languageCount {
+		languages = append(languages, schema.LanguageCount{
+			Name:  name,
+			Count: count,
+			Bytes: languageBytes[name],
+		})
 	}
+	// Repos fetched before the sizes were stored have no bytes, fall back to the repo count
 	sort.SliceStable(languages, func(i, j int) bool {
-		return languages[i].Count > languages[j].Count
+		if languages[i].Bytes != languages[j].Bytes {
+			return languages[i].Bytes > languages[j].Bytes
+		}
+		if languages[i].Count != languages[j].Count {
+			return languages[i].Count > languages[j].Count
+		}
+		return languages[i].Name < languages[j].Name
 	})
+	if len(languages) > 20 {
+		languages = languages[:20]
+	}
 
 	return &usersvc.User{
 		Login: login,
@@ -151,3 +208,28 @@ func (s *service) GetProfile(ctx context.Context, login string) (*usersvc.User,
 		},
 	}, nil
 }
+
+func (s *service) FindOne(ctx context.Context, owner, name string) (*schema.Repo, error) {
+	return s.model.FindOne(owner, name)
+}
+
+func (s *service) List(ctx context.Context, filter Filter, cursor string, limit int) (*Page, error) {
+	return s.model.List(filter, cursor, limit)
+}
+
+// CountBy returns the number of repos matching the filter, without listing them
+func (s *service) CountBy(ctx context.Context, filter Filter) (int, error) {
+	return s.model.CountBy(filter)
+}
+
+func (s *service) FindOriginals(ctx context.Context) ([]schema.Repo, error) {
+	return s.model.FindOriginals()
+}
+
+func (s *service) FindSimilar(ctx context.Context, owner, name string, limit int) ([]schema.SimilarRepo, error) {
+	return s.model.FindSimilar(owner, name, limit)
+}
+
+func (s *service) BulkUpsertSimilar(ctx context.Context, similar []SimilarRepos) error {
+	return s.model.BulkUpsertSimilar(fence.FromContext(ctx), similar)
+}
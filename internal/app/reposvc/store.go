@@ -3,6 +3,8 @@ package reposvc
 import (
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/partitioner"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 
@@ -17,21 +19,29 @@ import (
 type (
 	// Read defines all read operations by the store
 	Read interface {
-		Count() (int, error)
+		Count(region string) (int, error)
 		Distinct(field string) ([]string, error)
-		FindAll(limit int, sort []string) ([]schema.Repo, error)
-		GroupByLanguage(language string, limit int) ([]schema.UserCount, error)
-		GroupByLanguageSortByMostRecent(language string, limit int) ([]schema.Repo, error)
-		GroupByUser(limit int) ([]schema.UserCount, error)
-		Languages(limit int) ([]schema.LanguageCount, error)
+		FindAll(region string, limit int, sort []string) ([]schema.Repo, error)
+		FindOne(nameWithOwner string) (*schema.Repo, error)
+		FindPage(filter Filter, after *Cursor, limit int) ([]schema.Repo, error)
+		CountBy(filter Filter) (int, error)
+		GroupByLanguage(region, language string, limit int) ([]schema.UserCount, error)
+		GroupByLanguageSortByMostRecent(region, language string, limit int) ([]schema.Repo, error)
+		GroupByUser(region string, limit int) ([]schema.UserCount, error)
+		Languages(region string, limit int) ([]schema.LanguageCount, error)
 		LanguagesBy(login string, limit int) ([]schema.LanguageCount, error)
-		LastCreatedBy(login string) (*schema.Repo, error)
+		FindStale(limit int) ([]schema.Repo, error)
+		LastPushedBy(login string) (*schema.Repo, error)
 		ReposBy(login string) ([]schema.Repo, error)
+		FindOriginals() ([]schema.Repo, error)
+		FindSimilar(nameWithOwner string) (*SimilarRepos, error)
 	}
 
 	// Write defines the write operation for the store
 	Write interface {
-		BulkUpsert(repos []github.Repo) error
+		BulkUpsert(f fence.Fence, repos []github.Repo, region string) error
+		BulkUpdateCounters(ids []string, repos []github.Repo) error
+		BulkUpsertSimilar(f fence.Fence, similar []SimilarRepos) error
 		Init() error
 		Drop() error
 	}
@@ -46,19 +56,39 @@ type (
 	store struct {
 		db         *database.DB
 		collection string
+		similar    string
 	}
 )
 
-// NewStore returns a new store
-func NewStore(db *database.DB, collection string) Store {
-	return &store{db, collection}
+// NewStore returns a new store, with the similar repos kept in a separate collection
+func NewStore(db *database.DB, collection, similar string) Store {
+	return &store{db, collection, similar}
 }
 
 func (s *store) Init() error {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
-	return c.EnsureIndex(mgo.Index{
+	if err := c.EnsureIndex(mgo.Index{
+		Key:    []string{"nameWithOwner"},
+		Unique: true,
+	}); err != nil {
+		return err
+	}
+	if err := c.EnsureIndexKey("login", "-pushedAt"); err != nil {
+		return err
+	}
+	if err := c.EnsureIndexKey("countersRefreshedAt"); err != nil {
+		return err
+	}
+
+	// Indexes for the sort fields of the paginated repos, with the name to break ties
+	for _, field := range sortFields {
+		if err := c.EnsureIndexKey("-"+field, "nameWithOwner"); err != nil {
+			return err
+		}
+	}
+	return sess.DB(s.db.Name).C(s.similar).EnsureIndex(mgo.Index{
 		Key:    []string{"nameWithOwner"},
 		Unique: true,
 	})
@@ -71,7 +101,10 @@ func (s *store) Drop() error {
 	return c.DropCollection()
 }
 
-func (s *store) BulkUpsert(repos []github.Repo) error {
+// BulkUpsert upserts the repos, tagging them with the region of the owner. Under a lease,
+// the repos last written under a newer token of the lease are kept, and fence.ErrStale is
+// returned instead
+func (s *store) BulkUpsert(f fence.Fence, repos []github.Repo, region string) error {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
@@ -83,14 +116,19 @@ func (s *store) BulkUpsert(repos []github.Repo) error {
 
 		bulk := c.Bulk()
 		for _, repo := range repos[p.Start:p.End] {
+			doc := repo.BSON()
+			doc["region"] = region
 			bulk.Upsert(
-				bson.M{"nameWithOwner": repo.NameWithOwner},
+				byFence(f, bson.M{"nameWithOwner": repo.NameWithOwner}, doc),
 				bson.M{
-					"$set": repo.BSON(),
+					"$set": doc,
 				},
 			)
 		}
 		if _, err := bulk.Run(); err != nil {
+			if mgo.IsDup(err) {
+				return fence.ErrStale
+			}
 			return err
 		}
 	}
@@ -98,12 +136,12 @@ func (s *store) BulkUpsert(repos []github.Repo) error {
 	return nil
 }
 
-func (s *store) FindAll(limit int, sort []string) ([]schema.Repo, error) {
+func (s *store) FindAll(region string, limit int, sort []string) ([]schema.Repo, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
 	var repos []schema.Repo
-	err := c.Find(nil).
+	err := c.Find(byRegion(region, bson.M{})).
 		Sort(sort...).
 		Limit(limit).
 		All(&repos)
@@ -111,6 +149,41 @@ func (s *store) FindAll(limit int, sort []string) ([]schema.Repo, error) {
 	return repos, err
 }
 
+func (s *store) FindOne(nameWithOwner string) (*schema.Repo, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	var repo schema.Repo
+	if err := c.Find(bson.M{"nameWithOwner": nameWithOwner}).
+		One(&repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// FindPage returns the repos matching the filter, starting after the cursor when provided
+func (s *store) FindPage(filter Filter, after *Cursor, limit int) ([]schema.Repo, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	query, sort := byPage(filter, after)
+	var repos []schema.Repo
+	if err := c.Find(query).
+		Sort(sort...).
+		Limit(limit).
+		All(&repos); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// CountBy returns the number of repos matching the filter
+func (s *store) CountBy(filter Filter) (int, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+	return c.Find(byFilter(filter)).Count()
+}
+
 func (s *store) ReposBy(login string) ([]schema.Repo, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
@@ -129,38 +202,325 @@ func (s *store) ReposBy(login string) ([]schema.Repo, error) {
 	return repos, nil
 }
 
-func (s *store) LastCreatedBy(login string) (*schema.Repo, error) {
+// LastPushedBy returns the repo of the user that is pushed most recently
+func (s *store) LastPushedBy(login string) (*schema.Repo, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
 	var repo schema.Repo
 	query := bson.M{
-		"login": login,
+		"login":    login,
+		"pushedAt": bson.M{"$exists": true},
 	}
 
 	err := c.Find(query).
-		Sort("-createdAt").
+		Sort("-pushedAt").
 		One(&repo)
 
 	return &repo, err
 }
 
-func (s *store) Count() (int, error) {
+// FindOriginals returns the repos that are not forks, with only the fields used to find
+// the similar repos
+func (s *store) FindOriginals() ([]schema.Repo, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
-	return c.Count()
+
+	var repos []schema.Repo
+	err := c.Find(bson.M{"isFork": bson.M{"$ne": true}}).
+		Select(bson.M{
+			"nameWithOwner": 1,
+			"description":   1,
+			"languages":     1,
+			"topics":        1,
+			"stargazers":    1,
+		}).
+		All(&repos)
+
+	return repos, err
+}
+
+func (s *store) FindSimilar(nameWithOwner string) (*SimilarRepos, error) {
+	sess, c := s.db.Collection(s.similar)
+	defer sess.Close()
+
+	var res SimilarRepos
+	if err := c.Find(bson.M{"nameWithOwner": nameWithOwner}).
+		One(&res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// BulkUpsertSimilar replaces the similar repos of each repo, with the same fencing as
+// BulkUpsert
+func (s *store) BulkUpsertSimilar(f fence.Fence, similar []SimilarRepos) error {
+	sess, c := s.db.Collection(s.similar)
+	defer sess.Close()
+
+	perBulk := 500
+	partitions, bucket := partitioner.New(perBulk, len(similar))
+
+	for i := 0; i < bucket; i++ {
+		p := partitions[i]
+
+		bulk := c.Bulk()
+		for _, r := range similar[p.Start:p.End] {
+			doc := bson.M{
+				"similar":   r.Similar,
+				"updatedAt": moment.NewUTCDate(),
+			}
+			bulk.Upsert(
+				byFence(f, bson.M{"nameWithOwner": r.NameWithOwner}, doc),
+				bson.M{
+					"$set": doc,
+				},
+			)
+		}
+		if _, err := bulk.Run(); err != nil {
+			if mgo.IsDup(err) {
+				return fence.ErrStale
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindStale returns the repos with the counters that are least recently refreshed. Only the repos
+// with a node id can be refreshed, the rest get theirs when the owner's repos are fetched again
+func (s *store) FindStale(limit int) ([]schema.Repo, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	var repos []schema.Repo
+	err := c.Find(bson.M{
+		"nodeId": bson.M{"$exists": true, "$ne": ""},
+	}).
+		Sort("countersRefreshedAt").
+		Limit(limit).
+		All(&repos)
+
+	return repos, err
+}
+
+// BulkUpdateCounters updates the counters of the repos refreshed. The repos that are requested
+// but no longer exist are marked as refreshed too, so that they do not hold up the rest
+func (s *store) BulkUpdateCounters(ids []string, repos []github.Repo) error {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	found := make(map[string]bool)
+	bulk := c.Bulk()
+	bulk.Unordered()
+	for _, repo := range repos {
+		found[repo.ID] = true
+		bulk.Update(
+			bson.M{"nodeId": repo.ID},
+			bson.M{"$set": repo.CountersBSON()},
+		)
+	}
+	if len(repos) > 0 {
+		if _, err := bulk.Run(); err != nil {
+			return err
+		}
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	_, err := c.UpdateAll(
+		bson.M{"nodeId": bson.M{"$in": missing}},
+		bson.M{"$set": bson.M{"countersRefreshedAt": moment.NewUTCDate()}},
+	)
+	return err
+}
+
+func (s *store) Count(region string) (int, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+	return c.Find(byRegion(region, bson.M{})).Count()
 }
 
 // Languages returns the languages that exists in sorted by frequency
-func (s *store) Languages(limit int) ([]schema.LanguageCount, error) {
+func (s *store) Languages(region string, limit int) ([]schema.LanguageCount, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
-	pipeline := []bson.M{
-		bson.M{
-			"$match": bson.M{
-				"isFork": false,
+	var languages []schema.LanguageCount
+	err := c.Pipe(languagesPipeline(byRegion(region, bson.M{
+		"isFork": false,
+	}), limit)).All(&languages)
+
+	return languages, err
+}
+
+// GroupByUser will return the repo count by user
+func (s *store) GroupByUser(region string, limit int) ([]schema.UserCount, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	var users []schema.UserCount
+	err := c.Pipe(usersPipeline(byRegion(region, bson.M{
+		"isFork": false,
+	}), limit)).All(&users)
+	return users, err
+}
+
+// LanguagesBy returns the user's languages count
+func (s *store) LanguagesBy(login string, limit int) ([]schema.LanguageCount, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	var languages []schema.LanguageCount
+	err := c.Pipe(languagesPipeline(bson.M{
+		"isFork": false,
+		"login":  login,
+	}, limit)).All(&languages)
+	return languages, err
+}
+
+// GroupByLanguageSortByMostRecent returns the most recent repos by language
+func (s *store) GroupByLanguageSortByMostRecent(region, language string, limit int) ([]schema.Repo, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	var repos []schema.Repo
+	err := c.Pipe(mostRecentPipeline(region, language, limit)).All(&repos)
+	return repos, err
+}
+
+// GroupByLanguage returns the users by languages
+func (s *store) GroupByLanguage(region, language string, limit int) ([]schema.UserCount, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	var repos []schema.UserCount
+	err := c.Pipe(byLanguagePipeline(region, language, limit)).All(&repos)
+	return repos, err
+}
+
+// Distinct returns a distinct login, so that we don't need to query the repository if the user does not exists
+func (s *store) Distinct(field string) ([]string, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	var res []string
+	err := c.Find(nil).Distinct(field, &res)
+	return res, err
+}
+
+// byFence adds the fence to the query of the repo, and to the fields set. Under a lease,
+// the repo last written under a newer token of the lease is not matched
+func byFence(f fence.Fence, query, data bson.M) bson.M {
+	if !f.IsZero() {
+		query["$or"] = database.ByFence(f)
+		data["fence"] = f
+	}
+	return query
+}
+
+// byRegion adds the region to the query, or leaves the query as it is for all regions
+func byRegion(region string, query bson.M) bson.M {
+	if region != "" {
+		query["region"] = region
+	}
+	return query
+}
+
+// byFilter builds the query for the filter. Repos without the sort field, such as repos
+// that have never been pushed to, are excluded so that the pagination is stable
+func byFilter(f Filter) bson.M {
+	query := byRegion(f.Region, bson.M{})
+	if f.Language != "" {
+		query["languages"] = f.Language
+	}
+	if f.Owner != "" {
+		query["login"] = f.Owner
+	}
+	if f.IsFork != nil {
+		// Repos that are not forks may be stored without the field
+		if *f.IsFork {
+			query["isFork"] = true
+		} else {
+			query["isFork"] = bson.M{"$ne": true}
+		}
+	}
+	if f.MinStars > 0 {
+		query["stargazers"] = bson.M{"$gte": f.MinStars}
+	}
+	if r := byRange(f.CreatedFrom, f.CreatedTo); r != nil {
+		query["createdAt"] = r
+	}
+	if r := byRange(f.PushedFrom, f.PushedTo); r != nil {
+		query["pushedAt"] = r
+	}
+	if field, _, ok := f.SortField(); ok {
+		if _, exists := query[field]; !exists {
+			query[field] = bson.M{"$exists": true}
+		}
+	}
+	return query
+}
+
+// byPage builds the query and the sort of the page of repos after the cursor, with the name
+// to break the ties
+func byPage(filter Filter, after *Cursor) (bson.M, []string) {
+	field, desc, _ := filter.SortField()
+	order := field
+	if desc {
+		order = "-" + field
+	}
+
+	query := byFilter(filter)
+	if after != nil {
+		op := "$gt"
+		if desc {
+			op = "$lt"
+		}
+		query = bson.M{
+			"$and": []bson.M{
+				query,
+				bson.M{
+					"$or": []bson.M{
+						bson.M{field: bson.M{op: after.Value}},
+						bson.M{field: after.Value, "nameWithOwner": bson.M{"$gt": after.NameWithOwner}},
+					},
+				},
 			},
+		}
+	}
+
+	return query, []string{order, "nameWithOwner"}
+}
+
+// byRange matches the RFC3339 dates that fall within the days from and to (inclusive)
+func byRange(from, to string) bson.M {
+	if from == "" && to == "" {
+		return nil
+	}
+	r := bson.M{}
+	if from != "" {
+		r["$gte"] = from
+	}
+	if to != "" {
+		r["$lte"] = to + "T23:59:59Z"
+	}
+	return r
+}
+
+// languagesPipeline counts the repos matching the query by language, the most used first
+func languagesPipeline(match bson.M, limit int) []bson.M {
+	return []bson.M{
+		bson.M{
+			"$match": match,
 		},
 		bson.M{
 			"$unwind": bson.M{
@@ -191,22 +551,14 @@ func (s *store) Languages(limit int) ([]schema.LanguageCount, error) {
 			},
 		},
 	}
-	var languages []schema.LanguageCount
-	err := c.Pipe(pipeline).All(&languages)
-
-	return languages, err
 }
 
-// GroupByUser will return the repo count by user
-func (s *store) GroupByUser(limit int) ([]schema.UserCount, error) {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-
-	pipeline := []bson.M{
+// usersPipeline counts the repos matching the query by owner, the owner with the most repos
+// first
+func usersPipeline(match bson.M, limit int) []bson.M {
+	return []bson.M{
 		bson.M{
-			"$match": bson.M{
-				"isFork": false,
-			},
+			"$match": match,
 		},
 		bson.M{
 			"$group": bson.M{
@@ -233,87 +585,46 @@ func (s *store) GroupByUser(limit int) ([]schema.UserCount, error) {
 			},
 		},
 	}
-	var users []schema.UserCount
-	err := c.Pipe(pipeline).All(&users)
-	return users, err
-}
-
-// LanguagesBy returns the user's languages count
-func (s *store) LanguagesBy(login string, limit int) ([]schema.LanguageCount, error) {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-
-	pipeline := []bson.M{
-		bson.M{
-			"$match": bson.M{
-				"isFork": false,
-				"login":  login,
-			},
-		},
-		bson.M{
-			"$unwind": bson.M{
-				"path": "$languages",
-			},
-		},
-		bson.M{
-			"$group": bson.M{
-				"_id": bson.M{
-					"language": "$languages",
-				},
-				"count": bson.M{"$sum": 1},
-			},
-		},
-		bson.M{
-			"$sort": bson.M{
-				"count": -1,
-			},
-		},
-		bson.M{
-			"$limit": limit,
-		},
-		bson.M{
-			"$project": bson.M{
-				"count": 1,
-				"name":  "$_id.language",
-				"_id":   0,
-			},
-		},
-	}
-	var languages []schema.LanguageCount
-	err := c.Pipe(pipeline).All(&languages)
-	return languages, err
 }
 
-// GroupByLanguageSortByMostRecent returns the most recent repos by language
-func (s *store) GroupByLanguageSortByMostRecent(language string, limit int) ([]schema.Repo, error) {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-
-	pipeline := []bson.M{
+// mostRecentPipeline returns the most recently updated original repos of the region in the
+// language
+func mostRecentPipeline(region, language string, limit int) []bson.M {
+	return []bson.M{
 		bson.M{
-			"$match": bson.M{
+			"$match": byRegion(region, bson.M{
 				"isFork": false,
-			},
+			}),
 		},
 		bson.M{
 			"$project": bson.M{
 				"isLanguage": bson.M{
 					"$in": []string{language, "$languages"},
 				},
-				"name":          1,
-				"createdAt":     1,
-				"updatedAt":     1,
-				"description":   1,
-				"languages":     1,
-				"homepageUrl":   1,
-				"forks":         1,
-				"isFork":        1,
-				"nameWithOwner": 1,
-				"login":         1,
-				"avatarUrl":     1,
-				"stargazers":    1,
-				"watchers":      1,
-				"url":           1,
+				"name":                1,
+				"createdAt":           1,
+				"updatedAt":           1,
+				"description":         1,
+				"languages":           1,
+				"homepageUrl":         1,
+				"forks":               1,
+				"isFork":              1,
+				"nameWithOwner":       1,
+				"login":               1,
+				"avatarUrl":           1,
+				"stargazers":          1,
+				"watchers":            1,
+				"url":                 1,
+				"region":              1,
+				"pushedAt":            1,
+				"languageBytes":       1,
+				"primaryLanguage":     1,
+				"license":             1,
+				"topics":              1,
+				"isArchived":          1,
+				"diskUsage":           1,
+				"nodeId":              1,
+				"countersRefreshedAt": 1,
 			},
 		},
 		bson.M{
@@ -330,21 +641,15 @@ func (s *store) GroupByLanguageSortByMostRecent(language string, limit int) ([]s
 			"$limit": limit,
 		},
 	}
-	var repos []schema.Repo
-	err := c.Pipe(pipeline).All(&repos)
-	return repos, err
 }
 
-// GroupByLanguage returns the users by languages
-func (s *store) GroupByLanguage(language string, limit int) ([]schema.UserCount, error) {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-
-	pipeline := []bson.M{
+// byLanguagePipeline counts the original repos of the region in the language by owner
+func byLanguagePipeline(region, language string, limit int) []bson.M {
+	return []bson.M{
 		bson.M{
-			"$match": bson.M{
+			"$match": byRegion(region, bson.M{
 				"isFork": false,
-			},
+			}),
 		},
 		bson.M{
 			"$project": bson.M{
@@ -385,17 +690,4 @@ func (s *store) GroupByLanguage(language string, limit int) ([]schema.UserCount,
 			},
 		},
 	}
-	var repos []schema.UserCount
-	err := c.Pipe(pipeline).All(&repos)
-	return repos, err
-}
-
-// Distinct returns a distinct login, so that we don't need to query the repository if the user does not exists
-func (s *store) Distinct(field string) ([]string, error) {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-
-	var res []string
-	err := c.Find(nil).Distinct(field, &res)
-	return res, err
 }
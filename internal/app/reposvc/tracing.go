@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/bow"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 
@@ -23,96 +24,132 @@ type tracingMiddleware struct {
 	service Service
 }
 
-func (m *tracingMiddleware) BulkUpsert(ctx context.Context, repos []github.Repo) error {
+func (m *tracingMiddleware) BulkUpsert(ctx context.Context, repos []github.Repo, region string) error {
 	ctx, span := trace.StartSpan(ctx, "BulkUpsert")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("perPage", int64(len(repos))))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("perPage", int64(len(repos))))
 
-	return m.service.BulkUpsert(ctx, repos)
+	return m.service.BulkUpsert(ctx, repos, region)
 }
 
-func (m *tracingMiddleware) Count(ctx context.Context) (int, error) {
+func (m *tracingMiddleware) Count(ctx context.Context, region string) (int, error) {
 	ctx, span := trace.StartSpan(ctx, "Count")
 	defer span.End()
 
-	return m.service.Count(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.Count(ctx, region)
 }
 
-func (m *tracingMiddleware) LastCreatedBy(ctx context.Context, login string) (string, bool) {
-	ctx, span := trace.StartSpan(ctx, "LastCreatedBy")
+func (m *tracingMiddleware) LastPushedBy(ctx context.Context, login string) (string, bool) {
+	ctx, span := trace.StartSpan(ctx, "LastPushedBy")
 	defer span.End()
 
 	span.AddAttributes(trace.StringAttribute("login", login))
 
-	return m.service.LastCreatedBy(ctx, login)
+	return m.service.LastPushedBy(ctx, login)
 }
 
-func (m *tracingMiddleware) MostPopularLanguage(ctx context.Context, limit int) ([]schema.LanguageCount, error) {
-	ctx, span := trace.StartSpan(ctx, "MostPopularLanguage")
+func (m *tracingMiddleware) FindStale(ctx context.Context, limit int) ([]schema.Repo, error) {
+	ctx, span := trace.StartSpan(ctx, "FindStale")
 	defer span.End()
 
 	span.AddAttributes(trace.Int64Attribute("limit", int64(limit)))
 
-	return m.service.MostPopularLanguage(ctx, limit)
+	return m.service.FindStale(ctx, limit)
+}
+
+func (m *tracingMiddleware) RefreshCounters(ctx context.Context, ids []string, repos []github.Repo) error {
+	ctx, span := trace.StartSpan(ctx, "RefreshCounters")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.Int64Attribute("ids", int64(len(ids))),
+		trace.Int64Attribute("count", int64(len(repos))))
+
+	return m.service.RefreshCounters(ctx, ids, repos)
+}
+
+func (m *tracingMiddleware) MostPopularLanguage(ctx context.Context, region string, limit int) ([]schema.LanguageCount, error) {
+	ctx, span := trace.StartSpan(ctx, "MostPopularLanguage")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("limit", int64(limit)))
+
+	return m.service.MostPopularLanguage(ctx, region, limit)
 }
 
-func (m *tracingMiddleware) MostRecent(ctx context.Context, limit int) ([]schema.Repo, error) {
+func (m *tracingMiddleware) MostRecent(ctx context.Context, region string, limit int) ([]schema.Repo, error) {
 	ctx, span := trace.StartSpan(ctx, "MostRecent")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("limit", int64(limit)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("limit", int64(limit)))
 
-	return m.service.MostRecent(ctx, limit)
+	return m.service.MostRecent(ctx, region, limit)
 }
 
-func (m *tracingMiddleware) MostRecentReposByLanguage(ctx context.Context, language string, limit int) ([]schema.Repo, error) {
+func (m *tracingMiddleware) MostRecentReposByLanguage(ctx context.Context, region, language string, limit int) ([]schema.Repo, error) {
 	ctx, span := trace.StartSpan(ctx, "MostRecentReposByLanguage")
 	defer span.End()
 
 	span.AddAttributes(
+		trace.StringAttribute("region", region),
 		trace.StringAttribute("language", language),
 		trace.Int64Attribute("limit", int64(limit)))
 
-	return m.service.MostRecentReposByLanguage(ctx, language, limit)
+	return m.service.MostRecentReposByLanguage(ctx, region, language, limit)
 }
 
-func (m *tracingMiddleware) MostStars(ctx context.Context, limit int) ([]schema.Repo, error) {
+func (m *tracingMiddleware) MostStars(ctx context.Context, region string, limit int) ([]schema.Repo, error) {
 	ctx, span := trace.StartSpan(ctx, "MostStars")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("limit", int64(limit)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("limit", int64(limit)))
 
-	return m.service.MostStars(ctx, limit)
+	return m.service.MostStars(ctx, region, limit)
 }
 
-func (m *tracingMiddleware) MostForks(ctx context.Context, limit int) ([]schema.Repo, error) {
+func (m *tracingMiddleware) MostForks(ctx context.Context, region string, limit int) ([]schema.Repo, error) {
 	ctx, span := trace.StartSpan(ctx, "MostForks")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("limit", int64(limit)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("limit", int64(limit)))
 
-	return m.service.MostForks(ctx, limit)
+	return m.service.MostForks(ctx, region, limit)
 }
 
-func (m *tracingMiddleware) RepoCountByUser(ctx context.Context, limit int) ([]schema.UserCount, error) {
+func (m *tracingMiddleware) RepoCountByUser(ctx context.Context, region string, limit int) ([]schema.UserCount, error) {
 	ctx, span := trace.StartSpan(ctx, "RepoCountByUser")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("limit", int64(limit)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("limit", int64(limit)))
 
-	return m.service.RepoCountByUser(ctx, limit)
+	return m.service.RepoCountByUser(ctx, region, limit)
 }
 
-func (m *tracingMiddleware) ReposByLanguage(ctx context.Context, language string, limit int) ([]schema.UserCount, error) {
+func (m *tracingMiddleware) ReposByLanguage(ctx context.Context, region, language string, limit int) ([]schema.UserCount, error) {
 	ctx, span := trace.StartSpan(ctx, "ReposByLanguage")
 	defer span.End()
 
 	span.AddAttributes(
+		trace.StringAttribute("region", region),
 		trace.StringAttribute("language", language),
 		trace.Int64Attribute("limit", int64(limit)))
 
-	return m.service.ReposByLanguage(ctx, language, limit)
+	return m.service.ReposByLanguage(ctx, region, language, limit)
 }
 
 func (m *tracingMiddleware) Distinct(ctx context.Context, login string) ([]string, error) {
@@ -124,11 +161,81 @@ func (m *tracingMiddleware) Distinct(ctx context.Context, login string) ([]strin
 	return m.service.Distinct(ctx, login)
 }
 
-func (m *tracingMiddleware) GetProfile(ctx context.Context, login string) (*usersvc.User, error) {
+func (m *tracingMiddleware) Corpus(ctx context.Context) (*bow.Corpus, error) {
+	ctx, span := trace.StartSpan(ctx, "Corpus")
+	defer span.End()
+
+	return m.service.Corpus(ctx)
+}
+
+func (m *tracingMiddleware) GetProfile(ctx context.Context, login string, corpus *bow.Corpus) (*usersvc.User, error) {
 	ctx, span := trace.StartSpan(ctx, "GetProfile")
 	defer span.End()
 
 	span.AddAttributes(trace.StringAttribute("login", login))
 
-	return m.service.GetProfile(ctx, login)
+	return m.service.GetProfile(ctx, login, corpus)
+}
+
+func (m *tracingMiddleware) FindOne(ctx context.Context, owner, name string) (*schema.Repo, error) {
+	ctx, span := trace.StartSpan(ctx, "FindOne")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("owner", owner),
+		trace.StringAttribute("name", name))
+
+	return m.service.FindOne(ctx, owner, name)
+}
+
+func (m *tracingMiddleware) List(ctx context.Context, filter Filter, cursor string, limit int) (*Page, error) {
+	ctx, span := trace.StartSpan(ctx, "List")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", filter.Region),
+		trace.StringAttribute("sort", filter.Sort),
+		trace.StringAttribute("cursor", cursor),
+		trace.Int64Attribute("limit", int64(limit)))
+
+	return m.service.List(ctx, filter, cursor, limit)
+}
+
+func (m *tracingMiddleware) CountBy(ctx context.Context, filter Filter) (int, error) {
+	ctx, span := trace.StartSpan(ctx, "CountBy")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", filter.Region),
+		trace.StringAttribute("sort", filter.Sort))
+
+	return m.service.CountBy(ctx, filter)
+}
+
+func (m *tracingMiddleware) FindOriginals(ctx context.Context) ([]schema.Repo, error) {
+	ctx, span := trace.StartSpan(ctx, "FindOriginals")
+	defer span.End()
+
+	return m.service.FindOriginals(ctx)
+}
+
+func (m *tracingMiddleware) FindSimilar(ctx context.Context, owner, name string, limit int) ([]schema.SimilarRepo, error) {
+	ctx, span := trace.StartSpan(ctx, "FindSimilar")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("owner", owner),
+		trace.StringAttribute("name", name),
+		trace.Int64Attribute("limit", int64(limit)))
+
+	return m.service.FindSimilar(ctx, owner, name, limit)
+}
+
+func (m *tracingMiddleware) BulkUpsertSimilar(ctx context.Context, similar []SimilarRepos) error {
+	ctx, span := trace.StartSpan(ctx, "BulkUpsertSimilar")
+	defer span.End()
+
+	span.AddAttributes(trace.Int64Attribute("count", int64(len(similar))))
+
+	return m.service.BulkUpsertSimilar(ctx, similar)
 }
@@ -0,0 +1,90 @@
+package searchsvc
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// snippetWords is the number of words kept before and after the first match in a snippet
+const snippetWords = 10
+
+// terms returns the lowercased terms of the query, without quotes and negated terms
+func terms(q string) []string {
+	var res []string
+	for _, t := range strings.Fields(strings.ToLower(q)) {
+		if strings.HasPrefix(t, "-") {
+			continue
+		}
+		if t = strings.Trim(t, `"`); t != "" {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// matches returns true if the word shares its stem with one of the terms. The text index
+// matches words by their stems, so "databases" matches "database" and the other way round
+func matches(word string, terms []string) bool {
+	word = strings.TrimFunc(strings.ToLower(word), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if word == "" {
+		return false
+	}
+	for _, t := range terms {
+		if strings.HasPrefix(word, t) || (len(word) >= 3 && strings.HasPrefix(t, word)) {
+			return true
+		}
+	}
+	return false
+}
+
+// highlight returns a snippet of the text around the first match, with the matched words
+// wrapped in <em> tags. The text is escaped, so the snippet is safe to render as html.
+// An empty string is returned when there is no match
+func highlight(text string, terms []string) string {
+	words := strings.Fields(text)
+	first := -1
+	marked := make([]string, len(words))
+	for i, w := range words {
+		if !matches(w, terms) {
+			marked[i] = html.EscapeString(w)
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		marked[i] = "<em>" + html.EscapeString(w) + "</em>"
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start, end := first-snippetWords, first+snippetWords+1
+	if start < 0 {
+		start = 0
+	}
+	if end > len(words) {
+		end = len(words)
+	}
+	snippet := strings.Join(marked[start:end], " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(words) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// highlights returns the snippets of the fields that match the terms, by field name
+func highlights(fields map[string]string, terms []string) map[string]string {
+	res := make(map[string]string)
+	for name, text := range fields {
+		if snippet := highlight(text, terms); snippet != "" {
+			res[name] = snippet
+		}
+	}
+	return res
+}
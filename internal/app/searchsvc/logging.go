@@ -0,0 +1,39 @@
+package searchsvc
+
+import (
+	"context"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Logging adds logging capabilities to the service
+func Logging(l *logger.Logger) Middleware {
+	return func(s Service) Service {
+		return &loggingMiddleware{
+			service: s,
+			logger:  l,
+		}
+	}
+}
+
+type loggingMiddleware struct {
+	logger  *logger.Logger
+	service Service
+}
+
+func (m *loggingMiddleware) Search(ctx context.Context, q Query) (res *Result, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("Search"),
+			logger.Duration(start),
+			zap.String("q", q.Text),
+			zap.String("type", q.Type),
+			zap.String("region", q.Region))
+
+		logger.Maybe(L, "search", err)
+	}(time.Now())
+
+	return m.service.Search(ctx, q)
+}
@@ -0,0 +1,146 @@
+package searchsvc
+
+import (
+	"sort"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/bow"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/order"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+)
+
+// textOptions stems the terms of the query and the fields, as the text indexes in mongo do
+var textOptions = bow.Options{Stem: true}
+
+// memorySearcher searches the users and repos returned by the functions, for the tests and
+// the demo mode. The fields are weighted the same as the text indexes in mongo, and the
+// score is the weighted count of the terms of the query found in each field
+type memorySearcher struct {
+	users func() ([]usersvc.User, error)
+	repos func() ([]schema.Repo, error)
+}
+
+// NewMemorySearcher returns a searcher over the users and repos returned by the functions,
+// which are called on every search
+func NewMemorySearcher(users func() ([]usersvc.User, error), repos func() ([]schema.Repo, error)) Searcher {
+	return &memorySearcher{
+		users: users,
+		repos: repos,
+	}
+}
+
+func (s *memorySearcher) Init() error {
+	return nil
+}
+
+func (s *memorySearcher) SearchRepos(q Query) (*Result, error) {
+	repos, err := s.repos()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := queryTerms(q.Text)
+	var hits []Hit
+	languages := make(map[string]int)
+	for i := range repos {
+		r := repos[i]
+		if q.Region != "" && r.Region != q.Region {
+			continue
+		}
+		score := 10*textScore(terms, r.Name) +
+			5*textScore(terms, r.Description)
+		for _, lang := range r.Languages {
+			score += 3 * textScore(terms, lang)
+		}
+		if score == 0 {
+			continue
+		}
+		hits = append(hits, Hit{Score: score, Repo: &r})
+		for _, lang := range r.Languages {
+			languages[lang]++
+		}
+	}
+	return newResult(q, hits, map[string][]Facet{
+		"language": facets(languages),
+	}), nil
+}
+
+func (s *memorySearcher) SearchUsers(q Query) (*Result, error) {
+	users, err := s.users()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := queryTerms(q.Text)
+	var hits []Hit
+	companies := make(map[string]int)
+	languages := make(map[string]int)
+	for i := range users {
+		u := users[i]
+		if q.Region != "" && u.Region != q.Region {
+			continue
+		}
+		score := 10*textScore(terms, u.Name) +
+			5*textScore(terms, u.Company) +
+			3*textScore(terms, u.Bio)
+		if score == 0 {
+			continue
+		}
+		hits = append(hits, Hit{Score: score, User: &u})
+		companies[u.Company]++
+		for _, lang := range u.Languages {
+			languages[lang.Name]++
+		}
+	}
+	return newResult(q, hits, map[string][]Facet{
+		"company":  facets(companies),
+		"language": facets(languages),
+	}), nil
+}
+
+// queryTerms returns the set of the stemmed terms of the query
+func queryTerms(text string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, t := range bow.Extract(text, textOptions) {
+		terms[t.Key] = true
+	}
+	return terms
+}
+
+// textScore returns the number of the terms of the field that are in the query
+func textScore(terms map[string]bool, field string) float64 {
+	var score float64
+	for _, t := range bow.Extract(field, textOptions) {
+		if terms[t.Key] {
+			score++
+		}
+	}
+	return score
+}
+
+// newResult ranks the hits by the score, and keeps the number of hits requested
+func newResult(q Query, hits []Hit, facets map[string][]Facet) *Result {
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	count := len(hits)
+	if q.Limit > 0 && len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return &Result{
+		Query:  q,
+		Count:  count,
+		Hits:   hits,
+		Facets: facets,
+	}
+}
+
+// facets returns the values with the most matches, leaving out the empty values
+func facets(count map[string]int) []Facet {
+	delete(count, "")
+	var res []Facet
+	for _, value := range order.Counts(count, maxFacets) {
+		res = append(res, Facet{Value: value, Count: count[value]})
+	}
+	return res
+}
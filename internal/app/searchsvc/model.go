@@ -0,0 +1,96 @@
+package searchsvc
+
+import (
+	"log"
+	"strings"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+)
+
+var (
+	ErrInvalidQuery = apperr.Validation("query is required")
+	ErrInvalidType  = apperr.Validation("type must be one of repo or user")
+)
+
+type (
+	// Model represents the interface for the search business logic
+	Model interface {
+		Init() error
+		Search(q Query) (*Result, error)
+	}
+
+	model struct {
+		searcher Searcher
+	}
+)
+
+// NewModel returns a new search model
+func NewModel(s Searcher) Model {
+	m := model{
+		searcher: s,
+	}
+	if err := m.Init(); err != nil {
+		log.Fatal(err)
+	}
+	return &m
+}
+
+func (m *model) Init() error {
+	return m.searcher.Init()
+}
+
+// Search returns the repos or users matching the query, with the matched terms highlighted.
+// Repos are searched when the type is not provided
+func (m *model) Search(q Query) (*Result, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return nil, ErrInvalidQuery
+	}
+	q.Limit = setLimit(q.Limit)
+
+	var (
+		res *Result
+		err error
+	)
+	switch q.Type {
+	case TypeRepo, "":
+		q.Type = TypeRepo
+		res, err = m.searcher.SearchRepos(q)
+	case TypeUser:
+		res, err = m.searcher.SearchUsers(q)
+	default:
+		return nil, ErrInvalidType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := terms(q.Text)
+	for i, hit := range res.Hits {
+		switch {
+		case hit.Repo != nil:
+			res.Hits[i].Highlights = highlights(map[string]string{
+				"name":        hit.Repo.Name,
+				"description": hit.Repo.Description,
+				"languages":   strings.Join(hit.Repo.Languages, ", "),
+			}, t)
+		case hit.User != nil:
+			res.Hits[i].Highlights = highlights(map[string]string{
+				"name":    hit.User.Name,
+				"bio":     hit.User.Bio,
+				"company": hit.User.Company,
+			}, t)
+		}
+	}
+	return res, nil
+}
+
+func setLimit(limit int) int {
+	if limit < 1 {
+		return 10
+	}
+	if limit > 100 {
+		return 100
+	}
+	return limit
+}
@@ -0,0 +1,152 @@
+package searchsvc
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// mongoDriverSearcher searches the users and repos through the same text indexes as the
+// mongoSearcher, on the official mongo driver
+type mongoDriverSearcher struct {
+	db    *database.Mongo
+	users string
+	repos string
+}
+
+// NewMongoDriverSearcher returns a searcher over the users and repos collections, on the
+// official mongo driver
+func NewMongoDriverSearcher(db *database.Mongo, users, repos string) Searcher {
+	return &mongoDriverSearcher{
+		db:    db,
+		users: users,
+		repos: repos,
+	}
+}
+
+// Init creates the text indexes. A collection can only have one text index, so the
+// fields and the weights of each index are fixed here
+func (s *mongoDriverSearcher) Init() error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	if _, err := s.db.Collection(s.repos).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: database.Text("name", "description", "languages"),
+		Options: options.Index().SetName("search").SetWeights(bson.M{
+			"name":        10,
+			"description": 5,
+			"languages":   3,
+		}),
+	}); err != nil {
+		return err
+	}
+	_, err := s.db.Collection(s.users).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: database.Text("name", "bio", "company"),
+		Options: options.Index().SetName("search").SetWeights(bson.M{
+			"name":    10,
+			"company": 5,
+			"bio":     3,
+		}),
+	})
+	return err
+}
+
+func (s *mongoDriverSearcher) SearchRepos(q Query) (*Result, error) {
+	c := s.db.Collection(s.repos)
+	query := byText(q)
+
+	var docs []repoDoc
+	count, err := search(c, query, q.Limit, &docs)
+	if err != nil {
+		return nil, err
+	}
+	languages, err := aggregateFacet(c, query, "$languages", "$languages")
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, len(docs))
+	for i := range docs {
+		hits[i] = Hit{Score: docs[i].Score, Repo: &docs[i].Repo}
+	}
+	return &Result{
+		Query: q,
+		Count: count,
+		Hits:  hits,
+		Facets: map[string][]Facet{
+			"language": languages,
+		},
+	}, nil
+}
+
+func (s *mongoDriverSearcher) SearchUsers(q Query) (*Result, error) {
+	c := s.db.Collection(s.users)
+	query := byText(q)
+
+	var docs []userDoc
+	count, err := search(c, query, q.Limit, &docs)
+	if err != nil {
+		return nil, err
+	}
+	companies, err := aggregateFacet(c, query, "", "$company")
+	if err != nil {
+		return nil, err
+	}
+	languages, err := aggregateFacet(c, query, "$languages", "$languages.name")
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, len(docs))
+	for i := range docs {
+		hits[i] = Hit{Score: docs[i].Score, User: &docs[i].User}
+	}
+	return &Result{
+		Query: q,
+		Count: count,
+		Hits:  hits,
+		Facets: map[string][]Facet{
+			"company":  companies,
+			"language": languages,
+		},
+	}, nil
+}
+
+// search decodes the best matches of the query into docs, with their text score, and
+// returns the number of matches
+func search(c *mongo.Collection, query bson.M, limit int, docs interface{}) (int, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+	cur, err := c.Find(ctx, query, options.Find().
+		SetProjection(score).
+		SetSort(score).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return 0, err
+	}
+	if err := cur.All(ctx, docs); err != nil {
+		return 0, err
+	}
+	count, err := c.CountDocuments(ctx, query)
+	return int(count), err
+}
+
+// aggregateFacet is the equivalent of facet on the official mongo driver
+func aggregateFacet(c *mongo.Collection, query bson.M, array, field string) ([]Facet, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	cur, err := c.Aggregate(ctx, facetPipeline(query, array, field))
+	if err != nil {
+		return nil, err
+	}
+	var facets []Facet
+	if err := cur.All(ctx, &facets); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
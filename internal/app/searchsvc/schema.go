@@ -0,0 +1,43 @@
+package searchsvc
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+)
+
+const (
+	TypeRepo = "repo"
+	TypeUser = "user"
+)
+
+// Query represents the search query. An empty region searches all regions
+type Query struct {
+	Text   string `json:"q"`
+	Type   string `json:"type"`
+	Region string `json:"region,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// Hit represents a repo or user that matches the query, ranked by the score. Highlights
+// holds a snippet of each matching field, with the matched terms wrapped in <em> tags
+type Hit struct {
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+	Repo       *schema.Repo      `json:"repo,omitempty"`
+	User       *usersvc.User     `json:"user,omitempty"`
+}
+
+// Facet represents the number of matches for a value of the faceted field
+type Facet struct {
+	Value string `json:"value" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+}
+
+// Result represents the ranked hits, the total number of matches and the facets of the
+// matches by field, such as language and company
+type Result struct {
+	Query  Query              `json:"query"`
+	Count  int                `json:"count"`
+	Hits   []Hit              `json:"hits"`
+	Facets map[string][]Facet `json:"facets,omitempty"`
+}
@@ -0,0 +1,203 @@
+package searchsvc
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// maxFacets is the number of values returned for each facet
+const maxFacets = 10
+
+type (
+	// Searcher represents the search backend. The hits returned are ranked, but not
+	// highlighted, so that the backend can be swapped without changing the model
+	Searcher interface {
+		Init() error
+		SearchRepos(q Query) (*Result, error)
+		SearchUsers(q Query) (*Result, error)
+	}
+
+	// mongoSearcher searches the users and repos through the text indexes in Mongo
+	mongoSearcher struct {
+		db    *database.DB
+		users string
+		repos string
+	}
+
+	repoDoc struct {
+		schema.Repo `bson:",inline"`
+		Score       float64 `bson:"score"`
+	}
+
+	userDoc struct {
+		usersvc.User `bson:",inline"`
+		Score        float64 `bson:"score"`
+	}
+)
+
+// NewMongoSearcher returns a searcher over the users and repos collections
+func NewMongoSearcher(db *database.DB, users, repos string) Searcher {
+	return &mongoSearcher{
+		db:    db,
+		users: users,
+		repos: repos,
+	}
+}
+
+// Init creates the text indexes. A collection can only have one text index, so the
+// fields and the weights of each index are fixed here
+func (s *mongoSearcher) Init() error {
+	sess, c := s.db.Collection(s.repos)
+	defer sess.Close()
+
+	if err := c.EnsureIndex(mgo.Index{
+		Name: "search",
+		Key:  []string{"$text:name", "$text:description", "$text:languages"},
+		Weights: map[string]int{
+			"name":        10,
+			"description": 5,
+			"languages":   3,
+		},
+	}); err != nil {
+		return err
+	}
+
+	return sess.DB(s.db.Name).C(s.users).EnsureIndex(mgo.Index{
+		Name: "search",
+		Key:  []string{"$text:name", "$text:bio", "$text:company"},
+		Weights: map[string]int{
+			"name":    10,
+			"company": 5,
+			"bio":     3,
+		},
+	})
+}
+
+func (s *mongoSearcher) SearchRepos(q Query) (*Result, error) {
+	sess, c := s.db.Collection(s.repos)
+	defer sess.Close()
+
+	query := byText(q)
+	var docs []repoDoc
+	if err := c.Find(query).
+		Select(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		Sort("$textScore:score").
+		Limit(q.Limit).
+		All(&docs); err != nil {
+		return nil, err
+	}
+	count, err := c.Find(query).Count()
+	if err != nil {
+		return nil, err
+	}
+	languages, err := facet(c, query, "$languages", "$languages")
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, len(docs))
+	for i := range docs {
+		hits[i] = Hit{Score: docs[i].Score, Repo: &docs[i].Repo}
+	}
+	return &Result{
+		Query: q,
+		Count: count,
+		Hits:  hits,
+		Facets: map[string][]Facet{
+			"language": languages,
+		},
+	}, nil
+}
+
+func (s *mongoSearcher) SearchUsers(q Query) (*Result, error) {
+	sess, c := s.db.Collection(s.users)
+	defer sess.Close()
+
+	query := byText(q)
+	var docs []userDoc
+	if err := c.Find(query).
+		Select(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		Sort("$textScore:score").
+		Limit(q.Limit).
+		All(&docs); err != nil {
+		return nil, err
+	}
+	count, err := c.Find(query).Count()
+	if err != nil {
+		return nil, err
+	}
+	companies, err := facet(c, query, "", "$company")
+	if err != nil {
+		return nil, err
+	}
+	languages, err := facet(c, query, "$languages", "$languages.name")
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, len(docs))
+	for i := range docs {
+		hits[i] = Hit{Score: docs[i].Score, User: &docs[i].User}
+	}
+	return &Result{
+		Query: q,
+		Count: count,
+		Hits:  hits,
+		Facets: map[string][]Facet{
+			"company":  companies,
+			"language": languages,
+		},
+	}, nil
+}
+
+// byText matches the documents containing the terms of the query in the region
+func byText(q Query) bson.M {
+	query := bson.M{"$text": bson.M{"$search": q.Text}}
+	if q.Region != "" {
+		query["region"] = q.Region
+	}
+	return query
+}
+
+// facet counts the matches by the values of the field
+func facet(c *mgo.Collection, query bson.M, array, field string) ([]Facet, error) {
+	var facets []Facet
+	if err := c.Pipe(facetPipeline(query, array, field)).All(&facets); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// facetPipeline counts the matches by the values of the field. When the field is within an
+// array, the array is unwound first. Matches without a value are not counted
+func facetPipeline(query bson.M, array, field string) []bson.M {
+	pipeline := []bson.M{
+		bson.M{"$match": query},
+	}
+	if array != "" {
+		pipeline = append(pipeline, bson.M{"$unwind": array})
+	}
+	return append(pipeline,
+		bson.M{
+			"$group": bson.M{
+				"_id":   field,
+				"count": bson.M{"$sum": 1},
+			},
+		},
+		bson.M{
+			"$match": bson.M{
+				"_id": bson.M{"$nin": []interface{}{nil, ""}},
+			},
+		},
+		bson.M{
+			"$sort": bson.M{"count": -1},
+		},
+		bson.M{
+			"$limit": maxFacets,
+		},
+	)
+}
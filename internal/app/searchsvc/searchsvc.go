@@ -0,0 +1,16 @@
+package searchsvc
+
+import "github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+
+// New returns a new search service backed by the text indexes in Mongo
+func New(db *database.DB, ms ...Middleware) Service {
+	return NewWithSearcher(NewMongoSearcher(db, database.Users, database.Repos), ms...)
+}
+
+// NewWithSearcher returns a new search service backed by the searcher of any storage driver
+func NewWithSearcher(searcher Searcher, ms ...Middleware) Service {
+	model := NewModel(searcher)
+	service := NewService(model)
+	service = Decorate(service, ms...)
+	return service
+}
@@ -0,0 +1,23 @@
+package searchsvc
+
+import "context"
+
+type (
+	// Service represents the search service
+	Service interface {
+		Search(ctx context.Context, q Query) (*Result, error)
+	}
+
+	service struct {
+		model Model
+	}
+)
+
+// NewService returns a new search service
+func NewService(m Model) Service {
+	return &service{m}
+}
+
+func (s *service) Search(ctx context.Context, q Query) (*Result, error) {
+	return s.model.Search(q)
+}
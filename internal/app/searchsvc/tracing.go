@@ -0,0 +1,32 @@
+package searchsvc
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+)
+
+// Tracing adds tracing capabilities to the service
+func Tracing() Middleware {
+	return func(s Service) Service {
+		return &tracingMiddleware{
+			service: s,
+		}
+	}
+}
+
+type tracingMiddleware struct {
+	service Service
+}
+
+func (m *tracingMiddleware) Search(ctx context.Context, q Query) (*Result, error) {
+	ctx, span := trace.StartSpan(ctx, "Search")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("q", q.Text),
+		trace.StringAttribute("type", q.Type),
+		trace.StringAttribute("region", q.Region))
+
+	return m.service.Search(ctx, q)
+}
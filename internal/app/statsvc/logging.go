@@ -23,291 +23,491 @@ type loggingMiddleware struct {
 	service Service
 }
 
-func (m *loggingMiddleware) GetUserCount(ctx context.Context) (res *UserCount, err error) {
+func (m *loggingMiddleware) GetUserCount(ctx context.Context, region string) (res *UserCount, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetUserCount"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get user count", err)
 	}(time.Now())
 
-	return m.service.GetUserCount(ctx)
+	return m.service.GetUserCount(ctx, region)
 }
 
-func (m *loggingMiddleware) PostUserCount(ctx context.Context, count int) (err error) {
+func (m *loggingMiddleware) PostUserCount(ctx context.Context, region string, count int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostUserCount"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("count", count))
 
 		logger.Maybe(L, "post user count", err)
 	}(time.Now())
 
-	return m.service.PostUserCount(ctx, count)
+	return m.service.PostUserCount(ctx, region, count)
 }
 
-func (m *loggingMiddleware) GetRepoCount(ctx context.Context) (res *RepoCount, err error) {
+func (m *loggingMiddleware) GetRepoCount(ctx context.Context, region string) (res *RepoCount, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetRepoCount"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get repo count", err)
 	}(time.Now())
 
-	return m.service.GetRepoCount(ctx)
+	return m.service.GetRepoCount(ctx, region)
 }
 
-func (m *loggingMiddleware) PostRepoCount(ctx context.Context, count int) (err error) {
+func (m *loggingMiddleware) PostRepoCount(ctx context.Context, region string, count int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostRepoCount"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post repo count", err)
 	}(time.Now())
 
-	return m.service.PostRepoCount(ctx, count)
+	return m.service.PostRepoCount(ctx, region, count)
 }
 
-func (m *loggingMiddleware) GetReposMostRecent(ctx context.Context) (res *ReposMostRecent, err error) {
+func (m *loggingMiddleware) GetReposMostRecent(ctx context.Context, region string) (res *ReposMostRecent, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetReposMostRecent"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get repos most recent", err)
 	}(time.Now())
 
-	return m.service.GetReposMostRecent(ctx)
+	return m.service.GetReposMostRecent(ctx, region)
 }
 
-func (m *loggingMiddleware) PostReposMostRecent(ctx context.Context, repos []schema.Repo) (err error) {
+func (m *loggingMiddleware) PostReposMostRecent(ctx context.Context, region string, repos []schema.Repo) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostReposMostRecent"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post repos most recent", err)
 	}(time.Now())
 
-	return m.service.PostReposMostRecent(ctx, repos)
+	return m.service.PostReposMostRecent(ctx, region, repos)
 }
 
-func (m *loggingMiddleware) GetRepoCountByUser(ctx context.Context) (res *RepoCountByUser, err error) {
+func (m *loggingMiddleware) GetRepoCountByUser(ctx context.Context, region string) (res *RepoCountByUser, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetRepoCountByUser"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get repo count by user", err)
 	}(time.Now())
 
-	return m.service.GetRepoCountByUser(ctx)
+	return m.service.GetRepoCountByUser(ctx, region)
 }
 
-func (m *loggingMiddleware) PostRepoCountByUser(ctx context.Context, users []schema.UserCount) (err error) {
+func (m *loggingMiddleware) PostRepoCountByUser(ctx context.Context, region string, users []schema.UserCount) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostRepoCountByUser"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post repo count by user", err)
 	}(time.Now())
 
-	return m.service.PostRepoCountByUser(ctx, users)
+	return m.service.PostRepoCountByUser(ctx, region, users)
 }
 
-func (m *loggingMiddleware) GetReposMostStars(ctx context.Context) (res *ReposMostStars, err error) {
+func (m *loggingMiddleware) GetReposMostStars(ctx context.Context, region string) (res *ReposMostStars, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetReposMostStars"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get repos most stars", err)
 	}(time.Now())
 
-	return m.service.GetReposMostStars(ctx)
+	return m.service.GetReposMostStars(ctx, region)
 }
 
-func (m *loggingMiddleware) PostReposMostStars(ctx context.Context, repos []schema.Repo) (err error) {
+func (m *loggingMiddleware) PostReposMostStars(ctx context.Context, region string, repos []schema.Repo) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostReposMostStars"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post repos most stars", err)
 	}(time.Now())
 
-	return m.service.PostReposMostStars(ctx, repos)
+	return m.service.PostReposMostStars(ctx, region, repos)
 }
 
-func (m *loggingMiddleware) GetReposMostForks(ctx context.Context) (res *ReposMostForks, err error) {
+func (m *loggingMiddleware) GetReposMostForks(ctx context.Context, region string) (res *ReposMostForks, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetReposMostForks"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get repos most forks", err)
 	}(time.Now())
 
-	return m.service.GetReposMostForks(ctx)
+	return m.service.GetReposMostForks(ctx, region)
 }
 
-func (m *loggingMiddleware) PostReposMostForks(ctx context.Context, repos []schema.Repo) (err error) {
+func (m *loggingMiddleware) PostReposMostForks(ctx context.Context, region string, repos []schema.Repo) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostReposMostForks"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post repos most forks", err)
 	}(time.Now())
 
-	return m.service.PostReposMostForks(ctx, repos)
+	return m.service.PostReposMostForks(ctx, region, repos)
 }
 
-func (m *loggingMiddleware) GetMostPopularLanguage(ctx context.Context) (res *MostPopularLanguage, err error) {
+func (m *loggingMiddleware) GetMostPopularLanguage(ctx context.Context, region string) (res *MostPopularLanguage, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetMostPopularLanguage"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get most popular language", err)
 	}(time.Now())
 
-	return m.service.GetMostPopularLanguage(ctx)
+	return m.service.GetMostPopularLanguage(ctx, region)
 }
 
-func (m *loggingMiddleware) PostMostPopularLanguage(ctx context.Context, languages []schema.LanguageCount) (err error) {
+func (m *loggingMiddleware) PostMostPopularLanguage(ctx context.Context, region string, languages []schema.LanguageCount) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostMostPopularLanguage"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post most popular language", err)
 	}(time.Now())
 
-	return m.service.PostMostPopularLanguage(ctx, languages)
+	return m.service.PostMostPopularLanguage(ctx, region, languages)
 }
 
-func (m *loggingMiddleware) GetLanguageCountByUser(ctx context.Context) (res *LanguageCountByUser, err error) {
+func (m *loggingMiddleware) GetLanguageCountByUser(ctx context.Context, region string) (res *LanguageCountByUser, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetLanguageCountByUser"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get language count by user", err)
 	}(time.Now())
 
-	return m.service.GetLanguageCountByUser(ctx)
+	return m.service.GetLanguageCountByUser(ctx, region)
 }
 
-func (m *loggingMiddleware) PostLanguageCountByUser(ctx context.Context, languages []schema.LanguageCount) (err error) {
+func (m *loggingMiddleware) PostLanguageCountByUser(ctx context.Context, region string, languages []schema.LanguageCount) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostLanguageCountByUser"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post language count by user", err)
 	}(time.Now())
 
-	return m.service.PostLanguageCountByUser(ctx, languages)
+	return m.service.PostLanguageCountByUser(ctx, region, languages)
 }
 
-func (m *loggingMiddleware) GetMostRecentReposByLanguage(ctx context.Context) (res *MostRecentReposByLanguage, err error) {
+func (m *loggingMiddleware) GetMostRecentReposByLanguage(ctx context.Context, region string) (res *MostRecentReposByLanguage, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetMostRecentReposByLanguage"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get most recent repos by language", err)
 	}(time.Now())
 
-	return m.service.GetMostRecentReposByLanguage(ctx)
+	return m.service.GetMostRecentReposByLanguage(ctx, region)
 }
 
-func (m *loggingMiddleware) PostMostRecentReposByLanguage(ctx context.Context, repos []schema.RepoLanguage) (err error) {
+func (m *loggingMiddleware) PostMostRecentReposByLanguage(ctx context.Context, region string, repos []schema.RepoLanguage) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostMostRecentReposByLanguage"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post most recent repos by language", err)
 	}(time.Now())
 
-	return m.service.PostMostRecentReposByLanguage(ctx, repos)
+	return m.service.PostMostRecentReposByLanguage(ctx, region, repos)
 }
 
-func (m *loggingMiddleware) GetReposByLanguage(ctx context.Context) (res *ReposByLanguage, err error) {
+func (m *loggingMiddleware) GetReposByLanguage(ctx context.Context, region string) (res *ReposByLanguage, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetReposByLanguage"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get repos by language", err)
 	}(time.Now())
 
-	return m.service.GetReposByLanguage(ctx)
+	return m.service.GetReposByLanguage(ctx, region)
 }
 
-func (m *loggingMiddleware) PostReposByLanguage(ctx context.Context, users []schema.UserCountByLanguage) (err error) {
+func (m *loggingMiddleware) PostReposByLanguage(ctx context.Context, region string, users []schema.UserCountByLanguage) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostReposByLanguage"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post repos by language", err)
 	}(time.Now())
 
-	return m.service.PostReposByLanguage(ctx, users)
+	return m.service.PostReposByLanguage(ctx, region, users)
 }
 
-func (m *loggingMiddleware) GetCompanyCount(ctx context.Context) (res *CompanyCount, err error) {
+func (m *loggingMiddleware) GetCompanyCount(ctx context.Context, region string) (res *CompanyCount, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetCompanyCount"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get company count", err)
 	}(time.Now())
 
-	return m.service.GetCompanyCount(ctx)
+	return m.service.GetCompanyCount(ctx, region)
 }
 
-func (m *loggingMiddleware) PostCompanyCount(ctx context.Context, count int) (err error) {
+func (m *loggingMiddleware) PostCompanyCount(ctx context.Context, region string, count int) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostCompanyCount"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post company count", err)
 	}(time.Now())
 
-	return m.service.PostCompanyCount(ctx, count)
+	return m.service.PostCompanyCount(ctx, region, count)
 }
 
-func (m *loggingMiddleware) GetUsersByCompany(ctx context.Context) (res *UsersByCompany, err error) {
+func (m *loggingMiddleware) GetUsersByCompany(ctx context.Context, region string) (res *UsersByCompany, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("GetUsersByCompany"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get users by company", err)
 	}(time.Now())
 
-	return m.service.GetUsersByCompany(ctx)
+	return m.service.GetUsersByCompany(ctx, region)
 }
 
-func (m *loggingMiddleware) PostUsersByCompany(ctx context.Context, users []schema.Company) (err error) {
+func (m *loggingMiddleware) PostUsersByCompany(ctx context.Context, region string, users []schema.Company) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("PostUsersByCompany"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "post users by company", err)
 	}(time.Now())
 
-	return m.service.PostUsersByCompany(ctx, users)
+	return m.service.PostUsersByCompany(ctx, region, users)
+}
+
+func (m *loggingMiddleware) GetUserCountSeries(ctx context.Context, region, from, to string) (res []UserCount, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetUserCountSeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get user count series", err)
+	}(time.Now())
+
+	return m.service.GetUserCountSeries(ctx, region, from, to)
+}
+
+func (m *loggingMiddleware) GetRepoCountSeries(ctx context.Context, region, from, to string) (res []RepoCount, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetRepoCountSeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get repo count series", err)
+	}(time.Now())
+
+	return m.service.GetRepoCountSeries(ctx, region, from, to)
+}
+
+func (m *loggingMiddleware) GetReposMostRecentSeries(ctx context.Context, region, from, to string) (res []ReposMostRecent, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetReposMostRecentSeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get repos most recent series", err)
+	}(time.Now())
+
+	return m.service.GetReposMostRecentSeries(ctx, region, from, to)
+}
+
+func (m *loggingMiddleware) GetRepoCountByUserSeries(ctx context.Context, region, from, to string) (res []RepoCountByUser, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetRepoCountByUserSeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get repo count by user series", err)
+	}(time.Now())
+
+	return m.service.GetRepoCountByUserSeries(ctx, region, from, to)
+}
+
+func (m *loggingMiddleware) GetReposMostStarsSeries(ctx context.Context, region, from, to string) (res []ReposMostStars, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetReposMostStarsSeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get repos most stars series", err)
+	}(time.Now())
+
+	return m.service.GetReposMostStarsSeries(ctx, region, from, to)
+}
+
+func (m *loggingMiddleware) GetReposMostForksSeries(ctx context.Context, region, from, to string) (res []ReposMostForks, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetReposMostForksSeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get repos most forks series", err)
+	}(time.Now())
+
+	return m.service.GetReposMostForksSeries(ctx, region, from, to)
+}
+
+func (m *loggingMiddleware) GetMostPopularLanguageSeries(ctx context.Context, region, from, to string) (res []MostPopularLanguage, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetMostPopularLanguageSeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get most popular language series", err)
+	}(time.Now())
+
+	return m.service.GetMostPopularLanguageSeries(ctx, region, from, to)
+}
+
+func (m *loggingMiddleware) GetMostRecentReposByLanguageSeries(ctx context.Context, region, from, to string) (res []MostRecentReposByLanguage, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetMostRecentReposByLanguageSeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get most recent repos by language series", err)
+	}(time.Now())
+
+	return m.service.GetMostRecentReposByLanguageSeries(ctx, region, from, to)
+}
+
+func (m *loggingMiddleware) GetReposByLanguageSeries(ctx context.Context, region, from, to string) (res []ReposByLanguage, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetReposByLanguageSeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get repos by language series", err)
+	}(time.Now())
+
+	return m.service.GetReposByLanguageSeries(ctx, region, from, to)
+}
+
+func (m *loggingMiddleware) GetCompanyCountSeries(ctx context.Context, region, from, to string) (res []CompanyCount, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetCompanyCountSeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get company count series", err)
+	}(time.Now())
+
+	return m.service.GetCompanyCountSeries(ctx, region, from, to)
+}
+
+func (m *loggingMiddleware) GetUsersByCompanySeries(ctx context.Context, region, from, to string) (res []UsersByCompany, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("GetUsersByCompanySeries"),
+			logger.Duration(start),
+			zap.String("region", region),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Int("count", len(res)))
+
+		logger.Maybe(L, "get users by company series", err)
+	}(time.Now())
+
+	return m.service.GetUsersByCompanySeries(ctx, region, from, to)
 }
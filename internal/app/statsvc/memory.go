@@ -0,0 +1,460 @@
+package statsvc
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/kv"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// statKey identifies a stat by its type and region. The date is empty for the latest stat,
+// and set to the day of the snapshot for the stats in the history
+type statKey struct {
+	enum   string
+	region string
+	date   string
+}
+
+// String returns the key of the stat in the bucket
+func (k statKey) String() string {
+	return k.enum + ":" + k.region + ":" + k.date
+}
+
+// memoryStore keeps the stats and their history in memory, for the tests and the demo mode.
+// The stats are kept as documents and decoded with bson, so that they read back the same
+// as from the mongo store. With a bucket, the stats are written through to it as well
+type memoryStore struct {
+	sync.RWMutex
+	docs   map[statKey]bson.M
+	bucket kv.Bucket
+}
+
+// NewMemoryStore returns an empty store that keeps the stats in memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		docs: make(map[statKey]bson.M),
+	}
+}
+
+// NewKVStore returns a store that keeps the stats and their history in memory and in the
+// bucket, with the stats already in the bucket
+func NewKVStore(b kv.Bucket) (Store, error) {
+	s := &memoryStore{
+		docs:   make(map[statKey]bson.M),
+		bucket: b,
+	}
+	if err := b.ForEach(func(_ string, value []byte) error {
+		var doc bson.M
+		if err := bson.Unmarshal(value, &doc); err != nil {
+			return err
+		}
+		enum, _ := doc["type"].(string)
+		region, _ := doc["region"].(string)
+		date, _ := doc["date"].(string)
+		s.docs[statKey{enum, region, date}] = doc
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryStore) Init() error {
+	return nil
+}
+
+func (s *memoryStore) GetUserCount(region string) (*UserCount, error) {
+	var res UserCount
+	if err := s.find(EnumUserCount, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostUserCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumUserCount, region, bson.M{
+		"count":     count,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetRepoCount(region string) (*RepoCount, error) {
+	var res RepoCount
+	if err := s.find(EnumRepoCount, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostRepoCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumRepoCount, region, bson.M{
+		"count":     count,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetReposMostRecent(region string) (*ReposMostRecent, error) {
+	var res ReposMostRecent
+	if err := s.find(EnumReposMostRecent, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostReposMostRecent(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostRecent, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetRepoCountByUser(region string) (*RepoCountByUser, error) {
+	var res RepoCountByUser
+	if err := s.find(EnumRepoCountByUser, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostRepoCountByUser(f fence.Fence, region string, users []schema.UserCount) error {
+	return s.upsert(f, EnumRepoCountByUser, region, bson.M{
+		"users":     users,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetReposMostStars(region string) (*ReposMostStars, error) {
+	var res ReposMostStars
+	if err := s.find(EnumReposMostStars, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostReposMostStars(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostStars, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetReposMostForks(region string) (*ReposMostForks, error) {
+	var res ReposMostForks
+	if err := s.find(EnumReposMostForks, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostReposMostForks(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostForks, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetMostPopularLanguage(region string) (*MostPopularLanguage, error) {
+	var res MostPopularLanguage
+	if err := s.find(EnumMostPopularLanguage, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostMostPopularLanguage(f fence.Fence, region string, languages []schema.LanguageCount) error {
+	return s.upsert(f, EnumMostPopularLanguage, region, bson.M{
+		"languages": languages,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetLanguageCountByUser(region string) (*LanguageCountByUser, error) {
+	var res LanguageCountByUser
+	if err := s.find(EnumLanguageCountByUser, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostLanguageCountByUser(f fence.Fence, region string, languages []schema.LanguageCount) error {
+	return s.upsert(f, EnumLanguageCountByUser, region, bson.M{
+		"languages": languages,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetMostRecentReposByLanguage(region string) (*MostRecentReposByLanguage, error) {
+	var res MostRecentReposByLanguage
+	if err := s.find(EnumMostRecentReposByLanguage, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostMostRecentReposByLanguage(f fence.Fence, region string, repos []schema.RepoLanguage) error {
+	return s.upsert(f, EnumMostRecentReposByLanguage, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetReposByLanguage(region string) (*ReposByLanguage, error) {
+	var res ReposByLanguage
+	if err := s.find(EnumReposByLanguage, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostReposByLanguage(f fence.Fence, region string, users []schema.UserCountByLanguage) error {
+	return s.upsert(f, EnumReposByLanguage, region, bson.M{
+		"users":     users,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetCompanyCount(region string) (*CompanyCount, error) {
+	var res CompanyCount
+	if err := s.find(EnumCompanyCount, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostCompanyCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumCompanyCount, region, bson.M{
+		"count":     count,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetUsersByCompany(region string) (*UsersByCompany, error) {
+	var res UsersByCompany
+	if err := s.find(EnumUsersByCompany, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *memoryStore) PostUsersByCompany(f fence.Fence, region string, users []schema.Company) error {
+	return s.upsert(f, EnumUsersByCompany, region, bson.M{
+		"users":     users,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *memoryStore) GetUserCountSeries(region, from, to string) ([]UserCount, error) {
+	var res []UserCount
+	if err := s.series(EnumUserCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetRepoCountSeries(region, from, to string) ([]RepoCount, error) {
+	var res []RepoCount
+	if err := s.series(EnumRepoCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetReposMostRecentSeries(region, from, to string) ([]ReposMostRecent, error) {
+	var res []ReposMostRecent
+	if err := s.series(EnumReposMostRecent, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetRepoCountByUserSeries(region, from, to string) ([]RepoCountByUser, error) {
+	var res []RepoCountByUser
+	if err := s.series(EnumRepoCountByUser, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetReposMostStarsSeries(region, from, to string) ([]ReposMostStars, error) {
+	var res []ReposMostStars
+	if err := s.series(EnumReposMostStars, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetReposMostForksSeries(region, from, to string) ([]ReposMostForks, error) {
+	var res []ReposMostForks
+	if err := s.series(EnumReposMostForks, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetMostPopularLanguageSeries(region, from, to string) ([]MostPopularLanguage, error) {
+	var res []MostPopularLanguage
+	if err := s.series(EnumMostPopularLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetMostRecentReposByLanguageSeries(region, from, to string) ([]MostRecentReposByLanguage, error) {
+	var res []MostRecentReposByLanguage
+	if err := s.series(EnumMostRecentReposByLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetReposByLanguageSeries(region, from, to string) ([]ReposByLanguage, error) {
+	var res []ReposByLanguage
+	if err := s.series(EnumReposByLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetCompanyCountSeries(region, from, to string) ([]CompanyCount, error) {
+	var res []CompanyCount
+	if err := s.series(EnumCompanyCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *memoryStore) GetUsersByCompanySeries(region, from, to string) ([]UsersByCompany, error) {
+	var res []UsersByCompany
+	if err := s.series(EnumUsersByCompany, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// upsert replaces the latest stat of the type for the region, and the snapshot of the day
+func (s *memoryStore) upsert(f fence.Fence, enum, region string, data bson.M) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if doc, ok := s.docs[statKey{enum, region, ""}]; ok && f.Stale(fenceOf(doc)) {
+		return fence.ErrStale
+	}
+	if !f.IsZero() {
+		data["fence"] = bson.M{"name": f.Name, "token": f.Token}
+	}
+
+	changed := make(map[statKey]bson.M)
+	for _, key := range []statKey{
+		{enum, region, ""},
+		{enum, region, moment.NewUTCFormattedDate()},
+	} {
+		doc, ok := s.docs[key]
+		if !ok {
+			doc = bson.M{
+				"type":      enum,
+				"region":    region,
+				"createdAt": moment.NewUTCDate(),
+			}
+			if key.date != "" {
+				doc["date"] = key.date
+			}
+		}
+		doc = copyDoc(doc)
+		for field, value := range data {
+			doc[field] = value
+		}
+		changed[key] = doc
+	}
+
+	records := make(map[string]interface{})
+	for key, doc := range changed {
+		records[key.String()] = doc
+	}
+	if err := kv.Save(s.bucket, records); err != nil {
+		return err
+	}
+	for key, doc := range changed {
+		s.docs[key] = doc
+	}
+	return nil
+}
+
+// find decodes the latest stat of the type for the region into res
+func (s *memoryStore) find(enum, region string, res interface{}) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	doc, ok := s.docs[statKey{enum, region, ""}]
+	if !ok {
+		return apperr.ErrNotFound
+	}
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(b, res)
+}
+
+// series decodes the snapshots of the type for the region between the dates (inclusive)
+// into res, sorted from the oldest to the latest
+func (s *memoryStore) series(enum, region, from, to string, res interface{}) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	var docs []bson.M
+	for key, doc := range s.docs {
+		if key.enum == enum && key.region == region &&
+			key.date != "" && key.date >= from && key.date <= to {
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i]["date"].(string) < docs[j]["date"].(string)
+	})
+
+	// Only documents can be decoded, so the snapshots are wrapped in one
+	b, err := bson.Marshal(bson.M{"items": docs})
+	if err != nil {
+		return err
+	}
+	var wrapper struct {
+		Items bson.Raw `bson:"items"`
+	}
+	if err := bson.Unmarshal(b, &wrapper); err != nil {
+		return err
+	}
+	return wrapper.Items.Unmarshal(res)
+}
+
+// fenceOf returns the fence of the last write of the stat
+func fenceOf(doc bson.M) fence.Fence {
+	var f fence.Fence
+	m, ok := doc["fence"].(bson.M)
+	if !ok {
+		return f
+	}
+	f.Name, _ = m["name"].(string)
+	switch token := m["token"].(type) {
+	case int64:
+		f.Token = token
+	case int:
+		f.Token = int64(token)
+	}
+	return f
+}
+
+// copyDoc returns a shallow copy of the document, so that the stat kept is only changed once
+// it is saved
+func copyDoc(doc bson.M) bson.M {
+	res := make(bson.M, len(doc))
+	for field, value := range doc {
+		res[field] = value
+	}
+	return res
+}
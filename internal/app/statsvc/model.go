@@ -2,38 +2,63 @@ package statsvc
 
 import (
 	"log"
+	"time"
 
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 )
 
+// dateLayout is the layout of the snapshot dates, YYYY-MM-DD
+const dateLayout = "2006-01-02"
+
+// defaultSeriesDays is the number of days returned when the start of the series is not provided
+const defaultSeriesDays = 30
+
+var (
+	ErrInvalidDate      = apperr.Validation("date must be in the format YYYY-MM-DD")
+	ErrInvalidDateRange = apperr.Validation("from date must not be after the to date")
+)
+
 // Model represents the interface for the analytic business logic
 type (
 	Model interface {
 		Init() error
-		GetUserCount() (*UserCount, error)
-		PostUserCount(count int) error
-		GetRepoCount() (*RepoCount, error)
-		PostRepoCount(count int) error
-		GetReposMostRecent() (*ReposMostRecent, error)
-		PostReposMostRecent(data []schema.Repo) error
-		GetRepoCountByUser() (*RepoCountByUser, error)
-		PostRepoCountByUser(users []schema.UserCount) error
-		GetReposMostStars() (*ReposMostStars, error)
-		PostReposMostStars(repos []schema.Repo) error
-		GetReposMostForks() (*ReposMostForks, error)
-		PostReposMostForks(repos []schema.Repo) error
-		GetMostPopularLanguage() (*MostPopularLanguage, error)
-		PostMostPopularLanguage(languages []schema.LanguageCount) error
-		GetLanguageCountByUser() (*LanguageCountByUser, error)
-		PostLanguageCountByUser(languages []schema.LanguageCount) error
-		GetMostRecentReposByLanguage() (*MostRecentReposByLanguage, error)
-		PostMostRecentReposByLanguage(repos []schema.RepoLanguage) error
-		GetReposByLanguage() (*ReposByLanguage, error)
-		PostReposByLanguage(users []schema.UserCountByLanguage) error
-		GetCompanyCount() (*CompanyCount, error)
-		PostCompanyCount(count int) error
-		GetUsersByCompany() (*UsersByCompany, error)
-		PostUsersByCompany(users []schema.Company) error
+		GetUserCount(region string) (*UserCount, error)
+		PostUserCount(f fence.Fence, region string, count int) error
+		GetRepoCount(region string) (*RepoCount, error)
+		PostRepoCount(f fence.Fence, region string, count int) error
+		GetReposMostRecent(region string) (*ReposMostRecent, error)
+		PostReposMostRecent(f fence.Fence, region string, data []schema.Repo) error
+		GetRepoCountByUser(region string) (*RepoCountByUser, error)
+		PostRepoCountByUser(f fence.Fence, region string, users []schema.UserCount) error
+		GetReposMostStars(region string) (*ReposMostStars, error)
+		PostReposMostStars(f fence.Fence, region string, repos []schema.Repo) error
+		GetReposMostForks(region string) (*ReposMostForks, error)
+		PostReposMostForks(f fence.Fence, region string, repos []schema.Repo) error
+		GetMostPopularLanguage(region string) (*MostPopularLanguage, error)
+		PostMostPopularLanguage(f fence.Fence, region string, languages []schema.LanguageCount) error
+		GetLanguageCountByUser(region string) (*LanguageCountByUser, error)
+		PostLanguageCountByUser(f fence.Fence, region string, languages []schema.LanguageCount) error
+		GetMostRecentReposByLanguage(region string) (*MostRecentReposByLanguage, error)
+		PostMostRecentReposByLanguage(f fence.Fence, region string, repos []schema.RepoLanguage) error
+		GetReposByLanguage(region string) (*ReposByLanguage, error)
+		PostReposByLanguage(f fence.Fence, region string, users []schema.UserCountByLanguage) error
+		GetCompanyCount(region string) (*CompanyCount, error)
+		PostCompanyCount(f fence.Fence, region string, count int) error
+		GetUsersByCompany(region string) (*UsersByCompany, error)
+		PostUsersByCompany(f fence.Fence, region string, users []schema.Company) error
+		GetUserCountSeries(region, from, to string) ([]UserCount, error)
+		GetRepoCountSeries(region, from, to string) ([]RepoCount, error)
+		GetReposMostRecentSeries(region, from, to string) ([]ReposMostRecent, error)
+		GetRepoCountByUserSeries(region, from, to string) ([]RepoCountByUser, error)
+		GetReposMostStarsSeries(region, from, to string) ([]ReposMostStars, error)
+		GetReposMostForksSeries(region, from, to string) ([]ReposMostForks, error)
+		GetMostPopularLanguageSeries(region, from, to string) ([]MostPopularLanguage, error)
+		GetMostRecentReposByLanguageSeries(region, from, to string) ([]MostRecentReposByLanguage, error)
+		GetReposByLanguageSeries(region, from, to string) ([]ReposByLanguage, error)
+		GetCompanyCountSeries(region, from, to string) ([]CompanyCount, error)
+		GetUsersByCompanySeries(region, from, to string) ([]UsersByCompany, error)
 	}
 
 	model struct {
@@ -56,125 +81,238 @@ func (m *model) Init() error {
 	return m.store.Init()
 }
 
-func (m *model) GetUserCount() (*UserCount, error) {
-	return m.store.GetUserCount()
+func (m *model) GetUserCount(region string) (*UserCount, error) {
+	return m.store.GetUserCount(region)
 }
 
-func (m *model) PostUserCount(count int) error {
-	return m.store.PostUserCount(count)
+func (m *model) PostUserCount(f fence.Fence, region string, count int) error {
+	return m.store.PostUserCount(f, region, count)
 }
 
-func (m *model) GetRepoCount() (*RepoCount, error) {
-	return m.store.GetRepoCount()
+func (m *model) GetRepoCount(region string) (*RepoCount, error) {
+	return m.store.GetRepoCount(region)
 }
 
-func (m *model) PostRepoCount(count int) error {
-	return m.store.PostRepoCount(count)
+func (m *model) PostRepoCount(f fence.Fence, region string, count int) error {
+	return m.store.PostRepoCount(f, region, count)
 }
 
-func (m *model) GetReposMostRecent() (*ReposMostRecent, error) {
-	return m.store.GetReposMostRecent()
+func (m *model) GetReposMostRecent(region string) (*ReposMostRecent, error) {
+	return m.store.GetReposMostRecent(region)
 }
 
-func (m *model) PostReposMostRecent(repos []schema.Repo) error {
+func (m *model) PostReposMostRecent(f fence.Fence, region string, repos []schema.Repo) error {
 	if len(repos) == 0 {
 		return nil
 	}
-	return m.store.PostReposMostRecent(repos)
+	return m.store.PostReposMostRecent(f, region, repos)
 }
 
-func (m *model) GetRepoCountByUser() (*RepoCountByUser, error) {
-	return m.store.GetRepoCountByUser()
+func (m *model) GetRepoCountByUser(region string) (*RepoCountByUser, error) {
+	return m.store.GetRepoCountByUser(region)
 }
 
-func (m *model) PostRepoCountByUser(users []schema.UserCount) error {
+func (m *model) PostRepoCountByUser(f fence.Fence, region string, users []schema.UserCount) error {
 	if len(users) == 0 {
 		return nil
 	}
-	return m.store.PostRepoCountByUser(users)
+	return m.store.PostRepoCountByUser(f, region, users)
 }
 
-func (m *model) GetReposMostStars() (*ReposMostStars, error) {
-	return m.store.GetReposMostStars()
+func (m *model) GetReposMostStars(region string) (*ReposMostStars, error) {
+	return m.store.GetReposMostStars(region)
 }
 
-func (m *model) PostReposMostStars(repos []schema.Repo) error {
+func (m *model) PostReposMostStars(f fence.Fence, region string, repos []schema.Repo) error {
 	if len(repos) == 0 {
 		return nil
 	}
-	return m.store.PostReposMostStars(repos)
+	return m.store.PostReposMostStars(f, region, repos)
 }
 
-func (m *model) GetReposMostForks() (*ReposMostForks, error) {
-	return m.store.GetReposMostForks()
+func (m *model) GetReposMostForks(region string) (*ReposMostForks, error) {
+	return m.store.GetReposMostForks(region)
 }
 
-func (m *model) PostReposMostForks(repos []schema.Repo) error {
+func (m *model) PostReposMostForks(f fence.Fence, region string, repos []schema.Repo) error {
 	if len(repos) == 0 {
 		return nil
 	}
-	return m.store.PostReposMostForks(repos)
+	return m.store.PostReposMostForks(f, region, repos)
 }
 
-func (m *model) GetMostPopularLanguage() (*MostPopularLanguage, error) {
-	return m.store.GetMostPopularLanguage()
+func (m *model) GetMostPopularLanguage(region string) (*MostPopularLanguage, error) {
+	return m.store.GetMostPopularLanguage(region)
 }
 
-func (m *model) PostMostPopularLanguage(languages []schema.LanguageCount) error {
+func (m *model) PostMostPopularLanguage(f fence.Fence, region string, languages []schema.LanguageCount) error {
 	if len(languages) == 0 {
 		return nil
 	}
-	return m.store.PostMostPopularLanguage(languages)
+	return m.store.PostMostPopularLanguage(f, region, languages)
 }
 
-func (m *model) GetLanguageCountByUser() (*LanguageCountByUser, error) {
-	return m.store.GetLanguageCountByUser()
+func (m *model) GetLanguageCountByUser(region string) (*LanguageCountByUser, error) {
+	return m.store.GetLanguageCountByUser(region)
 }
 
-func (m *model) PostLanguageCountByUser(languages []schema.LanguageCount) error {
+func (m *model) PostLanguageCountByUser(f fence.Fence, region string, languages []schema.LanguageCount) error {
 	if len(languages) == 0 {
 		return nil
 	}
-	return m.store.PostLanguageCountByUser(languages)
+	return m.store.PostLanguageCountByUser(f, region, languages)
 }
 
-func (m *model) GetMostRecentReposByLanguage() (*MostRecentReposByLanguage, error) {
-	return m.store.GetMostRecentReposByLanguage()
+func (m *model) GetMostRecentReposByLanguage(region string) (*MostRecentReposByLanguage, error) {
+	return m.store.GetMostRecentReposByLanguage(region)
 }
 
-func (m *model) PostMostRecentReposByLanguage(repos []schema.RepoLanguage) error {
+func (m *model) PostMostRecentReposByLanguage(f fence.Fence, region string, repos []schema.RepoLanguage) error {
 	if len(repos) == 0 {
 		return nil
 	}
-	return m.store.PostMostRecentReposByLanguage(repos)
+	return m.store.PostMostRecentReposByLanguage(f, region, repos)
 }
 
-func (m *model) GetReposByLanguage() (*ReposByLanguage, error) {
-	return m.store.GetReposByLanguage()
+func (m *model) GetReposByLanguage(region string) (*ReposByLanguage, error) {
+	return m.store.GetReposByLanguage(region)
 }
 
-func (m *model) PostReposByLanguage(users []schema.UserCountByLanguage) error {
+func (m *model) PostReposByLanguage(f fence.Fence, region string, users []schema.UserCountByLanguage) error {
 	if len(users) == 0 {
 		return nil
 	}
-	return m.store.PostReposByLanguage(users)
+	return m.store.PostReposByLanguage(f, region, users)
 }
 
-func (m *model) GetCompanyCount() (*CompanyCount, error) {
-	return m.store.GetCompanyCount()
+func (m *model) GetCompanyCount(region string) (*CompanyCount, error) {
+	return m.store.GetCompanyCount(region)
 }
 
-func (m *model) PostCompanyCount(count int) error {
-	return m.store.PostCompanyCount(count)
+func (m *model) PostCompanyCount(f fence.Fence, region string, count int) error {
+	return m.store.PostCompanyCount(f, region, count)
 }
 
-func (m *model) GetUsersByCompany() (*UsersByCompany, error) {
-	return m.store.GetUsersByCompany()
+func (m *model) GetUsersByCompany(region string) (*UsersByCompany, error) {
+	return m.store.GetUsersByCompany(region)
 }
 
-func (m *model) PostUsersByCompany(users []schema.Company) error {
+func (m *model) PostUsersByCompany(f fence.Fence, region string, users []schema.Company) error {
 	if len(users) == 0 {
 		return nil
 	}
-	return m.store.PostUsersByCompany(users)
+	return m.store.PostUsersByCompany(f, region, users)
+}
+
+func (m *model) GetUserCountSeries(region, from, to string) ([]UserCount, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetUserCountSeries(region, from, to)
+}
+
+func (m *model) GetRepoCountSeries(region, from, to string) ([]RepoCount, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetRepoCountSeries(region, from, to)
+}
+
+func (m *model) GetReposMostRecentSeries(region, from, to string) ([]ReposMostRecent, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetReposMostRecentSeries(region, from, to)
+}
+
+func (m *model) GetRepoCountByUserSeries(region, from, to string) ([]RepoCountByUser, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetRepoCountByUserSeries(region, from, to)
+}
+
+func (m *model) GetReposMostStarsSeries(region, from, to string) ([]ReposMostStars, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetReposMostStarsSeries(region, from, to)
+}
+
+func (m *model) GetReposMostForksSeries(region, from, to string) ([]ReposMostForks, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetReposMostForksSeries(region, from, to)
+}
+
+func (m *model) GetMostPopularLanguageSeries(region, from, to string) ([]MostPopularLanguage, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetMostPopularLanguageSeries(region, from, to)
+}
+
+func (m *model) GetMostRecentReposByLanguageSeries(region, from, to string) ([]MostRecentReposByLanguage, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetMostRecentReposByLanguageSeries(region, from, to)
+}
+
+func (m *model) GetReposByLanguageSeries(region, from, to string) ([]ReposByLanguage, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetReposByLanguageSeries(region, from, to)
+}
+
+func (m *model) GetCompanyCountSeries(region, from, to string) ([]CompanyCount, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetCompanyCountSeries(region, from, to)
+}
+
+func (m *model) GetUsersByCompanySeries(region, from, to string) ([]UsersByCompany, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return m.store.GetUsersByCompanySeries(region, from, to)
+}
+
+// dateRange validates the dates of the series. The series ends today when the to date
+// is not provided, and starts defaultSeriesDays before the end when the from date is not provided
+func dateRange(from, to string) (string, string, error) {
+	end := time.Now().UTC()
+	if to != "" {
+		t, err := time.Parse(dateLayout, to)
+		if err != nil {
+			return "", "", ErrInvalidDate
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -defaultSeriesDays)
+	if from != "" {
+		t, err := time.Parse(dateLayout, from)
+		if err != nil {
+			return "", "", ErrInvalidDate
+		}
+		start = t
+	}
+	if start.After(end) {
+		return "", "", ErrInvalidDateRange
+	}
+	return start.Format(dateLayout), end.Format(dateLayout), nil
 }
@@ -0,0 +1,406 @@
+package statsvc
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoStore is the store on the official mongo driver, with the same documents and indexes
+// as the mgo store
+type mongoStore struct {
+	db         *database.Mongo
+	collection string
+	history    string
+}
+
+// NewMongoStore returns a new analytic store on the official mongo driver. The latest stats
+// are kept in the collection, and the dated snapshots in the history collection
+func NewMongoStore(db *database.Mongo, collection, history string) Store {
+	return &mongoStore{
+		db:         db,
+		collection: collection,
+		history:    history,
+	}
+}
+
+func (s *mongoStore) Init() error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	// Stats were previously unique by type only. Existing stats are assigned to
+	// the global region before the index is replaced
+	c := s.db.Collection(s.collection)
+	if _, err := c.Indexes().DropOne(ctx, "type_1"); err != nil && !isIndexNotFoundError(err) {
+		return err
+	}
+	if _, err := c.UpdateMany(ctx,
+		bson.M{"region": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"region": ""}},
+	); err != nil {
+		return err
+	}
+	if _, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    database.Sort("type", "region"),
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+	_, err := s.db.Collection(s.history).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    database.Sort("type", "region", "date"),
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (s *mongoStore) GetUserCount(region string) (*UserCount, error) {
+	var res UserCount
+	if err := s.find(EnumUserCount, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostUserCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumUserCount, region, bson.M{
+		"count":     count,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetRepoCount(region string) (*RepoCount, error) {
+	var res RepoCount
+	if err := s.find(EnumRepoCount, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostRepoCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumRepoCount, region, bson.M{
+		"count":     count,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetReposMostRecent(region string) (*ReposMostRecent, error) {
+	var res ReposMostRecent
+	if err := s.find(EnumReposMostRecent, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostReposMostRecent(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostRecent, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetRepoCountByUser(region string) (*RepoCountByUser, error) {
+	var res RepoCountByUser
+	if err := s.find(EnumRepoCountByUser, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostRepoCountByUser(f fence.Fence, region string, users []schema.UserCount) error {
+	return s.upsert(f, EnumRepoCountByUser, region, bson.M{
+		"users":     users,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetReposMostStars(region string) (*ReposMostStars, error) {
+	var res ReposMostStars
+	if err := s.find(EnumReposMostStars, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostReposMostStars(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostStars, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetReposMostForks(region string) (*ReposMostForks, error) {
+	var res ReposMostForks
+	if err := s.find(EnumReposMostForks, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostReposMostForks(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostForks, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetMostPopularLanguage(region string) (*MostPopularLanguage, error) {
+	var res MostPopularLanguage
+	if err := s.find(EnumMostPopularLanguage, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostMostPopularLanguage(f fence.Fence, region string, languages []schema.LanguageCount) error {
+	return s.upsert(f, EnumMostPopularLanguage, region, bson.M{
+		"languages": languages,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetLanguageCountByUser(region string) (*LanguageCountByUser, error) {
+	var res LanguageCountByUser
+	if err := s.find(EnumLanguageCountByUser, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostLanguageCountByUser(f fence.Fence, region string, languages []schema.LanguageCount) error {
+	return s.upsert(f, EnumLanguageCountByUser, region, bson.M{
+		"languages": languages,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetMostRecentReposByLanguage(region string) (*MostRecentReposByLanguage, error) {
+	var res MostRecentReposByLanguage
+	if err := s.find(EnumMostRecentReposByLanguage, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostMostRecentReposByLanguage(f fence.Fence, region string, repos []schema.RepoLanguage) error {
+	return s.upsert(f, EnumMostRecentReposByLanguage, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetReposByLanguage(region string) (*ReposByLanguage, error) {
+	var res ReposByLanguage
+	if err := s.find(EnumReposByLanguage, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostReposByLanguage(f fence.Fence, region string, users []schema.UserCountByLanguage) error {
+	return s.upsert(f, EnumReposByLanguage, region, bson.M{
+		"users":     users,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetCompanyCount(region string) (*CompanyCount, error) {
+	var res CompanyCount
+	if err := s.find(EnumCompanyCount, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostCompanyCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumCompanyCount, region, bson.M{
+		"count":     count,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetUsersByCompany(region string) (*UsersByCompany, error) {
+	var res UsersByCompany
+	if err := s.find(EnumUsersByCompany, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *mongoStore) PostUsersByCompany(f fence.Fence, region string, users []schema.Company) error {
+	return s.upsert(f, EnumUsersByCompany, region, bson.M{
+		"users":     users,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *mongoStore) GetUserCountSeries(region, from, to string) ([]UserCount, error) {
+	var res []UserCount
+	if err := s.series(EnumUserCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *mongoStore) GetRepoCountSeries(region, from, to string) ([]RepoCount, error) {
+	var res []RepoCount
+	if err := s.series(EnumRepoCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *mongoStore) GetReposMostRecentSeries(region, from, to string) ([]ReposMostRecent, error) {
+	var res []ReposMostRecent
+	if err := s.series(EnumReposMostRecent, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *mongoStore) GetRepoCountByUserSeries(region, from, to string) ([]RepoCountByUser, error) {
+	var res []RepoCountByUser
+	if err := s.series(EnumRepoCountByUser, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *mongoStore) GetReposMostStarsSeries(region, from, to string) ([]ReposMostStars, error) {
+	var res []ReposMostStars
+	if err := s.series(EnumReposMostStars, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *mongoStore) GetReposMostForksSeries(region, from, to string) ([]ReposMostForks, error) {
+	var res []ReposMostForks
+	if err := s.series(EnumReposMostForks, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *mongoStore) GetMostPopularLanguageSeries(region, from, to string) ([]MostPopularLanguage, error) {
+	var res []MostPopularLanguage
+	if err := s.series(EnumMostPopularLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *mongoStore) GetMostRecentReposByLanguageSeries(region, from, to string) ([]MostRecentReposByLanguage, error) {
+	var res []MostRecentReposByLanguage
+	if err := s.series(EnumMostRecentReposByLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *mongoStore) GetReposByLanguageSeries(region, from, to string) ([]ReposByLanguage, error) {
+	var res []ReposByLanguage
+	if err := s.series(EnumReposByLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *mongoStore) GetCompanyCountSeries(region, from, to string) ([]CompanyCount, error) {
+	var res []CompanyCount
+	if err := s.series(EnumCompanyCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *mongoStore) GetUsersByCompanySeries(region, from, to string) ([]UsersByCompany, error) {
+	var res []UsersByCompany
+	if err := s.series(EnumUsersByCompany, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// upsert replaces the latest stat of the type for the region, and keeps a dated snapshot
+// in the history. Stats computed more than once a day overwrite the snapshot of the day
+func (s *mongoStore) upsert(f fence.Fence, enum, region string, data bson.M) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	// The same as byFence, on the documents of the official driver
+	query := bson.M{"type": enum, "region": region}
+	if !f.IsZero() {
+		query["$or"] = database.ByFence(f)
+		data["fence"] = f
+	}
+	update := bson.M{
+		"$set": data,
+		"$setOnInsert": bson.M{
+			"createdAt": moment.NewUTCDate(),
+		},
+	}
+	opts := options.Update().SetUpsert(true)
+	if _, err := s.db.Collection(s.collection).UpdateOne(ctx,
+		query,
+		update, opts,
+	); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fence.ErrStale
+		}
+		return err
+	}
+
+	date := moment.NewUTCFormattedDate()
+	_, err := s.db.Collection(s.history).UpdateOne(ctx,
+		bson.M{"type": enum, "region": region, "date": date},
+		update, opts,
+	)
+	return err
+}
+
+// find decodes the latest stat of the type for the region into res
+func (s *mongoStore) find(enum, region string, res interface{}) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	err := s.db.Collection(s.collection).
+		FindOne(ctx, bson.M{"type": enum, "region": region}).
+		Decode(res)
+	if err == mongo.ErrNoDocuments {
+		return apperr.ErrNotFound
+	}
+	return err
+}
+
+// series decodes the snapshots of the type for the region between the dates (inclusive)
+// into res, sorted from the oldest to the latest
+func (s *mongoStore) series(enum, region, from, to string, res interface{}) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	cur, err := s.db.Collection(s.history).Find(ctx,
+		bson.M{
+			"type":   enum,
+			"region": region,
+			"date":   bson.M{"$gte": from, "$lte": to},
+		},
+		options.Find().SetSort(database.Sort("date")),
+	)
+	if err != nil {
+		return err
+	}
+	return cur.All(ctx, res)
+}
+
+// isIndexNotFoundError is the equivalent of isIndexNotFound for the errors of the official
+// driver
+func isIndexNotFoundError(err error) bool {
+	cerr, ok := err.(mongo.CommandError)
+	return ok && cerr.Code == 27
+}
@@ -0,0 +1,344 @@
+package statsvc
+
+import (
+	"fmt"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// postgresStore keeps the latest stats and their history as jsonb documents in their tables,
+// keyed by the type, the region and the day of the snapshot, with the fields of the mongo
+// documents
+type postgresStore struct {
+	db      *database.Postgres
+	table   string
+	history string
+}
+
+// NewPostgresStore returns a new analytic store on postgres. The latest stats are kept in
+// the table, and the dated snapshots in the history table
+func NewPostgresStore(db *database.Postgres, table, history string) Store {
+	return &postgresStore{
+		db:      db,
+		table:   table,
+		history: history,
+	}
+}
+
+func (s *postgresStore) Init() error {
+	if err := s.db.CreateTable(s.table); err != nil {
+		return err
+	}
+	return s.db.CreateTable(s.history)
+}
+
+func (s *postgresStore) GetUserCount(region string) (*UserCount, error) {
+	var res UserCount
+	if err := s.find(EnumUserCount, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostUserCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumUserCount, region, bson.M{
+		"count":     count,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetRepoCount(region string) (*RepoCount, error) {
+	var res RepoCount
+	if err := s.find(EnumRepoCount, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostRepoCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumRepoCount, region, bson.M{
+		"count":     count,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetReposMostRecent(region string) (*ReposMostRecent, error) {
+	var res ReposMostRecent
+	if err := s.find(EnumReposMostRecent, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostReposMostRecent(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostRecent, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetRepoCountByUser(region string) (*RepoCountByUser, error) {
+	var res RepoCountByUser
+	if err := s.find(EnumRepoCountByUser, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostRepoCountByUser(f fence.Fence, region string, users []schema.UserCount) error {
+	return s.upsert(f, EnumRepoCountByUser, region, bson.M{
+		"users":     users,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetReposMostStars(region string) (*ReposMostStars, error) {
+	var res ReposMostStars
+	if err := s.find(EnumReposMostStars, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostReposMostStars(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostStars, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetReposMostForks(region string) (*ReposMostForks, error) {
+	var res ReposMostForks
+	if err := s.find(EnumReposMostForks, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostReposMostForks(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostForks, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetMostPopularLanguage(region string) (*MostPopularLanguage, error) {
+	var res MostPopularLanguage
+	if err := s.find(EnumMostPopularLanguage, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostMostPopularLanguage(f fence.Fence, region string, languages []schema.LanguageCount) error {
+	return s.upsert(f, EnumMostPopularLanguage, region, bson.M{
+		"languages": languages,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetLanguageCountByUser(region string) (*LanguageCountByUser, error) {
+	var res LanguageCountByUser
+	if err := s.find(EnumLanguageCountByUser, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostLanguageCountByUser(f fence.Fence, region string, languages []schema.LanguageCount) error {
+	return s.upsert(f, EnumLanguageCountByUser, region, bson.M{
+		"languages": languages,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetMostRecentReposByLanguage(region string) (*MostRecentReposByLanguage, error) {
+	var res MostRecentReposByLanguage
+	if err := s.find(EnumMostRecentReposByLanguage, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostMostRecentReposByLanguage(f fence.Fence, region string, repos []schema.RepoLanguage) error {
+	return s.upsert(f, EnumMostRecentReposByLanguage, region, bson.M{
+		"repos":     repos,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetReposByLanguage(region string) (*ReposByLanguage, error) {
+	var res ReposByLanguage
+	if err := s.find(EnumReposByLanguage, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostReposByLanguage(f fence.Fence, region string, users []schema.UserCountByLanguage) error {
+	return s.upsert(f, EnumReposByLanguage, region, bson.M{
+		"users":     users,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetCompanyCount(region string) (*CompanyCount, error) {
+	var res CompanyCount
+	if err := s.find(EnumCompanyCount, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostCompanyCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumCompanyCount, region, bson.M{
+		"count":     count,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetUsersByCompany(region string) (*UsersByCompany, error) {
+	var res UsersByCompany
+	if err := s.find(EnumUsersByCompany, region, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *postgresStore) PostUsersByCompany(f fence.Fence, region string, users []schema.Company) error {
+	return s.upsert(f, EnumUsersByCompany, region, bson.M{
+		"users":     users,
+		"updatedAt": moment.NewUTCDate(),
+	})
+}
+
+func (s *postgresStore) GetUserCountSeries(region, from, to string) ([]UserCount, error) {
+	var res []UserCount
+	if err := s.series(EnumUserCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *postgresStore) GetRepoCountSeries(region, from, to string) ([]RepoCount, error) {
+	var res []RepoCount
+	if err := s.series(EnumRepoCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *postgresStore) GetReposMostRecentSeries(region, from, to string) ([]ReposMostRecent, error) {
+	var res []ReposMostRecent
+	if err := s.series(EnumReposMostRecent, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *postgresStore) GetRepoCountByUserSeries(region, from, to string) ([]RepoCountByUser, error) {
+	var res []RepoCountByUser
+	if err := s.series(EnumRepoCountByUser, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *postgresStore) GetReposMostStarsSeries(region, from, to string) ([]ReposMostStars, error) {
+	var res []ReposMostStars
+	if err := s.series(EnumReposMostStars, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *postgresStore) GetReposMostForksSeries(region, from, to string) ([]ReposMostForks, error) {
+	var res []ReposMostForks
+	if err := s.series(EnumReposMostForks, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *postgresStore) GetMostPopularLanguageSeries(region, from, to string) ([]MostPopularLanguage, error) {
+	var res []MostPopularLanguage
+	if err := s.series(EnumMostPopularLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *postgresStore) GetMostRecentReposByLanguageSeries(region, from, to string) ([]MostRecentReposByLanguage, error) {
+	var res []MostRecentReposByLanguage
+	if err := s.series(EnumMostRecentReposByLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *postgresStore) GetReposByLanguageSeries(region, from, to string) ([]ReposByLanguage, error) {
+	var res []ReposByLanguage
+	if err := s.series(EnumReposByLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *postgresStore) GetCompanyCountSeries(region, from, to string) ([]CompanyCount, error) {
+	var res []CompanyCount
+	if err := s.series(EnumCompanyCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *postgresStore) GetUsersByCompanySeries(region, from, to string) ([]UsersByCompany, error) {
+	var res []UsersByCompany
+	if err := s.series(EnumUsersByCompany, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// upsert replaces the latest stat of the type for the region, and the snapshot of the day.
+// Under a lease, the stat last written under a newer token of the lease is kept
+func (s *postgresStore) upsert(f fence.Fence, enum, region string, data bson.M) error {
+	onInsert := bson.M{
+		"type":      enum,
+		"region":    region,
+		"createdAt": moment.NewUTCDate(),
+	}
+	if err := s.db.Upsert(s.table, f, database.Doc{
+		ID:          statKey{enum, region, ""}.String(),
+		Set:         data,
+		SetOnInsert: onInsert,
+	}); err != nil {
+		return err
+	}
+
+	date := moment.NewUTCFormattedDate()
+	onInsert["date"] = date
+	return s.db.Upsert(s.history, fence.Fence{}, database.Doc{
+		ID:          statKey{enum, region, date}.String(),
+		Set:         data,
+		SetOnInsert: onInsert,
+	})
+}
+
+// find decodes the latest stat of the type for the region into res
+func (s *postgresStore) find(enum, region string, res interface{}) error {
+	return s.db.FindOne(res, fmt.Sprintf(`SELECT doc FROM %s WHERE id = $1`, s.table),
+		statKey{enum, region, ""}.String())
+}
+
+// series decodes the snapshots of the type for the region between the dates (inclusive)
+// into res, sorted from the oldest to the latest
+func (s *postgresStore) series(enum, region, from, to string, res interface{}) error {
+	return s.db.Find(res, fmt.Sprintf(`SELECT doc FROM %s WHERE doc->>'type' = $1 AND doc->>'region' = $2 AND %s BETWEEN $3 AND $4 ORDER BY %[2]s`,
+		s.history, database.JSONText("date")), enum, region, from, to)
+}
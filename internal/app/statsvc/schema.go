@@ -2,15 +2,18 @@ package statsvc
 
 import "github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 
-// DateInfo represents the created date and updated date, to be embedded
+// DateInfo represents the created date and updated date, to be embedded. Date is the day
+// of the snapshot in the format YYYY-MM-DD, and is only set for the stats in the history
 type DateInfo struct {
 	UpdatedAt string `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
 	CreatedAt string `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	Date      string `json:"date,omitempty" bson:"date,omitempty"`
 }
 
 // UserCount represents the user count analytics result
 type UserCount struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Count    int `json:"count,omitempty" bson:"count,omitempty"`
 }
@@ -18,6 +21,7 @@ type UserCount struct {
 // RepoCount represents the repo count analytics result
 type RepoCount struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Count    int `json:"count,omitempty" bson:"count,omitempty"`
 }
@@ -25,6 +29,7 @@ type RepoCount struct {
 // ReposMostRecent represents the most recent repos analytics result
 type ReposMostRecent struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Repos    []schema.Repo `json:"repos,omitempty" bson:"repos,omitempty"`
 }
@@ -32,6 +37,7 @@ type ReposMostRecent struct {
 // RepoCountByUser represents the repo count by users analytics result
 type RepoCountByUser struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Users    []schema.UserCount `json:"users,omitempty" bson:"users,omitempty"`
 }
@@ -39,6 +45,7 @@ type RepoCountByUser struct {
 // ReposMostStars represents the repos with most stars analytics result
 type ReposMostStars struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Repos    []schema.Repo `json:"repos,omitempty" bson:"repos,omitempty"`
 }
@@ -46,6 +53,7 @@ type ReposMostStars struct {
 // ReposMostForks represents the repos with most forks analytics result
 type ReposMostForks struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Repos    []schema.Repo `json:"repos,omitempty" bson:"repos,omitempty"`
 }
@@ -53,6 +61,7 @@ type ReposMostForks struct {
 // MostPopularLanguage represents the languages and the corresponding repo count analytics result
 type MostPopularLanguage struct {
 	Type      string `json:"type,omitempty" bson:"type,omitempty"`
+	Region    string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo  `bson:",inline"`
 	Languages []schema.LanguageCount `json:"languages,omitempty" bson:"languages,omitempty"`
 }
@@ -60,6 +69,7 @@ type MostPopularLanguage struct {
 // LanguageCountByUser represents the languages count by user analytics result
 type LanguageCountByUser struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Users    []schema.UserCount `json:"users,omitempty" bson:"users,omitempty"`
 }
@@ -67,6 +77,7 @@ type LanguageCountByUser struct {
 // MostRecentReposByLanguage represents the most recent repos by language analytics result
 type MostRecentReposByLanguage struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Repos    []schema.RepoLanguage `json:"repos,omitempty" bson:"repos,omitempty"`
 }
@@ -74,6 +85,7 @@ type MostRecentReposByLanguage struct {
 // ReposByLanguage represents the most repo counts for users by language analytics result
 type ReposByLanguage struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Users    []schema.UserCountByLanguage `json:"users,omitempty" bson:"users,omitempty"`
 }
@@ -86,6 +98,7 @@ type IndexResponse struct {
 // CompanyCount represents the company count analytics result
 type CompanyCount struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Count    int `json:"count,omitempty" bson:"count,omitempty"`
 }
@@ -93,6 +106,7 @@ type CompanyCount struct {
 // UsersByCompany represents the users by company analytic result
 type UsersByCompany struct {
 	Type     string `json:"type,omitempty" bson:"type,omitempty"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
 	DateInfo `bson:",inline"`
 	Users    []schema.Company `json:"users,omitempty" bson:"users,omitempty"`
 }
@@ -3,36 +3,48 @@ package statsvc
 import (
 	"context"
 
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 )
 
 type (
 	// Service represents the analytic service
 	Service interface {
-		GetUserCount(ctx context.Context) (*UserCount, error)
-		PostUserCount(ctx context.Context, count int) error
-		GetRepoCount(ctx context.Context) (*RepoCount, error)
-		PostRepoCount(ctx context.Context, count int) error
-		GetReposMostRecent(ctx context.Context) (*ReposMostRecent, error)
-		PostReposMostRecent(ctx context.Context, data []schema.Repo) error
-		GetRepoCountByUser(ctx context.Context) (*RepoCountByUser, error)
-		PostRepoCountByUser(ctx context.Context, users []schema.UserCount) error
-		GetReposMostStars(ctx context.Context) (*ReposMostStars, error)
-		PostReposMostStars(ctx context.Context, repos []schema.Repo) error
-		GetReposMostForks(ctx context.Context) (*ReposMostForks, error)
-		PostReposMostForks(ctx context.Context, repos []schema.Repo) error
-		GetMostPopularLanguage(ctx context.Context) (*MostPopularLanguage, error)
-		PostMostPopularLanguage(ctx context.Context, languages []schema.LanguageCount) error
-		GetLanguageCountByUser(ctx context.Context) (*LanguageCountByUser, error)
-		PostLanguageCountByUser(ctx context.Context, languages []schema.LanguageCount) error
-		GetMostRecentReposByLanguage(ctx context.Context) (*MostRecentReposByLanguage, error)
-		PostMostRecentReposByLanguage(ctx context.Context, repos []schema.RepoLanguage) error
-		GetReposByLanguage(ctx context.Context) (*ReposByLanguage, error)
-		PostReposByLanguage(ctx context.Context, users []schema.UserCountByLanguage) error
-		GetCompanyCount(ctx context.Context) (*CompanyCount, error)
-		PostCompanyCount(ctx context.Context, count int) error
-		GetUsersByCompany(ctx context.Context) (*UsersByCompany, error)
-		PostUsersByCompany(ctx context.Context, users []schema.Company) error
+		GetUserCount(ctx context.Context, region string) (*UserCount, error)
+		PostUserCount(ctx context.Context, region string, count int) error
+		GetRepoCount(ctx context.Context, region string) (*RepoCount, error)
+		PostRepoCount(ctx context.Context, region string, count int) error
+		GetReposMostRecent(ctx context.Context, region string) (*ReposMostRecent, error)
+		PostReposMostRecent(ctx context.Context, region string, data []schema.Repo) error
+		GetRepoCountByUser(ctx context.Context, region string) (*RepoCountByUser, error)
+		PostRepoCountByUser(ctx context.Context, region string, users []schema.UserCount) error
+		GetReposMostStars(ctx context.Context, region string) (*ReposMostStars, error)
+		PostReposMostStars(ctx context.Context, region string, repos []schema.Repo) error
+		GetReposMostForks(ctx context.Context, region string) (*ReposMostForks, error)
+		PostReposMostForks(ctx context.Context, region string, repos []schema.Repo) error
+		GetMostPopularLanguage(ctx context.Context, region string) (*MostPopularLanguage, error)
+		PostMostPopularLanguage(ctx context.Context, region string, languages []schema.LanguageCount) error
+		GetLanguageCountByUser(ctx context.Context, region string) (*LanguageCountByUser, error)
+		PostLanguageCountByUser(ctx context.Context, region string, languages []schema.LanguageCount) error
+		GetMostRecentReposByLanguage(ctx context.Context, region string) (*MostRecentReposByLanguage, error)
+		PostMostRecentReposByLanguage(ctx context.Context, region string, repos []schema.RepoLanguage) error
+		GetReposByLanguage(ctx context.Context, region string) (*ReposByLanguage, error)
+		PostReposByLanguage(ctx context.Context, region string, users []schema.UserCountByLanguage) error
+		GetCompanyCount(ctx context.Context, region string) (*CompanyCount, error)
+		PostCompanyCount(ctx context.Context, region string, count int) error
+		GetUsersByCompany(ctx context.Context, region string) (*UsersByCompany, error)
+		PostUsersByCompany(ctx context.Context, region string, users []schema.Company) error
+		GetUserCountSeries(ctx context.Context, region, from, to string) ([]UserCount, error)
+		GetRepoCountSeries(ctx context.Context, region, from, to string) ([]RepoCount, error)
+		GetReposMostRecentSeries(ctx context.Context, region, from, to string) ([]ReposMostRecent, error)
+		GetRepoCountByUserSeries(ctx context.Context, region, from, to string) ([]RepoCountByUser, error)
+		GetReposMostStarsSeries(ctx context.Context, region, from, to string) ([]ReposMostStars, error)
+		GetReposMostForksSeries(ctx context.Context, region, from, to string) ([]ReposMostForks, error)
+		GetMostPopularLanguageSeries(ctx context.Context, region, from, to string) ([]MostPopularLanguage, error)
+		GetMostRecentReposByLanguageSeries(ctx context.Context, region, from, to string) ([]MostRecentReposByLanguage, error)
+		GetReposByLanguageSeries(ctx context.Context, region, from, to string) ([]ReposByLanguage, error)
+		GetCompanyCountSeries(ctx context.Context, region, from, to string) ([]CompanyCount, error)
+		GetUsersByCompanySeries(ctx context.Context, region, from, to string) ([]UsersByCompany, error)
 	}
 
 	service struct {
@@ -44,98 +56,142 @@ func NewService(m Model) Service {
 	return &service{m}
 }
 
-func (s *service) GetUserCount(ctx context.Context) (*UserCount, error) {
-	return s.model.GetUserCount()
+func (s *service) GetUserCount(ctx context.Context, region string) (*UserCount, error) {
+	return s.model.GetUserCount(region)
 }
 
-func (s *service) PostUserCount(ctx context.Context, count int) error {
-	return s.model.PostUserCount(count)
+func (s *service) PostUserCount(ctx context.Context, region string, count int) error {
+	return s.model.PostUserCount(fence.FromContext(ctx), region, count)
 }
 
-func (s *service) GetRepoCount(ctx context.Context) (*RepoCount, error) {
-	return s.model.GetRepoCount()
+func (s *service) GetRepoCount(ctx context.Context, region string) (*RepoCount, error) {
+	return s.model.GetRepoCount(region)
 }
 
-func (s *service) PostRepoCount(ctx context.Context, count int) error {
-	return s.model.PostRepoCount(count)
+func (s *service) PostRepoCount(ctx context.Context, region string, count int) error {
+	return s.model.PostRepoCount(fence.FromContext(ctx), region, count)
 }
 
-func (s *service) GetReposMostRecent(ctx context.Context) (*ReposMostRecent, error) {
-	return s.model.GetReposMostRecent()
+func (s *service) GetReposMostRecent(ctx context.Context, region string) (*ReposMostRecent, error) {
+	return s.model.GetReposMostRecent(region)
 }
 
-func (s *service) PostReposMostRecent(ctx context.Context, data []schema.Repo) error {
-	return s.model.PostReposMostRecent(data)
+func (s *service) PostReposMostRecent(ctx context.Context, region string, data []schema.Repo) error {
+	return s.model.PostReposMostRecent(fence.FromContext(ctx), region, data)
 }
 
-func (s *service) GetRepoCountByUser(ctx context.Context) (*RepoCountByUser, error) {
-	return s.model.GetRepoCountByUser()
+func (s *service) GetRepoCountByUser(ctx context.Context, region string) (*RepoCountByUser, error) {
+	return s.model.GetRepoCountByUser(region)
 }
 
-func (s *service) PostRepoCountByUser(ctx context.Context, users []schema.UserCount) error {
-	return s.model.PostRepoCountByUser(users)
+func (s *service) PostRepoCountByUser(ctx context.Context, region string, users []schema.UserCount) error {
+	return s.model.PostRepoCountByUser(fence.FromContext(ctx), region, users)
 }
 
-func (s *service) GetReposMostStars(ctx context.Context) (*ReposMostStars, error) {
-	return s.model.GetReposMostStars()
+func (s *service) GetReposMostStars(ctx context.Context, region string) (*ReposMostStars, error) {
+	return s.model.GetReposMostStars(region)
 }
 
-func (s *service) PostReposMostStars(ctx context.Context, repos []schema.Repo) error {
-	return s.model.PostReposMostStars(repos)
+func (s *service) PostReposMostStars(ctx context.Context, region string, repos []schema.Repo) error {
+	return s.model.PostReposMostStars(fence.FromContext(ctx), region, repos)
 }
 
-func (s *service) GetReposMostForks(ctx context.Context) (*ReposMostForks, error) {
-	return s.model.GetReposMostForks()
+func (s *service) GetReposMostForks(ctx context.Context, region string) (*ReposMostForks, error) {
+	return s.model.GetReposMostForks(region)
 }
 
-func (s *service) PostReposMostForks(ctx context.Context, repos []schema.Repo) error {
-	return s.model.PostReposMostForks(repos)
+func (s *service) PostReposMostForks(ctx context.Context, region string, repos []schema.Repo) error {
+	return s.model.PostReposMostForks(fence.FromContext(ctx), region, repos)
 }
 
-func (s *service) GetMostPopularLanguage(ctx context.Context) (*MostPopularLanguage, error) {
-	return s.model.GetMostPopularLanguage()
+func (s *service) GetMostPopularLanguage(ctx context.Context, region string) (*MostPopularLanguage, error) {
+	return s.model.GetMostPopularLanguage(region)
 }
 
-func (s *service) PostMostPopularLanguage(ctx context.Context, languages []schema.LanguageCount) error {
-	return s.model.PostMostPopularLanguage(languages)
+func (s *service) PostMostPopularLanguage(ctx context.Context, region string, languages []schema.LanguageCount) error {
+	return s.model.PostMostPopularLanguage(fence.FromContext(ctx), region, languages)
 }
 
-func (s *service) GetLanguageCountByUser(ctx context.Context) (*LanguageCountByUser, error) {
-	return s.model.GetLanguageCountByUser()
+func (s *service) GetLanguageCountByUser(ctx context.Context, region string) (*LanguageCountByUser, error) {
+	return s.model.GetLanguageCountByUser(region)
 }
 
-func (s *service) PostLanguageCountByUser(ctx context.Context, languages []schema.LanguageCount) error {
-	return s.model.PostLanguageCountByUser(languages)
+func (s *service) PostLanguageCountByUser(ctx context.Context, region string, languages []schema.LanguageCount) error {
+	return s.model.PostLanguageCountByUser(fence.FromContext(ctx), region, languages)
 }
 
-func (s *service) GetMostRecentReposByLanguage(ctx context.Context) (*MostRecentReposByLanguage, error) {
-	return s.model.GetMostRecentReposByLanguage()
+func (s *service) GetMostRecentReposByLanguage(ctx context.Context, region string) (*MostRecentReposByLanguage, error) {
+	return s.model.GetMostRecentReposByLanguage(region)
 }
 
-func (s *service) PostMostRecentReposByLanguage(ctx context.Context, repos []schema.RepoLanguage) error {
-	return s.model.PostMostRecentReposByLanguage(repos)
+func (s *service) PostMostRecentReposByLanguage(ctx context.Context, region string, repos []schema.RepoLanguage) error {
+	return s.model.PostMostRecentReposByLanguage(fence.FromContext(ctx), region, repos)
 }
 
-func (s *service) GetReposByLanguage(ctx context.Context) (*ReposByLanguage, error) {
-	return s.model.GetReposByLanguage()
+func (s *service) GetReposByLanguage(ctx context.Context, region string) (*ReposByLanguage, error) {
+	return s.model.GetReposByLanguage(region)
 }
 
-func (s *service) PostReposByLanguage(ctx context.Context, users []schema.UserCountByLanguage) error {
-	return s.model.PostReposByLanguage(users)
+func (s *service) PostReposByLanguage(ctx context.Context, region string, users []schema.UserCountByLanguage) error {
+	return s.model.PostReposByLanguage(fence.FromContext(ctx), region, users)
 }
 
-func (s *service) GetCompanyCount(ctx context.Context) (*CompanyCount, error) {
-	return s.model.GetCompanyCount()
+func (s *service) GetCompanyCount(ctx context.Context, region string) (*CompanyCount, error) {
+	return s.model.GetCompanyCount(region)
 }
 
-func (s *service) PostCompanyCount(ctx context.Context, count int) error {
-	return s.model.PostCompanyCount(count)
+func (s *service) PostCompanyCount(ctx context.Context, region string, count int) error {
+	return s.model.PostCompanyCount(fence.FromContext(ctx), region, count)
 }
 
-func (s *service) GetUsersByCompany(ctx context.Context) (*UsersByCompany, error) {
-	return s.model.GetUsersByCompany()
+func (s *service) GetUsersByCompany(ctx context.Context, region string) (*UsersByCompany, error) {
+	return s.model.GetUsersByCompany(region)
 }
 
-func (s *service) PostUsersByCompany(ctx context.Context, users []schema.Company) error {
-	return s.model.PostUsersByCompany(users)
+func (s *service) PostUsersByCompany(ctx context.Context, region string, users []schema.Company) error {
+	return s.model.PostUsersByCompany(fence.FromContext(ctx), region, users)
+}
+
+func (s *service) GetUserCountSeries(ctx context.Context, region, from, to string) ([]UserCount, error) {
+	return s.model.GetUserCountSeries(region, from, to)
+}
+
+func (s *service) GetRepoCountSeries(ctx context.Context, region, from, to string) ([]RepoCount, error) {
+	return s.model.GetRepoCountSeries(region, from, to)
+}
+
+func (s *service) GetReposMostRecentSeries(ctx context.Context, region, from, to string) ([]ReposMostRecent, error) {
+	return s.model.GetReposMostRecentSeries(region, from, to)
+}
+
+func (s *service) GetRepoCountByUserSeries(ctx context.Context, region, from, to string) ([]RepoCountByUser, error) {
+	return s.model.GetRepoCountByUserSeries(region, from, to)
+}
+
+func (s *service) GetReposMostStarsSeries(ctx context.Context, region, from, to string) ([]ReposMostStars, error) {
+	return s.model.GetReposMostStarsSeries(region, from, to)
+}
+
+func (s *service) GetReposMostForksSeries(ctx context.Context, region, from, to string) ([]ReposMostForks, error) {
+	return s.model.GetReposMostForksSeries(region, from, to)
+}
+
+func (s *service) GetMostPopularLanguageSeries(ctx context.Context, region, from, to string) ([]MostPopularLanguage, error) {
+	return s.model.GetMostPopularLanguageSeries(region, from, to)
+}
+
+func (s *service) GetMostRecentReposByLanguageSeries(ctx context.Context, region, from, to string) ([]MostRecentReposByLanguage, error) {
+	return s.model.GetMostRecentReposByLanguageSeries(region, from, to)
+}
+
+func (s *service) GetReposByLanguageSeries(ctx context.Context, region, from, to string) ([]ReposByLanguage, error) {
+	return s.model.GetReposByLanguageSeries(region, from, to)
+}
+
+func (s *service) GetCompanyCountSeries(ctx context.Context, region, from, to string) ([]CompanyCount, error) {
+	return s.model.GetCompanyCountSeries(region, from, to)
+}
+
+func (s *service) GetUsersByCompanySeries(ctx context.Context, region, from, to string) ([]UsersByCompany, error) {
+	return s.model.GetUsersByCompanySeries(region, from, to)
 }
@@ -2,9 +2,13 @@ package statsvc
 
 import "github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
 
-// New returns a new stat service
+// New returns a new stat service backed by mgo
 func New(db *database.DB, ms ...Middleware) Service {
-	store := NewStore(db, database.Stats)
+	return NewWithStore(NewStore(db, database.Stats, database.StatsHistory), ms...)
+}
+
+// NewWithStore returns a new stat service backed by the store of any storage driver
+func NewWithStore(store Store, ms ...Middleware) Service {
 	model := NewModel(store)
 	service := NewService(model)
 	service = Decorate(service, ms...)
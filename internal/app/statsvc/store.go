@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 
@@ -16,35 +17,46 @@ var errTypeAssertion = errors.New("unable to perform type assertion")
 type (
 	// Read represents the read interface for the store
 	Read interface {
-		GetUserCount() (*UserCount, error)
-		GetRepoCount() (*RepoCount, error)
-		GetReposMostRecent() (*ReposMostRecent, error)
-		GetRepoCountByUser() (*RepoCountByUser, error)
-		GetReposMostStars() (*ReposMostStars, error)
-		GetReposMostForks() (*ReposMostForks, error)
-		GetMostPopularLanguage() (*MostPopularLanguage, error)
-		GetLanguageCountByUser() (*LanguageCountByUser, error)
-		GetMostRecentReposByLanguage() (*MostRecentReposByLanguage, error)
-		GetReposByLanguage() (*ReposByLanguage, error)
-		GetCompanyCount() (*CompanyCount, error)
-		GetUsersByCompany() (*UsersByCompany, error)
+		GetUserCount(region string) (*UserCount, error)
+		GetRepoCount(region string) (*RepoCount, error)
+		GetReposMostRecent(region string) (*ReposMostRecent, error)
+		GetRepoCountByUser(region string) (*RepoCountByUser, error)
+		GetReposMostStars(region string) (*ReposMostStars, error)
+		GetReposMostForks(region string) (*ReposMostForks, error)
+		GetMostPopularLanguage(region string) (*MostPopularLanguage, error)
+		GetLanguageCountByUser(region string) (*LanguageCountByUser, error)
+		GetMostRecentReposByLanguage(region string) (*MostRecentReposByLanguage, error)
+		GetReposByLanguage(region string) (*ReposByLanguage, error)
+		GetCompanyCount(region string) (*CompanyCount, error)
+		GetUsersByCompany(region string) (*UsersByCompany, error)
+		GetUserCountSeries(region, from, to string) ([]UserCount, error)
+		GetRepoCountSeries(region, from, to string) ([]RepoCount, error)
+		GetReposMostRecentSeries(region, from, to string) ([]ReposMostRecent, error)
+		GetRepoCountByUserSeries(region, from, to string) ([]RepoCountByUser, error)
+		GetReposMostStarsSeries(region, from, to string) ([]ReposMostStars, error)
+		GetReposMostForksSeries(region, from, to string) ([]ReposMostForks, error)
+		GetMostPopularLanguageSeries(region, from, to string) ([]MostPopularLanguage, error)
+		GetMostRecentReposByLanguageSeries(region, from, to string) ([]MostRecentReposByLanguage, error)
+		GetReposByLanguageSeries(region, from, to string) ([]ReposByLanguage, error)
+		GetCompanyCountSeries(region, from, to string) ([]CompanyCount, error)
+		GetUsersByCompanySeries(region, from, to string) ([]UsersByCompany, error)
 	}
 
 	// Write represents the write operation for the store
 	Write interface {
 		Init() error
-		PostUserCount(count int) error
-		PostRepoCount(count int) error
-		PostReposMostRecent(data []schema.Repo) error
-		PostRepoCountByUser(repos []schema.UserCount) error
-		PostReposMostStars(repos []schema.Repo) error
-		PostReposMostForks(repos []schema.Repo) error
-		PostMostPopularLanguage(languages []schema.LanguageCount) error
-		PostLanguageCountByUser(languages []schema.LanguageCount) error
-		PostMostRecentReposByLanguage(repos []schema.RepoLanguage) error
-		PostReposByLanguage(users []schema.UserCountByLanguage) error
-		PostCompanyCount(count int) error
-		PostUsersByCompany(users []schema.Company) error
+		PostUserCount(f fence.Fence, region string, count int) error
+		PostRepoCount(f fence.Fence, region string, count int) error
+		PostReposMostRecent(f fence.Fence, region string, data []schema.Repo) error
+		PostRepoCountByUser(f fence.Fence, region string, repos []schema.UserCount) error
+		PostReposMostStars(f fence.Fence, region string, repos []schema.Repo) error
+		PostReposMostForks(f fence.Fence, region string, repos []schema.Repo) error
+		PostMostPopularLanguage(f fence.Fence, region string, languages []schema.LanguageCount) error
+		PostLanguageCountByUser(f fence.Fence, region string, languages []schema.LanguageCount) error
+		PostMostRecentReposByLanguage(f fence.Fence, region string, repos []schema.RepoLanguage) error
+		PostReposByLanguage(f fence.Fence, region string, users []schema.UserCountByLanguage) error
+		PostCompanyCount(f fence.Fence, region string, count int) error
+		PostUsersByCompany(f fence.Fence, region string, users []schema.Company) error
 	}
 
 	// Store represents the interface for the analytic store
@@ -56,14 +68,17 @@ type (
 	store struct {
 		db         *database.DB
 		collection string
+		history    string
 	}
 )
 
-// NewStore returns a new analytic
-func NewStore(db *database.DB, collection string) Store {
+// NewStore returns a new analytic store. The latest stats are kept in the collection,
+// and the dated snapshots in the history collection
+func NewStore(db *database.DB, collection, history string) Store {
 	return &store{
 		db:         db,
 		collection: collection,
+		history:    history,
 	}
 }
 
@@ -71,225 +86,226 @@ func (s *store) Init() error {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
-	return c.EnsureIndex(mgo.Index{
-		Key:    []string{"type"},
+	// Stats were previously unique by type only. Existing stats are assigned to
+	// the global region before the index is replaced
+	if err := c.DropIndex("type"); err != nil && !isIndexNotFound(err) {
+		return err
+	}
+	if _, err := c.UpdateAll(
+		bson.M{"region": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"region": ""}},
+	); err != nil {
+		return err
+	}
+	if err := c.EnsureIndex(mgo.Index{
+		Key:    []string{"type", "region"},
+		Unique: true,
+	}); err != nil {
+		return err
+	}
+	return sess.DB(s.db.Name).C(s.history).EnsureIndex(mgo.Index{
+		Key:    []string{"type", "region", "date"},
 		Unique: true,
 	})
 }
 
-func (s *store) GetUserCount() (*UserCount, error) {
+func (s *store) GetUserCount(region string) (*UserCount, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res UserCount
 	if err := c.
-		Find(bson.M{"type": EnumUserCount}).
+		Find(bson.M{"type": EnumUserCount, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostUserCount(count int) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumUserCount, bson.M{
+func (s *store) PostUserCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumUserCount, region, bson.M{
 		"count":     count,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func (s *store) GetRepoCount() (*RepoCount, error) {
+func (s *store) GetRepoCount(region string) (*RepoCount, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res RepoCount
 	if err := c.
-		Find(bson.M{"type": EnumRepoCount}).
+		Find(bson.M{"type": EnumRepoCount, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostRepoCount(count int) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumRepoCount, bson.M{
+func (s *store) PostRepoCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumRepoCount, region, bson.M{
 		"count":     count,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func (s *store) GetReposMostRecent() (*ReposMostRecent, error) {
+func (s *store) GetReposMostRecent(region string) (*ReposMostRecent, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res ReposMostRecent
 	if err := c.
-		Find(bson.M{"type": EnumReposMostRecent}).
+		Find(bson.M{"type": EnumReposMostRecent, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostReposMostRecent(repos []schema.Repo) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumReposMostRecent, bson.M{
+func (s *store) PostReposMostRecent(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostRecent, region, bson.M{
 		"repos":     repos,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func (s *store) GetRepoCountByUser() (*RepoCountByUser, error) {
+func (s *store) GetRepoCountByUser(region string) (*RepoCountByUser, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res RepoCountByUser
 	if err := c.
-		Find(bson.M{"type": EnumRepoCountByUser}).
+		Find(bson.M{"type": EnumRepoCountByUser, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostRepoCountByUser(users []schema.UserCount) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumRepoCountByUser, bson.M{
+func (s *store) PostRepoCountByUser(f fence.Fence, region string, users []schema.UserCount) error {
+	return s.upsert(f, EnumRepoCountByUser, region, bson.M{
 		"users":     users,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func (s *store) GetReposMostStars() (*ReposMostStars, error) {
+func (s *store) GetReposMostStars(region string) (*ReposMostStars, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res ReposMostStars
 	if err := c.
-		Find(bson.M{"type": EnumReposMostStars}).
+		Find(bson.M{"type": EnumReposMostStars, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostReposMostStars(repos []schema.Repo) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumReposMostStars, bson.M{
+func (s *store) PostReposMostStars(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostStars, region, bson.M{
 		"repos":     repos,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func (s *store) GetReposMostForks() (*ReposMostForks, error) {
+func (s *store) GetReposMostForks(region string) (*ReposMostForks, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res ReposMostForks
 	if err := c.
-		Find(bson.M{"type": EnumReposMostForks}).
+		Find(bson.M{"type": EnumReposMostForks, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostReposMostForks(repos []schema.Repo) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumReposMostForks, bson.M{
+func (s *store) PostReposMostForks(f fence.Fence, region string, repos []schema.Repo) error {
+	return s.upsert(f, EnumReposMostForks, region, bson.M{
 		"repos":     repos,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func (s *store) GetMostPopularLanguage() (*MostPopularLanguage, error) {
+func (s *store) GetMostPopularLanguage(region string) (*MostPopularLanguage, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res MostPopularLanguage
 	if err := c.
-		Find(bson.M{"type": EnumMostPopularLanguage}).
+		Find(bson.M{"type": EnumMostPopularLanguage, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostMostPopularLanguage(languages []schema.LanguageCount) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumMostPopularLanguage, bson.M{
+func (s *store) PostMostPopularLanguage(f fence.Fence, region string, languages []schema.LanguageCount) error {
+	return s.upsert(f, EnumMostPopularLanguage, region, bson.M{
 		"languages": languages,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func (s *store) GetLanguageCountByUser() (*LanguageCountByUser, error) {
+func (s *store) GetLanguageCountByUser(region string) (*LanguageCountByUser, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res LanguageCountByUser
 	if err := c.
-		Find(bson.M{"type": EnumLanguageCountByUser}).
+		Find(bson.M{"type": EnumLanguageCountByUser, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostLanguageCountByUser(languages []schema.LanguageCount) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumLanguageCountByUser, bson.M{
+func (s *store) PostLanguageCountByUser(f fence.Fence, region string, languages []schema.LanguageCount) error {
+	return s.upsert(f, EnumLanguageCountByUser, region, bson.M{
 		"languages": languages,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func (s *store) GetMostRecentReposByLanguage() (*MostRecentReposByLanguage, error) {
+func (s *store) GetMostRecentReposByLanguage(region string) (*MostRecentReposByLanguage, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res MostRecentReposByLanguage
 	if err := c.
-		Find(bson.M{"type": EnumMostRecentReposByLanguage}).
+		Find(bson.M{"type": EnumMostRecentReposByLanguage, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostMostRecentReposByLanguage(repos []schema.RepoLanguage) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumMostRecentReposByLanguage, bson.M{
+func (s *store) PostMostRecentReposByLanguage(f fence.Fence, region string, repos []schema.RepoLanguage) error {
+	return s.upsert(f, EnumMostRecentReposByLanguage, region, bson.M{
 		"repos":     repos,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func (s *store) GetReposByLanguage() (*ReposByLanguage, error) {
+func (s *store) GetReposByLanguage(region string) (*ReposByLanguage, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res ReposByLanguage
 	if err := c.
-		Find(bson.M{"type": EnumReposByLanguage}).
+		Find(bson.M{"type": EnumReposByLanguage, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostReposByLanguage(users []schema.UserCountByLanguage) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumReposByLanguage, bson.M{
+func (s *store) PostReposByLanguage(f fence.Fence, region string, users []schema.UserCountByLanguage) error {
+	return s.upsert(f, EnumReposByLanguage, region, bson.M{
 		"users":     users,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func upsert(c *mgo.Collection, enum string, data bson.M) error {
+// upsert replaces the latest stat of the type for the region, and keeps a dated snapshot
+// in the history. Stats computed more than once a day overwrite the snapshot of the day
+func (s *store) upsert(f fence.Fence, enum, region string, data bson.M) error {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
 	if _, err := c.Upsert(
-		bson.M{"type": enum},
+		byFence(f, bson.M{"type": enum, "region": region}, data),
 		bson.M{
 			"$set": data,
 			"$setOnInsert": bson.M{
@@ -297,38 +313,65 @@ func upsert(c *mgo.Collection, enum string, data bson.M) error {
 			},
 		},
 	); err != nil {
+		if mgo.IsDup(err) {
+			return fence.ErrStale
+		}
 		return err
 	}
-	return nil
+
+	date := moment.NewUTCFormattedDate()
+	_, err := sess.DB(s.db.Name).C(s.history).Upsert(
+		bson.M{"type": enum, "region": region, "date": date},
+		bson.M{
+			"$set": data,
+			"$setOnInsert": bson.M{
+				"createdAt": moment.NewUTCDate(),
+			},
+		},
+	)
+	return err
+}
+
+// series returns the snapshots of the type for the region between the dates (inclusive),
+// sorted from the oldest to the latest
+func (s *store) series(enum, region, from, to string, res interface{}) error {
+	sess, c := s.db.Collection(s.history)
+	defer sess.Close()
+	return c.
+		Find(bson.M{
+			"type":   enum,
+			"region": region,
+			"date":   bson.M{"$gte": from, "$lte": to},
+		}).
+		Sort("date").
+		All(res)
 }
 
-func (s *store) GetCompanyCount() (*CompanyCount, error) {
+func (s *store) GetCompanyCount(region string) (*CompanyCount, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res CompanyCount
 	if err := c.
-		Find(bson.M{"type": EnumCompanyCount}).
+		Find(bson.M{"type": EnumCompanyCount, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
 	return &res, nil
 }
 
-func (s *store) PostCompanyCount(count int) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumCompanyCount, bson.M{
+func (s *store) PostCompanyCount(f fence.Fence, region string, count int) error {
+	return s.upsert(f, EnumCompanyCount, region, bson.M{
 		"count":     count,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
 
-func (s *store) GetUsersByCompany() (*UsersByCompany, error) {
+func (s *store) GetUsersByCompany(region string) (*UsersByCompany, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res UsersByCompany
 	if err := c.
-		Find(bson.M{"type": EnumUsersByCompany}).
+		Find(bson.M{"type": EnumUsersByCompany, "region": region}).
 		One(&res); err != nil {
 		return nil, err
 	}
@@ -336,11 +379,112 @@ func (s *store) GetUsersByCompany() (*UsersByCompany, error) {
 	return &res, nil
 }
 
-func (s *store) PostUsersByCompany(users []schema.Company) error {
-	sess, c := s.db.Collection(s.collection)
-	defer sess.Close()
-	return upsert(c, EnumUsersByCompany, bson.M{
+func (s *store) PostUsersByCompany(f fence.Fence, region string, users []schema.Company) error {
+	return s.upsert(f, EnumUsersByCompany, region, bson.M{
 		"users":     users,
 		"updatedAt": moment.NewUTCDate(),
 	})
 }
+
+func (s *store) GetUserCountSeries(region, from, to string) ([]UserCount, error) {
+	var res []UserCount
+	if err := s.series(EnumUserCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *store) GetRepoCountSeries(region, from, to string) ([]RepoCount, error) {
+	var res []RepoCount
+	if err := s.series(EnumRepoCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *store) GetReposMostRecentSeries(region, from, to string) ([]ReposMostRecent, error) {
+	var res []ReposMostRecent
+	if err := s.series(EnumReposMostRecent, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *store) GetRepoCountByUserSeries(region, from, to string) ([]RepoCountByUser, error) {
+	var res []RepoCountByUser
+	if err := s.series(EnumRepoCountByUser, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *store) GetReposMostStarsSeries(region, from, to string) ([]ReposMostStars, error) {
+	var res []ReposMostStars
+	if err := s.series(EnumReposMostStars, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *store) GetReposMostForksSeries(region, from, to string) ([]ReposMostForks, error) {
+	var res []ReposMostForks
+	if err := s.series(EnumReposMostForks, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *store) GetMostPopularLanguageSeries(region, from, to string) ([]MostPopularLanguage, error) {
+	var res []MostPopularLanguage
+	if err := s.series(EnumMostPopularLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *store) GetMostRecentReposByLanguageSeries(region, from, to string) ([]MostRecentReposByLanguage, error) {
+	var res []MostRecentReposByLanguage
+	if err := s.series(EnumMostRecentReposByLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *store) GetReposByLanguageSeries(region, from, to string) ([]ReposByLanguage, error) {
+	var res []ReposByLanguage
+	if err := s.series(EnumReposByLanguage, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *store) GetCompanyCountSeries(region, from, to string) ([]CompanyCount, error) {
+	var res []CompanyCount
+	if err := s.series(EnumCompanyCount, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *store) GetUsersByCompanySeries(region, from, to string) ([]UsersByCompany, error) {
+	var res []UsersByCompany
+	if err := s.series(EnumUsersByCompany, region, from, to, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// byFence adds the fence to the query of the latest stat, and to the fields set. Under a
+// lease, the stat last written under a newer token of the lease is not matched
+func byFence(f fence.Fence, query, data bson.M) bson.M {
+	if !f.IsZero() {
+		query["$or"] = database.ByFence(f)
+		data["fence"] = f
+	}
+	return query
+}
+
+func isIndexNotFound(err error) bool {
+	qerr, ok := err.(*mgo.QueryError)
+	return ok && qerr.Code == 27
+}
@@ -20,194 +20,374 @@ type tracingMiddleware struct {
 	service Service
 }
 
-func (m *tracingMiddleware) GetUserCount(ctx context.Context) (*UserCount, error) {
+func (m *tracingMiddleware) GetUserCount(ctx context.Context, region string) (*UserCount, error) {
 	ctx, span := trace.StartSpan(ctx, "GetUserCount")
 	defer span.End()
 
-	return m.service.GetUserCount(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetUserCount(ctx, region)
 }
 
-func (m *tracingMiddleware) PostUserCount(ctx context.Context, count int) error {
+func (m *tracingMiddleware) PostUserCount(ctx context.Context, region string, count int) error {
 	ctx, span := trace.StartSpan(ctx, "PostUserCount")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(count)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(count)))
 
-	return m.service.PostUserCount(ctx, count)
+	return m.service.PostUserCount(ctx, region, count)
 }
 
-func (m *tracingMiddleware) GetRepoCount(ctx context.Context) (*RepoCount, error) {
+func (m *tracingMiddleware) GetRepoCount(ctx context.Context, region string) (*RepoCount, error) {
 	ctx, span := trace.StartSpan(ctx, "GetRepoCount")
 	defer span.End()
 
-	return m.service.GetRepoCount(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetRepoCount(ctx, region)
 }
 
-func (m *tracingMiddleware) PostRepoCount(ctx context.Context, count int) error {
+func (m *tracingMiddleware) PostRepoCount(ctx context.Context, region string, count int) error {
 	ctx, span := trace.StartSpan(ctx, "PostRepoCount")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(count)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(count)))
 
-	return m.service.PostRepoCount(ctx, count)
+	return m.service.PostRepoCount(ctx, region, count)
 }
 
-func (m *tracingMiddleware) GetReposMostRecent(ctx context.Context) (*ReposMostRecent, error) {
+func (m *tracingMiddleware) GetReposMostRecent(ctx context.Context, region string) (*ReposMostRecent, error) {
 	ctx, span := trace.StartSpan(ctx, "GetReposMostRecent")
 	defer span.End()
 
-	return m.service.GetReposMostRecent(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetReposMostRecent(ctx, region)
 }
 
-func (m *tracingMiddleware) PostReposMostRecent(ctx context.Context, data []schema.Repo) error {
+func (m *tracingMiddleware) PostReposMostRecent(ctx context.Context, region string, data []schema.Repo) error {
 	ctx, span := trace.StartSpan(ctx, "PostReposMostRecent")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(len(data))))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(len(data))))
 
-	return m.service.PostReposMostRecent(ctx, data)
+	return m.service.PostReposMostRecent(ctx, region, data)
 }
 
-func (m *tracingMiddleware) GetRepoCountByUser(ctx context.Context) (*RepoCountByUser, error) {
+func (m *tracingMiddleware) GetRepoCountByUser(ctx context.Context, region string) (*RepoCountByUser, error) {
 	ctx, span := trace.StartSpan(ctx, "GetRepoCountByUser")
 	defer span.End()
 
-	return m.service.GetRepoCountByUser(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetRepoCountByUser(ctx, region)
 }
 
-func (m *tracingMiddleware) PostRepoCountByUser(ctx context.Context, users []schema.UserCount) error {
+func (m *tracingMiddleware) PostRepoCountByUser(ctx context.Context, region string, users []schema.UserCount) error {
 	ctx, span := trace.StartSpan(ctx, "PostRepoCountByUser")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(len(users))))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(len(users))))
 
-	return m.service.PostRepoCountByUser(ctx, users)
+	return m.service.PostRepoCountByUser(ctx, region, users)
 }
 
-func (m *tracingMiddleware) GetReposMostStars(ctx context.Context) (*ReposMostStars, error) {
+func (m *tracingMiddleware) GetReposMostStars(ctx context.Context, region string) (*ReposMostStars, error) {
 	ctx, span := trace.StartSpan(ctx, "GetReposMostStars")
 	defer span.End()
 
-	return m.service.GetReposMostStars(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetReposMostStars(ctx, region)
 }
 
-func (m *tracingMiddleware) PostReposMostStars(ctx context.Context, repos []schema.Repo) error {
+func (m *tracingMiddleware) PostReposMostStars(ctx context.Context, region string, repos []schema.Repo) error {
 	ctx, span := trace.StartSpan(ctx, "PostReposMostStars")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(len(repos))))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(len(repos))))
 
-	return m.service.PostReposMostStars(ctx, repos)
+	return m.service.PostReposMostStars(ctx, region, repos)
 }
 
-func (m *tracingMiddleware) GetReposMostForks(ctx context.Context) (*ReposMostForks, error) {
+func (m *tracingMiddleware) GetReposMostForks(ctx context.Context, region string) (*ReposMostForks, error) {
 	ctx, span := trace.StartSpan(ctx, "GetReposMostForks")
 	defer span.End()
 
-	return m.service.GetReposMostForks(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetReposMostForks(ctx, region)
 }
 
-func (m *tracingMiddleware) PostReposMostForks(ctx context.Context, repos []schema.Repo) error {
+func (m *tracingMiddleware) PostReposMostForks(ctx context.Context, region string, repos []schema.Repo) error {
 	ctx, span := trace.StartSpan(ctx, "PostReposMostForks")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(len(repos))))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(len(repos))))
 
-	return m.service.PostReposMostForks(ctx, repos)
+	return m.service.PostReposMostForks(ctx, region, repos)
 }
 
-func (m *tracingMiddleware) GetMostPopularLanguage(ctx context.Context) (*MostPopularLanguage, error) {
+func (m *tracingMiddleware) GetMostPopularLanguage(ctx context.Context, region string) (*MostPopularLanguage, error) {
 	ctx, span := trace.StartSpan(ctx, "GetMostPopularLanguage")
 	defer span.End()
 
-	return m.service.GetMostPopularLanguage(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetMostPopularLanguage(ctx, region)
 }
 
-func (m *tracingMiddleware) PostMostPopularLanguage(ctx context.Context, languages []schema.LanguageCount) error {
+func (m *tracingMiddleware) PostMostPopularLanguage(ctx context.Context, region string, languages []schema.LanguageCount) error {
 	ctx, span := trace.StartSpan(ctx, "PostMostPopularLanguage")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(len(languages))))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(len(languages))))
 
-	return m.service.PostMostPopularLanguage(ctx, languages)
+	return m.service.PostMostPopularLanguage(ctx, region, languages)
 }
 
-func (m *tracingMiddleware) GetLanguageCountByUser(ctx context.Context) (*LanguageCountByUser, error) {
+func (m *tracingMiddleware) GetLanguageCountByUser(ctx context.Context, region string) (*LanguageCountByUser, error) {
 	ctx, span := trace.StartSpan(ctx, "GetLanguageCountByUser")
 	defer span.End()
 
-	return m.service.GetLanguageCountByUser(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetLanguageCountByUser(ctx, region)
 }
 
-func (m *tracingMiddleware) PostLanguageCountByUser(ctx context.Context, languages []schema.LanguageCount) error {
+func (m *tracingMiddleware) PostLanguageCountByUser(ctx context.Context, region string, languages []schema.LanguageCount) error {
 	ctx, span := trace.StartSpan(ctx, "PostLanguageCountByUser")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(len(languages))))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(len(languages))))
 
-	return m.service.PostLanguageCountByUser(ctx, languages)
+	return m.service.PostLanguageCountByUser(ctx, region, languages)
 }
 
-func (m *tracingMiddleware) GetMostRecentReposByLanguage(ctx context.Context) (*MostRecentReposByLanguage, error) {
+func (m *tracingMiddleware) GetMostRecentReposByLanguage(ctx context.Context, region string) (*MostRecentReposByLanguage, error) {
 	ctx, span := trace.StartSpan(ctx, "GetMostRecentReposByLanguage")
 	defer span.End()
 
-	return m.service.GetMostRecentReposByLanguage(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetMostRecentReposByLanguage(ctx, region)
 }
 
-func (m *tracingMiddleware) PostMostRecentReposByLanguage(ctx context.Context, repos []schema.RepoLanguage) error {
+func (m *tracingMiddleware) PostMostRecentReposByLanguage(ctx context.Context, region string, repos []schema.RepoLanguage) error {
 	ctx, span := trace.StartSpan(ctx, "PostMostRecentReposByLanguage")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(len(repos))))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(len(repos))))
 
-	return m.service.PostMostRecentReposByLanguage(ctx, repos)
+	return m.service.PostMostRecentReposByLanguage(ctx, region, repos)
 }
 
-func (m *tracingMiddleware) GetReposByLanguage(ctx context.Context) (*ReposByLanguage, error) {
+func (m *tracingMiddleware) GetReposByLanguage(ctx context.Context, region string) (*ReposByLanguage, error) {
 	ctx, span := trace.StartSpan(ctx, "GetReposByLanguage")
 	defer span.End()
 
-	return m.service.GetReposByLanguage(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetReposByLanguage(ctx, region)
 }
 
-func (m *tracingMiddleware) PostReposByLanguage(ctx context.Context, users []schema.UserCountByLanguage) error {
+func (m *tracingMiddleware) PostReposByLanguage(ctx context.Context, region string, users []schema.UserCountByLanguage) error {
 	ctx, span := trace.StartSpan(ctx, "PostReposByLanguage")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(len(users))))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(len(users))))
 
-	return m.service.PostReposByLanguage(ctx, users)
+	return m.service.PostReposByLanguage(ctx, region, users)
 }
 
-func (m *tracingMiddleware) GetCompanyCount(ctx context.Context) (*CompanyCount, error) {
+func (m *tracingMiddleware) GetCompanyCount(ctx context.Context, region string) (*CompanyCount, error) {
 	ctx, span := trace.StartSpan(ctx, "GetCompanyCount")
 	defer span.End()
 
-	return m.service.GetCompanyCount(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetCompanyCount(ctx, region)
 }
 
-func (m *tracingMiddleware) PostCompanyCount(ctx context.Context, count int) error {
+func (m *tracingMiddleware) PostCompanyCount(ctx context.Context, region string, count int) error {
 	ctx, span := trace.StartSpan(ctx, "PostCompanyCount")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(count)))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(count)))
 
-	return m.service.PostCompanyCount(ctx, count)
+	return m.service.PostCompanyCount(ctx, region, count)
 }
 
-func (m *tracingMiddleware) GetUsersByCompany(ctx context.Context) (*UsersByCompany, error) {
+func (m *tracingMiddleware) GetUsersByCompany(ctx context.Context, region string) (*UsersByCompany, error) {
 	ctx, span := trace.StartSpan(ctx, "GetUsersByCompany")
 	defer span.End()
 
-	return m.service.GetUsersByCompany(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.GetUsersByCompany(ctx, region)
 }
 
-func (m *tracingMiddleware) PostUsersByCompany(ctx context.Context, users []schema.Company) error {
+func (m *tracingMiddleware) PostUsersByCompany(ctx context.Context, region string, users []schema.Company) error {
 	ctx, span := trace.StartSpan(ctx, "PostUsersByCompany")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("count", int64(len(users))))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.Int64Attribute("count", int64(len(users))))
+
+	return m.service.PostUsersByCompany(ctx, region, users)
+}
+
+func (m *tracingMiddleware) GetUserCountSeries(ctx context.Context, region, from, to string) ([]UserCount, error) {
+	ctx, span := trace.StartSpan(ctx, "GetUserCountSeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
+
+	return m.service.GetUserCountSeries(ctx, region, from, to)
+}
+
+func (m *tracingMiddleware) GetRepoCountSeries(ctx context.Context, region, from, to string) ([]RepoCount, error) {
+	ctx, span := trace.StartSpan(ctx, "GetRepoCountSeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
+
+	return m.service.GetRepoCountSeries(ctx, region, from, to)
+}
+
+func (m *tracingMiddleware) GetReposMostRecentSeries(ctx context.Context, region, from, to string) ([]ReposMostRecent, error) {
+	ctx, span := trace.StartSpan(ctx, "GetReposMostRecentSeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
+
+	return m.service.GetReposMostRecentSeries(ctx, region, from, to)
+}
+
+func (m *tracingMiddleware) GetRepoCountByUserSeries(ctx context.Context, region, from, to string) ([]RepoCountByUser, error) {
+	ctx, span := trace.StartSpan(ctx, "GetRepoCountByUserSeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
+
+	return m.service.GetRepoCountByUserSeries(ctx, region, from, to)
+}
+
+func (m *tracingMiddleware) GetReposMostStarsSeries(ctx context.Context, region, from, to string) ([]ReposMostStars, error) {
+	ctx, span := trace.StartSpan(ctx, "GetReposMostStarsSeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
+
+	return m.service.GetReposMostStarsSeries(ctx, region, from, to)
+}
+
+func (m *tracingMiddleware) GetReposMostForksSeries(ctx context.Context, region, from, to string) ([]ReposMostForks, error) {
+	ctx, span := trace.StartSpan(ctx, "GetReposMostForksSeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
+
+	return m.service.GetReposMostForksSeries(ctx, region, from, to)
+}
+
+func (m *tracingMiddleware) GetMostPopularLanguageSeries(ctx context.Context, region, from, to string) ([]MostPopularLanguage, error) {
+	ctx, span := trace.StartSpan(ctx, "GetMostPopularLanguageSeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
+
+	return m.service.GetMostPopularLanguageSeries(ctx, region, from, to)
+}
+
+func (m *tracingMiddleware) GetMostRecentReposByLanguageSeries(ctx context.Context, region, from, to string) ([]MostRecentReposByLanguage, error) {
+	ctx, span := trace.StartSpan(ctx, "GetMostRecentReposByLanguageSeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
+
+	return m.service.GetMostRecentReposByLanguageSeries(ctx, region, from, to)
+}
+
+func (m *tracingMiddleware) GetReposByLanguageSeries(ctx context.Context, region, from, to string) ([]ReposByLanguage, error) {
+	ctx, span := trace.StartSpan(ctx, "GetReposByLanguageSeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
+
+	return m.service.GetReposByLanguageSeries(ctx, region, from, to)
+}
+
+func (m *tracingMiddleware) GetCompanyCountSeries(ctx context.Context, region, from, to string) ([]CompanyCount, error) {
+	ctx, span := trace.StartSpan(ctx, "GetCompanyCountSeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
+
+	return m.service.GetCompanyCountSeries(ctx, region, from, to)
+}
+
+func (m *tracingMiddleware) GetUsersByCompanySeries(ctx context.Context, region, from, to string) ([]UsersByCompany, error) {
+	ctx, span := trace.StartSpan(ctx, "GetUsersByCompanySeries")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("from", from),
+		trace.StringAttribute("to", to))
 
-	return m.service.PostUsersByCompany(ctx, users)
+	return m.service.GetUsersByCompanySeries(ctx, region, from, to)
 }
@@ -0,0 +1,153 @@
+// Package bolt registers the bolt storage driver, which keeps the stores in a single file
+// with go.etcd.io/bbolt. The stores are kept in memory as well, and written through to the
+// file, so that the queries and aggregations are the ones of the memory driver. The file is
+// locked by the instance that opens it, so the driver suits a single instance
+package bolt
+
+import (
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/checkpointsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/jobsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/locksvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/searchsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/statsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/storage"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/kv"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
+)
+
+// Name is the name of the driver. The path to the file is read from the host of the config
+const Name = "bolt"
+
+// defaultPath is the file used when the config has no host
+const defaultPath = "scraper.db"
+
+func init() {
+	storage.Register(Name, open)
+}
+
+func open(cfg storage.Config) (*storage.Stores, error) {
+	path := cfg.Host
+	if path == "" {
+		path = defaultPath
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	stores, err := newStores(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return stores, nil
+}
+
+func newStores(db *bolt.DB) (*storage.Stores, error) {
+	checkpoints, err := checkpointsvc.NewKVStore(newBucket(db, database.Checkpoints))
+	if err != nil {
+		return nil, err
+	}
+	locks, err := locksvc.NewKVStore(newBucket(db, database.Locks))
+	if err != nil {
+		return nil, err
+	}
+	runs, err := jobsvc.NewKVStore(newBucket(db, database.JobRuns))
+	if err != nil {
+		return nil, err
+	}
+	stats, err := statsvc.NewKVStore(newBucket(db, database.Stats))
+	if err != nil {
+		return nil, err
+	}
+	repos, err := reposvc.NewKVStore(newBucket(db, database.Repos), newBucket(db, database.SimilarRepos))
+	if err != nil {
+		return nil, err
+	}
+	users, err := usersvc.NewKVStore(newBucket(db, database.Users))
+	if err != nil {
+		return nil, err
+	}
+	return &storage.Stores{
+		Checkpoint: checkpoints,
+		Lock:       locks,
+		Job:        runs,
+		Stat:       stats,
+		Repo:       repos,
+		User:       users,
+		Searcher: searchsvc.NewMemorySearcher(
+			func() ([]usersvc.User, error) { return users.FindAll(0, nil) },
+			func() ([]schema.Repo, error) { return repos.FindAll("", 0, nil) },
+		),
+		Close: func() { db.Close() },
+	}, nil
+}
+
+// bucket is the kv.Bucket of a bolt bucket, which is created on the first write
+type bucket struct {
+	db   *bolt.DB
+	name []byte
+}
+
+func newBucket(db *bolt.DB, name string) kv.Bucket {
+	return &bucket{db, []byte(name)}
+}
+
+func (b *bucket) ForEach(fn func(key string, value []byte) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(b.name)
+		if bkt == nil {
+			return nil
+		}
+		return bkt.ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
+
+func (b *bucket) Put(records map[string][]byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists(b.name)
+		if err != nil {
+			return err
+		}
+		for key, value := range records {
+			if err := bkt.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *bucket) Delete(keys ...string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(b.name)
+		if bkt == nil {
+			return nil
+		}
+		for _, key := range keys {
+			if err := bkt.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *bucket) Clear() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket(b.name)
+		if err == bolterrors.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
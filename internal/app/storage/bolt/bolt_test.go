@@ -0,0 +1,81 @@
+package bolt
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/checkpointsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/jobsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/storage"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/region"
+)
+
+// TestReopen checks that the records written are read back once the file is opened again
+func TestReopen(t *testing.T) {
+	cfg := storage.Config{Host: filepath.Join(t.TempDir(), "scraper.db")}
+	stores, err := open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Seed(stores, region.Region{Name: "malaysia", Locations: []string{"Malaysia"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Checkpoint.Upsert(checkpointsvc.Checkpoint{Key: "users", Cursor: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	lease, err := stores.Lock.Acquire("job", "a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Lock.Release(*lease); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, id := range []string{"older", "newer"} {
+		started := now.Add(time.Duration(i) * time.Hour)
+		if err := stores.Job.Insert(jobsvc.Run{ID: id, Name: "job", StartedAt: started, EndedAt: started}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stores.Stat.PostUserCount(fence.Fence{Name: "job", Token: 2}, "malaysia", 6); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.User.Drop(); err != nil {
+		t.Fatal(err)
+	}
+	stores.Close()
+
+	stores, err = open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stores.Close()
+
+	if cp, err := stores.Checkpoint.FindOne("users"); err != nil || cp.Cursor != "abc" {
+		t.Fatalf("want the checkpoint kept, got %+v, %v", cp, err)
+	}
+	if lease, err := stores.Lock.Acquire("job", "b", time.Minute); err != nil || lease.Token != 2 {
+		t.Fatalf("want the token to keep increasing, got %+v, %v", lease, err)
+	}
+	if runs, err := stores.Job.FindAll("job", 10); err != nil || len(runs) != 2 || runs[0].ID != "newer" {
+		t.Fatalf("want the runs kept with the most recent first, got %+v, %v", runs, err)
+	}
+	if stat, err := stores.Stat.GetUserCount("malaysia"); err != nil || stat.Count != 6 {
+		t.Fatalf("want the stat kept, got %+v, %v", stat, err)
+	}
+	if err := stores.Stat.PostUserCount(fence.Fence{Name: "job", Token: 1}, "malaysia", 1); err != fence.ErrStale {
+		t.Fatalf("want the fence of the stat kept, got %v", err)
+	}
+	today := now.Format("2006-01-02")
+	if series, err := stores.Stat.GetUserCountSeries("malaysia", today, today); err != nil || len(series) != 1 {
+		t.Fatalf("want the snapshot of the day kept, got %+v, %v", series, err)
+	}
+	if count, err := stores.Repo.Count("malaysia"); err != nil || count != 14 {
+		t.Fatalf("want the repos kept, got %d, %v", count, err)
+	}
+	if count, err := stores.User.Count("malaysia"); err != nil || count != 0 {
+		t.Fatalf("want the users dropped, got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,810 @@
+package storage_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/checkpointsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/jobsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/locksvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/searchsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/statsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/storage"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/storage/bolt"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/storage/postgres"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/region"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+	mgo "gopkg.in/mgo.v2"
+)
+
+// The mgo and mongo drivers are checked against the mongo db at TEST_MONGO_HOST, and skipped
+// when it is not set. Each run uses a new db, which is left for inspection
+const (
+	envMongoHost = "TEST_MONGO_HOST"
+	envMongoUser = "TEST_MONGO_USER"
+	envMongoPass = "TEST_MONGO_PASS"
+	envMongoAuth = "TEST_MONGO_AUTH"
+)
+
+// The postgres driver is checked against the postgres db at TEST_POSTGRES_URL, and skipped
+// when it is not set. Each run uses a new schema, which is left for inspection
+const envPostgresURL = "TEST_POSTGRES_URL"
+
+var testRegion = region.Region{
+	Name:      "malaysia",
+	Locations: []string{"Malaysia", "Kuala Lumpur"},
+}
+
+// TestDrivers checks that every driver registered follows the semantics of the stores, on
+// the sample users and repos of the demo mode
+func TestDrivers(t *testing.T) {
+	for _, name := range storage.Drivers() {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			stores := open(t, name)
+			defer stores.Close()
+
+			t.Run("checkpoint", func(t *testing.T) { testCheckpoint(t, stores.Checkpoint) })
+			t.Run("lock", func(t *testing.T) { testLock(t, stores.Lock) })
+			t.Run("job", func(t *testing.T) { testJob(t, stores.Job) })
+			t.Run("stat", func(t *testing.T) { testStat(t, stores.Stat) })
+
+			if err := storage.Seed(stores, testRegion); err != nil {
+				t.Fatal(err)
+			}
+			t.Run("repo", func(t *testing.T) { testRepo(t, stores.Repo) })
+			t.Run("user", func(t *testing.T) { testUser(t, stores.User) })
+			t.Run("search", func(t *testing.T) { testSearch(t, stores.Searcher) })
+			t.Run("aggregate", func(t *testing.T) { testAggregate(t, stores.Repo, stores.User) })
+			t.Run("zero counts", func(t *testing.T) { testZeroCounts(t, stores.User) })
+			t.Run("fence", func(t *testing.T) { testFence(t, stores.Repo, stores.User) })
+		})
+	}
+}
+
+// open opens the stores of the driver, with the indexes created. Drivers without a config
+// here fail, so that a new driver is not left out of the suite
+func open(t *testing.T, name string) *storage.Stores {
+	t.Helper()
+	var cfg storage.Config
+	switch name {
+	case storage.Memory:
+	case bolt.Name:
+		cfg.Host = filepath.Join(t.TempDir(), "scraper.db")
+	case storage.Mgo, storage.Mongo:
+		host := os.Getenv(envMongoHost)
+		if host == "" {
+			t.Skipf("%s is not set", envMongoHost)
+		}
+		cfg = storage.Config{
+			Host:     host,
+			User:     os.Getenv(envMongoUser),
+			Password: os.Getenv(envMongoPass),
+			Name:     fmt.Sprintf("conformance_%s_%d", name, time.Now().UnixNano()),
+			Auth:     os.Getenv(envMongoAuth),
+		}
+	case postgres.Name:
+		cfg.Host = postgresSchema(t, fmt.Sprintf("conformance_%d", time.Now().UnixNano()))
+	default:
+		t.Fatalf("no config for the driver %q", name)
+	}
+
+	stores, err := storage.Open(name, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, init := range []func() error{
+		stores.Checkpoint.Init,
+		stores.Lock.Init,
+		stores.Job.Init,
+		stores.Stat.Init,
+		stores.Repo.Init,
+		stores.User.Init,
+		stores.Searcher.Init,
+	} {
+		if err := init(); err != nil {
+			stores.Close()
+			t.Fatal(err)
+		}
+	}
+	return stores
+}
+
+// postgresSchema creates the schema in the db at TEST_POSTGRES_URL, and returns the url
+// with the schema as the search path, so that the tables are created in it
+func postgresSchema(t *testing.T, schema string) string {
+	t.Helper()
+	rawurl := os.Getenv(envPostgresURL)
+	if rawurl == "" {
+		t.Skipf("%s is not set", envPostgresURL)
+	}
+	db, err := sql.Open(postgres.Name, rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// isNotFound accepts the mgo.ErrNotFound of the mgo driver as well
+func isNotFound(err error) bool {
+	return apperr.IsNotFound(err) || err == mgo.ErrNotFound
+}
+
+func testCheckpoint(t *testing.T, s checkpointsvc.Store) {
+	if _, err := s.FindOne("users"); !isNotFound(err) {
+		t.Fatalf("want not found, got %v", err)
+	}
+
+	cp := checkpointsvc.Checkpoint{Key: "users", Query: "location:Malaysia", Cursor: "abc"}
+	if err := s.Upsert(cp); err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.FindOne("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Query != cp.Query || first.Cursor != cp.Cursor || first.CreatedAt == "" {
+		t.Fatalf("want %+v with the dates, got %+v", cp, first)
+	}
+
+	cp.Cursor = "def"
+	if err := s.Upsert(cp); err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.FindOne("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Cursor != "def" || second.CreatedAt != first.CreatedAt {
+		t.Fatalf("want the cursor replaced and the creation kept, got %+v", second)
+	}
+
+	// Under a lease, the write with an older token is rejected. The tokens of another
+	// lease are not comparable, and are let through
+	for _, tt := range []struct {
+		fence fence.Fence
+		err   error
+	}{
+		{fence.Fence{Name: "job", Token: 2}, nil},
+		{fence.Fence{Name: "job", Token: 1}, fence.ErrStale},
+		{fence.Fence{Name: "job", Token: 2}, nil},
+		{fence.Fence{}, nil},
+		{fence.Fence{Name: "job", Token: 1}, fence.ErrStale},
+		{fence.Fence{Name: "other", Token: 1}, nil},
+	} {
+		cp.Cursor, cp.Fence = fmt.Sprint(tt.fence), tt.fence
+		if err := s.Upsert(cp); err != tt.err {
+			t.Fatalf("want %v writing under %+v, got %v", tt.err, tt.fence, err)
+		}
+	}
+	if cp, err := s.FindOne("users"); err != nil || cp.Cursor != fmt.Sprint(fence.Fence{Name: "other", Token: 1}) {
+		t.Fatalf("want the checkpoint of the last write let through, got %+v, %v", cp, err)
+	}
+
+	if err := s.Delete("users"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FindOne("users"); !isNotFound(err) {
+		t.Fatalf("want not found after the delete, got %v", err)
+	}
+	if err := s.Delete("users"); err != nil {
+		t.Fatalf("want no error deleting a missing checkpoint, got %v", err)
+	}
+}
+
+func testLock(t *testing.T, s locksvc.Store) {
+	ttl := time.Minute
+	lease, err := s.Acquire("job", "a", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Holder != "a" || lease.Token != 1 || !lease.Held() {
+		t.Fatalf("want the lease held by a with token 1, got %+v", lease)
+	}
+	if _, err := s.Acquire("job", "b", ttl); err != locksvc.ErrLockHeld {
+		t.Fatalf("want %v, got %v", locksvc.ErrLockHeld, err)
+	}
+
+	stale := *lease
+	stale.Token++
+	if _, err := s.Renew(stale, ttl); err != locksvc.ErrLockLost {
+		t.Fatalf("want %v renewing another token, got %v", locksvc.ErrLockLost, err)
+	}
+	if _, err := s.Renew(*lease, ttl); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Release(*lease); err != nil {
+		t.Fatal(err)
+	}
+	next, err := s.Acquire("job", "b", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Holder != "b" || next.Token != 2 {
+		t.Fatalf("want the lease held by b with token 2, got %+v", next)
+	}
+	if _, err := s.Renew(*lease, ttl); err != locksvc.ErrLockLost {
+		t.Fatalf("want %v renewing the lease released, got %v", locksvc.ErrLockLost, err)
+	}
+
+	leases, err := s.FindAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 1 || leases[0].Name != "job" || leases[0].Holder != "b" {
+		t.Fatalf("want the lease of the job held by b, got %+v", leases)
+	}
+}
+
+func testJob(t *testing.T, s jobsvc.Store) {
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, started := range []time.Time{
+		now.Add(-2 * time.Hour),
+		now,
+		now.Add(-time.Hour),
+	} {
+		if err := s.Insert(jobsvc.Run{
+			ID:        fmt.Sprintf("run-%d", i),
+			Name:      "job",
+			StartedAt: started,
+			EndedAt:   started.Add(time.Second),
+			Status:    jobsvc.StatusSucceeded,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs, err := s.FindAll("job", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+	if want := []string{"run-1", "run-2"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("want the most recent runs %v, got %v", want, ids)
+	}
+	if !runs[0].StartedAt.Equal(now) {
+		t.Fatalf("want the run started at %v, got %v", now, runs[0].StartedAt)
+	}
+
+	runs, err = s.FindAll("other", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 0 {
+		t.Fatalf("want no runs of another job, got %d", len(runs))
+	}
+}
+
+func testStat(t *testing.T, s statsvc.Store) {
+	if _, err := s.GetUserCount("malaysia"); !isNotFound(err) {
+		t.Fatalf("want not found, got %v", err)
+	}
+
+	for _, count := range []int{3, 5} {
+		if err := s.PostUserCount(fence.Fence{}, "malaysia", count); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.PostUserCount(fence.Fence{}, "singapore", 7); err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.GetUserCount("malaysia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Count != 5 || res.Region != "malaysia" || res.Type != statsvc.EnumUserCount {
+		t.Fatalf("want the latest count of the region, got %+v", res)
+	}
+
+	// The stat last written under a newer token of the lease is kept
+	if err := s.PostUserCount(fence.Fence{Name: "job", Token: 2}, "malaysia", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PostUserCount(fence.Fence{Name: "job", Token: 1}, "malaysia", 1); err != fence.ErrStale {
+		t.Fatalf("want %v, got %v", fence.ErrStale, err)
+	}
+
+	languages := []schema.LanguageCount{{Name: "Go", Count: 4}, {Name: "Python", Count: 2}}
+	if err := s.PostMostPopularLanguage(fence.Fence{}, "malaysia", languages); err != nil {
+		t.Fatal(err)
+	}
+	popular, err := s.GetMostPopularLanguage("malaysia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(popular.Languages, languages) {
+		t.Fatalf("want %v, got %v", languages, popular.Languages)
+	}
+
+	// The stats posted on the same day overwrite the snapshot of the day
+	today := time.Now().UTC().Format("2006-01-02")
+	series, err := s.GetUserCountSeries("malaysia", today, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || series[0].Count != 5 || series[0].Date != today {
+		t.Fatalf("want the snapshot of today, got %+v", series)
+	}
+	series, err = s.GetUserCountSeries("malaysia", "2000-01-01", "2000-12-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 0 {
+		t.Fatalf("want no snapshots out of the range, got %+v", series)
+	}
+}
+
+func testRepo(t *testing.T, s reposvc.Store) {
+	repo, err := s.FindOne("aisyah/raft-kv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Login != "aisyah" || repo.Region != "malaysia" || repo.Stargazers != 310 ||
+		!reflect.DeepEqual(repo.Languages, []string{"Go"}) {
+		t.Fatalf("want the repo seeded, got %+v", repo)
+	}
+	if _, err := s.FindOne("aisyah/missing"); !isNotFound(err) {
+		t.Fatalf("want not found, got %v", err)
+	}
+
+	count, err := s.Count("malaysia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 14 {
+		t.Fatalf("want 14 repos, got %d", count)
+	}
+	if count, _ := s.Count("singapore"); count != 0 {
+		t.Fatalf("want no repos in another region, got %d", count)
+	}
+
+	// The pages follow each other by the cursor of the last repo
+	filter := reposvc.Filter{Region: "malaysia", Sort: "-stars"}
+	page, err := s.FindPage(filter, nil, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(page); !reflect.DeepEqual(got, []string{"aisyah/raft-kv", "weiming/ml-notebooks", "hafiz/react-dashboard"}) {
+		t.Fatalf("want the repos with the most stars, got %v", got)
+	}
+	last := page[len(page)-1]
+	page, err = s.FindPage(filter, &reposvc.Cursor{Value: last.Stargazers, NameWithOwner: last.NameWithOwner}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(page); !reflect.DeepEqual(got, []string{"aisyah/go-github-scraper", "aisyah/go-rate-limiter"}) {
+		t.Fatalf("want the next page, got %v", got)
+	}
+	count, err = s.CountBy(reposvc.Filter{Region: "malaysia", Language: "Go", Sort: "-stars"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Fatalf("want 4 repos in Go, got %d", count)
+	}
+
+	languages, err := s.Languages("malaysia", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []schema.LanguageCount{{Name: "Python", Count: 5}, {Name: "Go", Count: 4}}; !reflect.DeepEqual(languages, want) {
+		t.Fatalf("want %v, got %v", want, languages)
+	}
+	users, err := s.GroupByLanguage("malaysia", "Go", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []schema.UserCount{
+		{Name: "aisyah", Count: 3, AvatarURL: "https://github.com/aisyah.png"},
+		{Name: "arjun", Count: 1, AvatarURL: "https://github.com/arjun.png"},
+	}; !reflect.DeepEqual(users, want) {
+		t.Fatalf("want %v, got %v", want, users)
+	}
+
+	pushed, err := s.LastPushedBy("aisyah")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pushed.NameWithOwner != "aisyah/go-github-scraper" {
+		t.Fatalf("want the repo pushed last, got %s", pushed.NameWithOwner)
+	}
+
+	similar := []reposvc.SimilarRepos{{
+		NameWithOwner: "aisyah/raft-kv",
+		Similar:       []schema.SimilarRepo{{NameWithOwner: "aisyah/go-rate-limiter", Score: 0.5}},
+	}}
+	if err := s.BulkUpsertSimilar(fence.Fence{}, similar); err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.FindSimilar("aisyah/raft-kv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Similar, similar[0].Similar) || res.UpdatedAt == "" {
+		t.Fatalf("want %v, got %+v", similar[0].Similar, res)
+	}
+	if _, err := s.FindSimilar("aisyah/dotfiles"); !isNotFound(err) {
+		t.Fatalf("want not found, got %v", err)
+	}
+}
+
+func testUser(t *testing.T, s usersvc.Store) {
+	user, err := s.FindOne("aisyah")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Company != "Grab" || user.Region != "malaysia" || user.Followers != 120 ||
+		!reflect.DeepEqual(user.Locations, []string{"Malaysia"}) {
+		t.Fatalf("want the user seeded, got %+v", user)
+	}
+	if _, err := s.FindOne("missing"); !isNotFound(err) {
+		t.Fatalf("want not found, got %v", err)
+	}
+
+	count, err := s.Count("malaysia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 6 {
+		t.Fatalf("want 6 users, got %d", count)
+	}
+
+	last, err := s.FindLastCreated("Malaysia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Login != "nurul" {
+		t.Fatalf("want the user created last, got %s", last.Login)
+	}
+
+	companies, err := s.AggregateCompany("malaysia", 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := companyCounts(companies); !reflect.DeepEqual(got, map[string]int{"Grab": 2, "Shopee": 2}) {
+		t.Fatalf("want the companies with two users, got %v", got)
+	}
+
+	profile := usersvc.User{Login: "aisyah"}
+	profile.Stargazers = 525
+	profile.Languages = []schema.LanguageCount{{Name: "Go", Count: 3}}
+	if err := s.BulkUpdate(fence.Fence{}, []usersvc.User{profile}); err != nil {
+		t.Fatal(err)
+	}
+	page, err := s.FindPage(usersvc.Filter{Language: "Go", Sort: "-stars"}, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].Login != "aisyah" || page[0].Stargazers != 525 || page[0].Company != "Grab" {
+		t.Fatalf("want the profile set and the user kept, got %+v", page)
+	}
+
+	// The keywords are stored by their stem, and the keyword filtered by is stemmed alike
+	profile.Keywords = []schema.Keyword{{ID: "web scraper", Text: "web scrapers", Value: 2, Score: 3.5}}
+	if err := s.BulkUpdate(fence.Fence{}, []usersvc.User{profile}); err != nil {
+		t.Fatal(err)
+	}
+	for _, keyword := range []string{"web scraper", "Web Scrapers", "web-scrapers"} {
+		page, err := s.FindPage(usersvc.Filter{Keyword: keyword}, nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 1 || page[0].Login != "aisyah" || !reflect.DeepEqual(page[0].Keywords, profile.Keywords) {
+			t.Fatalf("%q: want the user with the keyword, got %+v", keyword, page)
+		}
+	}
+	for _, keyword := range []string{"scraper", "the"} {
+		count, err := s.CountBy(usersvc.Filter{Keyword: keyword})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatalf("%q: want no users, got %d", keyword, count)
+		}
+	}
+}
+
+func testSearch(t *testing.T, s searchsvc.Searcher) {
+	res, err := s.SearchRepos(searchsvc.Query{Text: "raft", Region: "malaysia", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Count != 1 || len(res.Hits) != 1 || res.Hits[0].Repo.NameWithOwner != "aisyah/raft-kv" {
+		t.Fatalf("want the repo matching the query, got %+v", res)
+	}
+}
+
+// testAggregate checks the aggregations of the memory stores against the pipelines of the
+// mongo stores, on repos and users of their own region. The forks, the placeholder companies
+// and the other regions are left out of the counts
+func testAggregate(t *testing.T, repos reposvc.Store, users usersvc.Store) {
+	if err := repos.BulkUpsert(fence.Fence{}, []github.Repo{
+		aggregateRepo("alice", "a1", false, "Go", "Python"),
+		aggregateRepo("alice", "a2", false, "Go"),
+		aggregateRepo("alice", "a3", false, "Rust"),
+		aggregateRepo("alice", "fork", true, "Go", "Python", "Rust"),
+		aggregateRepo("bob", "b1", false, "Go"),
+		aggregateRepo("bob", "b2", false, "Python"),
+		aggregateRepo("carol", "c1", false, "Go"),
+	}, "singapore"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.BulkUpsert(fence.Fence{}, []github.Repo{
+		aggregateRepo("dave", "d1", false, "Rust", "Python"),
+		aggregateRepo("dave", "d2", false, "Rust"),
+	}, "indonesia"); err != nil {
+		t.Fatal(err)
+	}
+
+	languages, err := repos.Languages("singapore", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []schema.LanguageCount{{Name: "Go", Count: 4}, {Name: "Python", Count: 2}}; !reflect.DeepEqual(languages, want) {
+		t.Fatalf("want %v, got %v", want, languages)
+	}
+	languages, err = repos.Languages("singapore", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(languages) != 3 || languages[2] != (schema.LanguageCount{Name: "Rust", Count: 1}) {
+		t.Fatalf("want Rust last with one repo, got %v", languages)
+	}
+
+	byUser, err := repos.GroupByUser("singapore", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []schema.UserCount{
+		{Name: "alice", Count: 3, AvatarURL: "https://github.com/alice.png"},
+		{Name: "bob", Count: 2, AvatarURL: "https://github.com/bob.png"},
+		{Name: "carol", Count: 1, AvatarURL: "https://github.com/carol.png"},
+	}; !reflect.DeepEqual(byUser, want) {
+		t.Fatalf("want %v, got %v", want, byUser)
+	}
+	byUser, err = repos.GroupByUser("singapore", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byUser) != 1 || byUser[0].Name != "alice" {
+		t.Fatalf("want only alice, got %v", byUser)
+	}
+
+	byLanguage, err := repos.GroupByLanguage("singapore", "Go", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := userCounts(byLanguage); !reflect.DeepEqual(got, map[string]int{"alice": 2, "bob": 1, "carol": 1}) {
+		t.Fatalf("want the original repos in Go by user, got %v", got)
+	}
+	if byLanguage[0].Name != "alice" {
+		t.Fatalf("want alice first, got %v", byLanguage)
+	}
+	byLanguage, err = repos.GroupByLanguage("singapore", "Rust", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := userCounts(byLanguage); !reflect.DeepEqual(got, map[string]int{"alice": 1}) {
+		t.Fatalf("want the repos of the region in Rust by user, got %v", got)
+	}
+	byLanguage, err = repos.GroupByLanguage("singapore", "Haskell", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byLanguage) != 0 {
+		t.Fatalf("want no users for a language without repos, got %v", byLanguage)
+	}
+
+	var sg []github.User
+	for i, company := range []string{"Acme", "Acme", "Acme", "Globex", "Globex", "Initech", "-", "-", "none", "None", ""} {
+		sg = append(sg, github.User{Login: fmt.Sprintf("sg%d", i), Company: company})
+	}
+	if err := users.BulkUpsert(fence.Fence{}, sg, "singapore", "Singapore"); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.BulkUpsert(fence.Fence{}, []github.User{
+		{Login: "id0", Company: "Initech"},
+		{Login: "id1", Company: "Initech"},
+	}, "indonesia", "Indonesia"); err != nil {
+		t.Fatal(err)
+	}
+
+	companies, err := users.AggregateCompany("singapore", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []schema.Company{
+		{Company: "Acme", Count: 3},
+		{Company: "Globex", Count: 2},
+		{Company: "Initech", Count: 1},
+	}; !reflect.DeepEqual(companies, want) {
+		t.Fatalf("want %v, got %v", want, companies)
+	}
+	// The min is inclusive and the max exclusive
+	companies, err = users.AggregateCompany("singapore", 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []schema.Company{{Company: "Globex", Count: 2}}; !reflect.DeepEqual(companies, want) {
+		t.Fatalf("want %v, got %v", want, companies)
+	}
+}
+
+// testZeroCounts checks that the users with no followers or no repos are listed, since the
+// counters are always written, and only the users without the sort field are left out
+func testZeroCounts(t *testing.T, s usersvc.Store) {
+	if err := s.BulkUpsert(fence.Fence{}, []github.User{
+		{Login: "nofollowers", Repositories: github.Repositories{TotalCount: 5}},
+		{Login: "norepos", Followers: github.Followers{TotalCount: 3}},
+	}, "brunei", "Brunei"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"norepos", "nofollowers"}},
+		{"repos", []string{"norepos", "nofollowers"}},
+		{"-repos", []string{"nofollowers", "norepos"}},
+	} {
+		filter := usersvc.Filter{Region: "brunei", Sort: tc.sort}
+		page, err := s.FindPage(filter, nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := logins(page); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("sort %q: want %v, got %v", tc.sort, tc.want, got)
+		}
+		count, err := s.CountBy(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(tc.want) {
+			t.Fatalf("sort %q: want %d users, got %d", tc.sort, len(tc.want), count)
+		}
+	}
+}
+
+// testFence checks that the writes of the users and the repos under an older token of the
+// lease are rejected, and that the writes without a lease or under another lease are not
+func testFence(t *testing.T, repos reposvc.Store, users usersvc.Store) {
+	var (
+		newer = fence.Fence{Name: "job", Token: 2}
+		older = fence.Fence{Name: "job", Token: 1}
+		other = fence.Fence{Name: "other", Token: 1}
+	)
+	user := func(followers int64) []github.User {
+		return []github.User{{Login: "somchai", Followers: github.Followers{TotalCount: followers}}}
+	}
+	repo := func(fork bool) []github.Repo {
+		return []github.Repo{aggregateRepo("somchai", "tuk-tuk", fork)}
+	}
+	profile := usersvc.User{Login: "somchai"}
+	profile.Stargazers = 7
+	match := usersvc.User{Login: "somchai"}
+	match.Matches = []schema.User{{Login: "aisyah", Score: 0.5}}
+	similar := []reposvc.SimilarRepos{{
+		NameWithOwner: "somchai/tuk-tuk",
+		Similar:       []schema.SimilarRepo{{NameWithOwner: "aisyah/raft-kv", Score: 0.5}},
+	}}
+
+	for i, tt := range []struct {
+		write func() error
+		err   error
+	}{
+		{func() error { return users.BulkUpsert(newer, user(1), "thailand", "Thailand") }, nil},
+		{func() error { return users.BulkUpsert(older, user(2), "thailand", "Thailand") }, fence.ErrStale},
+		{func() error { return users.BulkUpdate(older, []usersvc.User{profile}) }, fence.ErrStale},
+		{func() error { return users.BulkUpdateMatches(older, []usersvc.User{match}) }, fence.ErrStale},
+		{func() error { return users.BulkUpdate(newer, []usersvc.User{profile}) }, nil},
+		{func() error { return users.BulkUpdateMatches(newer, []usersvc.User{match}) }, nil},
+		{func() error { return users.BulkUpsert(fence.Fence{}, user(3), "thailand", "Thailand") }, nil},
+		{func() error { return users.BulkUpsert(older, user(4), "thailand", "Thailand") }, fence.ErrStale},
+		{func() error { return users.BulkUpsert(other, user(5), "thailand", "Thailand") }, nil},
+
+		{func() error { return repos.BulkUpsert(newer, repo(false), "thailand") }, nil},
+		{func() error { return repos.BulkUpsert(older, repo(true), "thailand") }, fence.ErrStale},
+		{func() error { return repos.BulkUpsertSimilar(newer, similar) }, nil},
+		{func() error { return repos.BulkUpsertSimilar(older, similar) }, fence.ErrStale},
+		{func() error { return repos.BulkUpsertSimilar(other, similar) }, nil},
+	} {
+		if err := tt.write(); err != tt.err {
+			t.Fatalf("write %d: want %v, got %v", i, tt.err, err)
+		}
+	}
+
+	u, err := users.FindOne("somchai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Followers != 5 || u.Stargazers != 7 || !reflect.DeepEqual(u.Matches, match.Matches) {
+		t.Fatalf("want the user of the writes let through, got %+v", u)
+	}
+	r, err := repos.FindOne("somchai/tuk-tuk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.IsFork {
+		t.Fatalf("want the repo of the newer write kept, got %+v", r)
+	}
+}
+
+func aggregateRepo(login, name string, fork bool, languages ...string) github.Repo {
+	repo := github.Repo{
+		ID:            "aggregate-" + login + "-" + name,
+		Name:          name,
+		NameWithOwner: login + "/" + name,
+		IsFork:        fork,
+		Owner:         github.Owner{Login: login, AvatarURL: "https://github.com/" + login + ".png"},
+	}
+	for _, lang := range languages {
+		repo.Languages.Edges = append(repo.Languages.Edges, github.LanguageEdge{
+			Node: github.LanguageNode{Name: lang},
+		})
+	}
+	return repo
+}
+
+func names(repos []schema.Repo) []string {
+	res := make([]string, len(repos))
+	for i, r := range repos {
+		res[i] = r.NameWithOwner
+	}
+	return res
+}
+
+func logins(users []usersvc.User) []string {
+	res := make([]string, len(users))
+	for i, u := range users {
+		res[i] = u.Login
+	}
+	return res
+}
+
+// companyCounts returns the count of each company, since the order of the companies with
+// the same count is not defined in mongo
+func companyCounts(companies []schema.Company) map[string]int {
+	res := make(map[string]int)
+	for _, c := range companies {
+		res[c.Company] = c.Count
+	}
+	return res
+}
+
+// userCounts returns the count of each user, since the order of the users with the same
+// count is not defined in mongo
+func userCounts(users []schema.UserCount) map[string]int {
+	res := make(map[string]int)
+	for _, u := range users {
+		res[u.Name] = u.Count
+	}
+	return res
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/app/checkpointsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/jobsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/locksvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/searchsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/statsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+)
+
+// Memory is the name of the driver that keeps everything in memory, which is lost when the
+// application stops. It needs no config
+const Memory = "memory"
+
+func init() {
+	Register(Memory, openMemory)
+}
+
+func openMemory(cfg Config) (*Stores, error) {
+	users := usersvc.NewMemoryStore()
+	repos := reposvc.NewMemoryStore()
+	return &Stores{
+		Checkpoint: checkpointsvc.NewMemoryStore(),
+		Lock:       locksvc.NewMemoryStore(),
+		Job:        jobsvc.NewMemoryStore(),
+		Stat:       statsvc.NewMemoryStore(),
+		Repo:       repos,
+		User:       users,
+		Searcher: searchsvc.NewMemorySearcher(
+			func() ([]usersvc.User, error) { return users.FindAll(0, nil) },
+			func() ([]schema.Repo, error) { return repos.FindAll("", 0, nil) },
+		),
+		Close: func() {},
+	}, nil
+}
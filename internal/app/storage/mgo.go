@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/app/checkpointsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/jobsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/locksvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/searchsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/statsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+)
+
+// Mgo is the name of the driver backed by gopkg.in/mgo.v2
+const Mgo = "mgo"
+
+func init() {
+	Register(Mgo, openMgo)
+}
+
+func openMgo(cfg Config) (*Stores, error) {
+	db := database.New(cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Auth)
+	return &Stores{
+		Checkpoint: checkpointsvc.NewStore(db, database.Checkpoints),
+		Lock:       locksvc.NewStore(db, database.Locks),
+		Job:        jobsvc.NewStore(db, database.JobRuns),
+		Stat:       statsvc.NewStore(db, database.Stats, database.StatsHistory),
+		Repo:       reposvc.NewStore(db, database.Repos, database.SimilarRepos),
+		User:       usersvc.NewStore(db, database.Users),
+		Searcher:   searchsvc.NewMongoSearcher(db, database.Users, database.Repos),
+		Close:      db.Close,
+	}, nil
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/app/checkpointsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/jobsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/locksvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/searchsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/statsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+)
+
+// Mongo is the name of the driver backed by the official go.mongodb.org/mongo-driver. It
+// keeps the same collections, documents and indexes as the mgo driver, so that either can
+// open the db of the other
+const Mongo = "mongo"
+
+func init() {
+	Register(Mongo, openMongo)
+}
+
+func openMongo(cfg Config) (*Stores, error) {
+	db, err := database.NewMongo(cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	return &Stores{
+		Checkpoint: checkpointsvc.NewMongoStore(db, database.Checkpoints),
+		Lock:       locksvc.NewMongoStore(db, database.Locks),
+		Job:        jobsvc.NewMongoStore(db, database.JobRuns),
+		Stat:       statsvc.NewMongoStore(db, database.Stats, database.StatsHistory),
+		Repo:       reposvc.NewMongoStore(db, database.Repos, database.SimilarRepos),
+		User:       usersvc.NewMongoStore(db, database.Users),
+		Searcher:   searchsvc.NewMongoDriverSearcher(db, database.Users, database.Repos),
+		Close:      db.Close,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/region"
+)
+
+// Seed stores a small set of sample users and repos in the region, which are found by the
+// first location of the region. It is meant for the demo mode, where nothing is fetched
+// from Github
+func Seed(s *Stores, r region.Region) error {
+	now := time.Now().UTC()
+	daysAgo := func(n int) time.Time {
+		return now.AddDate(0, 0, -n)
+	}
+
+	users := []github.User{
+		sampleUser("aisyah", "Aisyah Rahman", "Grab", "Backend engineer, Go and distributed systems", daysAgo(2100), 120, 42),
+		sampleUser("weiming", "Tan Wei Ming", "Shopee", "Machine learning and data pipelines", daysAgo(1800), 85, 30),
+		sampleUser("hafiz", "Hafiz Ismail", "Grab", "Frontend developer, React and TypeScript", daysAgo(1500), 64, 25),
+		sampleUser("meiling", "Lim Mei Ling", "Carousell", "Mobile apps with Flutter", daysAgo(900), 23, 12),
+		sampleUser("arjun", "Arjun Nair", "Shopee", "Web scraping and search", daysAgo(600), 41, 18),
+		sampleUser("nurul", "Nurul Huda", "", "Pelajar sains komputer", daysAgo(200), 5, 6),
+	}
+
+	repos := []github.Repo{
+		sampleRepo("aisyah", "go-github-scraper", "A Github scraper and recommendation engine written in Go", 120, 14, daysAgo(1), "Go", "Dockerfile"),
+		sampleRepo("aisyah", "raft-kv", "A distributed key value store built on the raft consensus algorithm", 310, 40, daysAgo(12), "Go"),
+		sampleRepo("aisyah", "go-rate-limiter", "Distributed rate limiter for Go services backed by redis", 95, 9, daysAgo(30), "Go", "Lua"),
+		sampleRepo("weiming", "ml-notebooks", "Notebooks on machine learning, from linear regression to transformers", 210, 55, daysAgo(3), "Jupyter Notebook", "Python"),
+		sampleRepo("weiming", "data-pipeline", "Data pipeline to ingest and clean the events for machine learning", 48, 6, daysAgo(40), "Python", "Shell"),
+		sampleRepo("weiming", "web-crawler-cn", "一个用 Python 写的网络爬虫，支持代理和并发", 77, 21, daysAgo(90), "Python"),
+		sampleRepo("hafiz", "react-dashboard", "Admin dashboard template built with React and TypeScript", 150, 33, daysAgo(5), "TypeScript", "CSS"),
+		sampleRepo("hafiz", "kedai-makan", "Aplikasi tempahan kedai makan secara dalam talian", 12, 3, daysAgo(60), "TypeScript", "HTML"),
+		sampleRepo("meiling", "flutter-expense-tracker", "Expense tracker mobile app built with Flutter", 66, 17, daysAgo(8), "Dart"),
+		sampleRepo("meiling", "flutter-weather", "Weather mobile app with Flutter and the open weather api", 19, 4, daysAgo(120), "Dart"),
+		sampleRepo("arjun", "search-engine", "A small full text search engine with tf-idf ranking", 88, 11, daysAgo(15), "Go"),
+		sampleRepo("arjun", "price-scraper", "Web scraper that tracks the prices of products in online stores", 57, 8, daysAgo(25), "Python", "JavaScript"),
+		sampleRepo("nurul", "projek-tahun-akhir", "Projek tahun akhir tentang pengesanan wajah dengan pembelajaran mesin", 4, 1, daysAgo(45), "Python"),
+		sampleRepo("nurul", "dotfiles", "My dotfiles", 1, 0, daysAgo(300), "Shell"),
+	}
+
+	location := r.Locations[0]
+	for i := range users {
+		users[i].Location = location
+	}
+	if err := s.User.BulkUpsert(fence.Fence{}, users, r.Name, location); err != nil {
+		return err
+	}
+	return s.Repo.BulkUpsert(fence.Fence{}, repos, r.Name)
+}
+
+func sampleUser(login, name, company, bio string, createdAt time.Time, followers, repos int64) github.User {
+	return github.User{
+		Login:        login,
+		Name:         name,
+		Company:      company,
+		Bio:          bio,
+		AvatarURL:    "https://github.com/" + login + ".png",
+		CreatedAt:    createdAt,
+		UpdatedAt:    createdAt,
+		Followers:    github.Followers{TotalCount: followers},
+		Repositories: github.Repositories{TotalCount: repos},
+	}
+}
+
+func sampleRepo(login, name, description string, stars, forks int64, pushedAt time.Time, languages ...string) github.Repo {
+	repo := github.Repo{
+		ID:            "demo-" + login + "-" + name,
+		Name:          name,
+		NameWithOwner: login + "/" + name,
+		Description:   description,
+		CreatedAt:     pushedAt.AddDate(0, -6, 0),
+		UpdatedAt:     pushedAt,
+		PushedAt:      pushedAt,
+		ForkCount:     forks,
+		Stargazers:    github.Stargazers{TotalCount: stars},
+		Watchers:      github.Watchers{TotalCount: stars / 10},
+		Owner:         github.Owner{Login: login, AvatarURL: "https://github.com/" + login + ".png"},
+		URL:           "https://github.com/" + login + "/" + name,
+	}
+	for i, lang := range languages {
+		repo.Languages.Edges = append(repo.Languages.Edges, github.LanguageEdge{
+			Size: int64(10000 / (i + 1)),
+			Node: github.LanguageNode{Name: lang},
+		})
+	}
+	if len(languages) > 0 {
+		repo.PrimaryLanguage = github.LanguageNode{Name: languages[0]}
+	}
+	for _, topic := range strings.Split(name, "-") {
+		repo.RepositoryTopics.Nodes = append(repo.RepositoryTopics.Nodes, github.RepositoryTopic{
+			Topic: github.Topic{Name: topic},
+		})
+	}
+	return repo
+}
@@ -0,0 +1,90 @@
+// Package storage opens the stores of the services with the storage driver configured, so
+// that the services do not depend on a particular database
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/checkpointsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/jobsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/locksvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/searchsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/statsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+)
+
+// Config holds the connection settings passed to the driver. Drivers that connect with a
+// single data source name, e.g. a postgres url or the path to a file, read it from Host
+type Config struct {
+	Host     string
+	User     string
+	Password string
+	Name     string
+	Auth     string
+}
+
+// Stores holds the store of each service, opened by the same driver
+type Stores struct {
+	Checkpoint checkpointsvc.Store
+	Lock       locksvc.Store
+	Job        jobsvc.Store
+	Stat       statsvc.Store
+	Repo       reposvc.Store
+	User       usersvc.Store
+	Searcher   searchsvc.Searcher
+
+	// Close releases the connections held by the driver
+	Close func()
+}
+
+// Driver opens the stores with the config
+type Driver func(cfg Config) (*Stores, error)
+
+var (
+	mu      sync.RWMutex
+	drivers = make(map[string]Driver)
+)
+
+// Register makes the driver available by the name. Drivers that depend on modules not
+// needed by the other drivers register themselves in the init of their own package, and
+// are included with a blank import
+func Register(name string, d Driver) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if d == nil {
+		panic("storage: register driver is nil")
+	}
+	if _, ok := drivers[name]; ok {
+		panic("storage: register called twice for driver " + name)
+	}
+	drivers[name] = d
+}
+
+// Drivers returns the sorted names of the drivers registered
+func Drivers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var names []string
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open returns the stores opened by the driver with the name
+func Open(name string, cfg Config) (*Stores, error) {
+	mu.RLock()
+	d, ok := drivers[name]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("storage: unknown driver %q, expected one of %v", name, Drivers())
+	}
+	return d(cfg)
+}
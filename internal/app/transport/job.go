@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/jobsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/locksvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/cronjob"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/encoder"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type jobEndpoints struct {
+	jobs      []*cronjob.Config
+	locks     locksvc.Service
+	runs      jobsvc.Service
+	threshold int
+}
+
+// NewJobEndpoints creates the endpoints of the cron jobs, with the leases that they hold and
+// the history of their runs. A job is degraded after the threshold of consecutive failures
+func NewJobEndpoints(jobs []*cronjob.Config, locks locksvc.Service, runs jobsvc.Service, threshold int) Endpoints {
+	return &jobEndpoints{jobs, locks, runs, threshold}
+}
+
+// GetJobs returns the cron jobs, with the lease and the health of each job. The lease is kept
+// after it is released, and held tells if the instance in the holder is running the job now
+func (e *jobEndpoints) GetJobs() Endpoint {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		ctx := r.Context()
+		leases, err := e.locks.FindAll(ctx)
+		if err != nil {
+			encoder.JSON(w, r, err, nil)
+			return
+		}
+		byName := make(map[string]locksvc.Lease)
+		for _, lease := range leases {
+			byName[lease.Name] = lease
+		}
+
+		jobs := make([]Data, len(e.jobs))
+		for i, job := range e.jobs {
+			health, err := e.runs.Health(ctx, job.Name, e.threshold)
+			if err != nil {
+				encoder.JSON(w, r, err, nil)
+				return
+			}
+			res := Data{
+				"name":                job.Name,
+				"description":         job.Description,
+				"cronTab":             job.CronTab,
+				"enabled":             job.Start,
+				"held":                false,
+				"health":              health.Status,
+				"consecutiveFailures": health.ConsecutiveFailures,
+			}
+			if lease, ok := byName[job.Name]; ok {
+				res["lock"] = lease
+				res["held"] = lease.Held()
+			}
+			if health.LastRun != nil {
+				res["lastRun"] = health.LastRun
+			}
+			if health.LastSucceededAt != "" {
+				res["lastSucceededAt"] = health.LastSucceededAt
+			}
+			jobs[i] = res
+		}
+		encoder.JSON(w, r, nil, jobs)
+	}
+}
+
+// GetJobRuns returns the most recent runs of the job, with the outcome of each task
+func (e *jobEndpoints) GetJobRuns() Endpoint {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := r.Context()
+		name := ps.ByName("name")
+		if !e.exists(name) {
+			encoder.JSON(w, r, apperr.ErrNotFound, nil)
+			return
+		}
+		limit, err := queryInt(r.URL.Query(), "limit")
+		if err != nil {
+			encoder.JSON(w, r, err, nil)
+			return
+		}
+		runs, err := e.runs.FindAll(ctx, name, int(limit))
+		encoder.JSON(w, r, err, runs)
+	}
+}
+
+func (e *jobEndpoints) exists(name string) bool {
+	for _, job := range e.jobs {
+		if job.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap registers the job routes. The name of the job is escaped in the path, e.g.
+// /jobs/Build%20Stats/runs
+func (e *jobEndpoints) Wrap(r *httprouter.Router) {
+	r.GET("/jobs", e.GetJobs())
+	r.GET("/jobs/:name/runs", e.GetJobRuns())
+}
@@ -2,8 +2,10 @@ package transport
 
 import (
 	"net/http"
+	"net/url"
 
 	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/encoder"
 
 	"github.com/julienschmidt/httprouter"
@@ -18,17 +20,120 @@ func NewRepoEndpoints(s reposvc.Service) Endpoints {
 	return &repoEndpoints{s}
 }
 
-func (e *repoEndpoints) GetRepoCount() Endpoint {
+// GetRepos returns a page of repos matching the filters in the query string, together with
+// the number of repos matching the filters. The next page is requested with the nextCursor
+func (e *repoEndpoints) GetRepos() Endpoint {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		ctx := r.Context()
-		count, err := e.service.Count(ctx)
+		e.list(w, r, r.URL.Query().Get("owner"))
+	}
+}
+
+// GetUserRepos returns a page of repos owned by the user, with the same filters as GetRepos
+func (e *repoEndpoints) GetUserRepos() Endpoint {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		e.list(w, r, ps.ByName("login"))
+	}
+}
 
-		encoder.JSON(w, err, Data{
+// GetRepoCount returns the number of repos matching the filters of GetRepos, or of all the
+// repos of the region without filters. It is served at /repos/count for the clients of the
+// count endpoint that preceded the listing
+func (e *repoEndpoints) GetRepoCount() Endpoint {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("owner") != "count" {
+			encoder.JSON(w, r, apperr.ErrNotFound, nil)
+			return
+		}
+		q := r.URL.Query()
+		filter, err := repoFilter(q, q.Get("owner"))
+		if err != nil {
+			encoder.JSON(w, r, err, nil)
+			return
+		}
+		count, err := e.service.CountBy(r.Context(), filter)
+		if err != nil {
+			encoder.JSON(w, r, err, nil)
+			return
+		}
+		encoder.JSON(w, r, nil, Data{
 			"data": count,
 		})
 	}
 }
 
+func (e *repoEndpoints) GetRepo() Endpoint {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := r.Context()
+		repo, err := e.service.FindOne(ctx, ps.ByName("owner"), ps.ByName("name"))
+		encoder.JSON(w, r, err, repo)
+	}
+}
+
+// GetSimilarRepos returns the repos most similar to the repo, with the reasons of each match
+func (e *repoEndpoints) GetSimilarRepos() Endpoint {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := r.Context()
+		limit, err := queryInt(r.URL.Query(), "limit")
+		if err != nil {
+			encoder.JSON(w, r, err, nil)
+			return
+		}
+		repos, err := e.service.FindSimilar(ctx, ps.ByName("owner"), ps.ByName("name"), int(limit))
+		encoder.JSON(w, r, err, repos)
+	}
+}
+
+func (e *repoEndpoints) list(w http.ResponseWriter, r *http.Request, owner string) {
+	page, err := e.page(r, owner)
+	encoder.JSON(w, r, err, page)
+}
+
+// page returns the page of repos matching the filters in the query string
+func (e *repoEndpoints) page(r *http.Request, owner string) (*reposvc.Page, error) {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	limit, err := queryInt(q, "limit")
+	if err != nil {
+		return nil, err
+	}
+	filter, err := repoFilter(q, owner)
+	if err != nil {
+		return nil, err
+	}
+	return e.service.List(ctx, filter, q.Get("cursor"), int(limit))
+}
+
+// repoFilter returns the filter of the repos of the owner in the query string
+func repoFilter(q url.Values, owner string) (reposvc.Filter, error) {
+	minStars, err := queryInt(q, "minStars")
+	if err != nil {
+		return reposvc.Filter{}, err
+	}
+	isFork, err := queryBool(q, "fork")
+	if err != nil {
+		return reposvc.Filter{}, err
+	}
+	return reposvc.Filter{
+		Region:      q.Get("region"),
+		Language:    q.Get("language"),
+		Owner:       owner,
+		IsFork:      isFork,
+		MinStars:    minStars,
+		CreatedFrom: q.Get("createdFrom"),
+		CreatedTo:   q.Get("createdTo"),
+		PushedFrom:  q.Get("pushedFrom"),
+		PushedTo:    q.Get("pushedTo"),
+		Sort:        q.Get("sort"),
+	}, nil
+}
+
+// Wrap registers the repo routes. The router does not allow /repos/count next to the
+// /repos/:owner/:name wildcard, so the count is served by /repos/:owner instead
 func (e *repoEndpoints) Wrap(r *httprouter.Router) {
-	r.GET("/repos/count", e.GetRepoCount())
+	r.GET("/repos", e.GetRepos())
+	r.GET("/repos/:owner", e.GetRepoCount())
+	r.GET("/repos/:owner/:name", e.GetRepo())
+	r.GET("/repos/:owner/:name/similar", e.GetSimilarRepos())
+	r.GET("/users/:login/repos", e.GetUserRepos())
 }
@@ -0,0 +1,112 @@
+package transport_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+)
+
+func TestGetRepos(t *testing.T) {
+	h := newHandler(t)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"language=Go&sort=-stars", []string{"aisyah/raft-kv", "aisyah/go-github-scraper", "aisyah/go-rate-limiter", "arjun/search-engine"}},
+		{"owner=meiling", []string{"meiling/flutter-expense-tracker", "meiling/flutter-weather"}},
+		{"minStars=150&sort=-stars", []string{"aisyah/raft-kv", "weiming/ml-notebooks", "hafiz/react-dashboard"}},
+	}
+	for _, tt := range tests {
+		var page reposvc.Page
+		if code := get(t, h, "/repos?"+tt.query, &page); code != http.StatusOK {
+			t.Fatalf("%s: want status 200, got %d", tt.query, code)
+		}
+		if got := names(page.Repos); !reflect.DeepEqual(got, tt.want) || page.Count != len(tt.want) {
+			t.Fatalf("%s: want %v, got %v of %d", tt.query, tt.want, got, page.Count)
+		}
+	}
+}
+
+func TestGetReposInvalid(t *testing.T) {
+	h := newHandler(t)
+
+	for _, query := range []string{"fork=maybe", "minStars=lots", "sort=name"} {
+		if code := get(t, h, "/repos?"+query, nil); code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: want status 422, got %d", query, code)
+		}
+	}
+}
+
+func TestGetUserRepos(t *testing.T) {
+	h := newHandler(t)
+
+	var page reposvc.Page
+	if code := get(t, h, "/users/weiming/repos?limit=1&sort=-stars", &page); code != http.StatusOK {
+		t.Fatalf("want status 200, got %d", code)
+	}
+	if got := names(page.Repos); !reflect.DeepEqual(got, []string{"weiming/ml-notebooks"}) || page.Count != 3 {
+		t.Fatalf("want the first of the 3 repos of the user, got %v of %d", got, page.Count)
+	}
+}
+
+func TestGetRepoCount(t *testing.T) {
+	h := newHandler(t)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 14},
+		{"region=malaysia", 14},
+		{"region=singapore", 0},
+		{"language=Python", 5},
+		{"owner=aisyah&language=Go", 3},
+	}
+	for _, tt := range tests {
+		// The count is nested in the data, as it was before the listing
+		var res struct {
+			Count int `json:"data"`
+		}
+		if code := get(t, h, "/repos/count?"+tt.query, &res); code != http.StatusOK {
+			t.Fatalf("%s: want status 200, got %d", tt.query, code)
+		}
+		if res.Count != tt.want {
+			t.Fatalf("%s: want %d repos, got %d", tt.query, tt.want, res.Count)
+		}
+	}
+	for _, query := range []string{"minStars=many", "sort=name"} {
+		if code := get(t, h, "/repos/count?"+query, nil); code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: want status 422, got %d", query, code)
+		}
+	}
+	if code := get(t, h, "/repos/aisyah", nil); code != http.StatusNotFound {
+		t.Fatalf("want status 404 for an owner without a name, got %d", code)
+	}
+}
+
+func TestGetRepo(t *testing.T) {
+	h := newHandler(t)
+
+	var repo schema.Repo
+	if code := get(t, h, "/repos/aisyah/raft-kv", &repo); code != http.StatusOK {
+		t.Fatalf("want status 200, got %d", code)
+	}
+	if repo.NameWithOwner != "aisyah/raft-kv" || repo.Stargazers != 310 {
+		t.Fatalf("want the repo seeded, got %+v", repo)
+	}
+	if code := get(t, h, "/repos/aisyah/missing", nil); code != http.StatusNotFound {
+		t.Fatalf("want status 404, got %d", code)
+	}
+}
+
+func names(repos []schema.Repo) []string {
+	res := make([]string, len(repos))
+	for i, r := range repos {
+		res[i] = r.NameWithOwner
+	}
+	return res
+}
@@ -0,0 +1,44 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/searchsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/encoder"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type searchEndpoints struct {
+	service searchsvc.Service
+}
+
+// NewSearchEndpoints creates a new endpoint based on the service provided
+func NewSearchEndpoints(s searchsvc.Service) Endpoints {
+	return &searchEndpoints{s}
+}
+
+// Search returns the repos or users matching the query, ranked by relevance
+func (e *searchEndpoints) Search() Endpoint {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		ctx := r.Context()
+		q := r.URL.Query()
+
+		limit, err := queryInt(q, "limit")
+		if err != nil {
+			encoder.JSON(w, r, err, nil)
+			return
+		}
+		res, err := e.service.Search(ctx, searchsvc.Query{
+			Text:   q.Get("q"),
+			Type:   q.Get("type"),
+			Region: q.Get("region"),
+			Limit:  int(limit),
+		})
+		encoder.JSON(w, r, err, res)
+	}
+}
+
+func (e *searchEndpoints) Wrap(r *httprouter.Router) {
+	r.GET("/search", e.Search())
+}
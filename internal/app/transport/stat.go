@@ -24,29 +24,32 @@ func (e *statEndpoints) GetStats() Endpoint {
 		ctx := r.Context()
 		var res interface{}
 		var err error
+
+		// The stats are computed for each region, and an empty region refers to all regions
+		region := r.URL.Query().Get("region")
 		switch r.URL.Query().Get("type") {
 		case statsvc.EnumUserCount:
-			res, err = e.service.GetUserCount(ctx)
+			res, err = e.service.GetUserCount(ctx, region)
 		case statsvc.EnumRepoCount:
-			res, err = e.service.GetRepoCount(ctx)
+			res, err = e.service.GetRepoCount(ctx, region)
 		case statsvc.EnumReposMostRecent:
-			res, err = e.service.GetReposMostRecent(ctx)
+			res, err = e.service.GetReposMostRecent(ctx, region)
 		case statsvc.EnumRepoCountByUser:
-			res, err = e.service.GetRepoCountByUser(ctx)
+			res, err = e.service.GetRepoCountByUser(ctx, region)
 		case statsvc.EnumReposMostStars:
-			res, err = e.service.GetReposMostStars(ctx)
+			res, err = e.service.GetReposMostStars(ctx, region)
 		case statsvc.EnumReposMostForks:
-			res, err = e.service.GetReposMostForks(ctx)
+			res, err = e.service.GetReposMostForks(ctx, region)
 		case statsvc.EnumMostPopularLanguage:
-			res, err = e.service.GetMostPopularLanguage(ctx)
+			res, err = e.service.GetMostPopularLanguage(ctx, region)
 		case statsvc.EnumMostRecentReposByLanguage:
-			res, err = e.service.GetMostRecentReposByLanguage(ctx)
+			res, err = e.service.GetMostRecentReposByLanguage(ctx, region)
 		case statsvc.EnumReposByLanguage:
-			res, err = e.service.GetReposByLanguage(ctx)
+			res, err = e.service.GetReposByLanguage(ctx, region)
 		case statsvc.EnumCompanyCount:
-			res, err = e.service.GetCompanyCount(ctx)
+			res, err = e.service.GetCompanyCount(ctx, region)
 		case statsvc.EnumUsersByCompany:
-			res, err = e.service.GetUsersByCompany(ctx)
+			res, err = e.service.GetUsersByCompany(ctx, region)
 		default:
 			res = Data{
 				"paths": []string{
@@ -64,10 +67,66 @@ func (e *statEndpoints) GetStats() Endpoint {
 				},
 			}
 		}
-		encoder.JSON(w, err, res)
+		encoder.JSON(w, r, err, res)
+	}
+}
+
+// GetStatsHistory returns the daily snapshots of the stat between the from and to dates,
+// in the format YYYY-MM-DD
+func (e *statEndpoints) GetStatsHistory() Endpoint {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		ctx := r.Context()
+		var res interface{}
+		var err error
+
+		query := r.URL.Query()
+		region := query.Get("region")
+		from, to := query.Get("from"), query.Get("to")
+		switch query.Get("type") {
+		case statsvc.EnumUserCount:
+			res, err = e.service.GetUserCountSeries(ctx, region, from, to)
+		case statsvc.EnumRepoCount:
+			res, err = e.service.GetRepoCountSeries(ctx, region, from, to)
+		case statsvc.EnumReposMostRecent:
+			res, err = e.service.GetReposMostRecentSeries(ctx, region, from, to)
+		case statsvc.EnumRepoCountByUser:
+			res, err = e.service.GetRepoCountByUserSeries(ctx, region, from, to)
+		case statsvc.EnumReposMostStars:
+			res, err = e.service.GetReposMostStarsSeries(ctx, region, from, to)
+		case statsvc.EnumReposMostForks:
+			res, err = e.service.GetReposMostForksSeries(ctx, region, from, to)
+		case statsvc.EnumMostPopularLanguage:
+			res, err = e.service.GetMostPopularLanguageSeries(ctx, region, from, to)
+		case statsvc.EnumMostRecentReposByLanguage:
+			res, err = e.service.GetMostRecentReposByLanguageSeries(ctx, region, from, to)
+		case statsvc.EnumReposByLanguage:
+			res, err = e.service.GetReposByLanguageSeries(ctx, region, from, to)
+		case statsvc.EnumCompanyCount:
+			res, err = e.service.GetCompanyCountSeries(ctx, region, from, to)
+		case statsvc.EnumUsersByCompany:
+			res, err = e.service.GetUsersByCompanySeries(ctx, region, from, to)
+		default:
+			res = Data{
+				"paths": []string{
+					"/stats/history?type=user_count",
+					"/stats/history?type=repo_count",
+					"/stats/history?type=company_count",
+					"/stats/history?type=repos_most_recent",
+					"/stats/history?type=repo_count_by_user",
+					"/stats/history?type=repos_most_stars",
+					"/stats/history?type=repos_most_forks",
+					"/stats/history?type=users_by_company",
+					"/stats/history?type=languages_most_popular",
+					"/stats/history?type=repos_most_recent_by_language",
+					"/stats/history?type=repos_by_language",
+				},
+			}
+		}
+		encoder.JSON(w, r, err, res)
 	}
 }
 
 func (e *statEndpoints) Wrap(r *httprouter.Router) {
 	r.GET("/stats", e.GetStats())
+	r.GET("/stats/history", e.GetStatsHistory())
 }
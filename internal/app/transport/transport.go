@@ -3,6 +3,15 @@
 package transport
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/logger"
+
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -38,3 +47,44 @@ func (t *transport) Init(endpoints ...Endpoints) {
 func New(r *httprouter.Router) Transport {
 	return &transport{r}
 }
+
+// RequestID assigns an id to every request, which is returned in the X-Request-ID header
+// and in the errors, and is logged by the services. The id sent by the client is kept
+func RequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if id := r.Header.Get("X-Request-ID"); id != "" {
+			ctx = context.WithValue(ctx, logger.RequestID, id)
+		} else {
+			ctx = logger.WrapContextWithRequestID(ctx)
+		}
+		w.Header().Set("X-Request-ID", ctx.Value(logger.RequestID).(string))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// queryInt parses the integer in the query string, returning zero if the key is not provided
+func queryInt(q url.Values, key string) (int64, error) {
+	v := q.Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, apperr.Validation(fmt.Sprintf("%s must be an integer", key))
+	}
+	return n, nil
+}
+
+// queryBool parses the boolean in the query string, returning nil if the key is not provided
+func queryBool(q url.Values, key string) (*bool, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, apperr.Validation(fmt.Sprintf("%s must be a boolean", key))
+	}
+	return &b, nil
+}
@@ -0,0 +1,54 @@
+package transport_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/storage"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/transport"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/region"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// newHandler returns the user and repo routes on the memory stores, with the sample users
+// and repos of the demo mode
+func newHandler(t *testing.T) http.Handler {
+	t.Helper()
+	stores, err := storage.Open(storage.Memory, storage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(stores.Close)
+	if err := storage.Seed(stores, region.Region{Name: "malaysia", Locations: []string{"Malaysia"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httprouter.New()
+	transport.New(r).Init(
+		transport.NewUserEndpoints(usersvc.NewWithStore(stores.User)),
+		transport.NewRepoEndpoints(reposvc.NewWithStore(stores.Repo)),
+	)
+	return r
+}
+
+// get serves the request, and decodes the data of the response into res when it succeeds
+func get(t *testing.T, h http.Handler, url string, res interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	if w.Code != http.StatusOK || res == nil {
+		return w.Code
+	}
+	body := struct {
+		Data interface{} `json:"data"`
+	}{res}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code
+}
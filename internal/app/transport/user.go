@@ -2,6 +2,7 @@ package transport
 
 import (
 	"net/http"
+	"net/url"
 
 	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/encoder"
@@ -19,26 +20,88 @@ func NewUserEndpoints(s usersvc.Service) Endpoints {
 	return &userEndpoints{s}
 }
 
+// GetUsers returns a page of users matching the filters in the query string, together with
+// the number of users matching the filters. The next page is requested with the nextCursor
+func (e *userEndpoints) GetUsers() Endpoint {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		q := r.URL.Query()
+		filter, err := userFilter(q)
+		if err != nil {
+			encoder.JSON(w, r, err, nil)
+			return
+		}
+		limit, err := queryInt(q, "limit")
+		if err != nil {
+			encoder.JSON(w, r, err, nil)
+			return
+		}
+		page, err := e.service.List(r.Context(), filter, q.Get("cursor"), int(limit))
+		encoder.JSON(w, r, err, page)
+	}
+}
+
+// GetUserCount returns the number of users matching the filters of GetUsers, or of all the
+// users of the region without filters, as the count endpoint that preceded the listing did
 func (e *userEndpoints) GetUserCount() Endpoint {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		ctx := r.Context()
-		count, err := e.service.Count(ctx)
-		encoder.JSON(w, err, Data{
+		filter, err := userFilter(r.URL.Query())
+		if err != nil {
+			encoder.JSON(w, r, err, nil)
+			return
+		}
+		count, err := e.service.CountBy(r.Context(), filter)
+		if err != nil {
+			encoder.JSON(w, r, err, nil)
+			return
+		}
+		encoder.JSON(w, r, nil, Data{
 			"count": count,
 		})
 	}
 }
 
+// GetUser returns the user with the login. The router does not allow /users/count next to
+// the /users/:login wildcard, so the count is served here instead
 func (e *userEndpoints) GetUser() Endpoint {
+	count := e.GetUserCount()
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		ctx := r.Context()
 		login := ps.ByName("login")
-		user, err := e.service.FindOne(ctx, login)
-		encoder.JSON(w, err, user)
+		if login == "count" {
+			count(w, r, ps)
+			return
+		}
+		user, err := e.service.FindOne(r.Context(), login)
+		encoder.JSON(w, r, err, user)
+	}
+}
+
+// userFilter returns the filter of the users in the query string
+func userFilter(q url.Values) (usersvc.Filter, error) {
+	ints := make(map[string]int64)
+	for _, key := range []string{"minFollowers", "maxFollowers", "minRepos", "maxRepos"} {
+		n, err := queryInt(q, key)
+		if err != nil {
+			return usersvc.Filter{}, err
+		}
+		ints[key] = n
 	}
+	return usersvc.Filter{
+		Region:       q.Get("region"),
+		Company:      q.Get("company"),
+		Location:     q.Get("location"),
+		Language:     q.Get("language"),
+		Keyword:      q.Get("keyword"),
+		CreatedFrom:  q.Get("createdFrom"),
+		CreatedTo:    q.Get("createdTo"),
+		MinFollowers: ints["minFollowers"],
+		MaxFollowers: ints["maxFollowers"],
+		MinRepos:     ints["minRepos"],
+		MaxRepos:     ints["maxRepos"],
+		Sort:         q.Get("sort"),
+	}, nil
 }
 
 func (e *userEndpoints) Wrap(r *httprouter.Router) {
 	r.GET("/users/:login", e.GetUser())
-	r.GET("/users", e.GetUserCount())
+	r.GET("/users", e.GetUsers())
 }
@@ -0,0 +1,115 @@
+package transport_test
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
+)
+
+func TestGetUsers(t *testing.T) {
+	h := newHandler(t)
+
+	var page usersvc.Page
+	if code := get(t, h, "/users?region=malaysia&limit=2", &page); code != http.StatusOK {
+		t.Fatalf("want status 200, got %d", code)
+	}
+	if got, want := logins(page.Users), []string{"aisyah", "weiming"}; !reflect.DeepEqual(got, want) || page.Count != 6 {
+		t.Fatalf("want %v of 6 users, got %v of %d", want, got, page.Count)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("want the cursor of the next page")
+	}
+
+	var next usersvc.Page
+	get(t, h, "/users?region=malaysia&limit=2&cursor="+url.QueryEscape(page.NextCursor), &next)
+	if got, want := logins(next.Users), []string{"hafiz", "arjun"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want the next page %v, got %v", want, got)
+	}
+}
+
+func TestGetUsersFilter(t *testing.T) {
+	h := newHandler(t)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"company=Grab", []string{"aisyah", "hafiz"}},
+		{"minFollowers=40&maxFollowers=90", []string{"weiming", "hafiz", "arjun"}},
+		{"minRepos=20&sort=repos", []string{"hafiz", "weiming", "aisyah"}},
+	}
+	for _, tt := range tests {
+		var page usersvc.Page
+		if code := get(t, h, "/users?"+tt.query, &page); code != http.StatusOK {
+			t.Fatalf("%s: want status 200, got %d", tt.query, code)
+		}
+		if got := logins(page.Users); !reflect.DeepEqual(got, tt.want) || page.Count != len(tt.want) {
+			t.Fatalf("%s: want %v, got %v of %d", tt.query, tt.want, got, page.Count)
+		}
+	}
+}
+
+func TestGetUsersInvalid(t *testing.T) {
+	h := newHandler(t)
+
+	for _, query := range []string{"minFollowers=many", "limit=ten", "sort=name"} {
+		if code := get(t, h, "/users?"+query, nil); code != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: want status 422, got %d", query, code)
+		}
+	}
+}
+
+func TestGetUserCount(t *testing.T) {
+	h := newHandler(t)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 6},
+		{"region=malaysia", 6},
+		{"region=singapore", 0},
+		{"company=Grab", 2},
+		{"minRepos=20&sort=repos", 3},
+	}
+	for _, tt := range tests {
+		var res struct {
+			Count int `json:"count"`
+		}
+		if code := get(t, h, "/users/count?"+tt.query, &res); code != http.StatusOK {
+			t.Fatalf("%s: want status 200, got %d", tt.query, code)
+		}
+		if res.Count != tt.want {
+			t.Fatalf("%s: want %d users, got %d", tt.query, tt.want, res.Count)
+		}
+	}
+	if code := get(t, h, "/users/count?minFollowers=many", nil); code != http.StatusUnprocessableEntity {
+		t.Fatalf("want status 422, got %d", code)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	h := newHandler(t)
+
+	var user usersvc.User
+	if code := get(t, h, "/users/aisyah", &user); code != http.StatusOK {
+		t.Fatalf("want status 200, got %d", code)
+	}
+	if user.Login != "aisyah" || user.Company != "Grab" || user.Followers != 120 {
+		t.Fatalf("want the user seeded, got %+v", user)
+	}
+	if code := get(t, h, "/users/missing", nil); code != http.StatusNotFound {
+		t.Fatalf("want status 404, got %d", code)
+	}
+}
+
+func logins(users []usersvc.User) []string {
+	res := make([]string, len(users))
+	for i, u := range users {
+		res[i] = u.Login
+	}
+	return res
+}
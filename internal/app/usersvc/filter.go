@@ -0,0 +1,138 @@
+package usersvc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/bow"
+)
+
+// dateLayout is the layout of the created-at range in the filter, YYYY-MM-DD
+const dateLayout = "2006-01-02"
+
+// sortFields maps the sort keys accepted by the api to the fields in the store
+var sortFields = map[string]string{
+	"followers": "followers",
+	"stars":     "stargazers",
+	"repos":     "repositories",
+	"createdAt": "createdAt",
+}
+
+// Filter represents the criteria to list the users. Zero values are not applied
+type Filter struct {
+	Region       string
+	Company      string
+	Location     string
+	Language     string
+	Keyword      string
+	CreatedFrom  string
+	CreatedTo    string
+	MinFollowers int64
+	MaxFollowers int64
+	MinRepos     int64
+	MaxRepos     int64
+
+	// Sort is one of followers, stars, repos or createdAt, sorted in ascending order
+	// unless prefixed with "-". Ties are broken by the login
+	Sort string
+}
+
+// Validate checks that the dates, ranges and the sort key of the filter are valid
+func (f Filter) Validate() error {
+	for _, d := range []string{f.CreatedFrom, f.CreatedTo} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, d); err != nil {
+			return ErrInvalidDate
+		}
+	}
+	if f.CreatedFrom != "" && f.CreatedTo != "" && f.CreatedFrom > f.CreatedTo {
+		return ErrInvalidRange
+	}
+	if f.MinFollowers < 0 || f.MinRepos < 0 ||
+		(f.MaxFollowers > 0 && f.MinFollowers > f.MaxFollowers) ||
+		(f.MaxRepos > 0 && f.MinRepos > f.MaxRepos) {
+		return ErrInvalidRange
+	}
+	if _, _, ok := f.SortField(); !ok {
+		return ErrInvalidSort
+	}
+	return nil
+}
+
+// KeywordKey returns the key of the keyword filtered by, normalized and stemmed the way the
+// keywords of the profiles are, so that e.g. "Scrapers" finds the users with "scraper". A
+// keyword with no key, such as a stopword, is kept lowercased and matches no user
+func (f Filter) KeywordKey() string {
+	if key := bow.Key(f.Keyword, bow.KeywordOptions); key != "" {
+		return key
+	}
+	return strings.ToLower(strings.TrimSpace(f.Keyword))
+}
+
+// SortField returns the field in the store to sort by and the direction. The users are
+// sorted by the most followers when no sort key is provided
+func (f Filter) SortField() (field string, desc bool, ok bool) {
+	key := f.Sort
+	if key == "" {
+		key = "-followers"
+	}
+	if strings.HasPrefix(key, "-") {
+		key, desc = key[1:], true
+	}
+	field, ok = sortFields[key]
+	return field, desc, ok
+}
+
+// Cursor marks the last user of a page, by the value of the sort field and the login.
+// Since the login is unique, the next page starts right after the user even when the
+// sort values are the same
+type Cursor struct {
+	Value interface{} `json:"v"`
+	Login string      `json:"l"`
+}
+
+// Encode returns the cursor as an opaque url-safe string
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses the cursor returned in the previous page
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Login == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// newCursor returns the cursor pointing to the user, for the field sorted by
+func newCursor(u User, field string) Cursor {
+	var value interface{}
+	switch field {
+	case "followers":
+		value = u.Followers
+	case "stargazers":
+		value = u.Stargazers
+	case "repositories":
+		value = u.Repositories
+	case "createdAt":
+		value = u.CreatedAt
+	}
+	return Cursor{Value: value, Login: u.Login}
+}
+
+// Page represents a page of users, and the cursor to the next page if there are more users
+type Page struct {
+	Users      []User `json:"users"`
+	Count      int    `json:"count"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
@@ -25,31 +25,34 @@ type loggingMiddleware struct {
 	service Service
 }
 
-func (m *loggingMiddleware) FindLastCreated(ctx context.Context) (lastCreated string, ok bool) {
+func (m *loggingMiddleware) FindLastCreated(ctx context.Context, location string) (lastCreated string, ok bool) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("FindLastCreated"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("location", location))
 
 		L.Info("get last created user",
 			zap.String("date", lastCreated),
 			zap.Bool("default", ok))
 	}(time.Now())
 
-	return m.service.FindLastCreated(ctx)
+	return m.service.FindLastCreated(ctx, location)
 }
 
-func (m *loggingMiddleware) BulkUpsert(ctx context.Context, users []github.User) (err error) {
+func (m *loggingMiddleware) BulkUpsert(ctx context.Context, users []github.User, region, location string) (err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("BulkUpsert"),
 			logger.Duration(start),
-			zap.Int("count", len(users)))
+			zap.Int("count", len(users)),
+			zap.String("region", region),
+			zap.String("location", location))
 
 		logger.Maybe(L, "bulk upsert users", err)
 	}(time.Now())
 
-	return m.service.BulkUpsert(ctx, users)
+	return m.service.BulkUpsert(ctx, users, region, location)
 }
 
 func (m *loggingMiddleware) FindLastFetched(ctx context.Context, limit int) (res []User, err error) {
@@ -78,16 +81,17 @@ func (m *loggingMiddleware) UpdateOne(ctx context.Context, login string) (err er
 	return m.service.UpdateOne(ctx, login)
 }
 
-func (m *loggingMiddleware) Count(ctx context.Context) (count int, err error) {
+func (m *loggingMiddleware) Count(ctx context.Context, region string) (count int, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("Count"),
-			logger.Duration(start))
+			logger.Duration(start),
+			zap.String("region", region))
 
 		logger.Maybe(L, "get user count", err)
 	}(time.Now())
 
-	return m.service.Count(ctx)
+	return m.service.Count(ctx, region)
 }
 
 func (m *loggingMiddleware) BulkUpdate(ctx context.Context, users []User) (err error) {
@@ -103,6 +107,19 @@ func (m *loggingMiddleware) BulkUpdate(ctx context.Context, users []User) (err e
 	return m.service.BulkUpdate(ctx, users)
 }
 
+func (m *loggingMiddleware) BulkUpdateMatches(ctx context.Context, users []User) (err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("BulkUpdateMatches"),
+			logger.Duration(start),
+			zap.Int("count", len(users)))
+
+		logger.Maybe(L, "bulk update matches", err)
+	}(time.Now())
+
+	return m.service.BulkUpdateMatches(ctx, users)
+}
+
 func (m *loggingMiddleware) WithRepos(ctx context.Context, count int) (users []User, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
@@ -116,44 +133,47 @@ func (m *loggingMiddleware) WithRepos(ctx context.Context, count int) (users []U
 	return m.service.WithRepos(ctx, count)
 }
 
-func (m *loggingMiddleware) DistinctCompany(ctx context.Context) (res []string, err error) {
+func (m *loggingMiddleware) DistinctCompany(ctx context.Context, region string) (res []string, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("DistinctCompany"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("count", len(res)))
 
 		logger.Maybe(L, "get companies with certain counts", err)
 	}(time.Now())
 
-	return m.service.DistinctCompany(ctx)
+	return m.service.DistinctCompany(ctx, region)
 }
 
-func (m *loggingMiddleware) FindByCompany(ctx context.Context, company string) (res []schema.User, err error) {
+func (m *loggingMiddleware) FindByCompany(ctx context.Context, region, company string) (res []schema.User, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("FindByCompany"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.String("company", company))
 
 		logger.Maybe(L, "get users by company", err)
 	}(time.Now())
 
-	return m.service.FindByCompany(ctx, company)
+	return m.service.FindByCompany(ctx, region, company)
 }
 
-func (m *loggingMiddleware) AggregateCompany(ctx context.Context, min, max int) (res []schema.Company, err error) {
+func (m *loggingMiddleware) AggregateCompany(ctx context.Context, region string, min, max int) (res []schema.Company, err error) {
 	defer func(start time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("AggregateCompany"),
 			logger.Duration(start),
+			zap.String("region", region),
 			zap.Int("min", min),
 			zap.Int("max", max))
 
 		logger.Maybe(L, "get companies", err)
 	}(time.Now())
 
-	return m.service.AggregateCompany(ctx, min, max)
+	return m.service.AggregateCompany(ctx, region, min, max)
 }
 
 func (m *loggingMiddleware) FindOne(ctx context.Context, login string) (res *User, err error) {
@@ -168,3 +188,31 @@ func (m *loggingMiddleware) FindOne(ctx context.Context, login string) (res *Use
 
 	return m.service.FindOne(ctx, login)
 }
+
+func (m *loggingMiddleware) List(ctx context.Context, filter Filter, cursor string, limit int) (res *Page, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("List"),
+			logger.Duration(start),
+			zap.Any("filter", filter),
+			zap.String("cursor", cursor),
+			zap.Int("limit", limit))
+
+		logger.Maybe(L, "list users", err)
+	}(time.Now())
+
+	return m.service.List(ctx, filter, cursor, limit)
+}
+
+func (m *loggingMiddleware) CountBy(ctx context.Context, filter Filter) (count int, err error) {
+	defer func(start time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("CountBy"),
+			logger.Duration(start),
+			zap.Any("filter", filter))
+
+		logger.Maybe(L, "count users", err)
+	}(time.Now())
+
+	return m.service.CountBy(ctx, filter)
+}
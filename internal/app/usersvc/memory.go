@@ -0,0 +1,465 @@
+package usersvc
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/kv"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/order"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// memoryStore keeps the users in memory, for the tests and the demo mode. The queries and
+// aggregations follow the semantics of the mongo store, except that the fields are never
+// missing: a user without a profile has zero stargazers rather than none. With a bucket,
+// the users are written through to it as well. The fences of the last writes are only kept
+// in memory, since the bucket is not shared with other instances
+type memoryStore struct {
+	sync.RWMutex
+	users  map[string]User
+	fences map[string]fence.Fence
+	bucket kv.Bucket
+}
+
+// NewMemoryStore returns an empty store that keeps the users in memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		users:  make(map[string]User),
+		fences: make(map[string]fence.Fence),
+	}
+}
+
+// NewKVStore returns a store that keeps the users in memory and in the bucket, with the
+// users already in the bucket
+func NewKVStore(b kv.Bucket) (Store, error) {
+	s := &memoryStore{
+		users:  make(map[string]User),
+		fences: make(map[string]fence.Fence),
+		bucket: b,
+	}
+	if err := b.ForEach(func(key string, value []byte) error {
+		var u User
+		if err := bson.Unmarshal(value, &u); err != nil {
+			return err
+		}
+		s.users[key] = u
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryStore) Init() error {
+	return nil
+}
+
+func (s *memoryStore) Drop() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := kv.Clear(s.bucket); err != nil {
+		return err
+	}
+	s.users = make(map[string]User)
+	s.fences = make(map[string]fence.Fence)
+	return nil
+}
+
+func (s *memoryStore) FindOne(login string) (*User, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	user, ok := s.users[login]
+	if !ok {
+		return nil, apperr.ErrNotFound
+	}
+	return &user, nil
+}
+
+// FindAll returns the users sorted by the keys, then by the login. A limit of zero returns
+// all users
+func (s *memoryStore) FindAll(limit int, sort []string) ([]User, error) {
+	users := s.find(func(User) bool { return true })
+	sortUsers(users, append(append([]string{}, sort...), "login")...)
+	return head(users, limit), nil
+}
+
+func (s *memoryStore) FindPage(filter Filter, after *Cursor, limit int) ([]User, error) {
+	field, desc, _ := filter.SortField()
+	users := s.find(func(u User) bool {
+		if !matchFilter(u, filter) {
+			return false
+		}
+		if after == nil {
+			return true
+		}
+		c := order.Compare(userValue(u, field), after.Value)
+		if desc {
+			c = -c
+		}
+		return c > 0 || (c == 0 && u.Login > after.Login)
+	})
+
+	key := field
+	if desc {
+		key = "-" + field
+	}
+	sortUsers(users, key, "login")
+	return head(users, limit), nil
+}
+
+func (s *memoryStore) CountBy(filter Filter) (int, error) {
+	return len(s.find(func(u User) bool { return matchFilter(u, filter) })), nil
+}
+
+func (s *memoryStore) FindByCompany(region, company string) ([]schema.User, error) {
+	users := s.find(func(u User) bool {
+		return inRegion(u, region) && u.Company == company
+	})
+	sortUsers(users, "login")
+
+	res := make([]schema.User, len(users))
+	for i, u := range users {
+		res[i] = schema.User{Login: u.Login, AvatarURL: u.AvatarURL}
+	}
+	return res, nil
+}
+
+func (s *memoryStore) FindLastCreated(location string) (*User, error) {
+	users := s.find(func(u User) bool { return contains(u.Locations, location) })
+	if len(users) == 0 {
+		users = s.find(func(u User) bool { return len(u.Locations) == 0 })
+	}
+	if len(users) == 0 {
+		return nil, apperr.ErrNotFound
+	}
+	sortUsers(users, "-createdAt", "login")
+	return &users[0], nil
+}
+
+func (s *memoryStore) PickLogin() ([]string, error) {
+	users := s.find(func(User) bool { return true })
+	sortUsers(users, "login")
+
+	logins := make([]string, len(users))
+	for i, u := range users {
+		logins[i] = u.Login
+	}
+	return logins, nil
+}
+
+func (s *memoryStore) Upsert(user github.User) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.save(fence.Fence{}, map[string]User{
+		user.Login: setGithubUser(s.users[user.Login], user),
+	})
+}
+
+// BulkUpsert upserts the users, tagging them with the region and the location searched.
+// Under a lease, no user is written when one was last written under a newer token of the
+// lease, and fence.ErrStale is returned instead
+func (s *memoryStore) BulkUpsert(f fence.Fence, users []github.User, region, location string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, user := range users {
+		if f.Stale(s.fences[user.Login]) {
+			return fence.ErrStale
+		}
+	}
+
+	changed := make(map[string]User)
+	for _, user := range users {
+		u := setGithubUser(s.user(changed, user.Login), user)
+		u.Region = region
+		if !contains(u.Locations, location) {
+			u.Locations = append(u.Locations[:len(u.Locations):len(u.Locations)], location)
+		}
+		changed[user.Login] = u
+	}
+	return s.save(f, changed)
+}
+
+// BulkUpdate sets the profile of the users, with the same fencing as BulkUpsert. As with
+// the mongo store, the keywords, languages and matches are only replaced when there are some
+func (s *memoryStore) BulkUpdate(f fence.Fence, users []User) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, user := range users {
+		if f.Stale(s.fences[user.Login]) {
+			return fence.ErrStale
+		}
+	}
+
+	changed := make(map[string]User)
+	for _, user := range users {
+		u := s.user(changed, user.Login)
+		u.Login = user.Login
+		u.Watchers = user.Watchers
+		u.Stargazers = user.Stargazers
+		u.Forks = user.Forks
+		if len(user.Keywords) > 0 {
+			u.Keywords = user.Keywords
+		}
+		if len(user.Languages) > 0 {
+			u.Languages = user.Languages
+		}
+		if len(user.Matches) > 0 {
+			u.Matches = user.Matches
+		}
+		u.UpdatedAt = moment.NewUTCDate()
+		changed[user.Login] = u
+	}
+	return s.save(f, changed)
+}
+
+// BulkUpdateMatches sets the matches of the users, leaving the rest of the profile as it is,
+// with the same fencing as BulkUpsert
+func (s *memoryStore) BulkUpdateMatches(f fence.Fence, users []User) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, user := range users {
+		if f.Stale(s.fences[user.Login]) {
+			return fence.ErrStale
+		}
+	}
+
+	changed := make(map[string]User)
+	for _, user := range users {
+		if _, ok := s.users[user.Login]; !ok {
+			continue
+		}
+		u := s.user(changed, user.Login)
+		u.Matches = user.Matches
+		u.UpdatedAt = moment.NewUTCDate()
+		changed[user.Login] = u
+	}
+	return s.save(f, changed)
+}
+
+func (s *memoryStore) Count(region string) (int, error) {
+	return len(s.find(func(u User) bool { return inRegion(u, region) })), nil
+}
+
+func (s *memoryStore) UpdateOne(login string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	u := s.users[login]
+	u.Login = login
+	u.FetchedAt = moment.NewUTCDate()
+	return s.save(fence.Fence{}, map[string]User{login: u})
+}
+
+func (s *memoryStore) WithRepos(count int) ([]User, error) {
+	users := s.find(func(u User) bool { return u.Repositories > int64(count) })
+	sortUsers(users, "login")
+	return users, nil
+}
+
+func (s *memoryStore) DistinctCompany(region string) ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+	for _, u := range s.find(func(u User) bool { return inRegion(u, region) }) {
+		if !seen[u.Company] {
+			seen[u.Company] = true
+			res = append(res, u.Company)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// AggregateCompany counts the users of each company, leaving out the placeholders for no
+// company, and returns the companies with at least min and less than max users
+func (s *memoryStore) AggregateCompany(region string, min, max int) ([]schema.Company, error) {
+	count := make(map[string]int)
+	for _, u := range s.find(func(u User) bool { return inRegion(u, region) }) {
+		switch u.Company {
+		case "-", "none", "None", "":
+			continue
+		}
+		count[u.Company]++
+	}
+
+	var companies []schema.Company
+	for _, company := range order.Counts(count, 0) {
+		if n := count[company]; n >= min && n < max {
+			companies = append(companies, schema.Company{Company: company, Count: n})
+		}
+	}
+	return companies, nil
+}
+
+// user returns the user changed so far, or the user kept when it has not changed
+func (s *memoryStore) user(changed map[string]User, login string) User {
+	if u, ok := changed[login]; ok {
+		return u
+	}
+	return s.users[login]
+}
+
+// save writes the users changed through to the bucket, then keeps them in memory with the
+// fence of the write. A write without a lease keeps the fence of the last write
+func (s *memoryStore) save(f fence.Fence, users map[string]User) error {
+	records := make(map[string]interface{}, len(users))
+	for login, u := range users {
+		records[login] = u
+	}
+	if err := kv.Save(s.bucket, records); err != nil {
+		return err
+	}
+	for login, u := range users {
+		s.users[login] = u
+		if !f.IsZero() {
+			s.fences[login] = f
+		}
+	}
+	return nil
+}
+
+// find returns a copy of the users matching the predicate
+func (s *memoryStore) find(fn func(User) bool) []User {
+	s.RLock()
+	defer s.RUnlock()
+
+	var users []User
+	for _, u := range s.users {
+		if fn(u) {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+// setGithubUser sets the fields of the user fetched from Github, the same fields set by
+// the mongo store
+func setGithubUser(u User, user github.User) User {
+	u.Name = user.Name
+	u.CreatedAt = user.CreatedAt.UTC().Format(time.RFC3339)
+	u.UpdatedAt = user.UpdatedAt.UTC().Format(time.RFC3339)
+	u.FetchedAt = moment.NewUTCDate()
+	u.Login = user.Login
+	u.Bio = user.Bio
+	u.Location = user.Location
+	u.Email = user.Email
+	u.Company = user.Company
+	u.AvatarURL = user.AvatarURL
+	u.WebsiteURL = user.WebsiteURL
+	u.Repositories = user.Repositories.TotalCount
+	u.Gists = user.Gists.TotalCount
+	u.Followers = user.Followers.TotalCount
+	u.Following = user.Following.TotalCount
+	return u
+}
+
+// matchFilter is the in memory equivalent of byFilter. The users without the sort field
+// are left out, where the counters are never missing and a date is missing when it is empty
+func matchFilter(u User, f Filter) bool {
+	if !inRegion(u, f.Region) ||
+		(f.Company != "" && u.Company != f.Company) ||
+		(f.Location != "" && !contains(u.Locations, f.Location)) ||
+		(f.Keyword != "" && !hasKeyword(u.Keywords, f.KeywordKey())) ||
+		(f.Language != "" && !hasLanguage(u.Languages, f.Language)) {
+		return false
+	}
+	if !inRange(u.CreatedAt, f.CreatedFrom, f.CreatedTo) ||
+		!inMinMax(u.Followers, f.MinFollowers, f.MaxFollowers) ||
+		!inMinMax(u.Repositories, f.MinRepos, f.MaxRepos) {
+		return false
+	}
+	field, _, _ := f.SortField()
+	return !order.Missing(userValue(u, field))
+}
+
+// userValue returns the value of the field of the user, by the name of the field in mongo
+func userValue(u User, field string) interface{} {
+	switch field {
+	case "login":
+		return u.Login
+	case "followers":
+		return u.Followers
+	case "stargazers":
+		return u.Stargazers
+	case "repositories":
+		return u.Repositories
+	case "createdAt":
+		return u.CreatedAt
+	case "updatedAt":
+		return u.UpdatedAt
+	case "fetchedAt":
+		return u.FetchedAt
+	}
+	return nil
+}
+
+func sortUsers(users []User, keys ...string) {
+	sort.SliceStable(users, order.Less(keys, func(i int, field string) interface{} {
+		return userValue(users[i], field)
+	}))
+}
+
+func head(users []User, limit int) []User {
+	if limit > 0 && len(users) > limit {
+		return users[:limit]
+	}
+	return users
+}
+
+func inRegion(u User, region string) bool {
+	return region == "" || u.Region == region
+}
+
+// inRange returns true if the date falls within the days from and to (inclusive). An empty
+// date is missing, and is never in a range
+func inRange(date, from, to string) bool {
+	if from == "" && to == "" {
+		return true
+	}
+	return date != "" &&
+		(from == "" || date >= from) &&
+		(to == "" || date <= to+"T23:59:59Z")
+}
+
+func inMinMax(n, min, max int64) bool {
+	return (min == 0 || n >= min) && (max == 0 || n <= max)
+}
+
+func hasKeyword(keywords []schema.Keyword, id string) bool {
+	for _, k := range keywords {
+		if k.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLanguage(languages []schema.LanguageCount, name string) bool {
+	for _, l := range languages {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,31 +1,35 @@
 package usersvc
 
 import (
-	"errors"
 	"log"
 
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 )
 
 type (
 	// Model contains business logic for user-related operations
 	Model interface {
-		AggregateCompany(min, max int) ([]schema.Company, error)
-		BulkUpsert(users []github.User) error
-		BulkUpdate(users []User) error
-		Count() (int, error)
+		AggregateCompany(region string, min, max int) ([]schema.Company, error)
+		BulkUpsert(f fence.Fence, users []github.User, region, location string) error
+		BulkUpdate(f fence.Fence, users []User) error
+		BulkUpdateMatches(f fence.Fence, users []User) error
+		Count(region string) (int, error)
+		CountBy(filter Filter) (int, error)
 		Drop() error
-		FindByCompany(company string) ([]schema.User, error)
+		FindByCompany(region, company string) ([]schema.User, error)
 		FindOne(login string) (*User, error)
-		FindLastCreated() (*User, error)
+		FindLastCreated(location string) (*User, error)
 		FindLastFetched(limit int) ([]User, error)
+		List(filter Filter, cursor string, limit int) (*Page, error)
 		MostRecent(limit int) ([]User, error)
 		Init() error
 		UpdateOne(login string) error
 		PickLogin() ([]string, error)
 		WithRepos(count int) ([]User, error)
-		DistinctCompany() ([]string, error)
+		DistinctCompany(region string) ([]string, error)
 	}
 
 	model struct {
@@ -34,9 +38,17 @@ type (
 )
 
 var (
-	ErrInvalidLogin = errors.New("login provided is invalid")
+	ErrInvalidLogin    = apperr.Validation("login provided is invalid")
+	ErrInvalidLocation = apperr.Validation("location is required")
+	ErrInvalidDate     = apperr.Validation("date must be in the format YYYY-MM-DD")
+	ErrInvalidRange    = apperr.Validation("min must not be greater than max")
+	ErrInvalidSort     = apperr.Validation("sort must be one of followers, stars, repos or createdAt")
+	ErrInvalidCursor   = apperr.Validation("cursor provided is invalid")
 )
 
+// defaultPageSize is the number of users returned when the limit is not provided
+const defaultPageSize = 20
+
 // NewModel returns a new model with the store
 func NewModel(store Store) Model {
 	m := model{store: store}
@@ -55,18 +67,28 @@ func (m *model) MostRecent(limit int) ([]User, error) {
 	return m.store.FindAll(limit, []string{"-createdAt"})
 }
 
-func (m *model) BulkUpsert(users []github.User) error {
+func (m *model) BulkUpsert(f fence.Fence, users []github.User, region, location string) error {
+	if len(users) == 0 {
+		return nil
+	}
+	if location == "" {
+		return ErrInvalidLocation
+	}
+	return m.store.BulkUpsert(f, users, region, location)
+}
+
+func (m *model) BulkUpdate(f fence.Fence, users []User) error {
 	if len(users) == 0 {
 		return nil
 	}
-	return m.store.BulkUpsert(users)
+	return m.store.BulkUpdate(f, users)
 }
 
-func (m *model) BulkUpdate(users []User) error {
+func (m *model) BulkUpdateMatches(f fence.Fence, users []User) error {
 	if len(users) == 0 {
 		return nil
 	}
-	return m.store.BulkUpdate(users)
+	return m.store.BulkUpdateMatches(f, users)
 }
 
 func (m *model) Drop() error {
@@ -75,12 +97,15 @@ func (m *model) Drop() error {
 
 // FindLastCreated returns the last created date in the format YYYY-MM-DD, and a boolean to indicate
 // if the value returned exists or is default
-func (m *model) FindLastCreated() (*User, error) {
-	return m.store.FindLastCreated()
+func (m *model) FindLastCreated(location string) (*User, error) {
+	if location == "" {
+		return nil, ErrInvalidLocation
+	}
+	return m.store.FindLastCreated(location)
 }
 
-func (m *model) FindByCompany(company string) ([]schema.User, error) {
-	return m.store.FindByCompany(company)
+func (m *model) FindByCompany(region, company string) ([]schema.User, error) {
+	return m.store.FindByCompany(region, company)
 }
 
 func (m *model) FindLastFetched(limit int) ([]User, error) {
@@ -88,8 +113,57 @@ func (m *model) FindLastFetched(limit int) ([]User, error) {
 	return m.store.FindAll(limit, []string{"fetchedAt"})
 }
 
-func (m *model) Count() (int, error) {
-	return m.store.Count()
+// List returns a page of the users matching the filter, starting after the cursor
+func (m *model) List(filter Filter, cursor string, limit int) (*Page, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	var after *Cursor
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = c
+	}
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	limit = setLimit(limit)
+
+	// Fetch an extra user to know if there is a next page
+	users, err := m.store.FindPage(filter, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	count, err := m.store.CountBy(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := Page{Users: users, Count: count}
+	if len(users) > limit {
+		field, _, _ := filter.SortField()
+		page.Users = users[:limit]
+		page.NextCursor = newCursor(page.Users[limit-1], field).Encode()
+	}
+	return &page, nil
+}
+
+func (m *model) Count(region string) (int, error) {
+	return m.store.Count(region)
+}
+
+// CountBy returns the number of users matching the filter. The users of the region are
+// counted as a whole when there is no other filter, including the users that have no sort field
+func (m *model) CountBy(filter Filter) (int, error) {
+	if filter == (Filter{Region: filter.Region}) {
+		return m.store.Count(filter.Region)
+	}
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+	return m.store.CountBy(filter)
 }
 
 func (m *model) UpdateOne(login string) (err error) {
@@ -114,12 +188,12 @@ func (m *model) WithRepos(count int) ([]User, error) {
 	return m.store.WithRepos(count)
 }
 
-func (m *model) AggregateCompany(min, max int) ([]schema.Company, error) {
-	return m.store.AggregateCompany(min, max)
+func (m *model) AggregateCompany(region string, min, max int) ([]schema.Company, error) {
+	return m.store.AggregateCompany(region, min, max)
 }
 
-func (m *model) DistinctCompany() ([]string, error) {
-	return m.store.DistinctCompany()
+func (m *model) DistinctCompany(region string) ([]string, error) {
+	return m.store.DistinctCompany(region)
 }
 
 func setLimit(limit int) int {
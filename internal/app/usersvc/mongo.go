@@ -0,0 +1,323 @@
+package usersvc
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/partitioner"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// mongoStore is the store on the official mongo driver. The queries and pipelines are the
+// ones of the mgo store, which the driver encodes the same
+type mongoStore struct {
+	db         *database.Mongo
+	collection string
+}
+
+// NewMongoStore returns a new store on the official mongo driver
+func NewMongoStore(db *database.Mongo, collection string) Store {
+	return &mongoStore{
+		db:         db,
+		collection: collection,
+	}
+}
+
+func (s *mongoStore) Init() error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    database.Sort("login"),
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	// Indexes for the sort fields of the paginated users, with the login to break ties
+	for _, field := range sortFields {
+		indexes = append(indexes, mongo.IndexModel{
+			Keys: database.Sort("-"+field, "login"),
+		})
+	}
+	_, err := s.db.Collection(s.collection).Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+func (s *mongoStore) FindOne(login string) (*User, error) {
+	var user User
+	if err := s.findOne(bson.M{"login": login}, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *mongoStore) FindAll(limit int, sort []string) ([]User, error) {
+	var users []User
+	if err := s.find(bson.M{}, options.Find().
+		SetSort(database.Sort(sort...)).
+		SetLimit(int64(limit)), &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindPage returns the users matching the filter, starting after the cursor when provided
+func (s *mongoStore) FindPage(filter Filter, after *Cursor, limit int) ([]User, error) {
+	query, sort := byPage(filter, after)
+	var users []User
+	if err := s.find(query, options.Find().
+		SetSort(database.Sort(sort...)).
+		SetLimit(int64(limit)), &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CountBy returns the number of users matching the filter
+func (s *mongoStore) CountBy(filter Filter) (int, error) {
+	return s.count(byFilter(filter))
+}
+
+func (s *mongoStore) FindByCompany(region, company string) ([]schema.User, error) {
+	var users []schema.User
+	if err := s.find(byRegion(region, bson.M{
+		"company": company,
+	}), nil, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindLastCreated returns the last created user that is found by searching the location. Users
+// that were crawled before the locations were recorded have none, and are used instead when no
+// user is found by the location yet
+func (s *mongoStore) FindLastCreated(location string) (*User, error) {
+	opts := options.FindOne().SetSort(database.Sort("-createdAt"))
+	var user User
+	err := s.findOne(bson.M{"locations": location}, opts, &user)
+	if err == apperr.ErrNotFound {
+		err = s.findOne(bson.M{"locations": bson.M{"$exists": false}}, opts, &user)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// PickLogin takes the login field
+func (s *mongoStore) PickLogin() ([]string, error) {
+	var res []Logins
+	if err := s.aggregate(loginsPipeline(), &res); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, apperr.ErrNotFound
+	}
+	return res[0].Items, nil
+}
+
+func (s *mongoStore) Upsert(user github.User) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	_, err := s.db.Collection(s.collection).UpdateOne(ctx,
+		bson.M{"login": user.Login},
+		bson.M{"$set": user.BSON()},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// BulkUpsert upserts the users, tagging them with the region and the location searched,
+// with the fencing of the mgo store
+func (s *mongoStore) BulkUpsert(f fence.Fence, users []github.User, region, location string) error {
+	models := make([]mongo.WriteModel, len(users))
+	for i, user := range users {
+		doc := user.BSON()
+		doc["region"] = region
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(byFence(f, bson.M{"login": user.Login}, doc)).
+			SetUpdate(bson.M{
+				"$set":      doc,
+				"$addToSet": bson.M{"locations": location},
+			}).
+			SetUpsert(true)
+	}
+	_, err := s.bulkWrite(models)
+	return err
+}
+
+func (s *mongoStore) BulkUpdate(f fence.Fence, users []User) error {
+	models := make([]mongo.WriteModel, len(users))
+	for i, user := range users {
+		doc := user.Profile.BSON()
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(byFence(f, bson.M{"login": user.Login}, doc)).
+			SetUpdate(bson.M{"$set": doc}).
+			SetUpsert(true)
+	}
+	_, err := s.bulkWrite(models)
+	return err
+}
+
+// BulkUpdateMatches sets the matches of the users, leaving the rest of the profile as it is,
+// with the fencing of the mgo store
+func (s *mongoStore) BulkUpdateMatches(f fence.Fence, users []User) error {
+	models := make([]mongo.WriteModel, len(users))
+	for i, user := range users {
+		doc := bson.M{
+			"matches":   user.Matches,
+			"updatedAt": moment.NewUTCDate(),
+		}
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(byFence(f, bson.M{"login": user.Login}, doc)).
+			SetUpdate(bson.M{"$set": doc})
+	}
+	matched, err := s.bulkWrite(models)
+	if err != nil {
+		return err
+	}
+	if !f.IsZero() && matched < len(models) {
+		return fence.ErrStale
+	}
+	return nil
+}
+
+func (s *mongoStore) Count(region string) (int, error) {
+	return s.count(byRegion(region, bson.M{}))
+}
+
+func (s *mongoStore) Drop() error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	return s.db.Collection(s.collection).Drop(ctx)
+}
+
+func (s *mongoStore) UpdateOne(login string) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	_, err := s.db.Collection(s.collection).UpdateOne(ctx,
+		bson.M{"login": login},
+		bson.M{
+			"$set": bson.M{
+				"fetchedAt": moment.NewUTCDate(),
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (s *mongoStore) WithRepos(count int) ([]User, error) {
+	var users []User
+	if err := s.find(bson.M{
+		"repositories": bson.M{
+			"$gt": count,
+		},
+	}, nil, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *mongoStore) DistinctCompany(region string) ([]string, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	values, err := s.db.Collection(s.collection).Distinct(ctx, "company", byRegion(region, bson.M{}))
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, v := range values {
+		if company, ok := v.(string); ok {
+			res = append(res, company)
+		}
+	}
+	return res, nil
+}
+
+func (s *mongoStore) AggregateCompany(region string, min, max int) ([]schema.Company, error) {
+	var companies []schema.Company
+	if err := s.aggregate(companyPipeline(region, min, max), &companies); err != nil {
+		return nil, err
+	}
+	return companies, nil
+}
+
+// find decodes the users matching the query into res
+func (s *mongoStore) find(query bson.M, opts *options.FindOptions, res interface{}) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	cur, err := s.db.Collection(s.collection).Find(ctx, query, opts)
+	if err != nil {
+		return err
+	}
+	return cur.All(ctx, res)
+}
+
+// findOne decodes the first user matching the query into res
+func (s *mongoStore) findOne(query bson.M, opts *options.FindOneOptions, res interface{}) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	err := s.db.Collection(s.collection).FindOne(ctx, query, opts).Decode(res)
+	if err == mongo.ErrNoDocuments {
+		return apperr.ErrNotFound
+	}
+	return err
+}
+
+func (s *mongoStore) count(query bson.M) (int, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	n, err := s.db.Collection(s.collection).CountDocuments(ctx, query)
+	return int(n), err
+}
+
+// aggregate decodes the result of the pipeline on the users into res
+func (s *mongoStore) aggregate(pipeline []bson.M, res interface{}) error {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	cur, err := s.db.Collection(s.collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cur.All(ctx, res)
+}
+
+// bulkWrite runs the writes in bulks of 500, as with the mgo store, and returns the number
+// of users matched. The upserts of a stale fence fail on the unique login, and return
+// fence.ErrStale
+func (s *mongoStore) bulkWrite(models []mongo.WriteModel) (int, error) {
+	ctx, cancel := database.Context()
+	defer cancel()
+
+	perBulk := 500
+	partitions, bucket := partitioner.New(perBulk, len(models))
+
+	var matched int
+	for i := 0; i < bucket; i++ {
+		p := partitions[i]
+		res, err := s.db.Collection(s.collection).BulkWrite(ctx, models[p.Start:p.End])
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return matched, fence.ErrStale
+			}
+			return matched, err
+		}
+		matched += int(res.MatchedCount)
+	}
+	return matched, nil
+}
@@ -0,0 +1,338 @@
+package usersvc
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
+)
+
+// postgresStore keeps the users as jsonb documents in a table keyed by the login. The
+// documents have the fields of the mongo documents, and the queries follow the semantics of
+// the memory store, where the counters are never missing and a date is missing when it is
+// empty
+type postgresStore struct {
+	db    *database.Postgres
+	table string
+}
+
+// NewPostgresStore returns a new store on postgres
+func NewPostgresStore(db *database.Postgres, table string) Store {
+	return &postgresStore{
+		db:    db,
+		table: table,
+	}
+}
+
+func (s *postgresStore) Init() error {
+	indexes := []string{
+		`USING gin (doc jsonb_path_ops)`,
+		`((doc->>'region'), (doc->>'company'))`,
+	}
+	// Indexes for the sort fields of the paginated users, with the login to break ties
+	for _, field := range sortFields {
+		indexes = append(indexes, fmt.Sprintf(`(%s, %s)`, userColumn(field), userColumn("login")))
+	}
+	return s.db.CreateTable(s.table, indexes...)
+}
+
+func (s *postgresStore) FindOne(login string) (*User, error) {
+	var user User
+	if err := s.db.FindOne(&user, fmt.Sprintf(`SELECT doc FROM %s WHERE id = $1`, s.table), login); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *postgresStore) FindAll(limit int, sort []string) ([]User, error) {
+	return s.find(fmt.Sprintf(`%s%s`, userOrder(sort...), database.Limit(limit)))
+}
+
+// FindPage returns the users matching the filter, starting after the cursor when provided
+func (s *postgresStore) FindPage(filter Filter, after *Cursor, limit int) ([]User, error) {
+	field, desc, _ := filter.SortField()
+	w := byPostgresFilter(filter)
+	if after != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		w.Add(fmt.Sprintf(`%[1]s %[2]s %%[1]s OR (%[1]s = %%[1]s AND %[3]s > %%[2]s)`,
+			userColumn(field), op, userColumn("login")), after.Value, after.Login)
+	}
+	key := field
+	if desc {
+		key = "-" + field
+	}
+	return s.find(fmt.Sprintf(`%s%s%s`, w.String(), userOrder(key), database.Limit(limit)), w.Args...)
+}
+
+// CountBy returns the number of users matching the filter
+func (s *postgresStore) CountBy(filter Filter) (int, error) {
+	w := byPostgresFilter(filter)
+	return s.db.Count(fmt.Sprintf(`SELECT count(*) FROM %s%s`, s.table, w.String()), w.Args...)
+}
+
+func (s *postgresStore) FindByCompany(region, company string) ([]schema.User, error) {
+	var w database.Where
+	byPostgresRegion(&w, region)
+	w.Add(`doc->>'company' = %s`, company)
+	users, err := s.find(fmt.Sprintf(`%s%s`, w.String(), userOrder()), w.Args...)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]schema.User, len(users))
+	for i, u := range users {
+		res[i] = schema.User{Login: u.Login, AvatarURL: u.AvatarURL}
+	}
+	return res, nil
+}
+
+// FindLastCreated returns the last created user that is found by searching the location,
+// or among the users without locations when there is none yet
+func (s *postgresStore) FindLastCreated(location string) (*User, error) {
+	users, err := s.find(fmt.Sprintf(`WHERE doc->'locations' @> jsonb_build_array($1::text)%s LIMIT 1`,
+		userOrder("-createdAt")), location)
+	if err == nil && len(users) == 0 {
+		users, err = s.find(fmt.Sprintf(`WHERE jsonb_array_length(%s) = 0%s LIMIT 1`,
+			database.JSONArray("doc->'locations'"), userOrder("-createdAt")))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, apperr.ErrNotFound
+	}
+	return &users[0], nil
+}
+
+func (s *postgresStore) PickLogin() ([]string, error) {
+	var logins []string
+	err := s.db.Rows(func(rows *sql.Rows) error {
+		var login string
+		if err := rows.Scan(&login); err != nil {
+			return err
+		}
+		logins = append(logins, login)
+		return nil
+	}, fmt.Sprintf(`SELECT id FROM %s%s`, s.table, userOrder()))
+	return logins, err
+}
+
+func (s *postgresStore) Upsert(user github.User) error {
+	return s.db.Upsert(s.table, fence.Fence{}, database.Doc{
+		ID:  user.Login,
+		Set: user.BSON(),
+	})
+}
+
+// BulkUpsert upserts the users, tagging them with the region and the location searched.
+// Under a lease, no user is written when one was last written under a newer token of the
+// lease, and fence.ErrStale is returned instead
+func (s *postgresStore) BulkUpsert(f fence.Fence, users []github.User, region, location string) error {
+	docs := make([]database.Doc, len(users))
+	for i, user := range users {
+		set := user.BSON()
+		set["region"] = region
+		docs[i] = database.Doc{
+			ID:       user.Login,
+			Set:      set,
+			AddToSet: map[string]interface{}{"locations": location},
+		}
+	}
+	return s.db.Upsert(s.table, f, docs...)
+}
+
+// BulkUpdate sets the profile of the users, with the same fencing as BulkUpsert. As with
+// the mongo store, the keywords, languages and matches are only replaced when there are some
+func (s *postgresStore) BulkUpdate(f fence.Fence, users []User) error {
+	docs := make([]database.Doc, len(users))
+	for i, user := range users {
+		set := user.Profile.BSON()
+		set["login"] = user.Login
+		docs[i] = database.Doc{ID: user.Login, Set: set}
+	}
+	return s.db.Upsert(s.table, f, docs...)
+}
+
+// BulkUpdateMatches sets the matches of the users, leaving the rest of the profile as it is.
+// Under a lease, no user is written when one is not matched, being last written under a
+// newer token of the lease, and fence.ErrStale is returned instead
+func (s *postgresStore) BulkUpdateMatches(f fence.Fence, users []User) error {
+	return s.db.Tx(func(tx *sql.Tx) error {
+		for _, user := range users {
+			set := map[string]interface{}{
+				"matches":   user.Matches,
+				"updatedAt": moment.NewUTCDate(),
+			}
+			if !f.IsZero() {
+				set["fence"] = f
+			}
+			var w database.Where
+			doc, err := w.JSONArg(set)
+			if err != nil {
+				return err
+			}
+			w.Add(`id = %s`, user.Login)
+			w.ByFence(f, "doc")
+			res, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET doc = doc || %s%s`, s.table, doc, w.String()), w.Args...)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return err
+			} else if n == 0 && !f.IsZero() {
+				return fence.ErrStale
+			}
+		}
+		return nil
+	})
+}
+
+func (s *postgresStore) Count(region string) (int, error) {
+	var w database.Where
+	byPostgresRegion(&w, region)
+	return s.db.Count(fmt.Sprintf(`SELECT count(*) FROM %s%s`, s.table, w.String()), w.Args...)
+}
+
+func (s *postgresStore) Drop() error {
+	_, err := s.db.Exec(fmt.Sprintf(`TRUNCATE %s`, s.table))
+	return err
+}
+
+func (s *postgresStore) UpdateOne(login string) error {
+	return s.db.Upsert(s.table, fence.Fence{}, database.Doc{
+		ID: login,
+		Set: map[string]interface{}{
+			"login":     login,
+			"fetchedAt": moment.NewUTCDate(),
+		},
+	})
+}
+
+func (s *postgresStore) WithRepos(count int) ([]User, error) {
+	return s.find(fmt.Sprintf(`WHERE %s > $1%s`, userColumn("repositories"), userOrder()), count)
+}
+
+func (s *postgresStore) DistinctCompany(region string) ([]string, error) {
+	var w database.Where
+	byPostgresRegion(&w, region)
+	var res []string
+	err := s.db.Rows(func(rows *sql.Rows) error {
+		var company string
+		if err := rows.Scan(&company); err != nil {
+			return err
+		}
+		res = append(res, company)
+		return nil
+	}, fmt.Sprintf(`SELECT DISTINCT COALESCE(doc->>'company', '') FROM %s%s`, s.table, w.String()), w.Args...)
+	sort.Strings(res)
+	return res, err
+}
+
+// AggregateCompany counts the users of each company, leaving out the placeholders for no
+// company, and returns the companies with at least min and less than max users
+func (s *postgresStore) AggregateCompany(region string, min, max int) ([]schema.Company, error) {
+	var w database.Where
+	byPostgresRegion(&w, region)
+	w.Add(`doc->>'company' NOT IN ('-', 'none', 'None', '')`)
+	having := fmt.Sprintf(` HAVING count(*) >= %s AND count(*) < %s`, w.Arg(min), w.Arg(max))
+
+	var companies []schema.Company
+	err := s.db.Rows(func(rows *sql.Rows) error {
+		var c schema.Company
+		if err := rows.Scan(&c.Company, &c.Count); err != nil {
+			return err
+		}
+		companies = append(companies, c)
+		return nil
+	}, fmt.Sprintf(`SELECT doc->>'company', count(*) FROM %s%s GROUP BY doc->>'company'%s ORDER BY count(*) DESC, (doc->>'company') COLLATE "C"`,
+		s.table, w.String(), having), w.Args...)
+	return companies, err
+}
+
+// find returns the users of the rest of the query, after the select
+func (s *postgresStore) find(query string, args ...interface{}) ([]User, error) {
+	var users []User
+	if err := s.db.Find(&users, fmt.Sprintf(`SELECT doc FROM %s %s`, s.table, query), args...); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// byPostgresFilter builds the conditions of the filter, the equivalent of byFilter. The
+// users without the sort field are left out
+func byPostgresFilter(f Filter) *database.Where {
+	var w database.Where
+	byPostgresRegion(&w, f.Region)
+	if f.Company != "" {
+		w.Add(`doc->>'company' = %s`, f.Company)
+	}
+	if f.Location != "" {
+		w.Add(`doc->'locations' @> jsonb_build_array(%s::text)`, f.Location)
+	}
+	if f.Language != "" {
+		w.Add(`doc->'languages' @> jsonb_build_array(jsonb_build_object('name', %s::text))`, f.Language)
+	}
+	if f.Keyword != "" {
+		w.Add(`doc->'keywords' @> jsonb_build_array(jsonb_build_object('key', %s::text))`, f.KeywordKey())
+	}
+	if f.CreatedFrom != "" {
+		w.Add(userColumn("createdAt")+" >= %s", f.CreatedFrom)
+	}
+	if f.CreatedTo != "" {
+		w.Add(userColumn("createdAt")+" <= %s", f.CreatedTo+"T23:59:59Z")
+	}
+	byPostgresMinMax(&w, "followers", f.MinFollowers, f.MaxFollowers)
+	byPostgresMinMax(&w, "repositories", f.MinRepos, f.MaxRepos)
+	if field, _, ok := f.SortField(); ok {
+		w.Add(userColumn(field) + " IS NOT NULL")
+	}
+	return &w
+}
+
+func byPostgresRegion(w *database.Where, region string) {
+	if region != "" {
+		w.Add(`doc->>'region' = %s`, region)
+	}
+}
+
+// byPostgresMinMax matches the counts of the field between min and max (inclusive), where
+// zero is not applied
+func byPostgresMinMax(w *database.Where, field string, min, max int64) {
+	if min > 0 {
+		w.Add(userColumn(field)+" >= %s", min)
+	}
+	if max > 0 {
+		w.Add(userColumn(field)+" <= %s", max)
+	}
+}
+
+// userColumn returns the expression of the field of the user, by the name of the field in
+// mongo
+func userColumn(field string) string {
+	switch field {
+	case "login":
+		return `id COLLATE "C"`
+	case "followers", "following", "stargazers", "repositories", "gists", "watchers", "forks":
+		return database.JSONInt(field)
+	}
+	return database.JSONText(field)
+}
+
+// userOrder returns the order by clause of the sort keys, then of the login
+func userOrder(keys ...string) string {
+	terms := make([]string, 0, len(keys)+1)
+	for _, key := range append(append([]string{}, keys...), "login") {
+		desc := strings.HasPrefix(key, "-")
+		terms = append(terms, database.OrderBy(userColumn(strings.TrimPrefix(key, "-")), desc))
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
@@ -6,22 +6,26 @@ import (
 
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/constant"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
 )
 
 // Service represents the model of the user
 type Service interface {
-	FindLastCreated(ctx context.Context) (string, bool)
-	BulkUpsert(ctx context.Context, users []github.User) error
+	FindLastCreated(ctx context.Context, location string) (string, bool)
+	BulkUpsert(ctx context.Context, users []github.User, region, location string) error
 	FindLastFetched(ctx context.Context, limit int) ([]User, error)
 	UpdateOne(ctx context.Context, login string) error
-	Count(ctx context.Context) (int, error)
+	Count(ctx context.Context, region string) (int, error)
 	BulkUpdate(ctx context.Context, users []User) error
+	BulkUpdateMatches(ctx context.Context, users []User) error
 	WithRepos(ctx context.Context, count int) ([]User, error)
-	DistinctCompany(ctx context.Context) ([]string, error)
-	FindByCompany(ctx context.Context, company string) ([]schema.User, error)
-	AggregateCompany(ctx context.Context, min, max int) ([]schema.Company, error)
+	DistinctCompany(ctx context.Context, region string) ([]string, error)
+	FindByCompany(ctx context.Context, region, company string) ([]schema.User, error)
+	AggregateCompany(ctx context.Context, region string, min, max int) ([]schema.Company, error)
 	FindOne(ctx context.Context, login string) (*User, error)
+	List(ctx context.Context, filter Filter, cursor string, limit int) (*Page, error)
+	CountBy(ctx context.Context, filter Filter) (int, error)
 }
 
 type service struct {
@@ -33,8 +37,8 @@ func NewService(m Model) Service {
 	return &service{m}
 }
 
-func (s *service) FindLastCreated(ctx context.Context) (string, bool) {
-	user, err := s.model.FindLastCreated()
+func (s *service) FindLastCreated(ctx context.Context, location string) (string, bool) {
+	user, err := s.model.FindLastCreated(location)
 	if err != nil || user == nil {
 		return constant.GithubCreatedAt, false
 	}
@@ -46,8 +50,8 @@ func (s *service) FindLastCreated(ctx context.Context) (string, bool) {
 	return t.Format("2006-01-02"), true
 }
 
-func (s *service) BulkUpsert(ctx context.Context, users []github.User) error {
-	return s.model.BulkUpsert(users)
+func (s *service) BulkUpsert(ctx context.Context, users []github.User, region, location string) error {
+	return s.model.BulkUpsert(fence.FromContext(ctx), users, region, location)
 }
 
 func (s *service) FindLastFetched(ctx context.Context, limit int) ([]User, error) {
@@ -58,30 +62,43 @@ func (s *service) UpdateOne(ctx context.Context, login string) error {
 	return s.model.UpdateOne(login)
 }
 
-func (s *service) Count(ctx context.Context) (int, error) {
-	return s.model.Count()
+func (s *service) Count(ctx context.Context, region string) (int, error) {
+	return s.model.Count(region)
 }
 
 func (s *service) BulkUpdate(ctx context.Context, users []User) error {
-	return s.model.BulkUpdate(users)
+	return s.model.BulkUpdate(fence.FromContext(ctx), users)
+}
+
+func (s *service) BulkUpdateMatches(ctx context.Context, users []User) error {
+	return s.model.BulkUpdateMatches(fence.FromContext(ctx), users)
 }
 
 func (s *service) WithRepos(ctx context.Context, count int) ([]User, error) {
 	return s.model.WithRepos(count)
 }
 
-func (s *service) DistinctCompany(ctx context.Context) ([]string, error) {
-	return s.model.DistinctCompany()
+func (s *service) DistinctCompany(ctx context.Context, region string) ([]string, error) {
+	return s.model.DistinctCompany(region)
 }
 
-func (s *service) FindByCompany(ctx context.Context, company string) ([]schema.User, error) {
-	return s.model.FindByCompany(company)
+func (s *service) FindByCompany(ctx context.Context, region, company string) ([]schema.User, error) {
+	return s.model.FindByCompany(region, company)
 }
 
-func (s *service) AggregateCompany(ctx context.Context, min, max int) ([]schema.Company, error) {
-	return s.model.AggregateCompany(min, max)
+func (s *service) AggregateCompany(ctx context.Context, region string, min, max int) ([]schema.Company, error) {
+	return s.model.AggregateCompany(region, min, max)
 }
 
 func (s *service) FindOne(ctx context.Context, login string) (*User, error) {
 	return s.model.FindOne(login)
 }
+
+func (s *service) List(ctx context.Context, filter Filter, cursor string, limit int) (*Page, error) {
+	return s.model.List(filter, cursor, limit)
+}
+
+// CountBy returns the number of users matching the filter, without listing them
+func (s *service) CountBy(ctx context.Context, filter Filter) (int, error) {
+	return s.model.CountBy(filter)
+}
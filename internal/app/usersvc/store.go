@@ -3,6 +3,7 @@ package usersvc
 import (
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/moment"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/partitioner"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/schema"
@@ -14,15 +15,17 @@ import (
 type (
 	// Read represents the read interface for the store
 	Read interface {
-		AggregateCompany(min, max int) ([]schema.Company, error)
-		Count() (int, error)
+		AggregateCompany(region string, min, max int) ([]schema.Company, error)
+		Count(region string) (int, error)
 		FindAll(limit int, sort []string) ([]User, error)
-		FindByCompany(company string) ([]schema.User, error)
-		FindLastCreated() (*User, error)
+		FindPage(filter Filter, after *Cursor, limit int) ([]User, error)
+		CountBy(filter Filter) (int, error)
+		FindByCompany(region, company string) ([]schema.User, error)
+		FindLastCreated(location string) (*User, error)
 		FindOne(login string) (*User, error)
 		PickLogin() ([]string, error)
 		WithRepos(count int) ([]User, error)
-		DistinctCompany() ([]string, error)
+		DistinctCompany(region string) ([]string, error)
 	}
 
 	// Write represents the write interface for the store
@@ -31,8 +34,9 @@ type (
 		UpdateOne(login string) error
 		Upsert(github.User) error
 		Init() error
-		BulkUpsert(users []github.User) error
-		BulkUpdate(users []User) error
+		BulkUpsert(f fence.Fence, users []github.User, region, location string) error
+		BulkUpdate(f fence.Fence, users []User) error
+		BulkUpdateMatches(f fence.Fence, users []User) error
 	}
 
 	// Store provides the interface for the Service struct
@@ -61,10 +65,20 @@ func (s *store) Init() error {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
-	return c.EnsureIndex(mgo.Index{
+	if err := c.EnsureIndex(mgo.Index{
 		Key:    []string{"login"},
 		Unique: true,
-	})
+	}); err != nil {
+		return err
+	}
+
+	// Indexes for the sort fields of the paginated users, with the login to break ties
+	for _, field := range sortFields {
+		if err := c.EnsureIndexKey("-"+field, "login"); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *store) FindOne(login string) (*User, error) {
@@ -94,28 +108,60 @@ func (s *store) FindAll(limit int, sort []string) ([]User, error) {
 	return users, nil
 }
 
-func (s *store) FindByCompany(company string) ([]schema.User, error) {
+// FindPage returns the users matching the filter, starting after the cursor when provided
+func (s *store) FindPage(filter Filter, after *Cursor, limit int) ([]User, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	query, sort := byPage(filter, after)
+	var users []User
+	if err := c.Find(query).
+		Sort(sort...).
+		Limit(limit).
+		All(&users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CountBy returns the number of users matching the filter
+func (s *store) CountBy(filter Filter) (int, error) {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+	return c.Find(byFilter(filter)).Count()
+}
+
+func (s *store) FindByCompany(region, company string) ([]schema.User, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
 	var users []schema.User
-	if err := c.Find(bson.M{
+	if err := c.Find(byRegion(region, bson.M{
 		"company": company,
-	}).
+	})).
 		All(&users); err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-func (s *store) FindLastCreated() (*User, error) {
+// FindLastCreated returns the last created user that is found by searching the location. Users
+// that were crawled before the locations were recorded have none, and are used instead when no
+// user is found by the location yet
+func (s *store) FindLastCreated(location string) (*User, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
 	var user User
-	if err := c.Find(bson.M{}).
-		Sort("-$createdAt").
-		One(&user); err != nil {
+	err := c.Find(bson.M{"locations": location}).
+		Sort("-createdAt").
+		One(&user)
+	if err == mgo.ErrNotFound {
+		err = c.Find(bson.M{"locations": bson.M{"$exists": false}}).
+			Sort("-createdAt").
+			One(&user)
+	}
+	if err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -126,24 +172,8 @@ func (s *store) PickLogin() ([]string, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
-	pipeline := []bson.M{
-		bson.M{
-			"$group": bson.M{
-				"_id": nil,
-				"logins": bson.M{
-					"$push": "$login",
-				},
-			},
-		},
-		bson.M{
-			"$project": bson.M{
-				"items": "$logins",
-			},
-		},
-	}
-
 	var i Logins
-	if err := c.Pipe(pipeline).One(&i); err != nil {
+	if err := c.Pipe(loginsPipeline()).One(&i); err != nil {
 		return i.Items, err
 	}
 	return i.Items, nil
@@ -164,7 +194,11 @@ func (s *store) Upsert(user github.User) error {
 	return nil
 }
 
-func (s *store) BulkUpsert(users []github.User) error {
+// BulkUpsert upserts the users, tagging them with the region and the location searched
+// BulkUpsert upserts the users, tagging them with the region and the location searched.
+// Under a lease, the users last written under a newer token of the lease are kept, and
+// fence.ErrStale is returned instead
+func (s *store) BulkUpsert(f fence.Fence, users []github.User, region, location string) error {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
@@ -177,15 +211,21 @@ func (s *store) BulkUpsert(users []github.User) error {
 
 		bulk := c.Bulk()
 		for _, user := range users[p.Start:p.End] {
+			doc := user.BSON()
+			doc["region"] = region
 			bulk.Upsert(
-				bson.M{"login": user.Login},
+				byFence(f, bson.M{"login": user.Login}, doc),
 				bson.M{
-					"$set": user.BSON(),
+					"$set":      doc,
+					"$addToSet": bson.M{"locations": location},
 				},
 			)
 		}
 
 		if _, err := bulk.Run(); err != nil {
+			if mgo.IsDup(err) {
+				return fence.ErrStale
+			}
 			return err
 		}
 	}
@@ -193,7 +233,8 @@ func (s *store) BulkUpsert(users []github.User) error {
 	return nil
 }
 
-func (s *store) BulkUpdate(users []User) error {
+// BulkUpdate sets the profile of the users, with the same fencing as BulkUpsert
+func (s *store) BulkUpdate(f fence.Fence, users []User) error {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
@@ -206,14 +247,18 @@ func (s *store) BulkUpdate(users []User) error {
 
 		bulk := c.Bulk()
 		for _, user := range users[p.Start:p.End] {
+			doc := user.Profile.BSON()
 			bulk.Upsert(
-				bson.M{"login": user.Login},
+				byFence(f, bson.M{"login": user.Login}, doc),
 				bson.M{
-					"$set": user.Profile.BSON(),
+					"$set": doc,
 				},
 			)
 		}
 		if _, err := bulk.Run(); err != nil {
+			if mgo.IsDup(err) {
+				return fence.ErrStale
+			}
 			return err
 		}
 	}
@@ -221,10 +266,48 @@ func (s *store) BulkUpdate(users []User) error {
 	return nil
 }
 
-func (s *store) Count() (int, error) {
+// BulkUpdateMatches sets the matches of the users, leaving the rest of the profile as it is.
+// Under a lease, the users not matched, which were last written under a newer token of the
+// lease, are kept and fence.ErrStale is returned
+func (s *store) BulkUpdateMatches(f fence.Fence, users []User) error {
+	sess, c := s.db.Collection(s.collection)
+	defer sess.Close()
+
+	perBulk := 500
+	partitions, bucket := partitioner.New(perBulk, len(users))
+
+	for i := 0; i < bucket; i++ {
+		p := partitions[i]
+
+		bulk := c.Bulk()
+		for _, user := range users[p.Start:p.End] {
+			doc := bson.M{
+				"matches":   user.Matches,
+				"updatedAt": moment.NewUTCDate(),
+			}
+			bulk.Update(
+				byFence(f, bson.M{"login": user.Login}, doc),
+				bson.M{
+					"$set": doc,
+				},
+			)
+		}
+		res, err := bulk.Run()
+		if err != nil {
+			return err
+		}
+		if !f.IsZero() && res.Matched < p.End-p.Start {
+			return fence.ErrStale
+		}
+	}
+
+	return nil
+}
+
+func (s *store) Count(region string) (int, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
-	return c.Count()
+	return c.Find(byRegion(region, bson.M{})).Count()
 }
 
 func (s *store) Drop() error {
@@ -264,29 +347,170 @@ func (s *store) WithRepos(count int) ([]User, error) {
 	return users, nil
 }
 
-func (s *store) DistinctCompany() ([]string, error) {
+func (s *store) DistinctCompany(region string) ([]string, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 	var res []string
-	if err := c.Find(nil).Distinct("company", &res); err != nil {
+	if err := c.Find(byRegion(region, bson.M{})).Distinct("company", &res); err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-func (s *store) AggregateCompany(min, max int) ([]schema.Company, error) {
+func (s *store) AggregateCompany(region string, min, max int) ([]schema.Company, error) {
 	sess, c := s.db.Collection(s.collection)
 	defer sess.Close()
 
-	pipeline := []bson.M{
+	var companies []schema.Company
+	if err := c.Pipe(companyPipeline(region, min, max)).All(&companies); err != nil {
+		return nil, err
+	}
+	return companies, nil
+}
+
+// byFence adds the fence to the query of the user, and to the fields set. Under a lease,
+// the user last written under a newer token of the lease is not matched
+func byFence(f fence.Fence, query, data bson.M) bson.M {
+	if !f.IsZero() {
+		query["$or"] = database.ByFence(f)
+		data["fence"] = f
+	}
+	return query
+}
+
+// byRegion adds the region to the query, or leaves the query as it is for all regions
+func byRegion(region string, query bson.M) bson.M {
+	if region != "" {
+		query["region"] = region
+	}
+	return query
+}
+
+// byFilter builds the query for the filter. Users without the sort field, such as users
+// whose profile is not computed yet, are excluded so that the pagination is stable
+func byFilter(f Filter) bson.M {
+	query := byRegion(f.Region, bson.M{})
+	if f.Company != "" {
+		query["company"] = f.Company
+	}
+	if f.Location != "" {
+		query["locations"] = f.Location
+	}
+	if f.Language != "" {
+		query["languages.name"] = f.Language
+	}
+	if f.Keyword != "" {
+		query["keywords._id"] = f.KeywordKey()
+	}
+	if r := byRange(f.CreatedFrom, f.CreatedTo); r != nil {
+		query["createdAt"] = r
+	}
+	if r := byMinMax(f.MinFollowers, f.MaxFollowers); r != nil {
+		query["followers"] = r
+	}
+	if r := byMinMax(f.MinRepos, f.MaxRepos); r != nil {
+		query["repositories"] = r
+	}
+	if field, _, ok := f.SortField(); ok {
+		if _, exists := query[field]; !exists {
+			query[field] = bson.M{"$exists": true}
+		}
+	}
+	return query
+}
+
+// byPage builds the query and the sort of the page of users after the cursor, with the login
+// to break the ties
+func byPage(filter Filter, after *Cursor) (bson.M, []string) {
+	field, desc, _ := filter.SortField()
+	order := field
+	if desc {
+		order = "-" + field
+	}
+
+	query := byFilter(filter)
+	if after != nil {
+		op := "$gt"
+		if desc {
+			op = "$lt"
+		}
+		query = bson.M{
+			"$and": []bson.M{
+				query,
+				bson.M{
+					"$or": []bson.M{
+						bson.M{field: bson.M{op: after.Value}},
+						bson.M{field: after.Value, "login": bson.M{"$gt": after.Login}},
+					},
+				},
+			},
+		}
+	}
+
+	return query, []string{order, "login"}
+}
+
+// byRange matches the RFC3339 dates that fall within the days from and to (inclusive)
+func byRange(from, to string) bson.M {
+	if from == "" && to == "" {
+		return nil
+	}
+	r := bson.M{}
+	if from != "" {
+		r["$gte"] = from
+	}
+	if to != "" {
+		r["$lte"] = to + "T23:59:59Z"
+	}
+	return r
+}
+
+// byMinMax matches the counts between min and max (inclusive), where zero is not applied
+func byMinMax(min, max int64) bson.M {
+	if min == 0 && max == 0 {
+		return nil
+	}
+	r := bson.M{}
+	if min > 0 {
+		r["$gte"] = min
+	}
+	if max > 0 {
+		r["$lte"] = max
+	}
+	return r
+}
+
+// loginsPipeline collects the logins of all users in the items of a single document
+func loginsPipeline() []bson.M {
+	return []bson.M{
 		bson.M{
-			"$match": bson.M{
+			"$group": bson.M{
+				"_id": nil,
+				"logins": bson.M{
+					"$push": "$login",
+				},
+			},
+		},
+		bson.M{
+			"$project": bson.M{
+				"items": "$logins",
+			},
+		},
+	}
+}
+
+// companyPipeline counts the users of the region by company, leaving out the placeholders for
+// no company, and keeps the companies with at least min and less than max users
+func companyPipeline(region string, min, max int) []bson.M {
+	return []bson.M{
+		bson.M{
+			"$match": byRegion(region, bson.M{
 				"company": bson.M{
 					"$exists": true,
 					"$nin":    []string{"-", "none", "None", ""}, // NOTE: Excluding empty strings does not work
 					"$ne":     "",
 				},
-			},
+			}),
 		},
 		bson.M{
 			"$group": bson.M{
@@ -318,9 +542,4 @@ func (s *store) AggregateCompany(min, max int) ([]schema.Company, error) {
 			},
 		},
 	}
-	var companies []schema.Company
-	if err := c.Pipe(pipeline).All(&companies); err != nil {
-		return nil, err
-	}
-	return companies, nil
 }
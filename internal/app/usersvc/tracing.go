@@ -21,20 +21,25 @@ type tracingMiddleware struct {
 	service Service
 }
 
-func (m *tracingMiddleware) FindLastCreated(ctx context.Context) (string, bool) {
+func (m *tracingMiddleware) FindLastCreated(ctx context.Context, location string) (string, bool) {
 	ctx, span := trace.StartSpan(ctx, "FindLastCreated")
 	defer span.End()
 
-	return m.service.FindLastCreated(ctx)
+	span.AddAttributes(trace.StringAttribute("location", location))
+
+	return m.service.FindLastCreated(ctx, location)
 }
 
-func (m *tracingMiddleware) BulkUpsert(ctx context.Context, users []github.User) error {
+func (m *tracingMiddleware) BulkUpsert(ctx context.Context, users []github.User, region, location string) error {
 	ctx, span := trace.StartSpan(ctx, "BulkUpsert")
 	defer span.End()
 
-	span.AddAttributes(trace.Int64Attribute("perPage", int64(len(users))))
+	span.AddAttributes(
+		trace.Int64Attribute("perPage", int64(len(users))),
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("location", location))
 
-	return m.service.BulkUpsert(ctx, users)
+	return m.service.BulkUpsert(ctx, users, region, location)
 }
 
 func (m *tracingMiddleware) FindLastFetched(ctx context.Context, limit int) ([]User, error) {
@@ -55,11 +60,13 @@ func (m *tracingMiddleware) UpdateOne(ctx context.Context, login string) error {
 	return m.service.UpdateOne(ctx, login)
 }
 
-func (m *tracingMiddleware) Count(ctx context.Context) (int, error) {
+func (m *tracingMiddleware) Count(ctx context.Context, region string) (int, error) {
 	ctx, span := trace.StartSpan(ctx, "ctx")
 	defer span.End()
 
-	return m.service.Count(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.Count(ctx, region)
 }
 
 func (m *tracingMiddleware) BulkUpdate(ctx context.Context, users []User) error {
@@ -71,6 +78,15 @@ func (m *tracingMiddleware) BulkUpdate(ctx context.Context, users []User) error
 	return m.service.BulkUpdate(ctx, users)
 }
 
+func (m *tracingMiddleware) BulkUpdateMatches(ctx context.Context, users []User) error {
+	ctx, span := trace.StartSpan(ctx, "BulkUpdateMatches")
+	defer span.End()
+
+	span.AddAttributes(trace.Int64Attribute("count", int64(len(users))))
+
+	return m.service.BulkUpdateMatches(ctx, users)
+}
+
 func (m *tracingMiddleware) WithRepos(ctx context.Context, count int) ([]User, error) {
 	ctx, span := trace.StartSpan(ctx, "WithRepos")
 	defer span.End()
@@ -80,31 +96,36 @@ func (m *tracingMiddleware) WithRepos(ctx context.Context, count int) ([]User, e
 	return m.service.WithRepos(ctx, count)
 }
 
-func (m *tracingMiddleware) DistinctCompany(ctx context.Context) ([]string, error) {
+func (m *tracingMiddleware) DistinctCompany(ctx context.Context, region string) ([]string, error) {
 	ctx, span := trace.StartSpan(ctx, "DistinctCompany")
 	defer span.End()
 
-	return m.service.DistinctCompany(ctx)
+	span.AddAttributes(trace.StringAttribute("region", region))
+
+	return m.service.DistinctCompany(ctx, region)
 }
 
-func (m *tracingMiddleware) FindByCompany(ctx context.Context, company string) ([]schema.User, error) {
+func (m *tracingMiddleware) FindByCompany(ctx context.Context, region, company string) ([]schema.User, error) {
 	ctx, span := trace.StartSpan(ctx, "FindByCompany")
 	defer span.End()
 
-	span.AddAttributes(trace.StringAttribute("company", company))
+	span.AddAttributes(
+		trace.StringAttribute("region", region),
+		trace.StringAttribute("company", company))
 
-	return m.service.FindByCompany(ctx, company)
+	return m.service.FindByCompany(ctx, region, company)
 }
 
-func (m *tracingMiddleware) AggregateCompany(ctx context.Context, min, max int) ([]schema.Company, error) {
+func (m *tracingMiddleware) AggregateCompany(ctx context.Context, region string, min, max int) ([]schema.Company, error) {
 	ctx, span := trace.StartSpan(ctx, "AggregateCompany")
 	defer span.End()
 
 	span.AddAttributes(
+		trace.StringAttribute("region", region),
 		trace.Int64Attribute("min", int64(min)),
 		trace.Int64Attribute("max", int64(max)))
 
-	return m.service.AggregateCompany(ctx, min, max)
+	return m.service.AggregateCompany(ctx, region, min, max)
 }
 
 func (m *tracingMiddleware) FindOne(ctx context.Context, login string) (*User, error) {
@@ -115,3 +136,27 @@ func (m *tracingMiddleware) FindOne(ctx context.Context, login string) (*User, e
 
 	return m.service.FindOne(ctx, login)
 }
+
+func (m *tracingMiddleware) List(ctx context.Context, filter Filter, cursor string, limit int) (*Page, error) {
+	ctx, span := trace.StartSpan(ctx, "List")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", filter.Region),
+		trace.StringAttribute("sort", filter.Sort),
+		trace.StringAttribute("cursor", cursor),
+		trace.Int64Attribute("limit", int64(limit)))
+
+	return m.service.List(ctx, filter, cursor, limit)
+}
+
+func (m *tracingMiddleware) CountBy(ctx context.Context, filter Filter) (int, error) {
+	ctx, span := trace.StartSpan(ctx, "CountBy")
+	defer span.End()
+
+	span.AddAttributes(
+		trace.StringAttribute("region", filter.Region),
+		trace.StringAttribute("sort", filter.Sort))
+
+	return m.service.CountBy(ctx, filter)
+}
@@ -4,9 +4,13 @@ import (
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
 )
 
-// New returns a new user service
+// New returns a new user service backed by mgo
 func New(db *database.DB, m ...Middleware) Service {
-	store := NewStore(db, database.Users)
+	return NewWithStore(NewStore(db, database.Users), m...)
+}
+
+// NewWithStore returns a new user service backed by the store of any storage driver
+func NewWithStore(store Store, m ...Middleware) Service {
 	model := NewModel(store)
 	service := NewService(model)
 	service = Decorate(service, m...)
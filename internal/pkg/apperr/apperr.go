@@ -0,0 +1,34 @@
+// Package apperr defines the kinds of errors returned by the services, so that the
+// transport can answer them with the corresponding status codes
+package apperr
+
+import "errors"
+
+// ErrNotFound is returned by the stores when the record requested does not exist. Every
+// storage driver returns it, except for mgo which returns mgo.ErrNotFound
+var ErrNotFound = errors.New("not found")
+
+// ValidationError is returned when the input provided fails the validation
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Validation returns a new validation error with the message
+func Validation(msg string) error {
+	return &ValidationError{Message: msg}
+}
+
+// IsValidation returns true if the error is caused by an invalid input
+func IsValidation(err error) bool {
+	_, ok := err.(*ValidationError)
+	return ok
+}
+
+// IsNotFound returns true if the record requested does not exist
+func IsNotFound(err error) bool {
+	return err == ErrNotFound
+}
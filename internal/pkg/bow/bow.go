@@ -1,38 +1,13 @@
 // Package bow is an application that performs bag of words
 package bow
 
-import (
-	"log"
-	"regexp"
-	"sort"
-	"strings"
-)
+import "sort"
 
-var stopwords string
-
-var r *regexp.Regexp
-
-func init() {
-	reg, err := regexp.Compile("[^a-zA-Z0-9]+")
-	if err != nil {
-		log.Fatal(err)
-	}
-	r = reg
-
-	stopwords = strings.Join([]string{"i", "me", "my", "myself", "we", "our", "ours", "ourselves", "you", "your", "yours", "yourself", "yourselves", "he", "him", "his", "himself", "she", "her", "hers", "herself", "it", "its", "itself", "they", "them", "their", "theirs", "themselves", "what", "which", "who", "whom", "this", "that", "these", "those", "am", "is", "are", "was", "were", "be", "been", "being", "have", "has", "had", "having", "do", "does", "did", "doing", "a", "an", "the", "and", "but", "if", "or", "because", "as", "until", "while", "of", "at", "by", "for", "with", "about", "against", "between", "into", "through", "during", "before", "after", "above", "below", "to", "from", "up", "down", "in", "out", "on", "off", "over", "under", "again", "further", "then", "once", "here", "there", "when", "where", "why", "how", "all", "any", "both", "each", "few", "more", "most", "other", "some", "such", "no", "nor", "not", "only", "own", "same", "so", "than", "too", "very", "s", "t", "can", "will", "just", "don", "should", "now"}, " ")
-}
-
-// Parse will take an array of strings and return a processed string
+// Parse will take an array of strings and return the words that are not stopwords
 func Parse(rows ...string) (words []string) {
-	for i := 0; i < len(rows); i++ {
-		row := rows[i]
-		s := r.ReplaceAllString(row, " ") // Remove special characters
-		s = strings.ToLower(s)
-		splitWords := strings.Split(s, " ")
-		for _, w := range splitWords {
-			if !strings.Contains(stopwords, w) {
-				words = append(words, w)
-			}
+	for _, row := range rows {
+		for _, t := range Extract(row, Options{}) {
+			words = append(words, t.Text)
 		}
 	}
 	return
@@ -44,7 +19,8 @@ type Dict struct {
 	Value int
 }
 
-// Top will return the top n words based on count
+// Top will return the top n words based on count. Words with the same count are sorted
+// alphabetically
 func Top(words []string, n int) []Dict {
 	kv := make(map[string]int)
 	// Store the values in a map
@@ -60,8 +36,11 @@ func Top(words []string, n int) []Dict {
 		dict = append(dict, Dict{k, v})
 	}
 
-	sort.SliceStable(dict, func(i, j int) bool {
-		return dict[i].Value > dict[j].Value
+	sort.Slice(dict, func(i, j int) bool {
+		if dict[i].Value != dict[j].Value {
+			return dict[i].Value > dict[j].Value
+		}
+		return dict[i].Key < dict[j].Key
 	})
 
 	if len(kv) < n {
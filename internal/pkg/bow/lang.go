@@ -0,0 +1,169 @@
+package bow
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// The languages detected in the text, as ISO 639-1 codes
+const (
+	English    = "en"
+	Malay      = "ms"
+	Indonesian = "id"
+	Chinese    = "zh"
+	Japanese   = "ja"
+	Korean     = "ko"
+)
+
+// profileSize is the number of the most frequent trigrams kept in the language profiles
+const profileSize = 300
+
+// minWords is the number of words below which latin text is assumed to be english, since
+// there are too few trigrams to tell the languages apart
+const minWords = 3
+
+// profiles holds the rank of the trigrams of each latin language, built from the samples
+var profiles = make(map[string]map[string]int)
+
+func init() {
+	for lang, sample := range samples {
+		profiles[lang] = rank(trigrams(sample), profileSize)
+	}
+}
+
+// Detect returns the language of the text. CJK languages are told apart by their scripts.
+// Text is chinese when it has as many han characters as latin letters, so that an english
+// description mentioning a chinese name is still english. Latin text is matched against
+// the trigram profile of each language, the closest profile being the one with the smallest
+// out-of-place distance (Cavnar and Trenkle, 1994). Short text, and text that is as close
+// to english as to the other languages, is english
+func Detect(text string) string {
+	var han, kana, hangul, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.IsLetter(r):
+			latin++
+		}
+	}
+	switch {
+	case hangul > 0 && hangul >= han:
+		return Korean
+	case kana > 0:
+		return Japanese
+	case han > 1 && han >= latin:
+		return Chinese
+	}
+
+	text = strings.ToLower(text)
+	if len(strings.FieldsFunc(text, notLetter)) < minWords {
+		return English
+	}
+	doc := rank(trigrams(text), profileSize)
+
+	best, min := English, distance(doc, profiles[English])
+	for _, lang := range []string{Malay, Indonesian} {
+		if d := distance(doc, profiles[lang]); d < min {
+			best, min = lang, d
+		}
+	}
+	return best
+}
+
+// trigrams counts the trigrams of the words in the text, padded with a space on both ends
+// so that the prefixes and suffixes are counted, e.g. " di" and "kan "
+func trigrams(text string) map[string]int {
+	count := make(map[string]int)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), notLetter) {
+		runes := []rune(" " + w + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			count[string(runes[i:i+3])]++
+		}
+	}
+	return count
+}
+
+// rank returns the rank of the n most frequent trigrams, the most frequent being zero
+func rank(count map[string]int, n int) map[string]int {
+	grams := make([]string, 0, len(count))
+	for g := range count {
+		grams = append(grams, g)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if count[grams[i]] != count[grams[j]] {
+			return count[grams[i]] > count[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > n {
+		grams = grams[:n]
+	}
+	res := make(map[string]int, len(grams))
+	for i, g := range grams {
+		res[g] = i
+	}
+	return res
+}
+
+// distance sums how far each trigram of the document is ranked from the same trigram in
+// the profile. Trigrams missing from the profile get the maximum penalty
+func distance(doc, profile map[string]int) int {
+	var d int
+	for g, i := range doc {
+		j, ok := profile[g]
+		if !ok {
+			d += profileSize
+			continue
+		}
+		if i > j {
+			d += i - j
+		} else {
+			d += j - i
+		}
+	}
+	return d
+}
+
+func notLetter(r rune) bool {
+	return !unicode.IsLetter(r)
+}
+
+// samples are the texts the trigram profiles are built from, written in the register of
+// the repo descriptions
+var samples = map[string]string{
+	English: `A simple and fast web scraper written in Go that collects the users and the repositories
+	of a region. This is a library for building command line tools, with support for configuration
+	files and environment variables. An example of how to deploy the application to the cloud with
+	docker and kubernetes. The project contains my notes and the solutions to the exercises of the
+	course, which are updated when I have the time. It provides a clean interface to the database
+	and handles the connection pool for you. Personal website and blog built with the static site
+	generator, where I share what I have learned about programming and machine learning. A mobile
+	application that helps people find the nearest restaurants and read the reviews of others.
+	Collection of the scripts that I use every day to automate the boring things on my computer.`,
+
+	Malay: `Aplikasi ini dibina untuk membantu pengguna mencari maklumat dengan lebih mudah dan pantas.
+	Projek ini adalah sebahagian daripada kursus pengaturcaraan di universiti, dan kod sumber boleh
+	digunakan oleh sesiapa sahaja. Laman web peribadi saya yang menggunakan penjana laman statik,
+	di mana saya berkongsi pengalaman dan nota tentang pembelajaran mesin. Sistem pengurusan
+	kedai makan yang dibangunkan kerana pelanggan mahu membuat tempahan secara dalam talian.
+	Perpustakaan ringkas untuk menyambung kepada pangkalan data, tetapi ia masih dalam pembangunan.
+	Koleksi skrip yang saya gunakan setiap hari bagi mengautomasikan kerja yang membosankan.
+	Contoh bagaimana hendak memasang aplikasi pada awan dengan bekas. Jangan lupa baca panduan ini
+	sebelum bermula, kerana terdapat beberapa perkara yang perlu dipasang terlebih dahulu.`,
+
+	Indonesian: `Aplikasi ini dibuat untuk membantu pengguna mencari informasi dengan lebih mudah dan cepat.
+	Proyek ini merupakan bagian dari tugas kuliah pemrograman di kampus, dan kode sumbernya bisa
+	dipakai oleh siapa saja. Situs web pribadi saya yang menggunakan generator situs statis, tempat
+	saya berbagi pengalaman dan catatan tentang pembelajaran mesin. Sistem kasir untuk rumah makan
+	yang dikembangkan karena pelanggan ingin memesan secara daring. Pustaka sederhana untuk
+	terhubung ke basis data, tetapi masih dalam tahap pengembangan. Kumpulan skrip yang saya pakai
+	setiap hari untuk mengotomatisasi pekerjaan yang membosankan. Contoh cara memasang aplikasi di
+	server dengan kontainer. Jangan lupa baca panduan ini sebelum mulai, karena ada beberapa hal
+	yang harus dipasang terlebih dahulu. Silakan kirim masukan kalau menemukan kesalahan.`,
+}
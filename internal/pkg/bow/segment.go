@@ -0,0 +1,155 @@
+package bow
+
+import (
+	"strings"
+	"unicode"
+)
+
+// script is the writing system of a rune, as far as the segmentation is concerned
+type script int
+
+const (
+	// latin covers every script that separates the words with spaces, except hangul
+	latin script = iota
+	han
+	hiragana
+	katakana
+	hangul
+)
+
+// koreanParticles are the particles attached to the end of korean nouns, longest first
+var koreanParticles = []string{
+	"에서", "으로", "에게", "까지", "부터", "처럼",
+	"을", "를", "은", "는", "이", "가", "의", "에", "로", "와", "과", "도", "만",
+}
+
+// maxWordLen is the number of characters of the longest word in the chinese dictionary
+const maxWordLen = 4
+
+// chineseWords is the dictionary the runs of han characters are segmented with. It holds
+// the words common in the descriptions of the repos, and the words they are made of
+var chineseWords = makeSet(
+	"网络", "爬虫", "网页", "网站", "数据", "数据库", "数据集", "分析", "数据分析", "挖掘", "可视化", "统计",
+	"算法", "模型", "训练", "预测", "分类", "聚类", "回归", "机器", "学习", "机器学习", "深度", "深度学习",
+	"神经", "神经网络", "人工", "智能", "人工智能", "自然", "语言", "自然语言", "处理", "图像", "识别", "视觉",
+	"计算机", "语音", "文本", "翻译", "推荐", "推荐系统", "搜索", "引擎", "搜索引擎", "检索", "索引", "系统",
+	"管理", "管理系统", "后台", "前端", "后端", "全栈", "服务", "服务器", "服务端", "客户端", "接口", "框架",
+	"组件", "插件", "模板", "模块", "工具", "工具箱", "工具包", "脚本", "命令", "命令行", "终端", "配置",
+	"部署", "运维", "监控", "日志", "测试", "单元", "调试", "性能", "优化", "缓存", "队列", "消息",
+	"分布式", "微服务", "容器", "集群", "计算", "存储", "文件", "上传", "下载", "同步", "异步",
+	"并发", "线程", "进程", "协程", "内存", "安全", "加密", "解密", "认证", "授权", "登录", "注册",
+	"用户", "权限", "密码", "支付", "订单", "商城", "电商", "购物", "博客", "论坛", "社区", "聊天",
+	"即时", "通讯", "视频", "音频", "音乐", "播放器", "直播", "游戏", "地图", "天气", "新闻", "小说",
+	"阅读", "阅读器", "笔记", "文档", "教程", "入门", "实战", "源码", "代码", "开源", "项目", "示例",
+	"例子", "练习", "面试", "题目", "算法题", "刷题", "课程", "作业", "毕业", "设计", "毕业设计", "大学",
+	"学生", "学校", "教育", "考试", "书籍", "电子书", "资料", "资源", "合集", "收集", "整理", "总结",
+	"学习笔记", "知识", "指南", "手册", "规范", "最佳", "实践", "最佳实践", "开发", "开发者", "程序", "程序员",
+	"编程", "编程语言", "编译", "编译器", "解释器", "虚拟机", "操作系统", "内核", "驱动", "嵌入式", "单片机", "硬件",
+	"物联网", "区块链", "智能合约", "钱包", "交易", "股票", "量化", "金融", "银行", "移动", "手机", "安卓",
+	"苹果", "应用", "小程序", "微信", "公众号", "支付宝", "淘宝", "京东", "百度", "阿里", "腾讯", "字节",
+	"知乎", "微博", "豆瓣", "哔哩", "抖音", "界面", "页面", "布局", "样式", "动画", "主题", "图标",
+	"字体", "图片", "照片", "相机", "二维码", "表格", "图表", "报表", "表单", "编辑器", "富文本", "浏览器",
+	"扩展", "下载器", "解析", "解析器", "转换", "生成", "生成器", "自动", "自动化", "批量", "定时", "任务",
+	"调度", "爬取", "抓取", "采集", "代理", "请求", "响应", "协议", "通信", "传输", "连接", "路由",
+	"网关", "负载", "均衡", "负载均衡", "简单", "简易", "快速", "高效", "轻量", "轻量级", "基于", "实现",
+	"使用", "支持", "提供", "包括", "包含", "一个", "一款", "一些", "相关", "常用", "各种", "中文",
+	"英文", "中国", "国内", "个人", "主页", "目的",
+)
+
+func scriptOf(r rune) script {
+	switch {
+	case unicode.Is(unicode.Han, r) || r == '々':
+		return han
+	case unicode.Is(unicode.Hiragana, r):
+		return hiragana
+	case unicode.Is(unicode.Katakana, r) || r == 'ー':
+		return katakana
+	case unicode.Is(unicode.Hangul, r):
+		return hangul
+	}
+	return latin
+}
+
+// segment breaks the chinese and japanese words into terms, and returns false for the words
+// of the other scripts. Since these languages are written without spaces, a word here is a
+// run of characters of the same script. Runs of han characters are split into the longest
+// words of the dictionary from the left, e.g. 网络爬虫框架 gives 网络, 爬虫 and 框架. A
+// compound also gives the words it is made of, so that 机器学习 is found by 学习. The
+// characters between the words of the dictionary are split at the stopwords and kept as
+// one term, as they are mostly names, and single characters are dropped. Runs of katakana,
+// mostly loanwords, are kept whole, while runs of hiragana are dropped
+func segment(word, lang string) ([]string, bool) {
+	runes := []rune(word)
+	switch scriptOf(runes[0]) {
+	case hiragana:
+		return nil, true
+	case katakana:
+		return []string{word}, true
+	case han:
+	default:
+		return nil, false
+	}
+
+	var res []string
+	var unknown []rune
+	flush := func() {
+		if len(unknown) > 1 {
+			res = append(res, string(unknown))
+		}
+		unknown = nil
+	}
+	for i := 0; i < len(runes); {
+		n := maxWordLen
+		if n > len(runes)-i {
+			n = len(runes) - i
+		}
+		for ; n > 1; n-- {
+			if chineseWords[string(runes[i:i+n])] {
+				break
+			}
+		}
+		if n < 2 {
+			if IsStopword(string(runes[i]), lang) {
+				flush()
+			} else {
+				unknown = append(unknown, runes[i])
+			}
+			i++
+			continue
+		}
+		flush()
+		res = append(res, string(runes[i:i+n]))
+		res = append(res, subwords(runes[i:i+n])...)
+		i += n
+	}
+	flush()
+	return res, true
+}
+
+// subwords returns the shorter words of the dictionary found within the compound
+func subwords(compound []rune) []string {
+	var res []string
+	for n := len(compound) - 1; n > 1; n-- {
+		for i := 0; i+n <= len(compound); i++ {
+			if w := string(compound[i : i+n]); chineseWords[w] {
+				res = append(res, w)
+			}
+		}
+	}
+	return res
+}
+
+// stripParticle removes the particle at the end of a korean word, e.g. 파이썬으로 gives 파이썬.
+// At least two syllables are left, so that short words ending in a particle are kept
+func stripParticle(word string) string {
+	runes := []rune(word)
+	if scriptOf(runes[0]) != hangul {
+		return word
+	}
+	for _, p := range koreanParticles {
+		if strings.HasSuffix(word, p) && len(runes)-len([]rune(p)) >= 2 {
+			return strings.TrimSuffix(word, p)
+		}
+	}
+	return word
+}
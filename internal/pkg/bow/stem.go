@@ -0,0 +1,303 @@
+package bow
+
+// Stem reduces the english word to its stem with the Porter stemming algorithm, so that
+// words such as "scraper", "scrapers" and "scraping" are counted as the same term. The
+// word is expected to be lowercased. Words shorter than three letters are kept as they are
+func Stem(word string) string {
+	if len(word) < 3 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	s := &stemmer{b: []byte(word), k: len(word) - 1}
+	s.step1ab()
+	if s.k > 0 {
+		s.step1c()
+		s.step2()
+		s.step3()
+		s.step4()
+		s.step5()
+	}
+	return string(s.b[:s.k+1])
+}
+
+// stemmer holds the word being stemmed. k is the offset to the end of the stem, and j is
+// a general offset into the word
+type stemmer struct {
+	b    []byte
+	k, j int
+}
+
+// cons returns true if the letter at i is a consonant
+func (s *stemmer) cons(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		if i == 0 {
+			return true
+		}
+		return !s.cons(i - 1)
+	}
+	return true
+}
+
+// m measures the number of consonant sequences between 0 and j. With c a consonant
+// sequence and v a vowel sequence, <c><v> gives 0, <c>vc<v> gives 1, <c>vcvc<v> gives 2
+func (s *stemmer) m() int {
+	n, i := 0, 0
+	for {
+		if i > s.j {
+			return n
+		}
+		if !s.cons(i) {
+			break
+		}
+		i++
+	}
+	i++
+	for {
+		for {
+			if i > s.j {
+				return n
+			}
+			if s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+		n++
+		for {
+			if i > s.j {
+				return n
+			}
+			if !s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+	}
+}
+
+// vowelInStem returns true if there is a vowel between 0 and j
+func (s *stemmer) vowelInStem() bool {
+	for i := 0; i <= s.j; i++ {
+		if !s.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doublec returns true if the letters at j-1 and j are the same consonant
+func (s *stemmer) doublec(j int) bool {
+	if j < 1 || s.b[j] != s.b[j-1] {
+		return false
+	}
+	return s.cons(j)
+}
+
+// cvc returns true if the letters at i-2, i-1 and i are consonant, vowel, consonant and the
+// last consonant is not w, x or y. It restores an e at the end of short words, e.g. hop(e)
+func (s *stemmer) cvc(i int) bool {
+	if i < 2 || !s.cons(i) || s.cons(i-1) || !s.cons(i-2) {
+		return false
+	}
+	switch s.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends returns true if the stem ends with the suffix, and sets j to the end of the rest
+func (s *stemmer) ends(suffix string) bool {
+	n := len(suffix)
+	if n > s.k+1 || string(s.b[s.k-n+1:s.k+1]) != suffix {
+		return false
+	}
+	s.j = s.k - n
+	return true
+}
+
+// setTo replaces the letters after j with the suffix, and moves k to the new end
+func (s *stemmer) setTo(suffix string) {
+	s.b = append(s.b[:s.j+1], suffix...)
+	s.k = s.j + len(suffix)
+}
+
+// r replaces the suffix when the rest of the word has a measure greater than zero
+func (s *stemmer) r(suffix string) {
+	if s.m() > 0 {
+		s.setTo(suffix)
+	}
+}
+
+// step1ab removes the plurals, -ed and -ing
+func (s *stemmer) step1ab() {
+	if s.b[s.k] == 's' {
+		switch {
+		case s.ends("sses"):
+			s.k -= 2
+		case s.ends("ies"):
+			s.setTo("i")
+		case s.k > 0 && s.b[s.k-1] != 's':
+			s.k--
+		}
+	}
+	if s.ends("eed") {
+		if s.m() > 0 {
+			s.k--
+		}
+		return
+	}
+	if (s.ends("ed") || s.ends("ing")) && s.vowelInStem() {
+		s.k = s.j
+		switch {
+		case s.ends("at"):
+			s.setTo("ate")
+		case s.ends("bl"):
+			s.setTo("ble")
+		case s.ends("iz"):
+			s.setTo("ize")
+		case s.doublec(s.k):
+			s.k--
+			switch s.b[s.k] {
+			case 'l', 's', 'z':
+				s.k++
+			}
+		default:
+			s.j = s.k
+			if s.m() == 1 && s.cvc(s.k) {
+				s.setTo("e")
+			}
+		}
+	}
+}
+
+// step1c turns a terminal y into i when there is another vowel in the stem
+func (s *stemmer) step1c() {
+	if s.ends("y") && s.vowelInStem() {
+		s.b[s.k] = 'i'
+	}
+}
+
+// step2 maps the double suffixes to single ones, e.g. -ization to -ize
+func (s *stemmer) step2() {
+	if s.k < 1 {
+		return
+	}
+	var rules [][2]string
+	switch s.b[s.k-1] {
+	case 'a':
+		rules = [][2]string{{"ational", "ate"}, {"tional", "tion"}}
+	case 'c':
+		rules = [][2]string{{"enci", "ence"}, {"anci", "ance"}}
+	case 'e':
+		rules = [][2]string{{"izer", "ize"}}
+	case 'l':
+		rules = [][2]string{{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}}
+	case 'o':
+		rules = [][2]string{{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}}
+	case 's':
+		rules = [][2]string{{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"}}
+	case 't':
+		rules = [][2]string{{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}}
+	case 'g':
+		rules = [][2]string{{"logi", "log"}}
+	}
+	s.apply(rules)
+}
+
+// step3 handles -ic-, -full, -ness etc.
+func (s *stemmer) step3() {
+	var rules [][2]string
+	switch s.b[s.k] {
+	case 'e':
+		rules = [][2]string{{"icate", "ic"}, {"ative", ""}, {"alize", "al"}}
+	case 'i':
+		rules = [][2]string{{"iciti", "ic"}}
+	case 'l':
+		rules = [][2]string{{"ical", "ic"}, {"ful", ""}}
+	case 's':
+		rules = [][2]string{{"ness", ""}}
+	}
+	s.apply(rules)
+}
+
+// apply replaces the first suffix that the stem ends with
+func (s *stemmer) apply(rules [][2]string) {
+	for _, rule := range rules {
+		if s.ends(rule[0]) {
+			s.r(rule[1])
+			return
+		}
+	}
+}
+
+// step4 removes -ant, -ence etc. in the context of <c>vcvc<v>
+func (s *stemmer) step4() {
+	if s.k < 1 {
+		return
+	}
+	var suffixes []string
+	switch s.b[s.k-1] {
+	case 'a':
+		suffixes = []string{"al"}
+	case 'c':
+		suffixes = []string{"ance", "ence"}
+	case 'e':
+		suffixes = []string{"er"}
+	case 'i':
+		suffixes = []string{"ic"}
+	case 'l':
+		suffixes = []string{"able", "ible"}
+	case 'n':
+		suffixes = []string{"ant", "ement", "ment", "ent"}
+	case 'o':
+		if s.ends("ion") && s.j >= 0 && (s.b[s.j] == 's' || s.b[s.j] == 't') {
+			if s.m() > 1 {
+				s.k = s.j
+			}
+			return
+		}
+		suffixes = []string{"ou"}
+	case 's':
+		suffixes = []string{"ism"}
+	case 't':
+		suffixes = []string{"ate", "iti"}
+	case 'u':
+		suffixes = []string{"ous"}
+	case 'v':
+		suffixes = []string{"ive"}
+	case 'z':
+		suffixes = []string{"ize"}
+	}
+	for _, suffix := range suffixes {
+		if s.ends(suffix) {
+			if s.m() > 1 {
+				s.k = s.j
+			}
+			return
+		}
+	}
+}
+
+// step5 removes a final -e when the measure is greater than one, and changes -ll to -l
+func (s *stemmer) step5() {
+	s.j = s.k
+	if s.b[s.k] == 'e' {
+		a := s.m()
+		if a > 1 || (a == 1 && !s.cvc(s.k-1)) {
+			s.k--
+		}
+	}
+	if s.b[s.k] == 'l' && s.doublec(s.k) && s.m() > 1 {
+		s.k--
+	}
+}
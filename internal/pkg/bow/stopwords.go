@@ -0,0 +1,86 @@
+package bow
+
+// stopwords are the common words of each language that carry no meaning on their own, and
+// are left out of the terms
+var stopwords = map[string]map[string]bool{
+	English:    englishStopwords,
+	Malay:      malayStopwords,
+	Indonesian: indonesianStopwords,
+	Chinese:    chineseStopwords,
+	Japanese:   japaneseStopwords,
+	Korean:     koreanStopwords,
+}
+
+var englishStopwords = makeSet(
+	"i", "me", "my", "myself", "we", "our", "ours", "ourselves", "you", "your", "yours",
+	"yourself", "yourselves", "he", "him", "his", "himself", "she", "her", "hers", "herself",
+	"it", "its", "itself", "they", "them", "their", "theirs", "themselves", "what", "which",
+	"who", "whom", "this", "that", "these", "those", "am", "is", "are", "was", "were", "be",
+	"been", "being", "have", "has", "had", "having", "do", "does", "did", "doing", "a", "an",
+	"the", "and", "but", "if", "or", "because", "as", "until", "while", "of", "at", "by",
+	"for", "with", "about", "against", "between", "into", "through", "during", "before",
+	"after", "above", "below", "to", "from", "up", "down", "in", "out", "on", "off", "over",
+	"under", "again", "further", "then", "once", "here", "there", "when", "where", "why",
+	"how", "all", "any", "both", "each", "few", "more", "most", "other", "some", "such", "no",
+	"nor", "not", "only", "own", "same", "so", "than", "too", "very", "s", "t", "can", "will",
+	"just", "don", "should", "now", "also", "via", "using", "use", "used", "etc", "like",
+	"yet", "another", "one", "get", "make", "made", "way", "would", "could", "may", "might",
+	"must", "shall", "let", "lets", "within", "without", "us", "re", "ve", "ll", "d", "m",
+)
+
+var malayStopwords = makeSet(
+	"dan", "yang", "di", "ke", "dari", "daripada", "untuk", "dengan", "ini", "itu", "adalah",
+	"ialah", "pada", "dalam", "atau", "tidak", "tak", "akan", "boleh", "juga", "oleh", "saya",
+	"kami", "kita", "anda", "awak", "mereka", "dia", "ia", "telah", "sudah", "sebagai", "bagi",
+	"kepada", "secara", "lebih", "sangat", "hanya", "sahaja", "jika", "kalau", "kerana",
+	"tetapi", "masih", "semua", "setiap", "satu", "sebuah", "seorang", "antara", "tentang",
+	"bila", "apabila", "apa", "mana", "siapa", "ada", "nak", "hendak", "lagi", "pun", "lah",
+	"menggunakan", "guna", "buat", "sini", "situ", "iaitu", "supaya", "agar", "bukan",
+)
+
+var indonesianStopwords = makeSet(
+	"dan", "yang", "di", "ke", "dari", "untuk", "dengan", "ini", "itu", "adalah", "ialah",
+	"pada", "dalam", "atau", "tidak", "tak", "akan", "bisa", "dapat", "juga", "oleh", "saya",
+	"aku", "kami", "kita", "anda", "kamu", "mereka", "dia", "ia", "telah", "sudah", "sebagai",
+	"bagi", "kepada", "secara", "lebih", "sangat", "hanya", "saja", "jika", "kalau", "karena",
+	"tetapi", "tapi", "masih", "semua", "setiap", "satu", "sebuah", "seorang", "antara",
+	"tentang", "apa", "mana", "siapa", "ada", "yaitu", "yakni", "agar", "supaya", "nya",
+	"lagi", "pun", "menggunakan", "pakai", "buat", "sini", "situ", "bukan", "dong", "sih",
+)
+
+// chineseStopwords are the function characters that break the runs of han characters
+// before they are segmented, and the filler words that the segmentation finds
+var chineseStopwords = makeSet(
+	"的", "了", "是", "在", "和", "与", "及", "或", "也", "都", "就", "而", "之", "这", "那",
+	"个", "着", "吗", "呢", "吧", "啊", "把", "被", "让", "给", "从", "向", "很", "还", "我",
+	"你", "他", "她", "它", "们", "其", "此", "该", "等",
+	"基于", "使用", "实现", "支持", "提供", "包括", "包含", "一个", "一款", "一些", "相关", "常用",
+	"各种", "简单", "简易",
+)
+
+// japaneseStopwords are the function words written in kanji or katakana. Runs of hiragana
+// are mostly particles and inflections, and are dropped altogether
+var japaneseStopwords = makeSet(
+	"的", "等", "用", "版", "事", "物", "為", "私", "僕", "者",
+)
+
+var koreanStopwords = makeSet(
+	"이", "그", "저", "것", "수", "등", "및", "위한", "위해", "있는", "하는", "한", "할",
+	"에서", "으로", "그리고", "또는", "하지만", "입니다", "합니다", "있습니다", "사용한", "이용한",
+	"만든", "간단한", "나의", "내", "제",
+)
+
+// IsStopword returns true if the lowercased word is a stopword of the language. English
+// stopwords are left out of every language, since the descriptions are often written in a
+// mix of english and the user's language
+func IsStopword(word, lang string) bool {
+	return stopwords[English][word] || stopwords[lang][word]
+}
+
+func makeSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
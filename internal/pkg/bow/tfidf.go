@@ -0,0 +1,118 @@
+package bow
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Keyword is a term scored by how often it appears in the documents and how rare it is
+// in the corpus
+type Keyword struct {
+	Key   string
+	Text  string
+	Count int
+	Score float64
+}
+
+// Corpus holds the number of documents each term appears in, so that the terms common to
+// most documents, e.g. "library" or "simple", are weighted down
+type Corpus struct {
+	opts Options
+	docs int
+	df   map[string]int
+}
+
+// NewCorpus returns an empty corpus, with the options used to extract the terms
+func NewCorpus(opts Options) *Corpus {
+	return &Corpus{
+		opts: opts,
+		df:   make(map[string]int),
+	}
+}
+
+// Add counts the terms of the document. Each term is counted once per document
+func (c *Corpus) Add(doc string) {
+	seen := make(map[string]bool)
+	for _, t := range Extract(doc, c.opts) {
+		if !seen[t.Key] {
+			seen[t.Key] = true
+			c.df[t.Key]++
+		}
+	}
+	c.docs++
+}
+
+// Len returns the number of documents in the corpus
+func (c *Corpus) Len() int {
+	return c.docs
+}
+
+// IDF returns the smoothed inverse document frequency of the term. Every term has an idf of
+// one in an empty corpus, which ranks the keywords by their count
+func (c *Corpus) IDF(key string) float64 {
+	return math.Log(float64(1+c.docs)/float64(1+c.df[key])) + 1
+}
+
+// Keywords returns the top n terms of the documents, scored by their tf-idf. The term
+// frequency is dampened, so that a word repeated in many documents does not outweigh
+// the rarer ones. A bigram is only kept if it appears in more than one of the documents,
+// or in more than one document of the corpus, since most pairs of words are not phrases
+func (c *Corpus) Keywords(n int, docs ...string) []Keyword {
+	count := make(map[string]int)
+	used := make(map[string]int)
+	texts := make(map[string]map[string]int)
+	for _, doc := range docs {
+		seen := make(map[string]bool)
+		for _, t := range Extract(doc, c.opts) {
+			count[t.Key]++
+			if !seen[t.Key] {
+				seen[t.Key] = true
+				used[t.Key]++
+			}
+			if texts[t.Key] == nil {
+				texts[t.Key] = make(map[string]int)
+			}
+			texts[t.Key][t.Text]++
+		}
+	}
+
+	var keywords []Keyword
+	for key, tf := range count {
+		if strings.Contains(key, " ") && used[key] < 2 && c.df[key] < 2 {
+			continue
+		}
+		keywords = append(keywords, Keyword{
+			Key:   key,
+			Text:  mostCommon(texts[key]),
+			Count: tf,
+			Score: (1 + math.Log(float64(tf))) * c.IDF(key),
+		})
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Score != keywords[j].Score {
+			return keywords[i].Score > keywords[j].Score
+		}
+		if keywords[i].Count != keywords[j].Count {
+			return keywords[i].Count > keywords[j].Count
+		}
+		return keywords[i].Key < keywords[j].Key
+	})
+	if len(keywords) > n {
+		keywords = keywords[:n]
+	}
+	return keywords
+}
+
+// mostCommon returns the text written the most often for a term, so that the stemmed
+// keywords are shown as words
+func mostCommon(texts map[string]int) string {
+	var res string
+	var max int
+	for text, n := range texts {
+		if n > max || (n == max && text < res) {
+			res, max = text, n
+		}
+	}
+	return res
+}
@@ -0,0 +1,171 @@
+package bow
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Options configures how the text is broken into terms
+type Options struct {
+	// Stem groups the inflections of a word under the same key, e.g. scraper and scrapers
+	Stem bool
+
+	// Bigrams adds the pairs of adjacent words within a phrase as terms, e.g. "web scraper"
+	Bigrams bool
+}
+
+// KeywordOptions are the options used to extract the keywords of the profiles from the repo
+// descriptions, and to look the keywords up by their key
+var KeywordOptions = Options{Stem: true, Bigrams: true}
+
+// Term is a word or a phrase found in the text. Key identifies the term, and is the stem
+// of the words when stemming. Text is the term as written, lowercased
+type Term struct {
+	Key  string
+	Text string
+}
+
+// Tokenize splits the text into lowercased words, grouped by the phrases they appear in.
+// Phrases are broken by punctuation, e.g. commas and full stops, but not by hyphens.
+// Dots and trailing plus or hash signs within a word are kept, so that node.js, c++ and
+// c# are read as single words. Words also end where the script changes, so that Go语言
+// gives go and 语言, and at the humps of camel case before the words are lowercased, so
+// that HTTPResponse gives http and response. Words of a single letter and numbers are dropped,
+// except c and r
+func Tokenize(text string) [][]string {
+	var (
+		phrases [][]string
+		phrase  []string
+		word    strings.Builder
+		current script
+	)
+	runes := []rune(text)
+
+	endWord := func() {
+		w := word.String()
+		word.Reset()
+		if keep(w) {
+			phrase = append(phrase, w)
+		}
+	}
+	endPhrase := func() {
+		endWord()
+		if len(phrase) > 0 {
+			phrases = append(phrases, phrase)
+		}
+		phrase = nil
+	}
+
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == 'ー' || r == '々':
+			if s := scriptOf(r); s != current {
+				endWord()
+				current = s
+			} else if word.Len() > 0 && isHump(runes, i) {
+				endWord()
+			}
+			word.WriteRune(unicode.ToLower(r))
+		case r == '.' && word.Len() > 0 && i+1 < len(runes) && isAlnum(runes[i+1]):
+			word.WriteRune(r)
+		case (r == '+' || r == '#') && word.Len() > 0:
+			word.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_' || r == '\'' || r == '’':
+			endWord()
+		default:
+			endPhrase()
+		}
+	}
+	endPhrase()
+	return phrases
+}
+
+// Extract returns the terms in the text, without the stopwords of the language detected.
+// Chinese and japanese words are segmented into terms that are never paired into bigrams.
+// The stemmer only knows english, and is not applied to malay and indonesian text
+func Extract(text string, opts Options) []Term {
+	lang := Detect(text)
+	stem := opts.Stem && lang != Malay && lang != Indonesian
+
+	var terms []Term
+	for _, phrase := range Tokenize(text) {
+		var prev *Term
+		for _, w := range phrase {
+			if words, ok := segment(w, lang); ok {
+				for _, s := range words {
+					if !IsStopword(s, lang) {
+						terms = append(terms, Term{Key: s, Text: s})
+					}
+				}
+				prev = nil
+				continue
+			}
+			w = stripParticle(w)
+			if IsStopword(w, lang) {
+				// Stopwords break the phrase, so that no bigram spans across them
+				prev = nil
+				continue
+			}
+			t := Term{Key: w, Text: w}
+			if stem {
+				t.Key = Stem(w)
+			}
+			terms = append(terms, t)
+			if opts.Bigrams && prev != nil {
+				terms = append(terms, Term{
+					Key:  prev.Key + " " + t.Key,
+					Text: prev.Text + " " + t.Text,
+				})
+			}
+			prev = &t
+		}
+	}
+	return terms
+}
+
+// Key returns the key of the keyword as written, e.g. in a search, so that it matches the keys
+// of the terms extracted from the text with the same options. The words are normalized and
+// stemmed the same way, and joined as in the bigrams, e.g. "Web Scrapers" gives "web scraper".
+// Stopwords have no key
+func Key(keyword string, opts Options) string {
+	opts.Bigrams = false
+	var keys []string
+	for _, t := range Extract(keyword, opts) {
+		keys = append(keys, t.Key)
+	}
+	return strings.Join(keys, " ")
+}
+
+// letters are the words of a single letter that are kept, being the names of languages
+var letters = map[string]bool{"c": true, "r": true}
+
+// keep returns true if the word is longer than a letter, or is one of the letters kept,
+// and is not a number or a version
+func keep(w string) bool {
+	if len([]rune(w)) < 2 && !letters[w] {
+		return false
+	}
+	for _, r := range w {
+		if !unicode.IsDigit(r) && r != '.' {
+			return true
+		}
+	}
+	return false
+}
+
+// isHump returns true if a camel case word starts at the rune, after a lowercase letter as
+// in camelCase, or at the last capital of an acronym followed by a word as in HTTPResponse
+func isHump(runes []rune, i int) bool {
+	if i == 0 || !unicode.IsUpper(runes[i]) {
+		return false
+	}
+	prev := runes[i-1]
+	if unicode.IsLower(prev) {
+		return true
+	}
+	return unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+}
+
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
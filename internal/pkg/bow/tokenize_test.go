@@ -0,0 +1,53 @@
+package bow_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/bow"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want [][]string
+	}{
+		{"written in C and R", [][]string{{"written", "in", "c", "and", "r"}}},
+		{"A parser in C++, for C#", [][]string{{"parser", "in", "c++"}, {"for", "c#"}}},
+		{"HTTPResponse for node.js v1.2", [][]string{{"http", "response", "for", "node.js", "v1.2"}}},
+		{"a 2018 x", nil},
+	}
+	for _, tt := range tests {
+		if got := bow.Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%q: want %q, got %q", tt.text, tt.want, got)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	var keys []string
+	for _, term := range bow.Extract("Statistics written in C and R", bow.KeywordOptions) {
+		keys = append(keys, term.Key)
+	}
+	want := []string{"statist", "written", "statist written", "c", "r"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("want the languages of a single letter kept %q, got %q", want, keys)
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		keyword string
+		want    string
+	}{
+		{"Web Scrapers", "web scraper"},
+		{"web-scrapers", "web scraper"},
+		{"C", "c"},
+		{"the", ""},
+	}
+	for _, tt := range tests {
+		if got := bow.Key(tt.keyword, bow.KeywordOptions); got != tt.want {
+			t.Fatalf("%q: want %q, got %q", tt.keyword, tt.want, got)
+		}
+	}
+}
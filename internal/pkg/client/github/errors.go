@@ -0,0 +1,169 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxBodyLength is the maximum length of the response body kept in the error
+const maxBodyLength = 256
+
+// GraphQLError represents a single error returned in the GraphQL errors array
+type GraphQLError struct {
+	Type    string        `json:"type,omitempty"`
+	Message string        `json:"message,omitempty"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// PathString returns the path of the field that caused the error, e.g. search.edges.0.node
+func (e GraphQLError) PathString() string {
+	path := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		path[i] = fmt.Sprint(p)
+	}
+	return strings.Join(path, ".")
+}
+
+func (e GraphQLError) String() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (path: %s)", e.Message, e.PathString())
+}
+
+// AuthError is returned when the token is missing, invalid or lacks the required scopes
+type AuthError struct {
+	Status  int
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("github: auth error with status %d: %s", e.Status, e.Message)
+}
+
+// RateLimitError is returned when the primary or secondary rate limit is exceeded
+type RateLimitError struct {
+	Status  int
+	Message string
+	Path    string
+	ResetAt time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("github: rate limited with status %d: %s", e.Status, e.Message)
+}
+
+// NotFoundError is returned when the resource requested does not exist
+type NotFoundError struct {
+	Message string
+	Path    string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("github: not found: %s", e.Message)
+	}
+	return fmt.Sprintf("github: not found at %s: %s", e.Path, e.Message)
+}
+
+// QueryError is returned when the GraphQL response contains an errors array
+type QueryError struct {
+	Errors []GraphQLError
+}
+
+func (e *QueryError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.String()
+	}
+	return fmt.Sprintf("github: query error: %s", strings.Join(msgs, "; "))
+}
+
+// TransportError is returned when the call fails, or the response cannot be decoded
+type TransportError struct {
+	Status int
+	Body   string
+	Err    error
+}
+
+func (e *TransportError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("github: transport error with status %d: %s", e.Status, e.Err.Error())
+	}
+	return fmt.Sprintf("github: transport error with status %d: %s", e.Status, e.Body)
+}
+
+// PartialError is returned when the pagination fails midway, together with the results
+// collected so far. Cursor is the end cursor of the last page fetched successfully,
+// which the caller can use to resume the pagination
+type PartialError struct {
+	Cursor string
+	Err    error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("github: pagination stopped after cursor %q: %s", e.Cursor, e.Err.Error())
+}
+
+// newStatusError maps a non-successful http response to the corresponding error
+func newStatusError(status int, body []byte) error {
+	msg := truncate(string(body))
+	switch status {
+	case http.StatusUnauthorized:
+		return &AuthError{Status: status, Message: msg}
+	case http.StatusForbidden:
+		if strings.Contains(strings.ToLower(msg), "rate limit") {
+			return &RateLimitError{Status: status, Message: msg}
+		}
+		return &AuthError{Status: status, Message: msg}
+	case http.StatusNotFound:
+		return &NotFoundError{Message: msg}
+	default:
+		return &TransportError{Status: status, Body: msg}
+	}
+}
+
+// newQueryError maps the GraphQL errors array to the corresponding error, based on the
+// type of the first error
+func newQueryError(errs []GraphQLError, rateLimit RateLimit) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	first := errs[0]
+	switch first.Type {
+	case "RATE_LIMITED":
+		return &RateLimitError{
+			Status:  http.StatusOK,
+			Message: first.Message,
+			Path:    first.PathString(),
+			ResetAt: rateLimit.ResetAt,
+		}
+	case "NOT_FOUND":
+		return &NotFoundError{
+			Message: first.Message,
+			Path:    first.PathString(),
+		}
+	default:
+		return &QueryError{Errors: errs}
+	}
+}
+
+// withoutNotFound returns the errors that are not caused by missing nodes
+func withoutNotFound(errs []GraphQLError) []GraphQLError {
+	var res []GraphQLError
+	for _, err := range errs {
+		if err.Type != "NOT_FOUND" {
+			res = append(res, err)
+		}
+	}
+	return res
+}
+
+func truncate(s string) string {
+	if len(s) > maxBodyLength {
+		return s[:maxBodyLength]
+	}
+	return s
+}
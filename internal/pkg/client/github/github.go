@@ -4,9 +4,9 @@ import (
 	"net/http"
 )
 
-// New returns a new github api
-func New(client *http.Client, token, endpoint string, middlewares ...Middleware) Service {
-	store := NewStore(client, token, endpoint)
+// New returns a new github api, which spreads the calls across the tokens provided
+func New(client *http.Client, tokens []string, endpoint string, middlewares ...Middleware) Service {
+	store := NewStore(client, tokens, endpoint)
 	model := NewModel(store)
 	service := NewService(model)
 
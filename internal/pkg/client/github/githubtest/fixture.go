@@ -0,0 +1,28 @@
+package githubtest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+)
+
+// Fixtures holds the users and repos served by the fake, in the same shape as the nodes
+// returned by Github, so that recorded responses can be copied in as they are
+type Fixtures struct {
+	Users []github.User `json:"users"`
+	Repos []github.Repo `json:"repos"`
+}
+
+// LoadFixtures reads the fixtures from the json file, e.g. testdata/fixtures.json
+func LoadFixtures(filename string) (*Fixtures, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixtures
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
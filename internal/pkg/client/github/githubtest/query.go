@@ -0,0 +1,164 @@
+package githubtest
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+)
+
+// searchQuery represents the search string passed in the query variable, e.g.
+// location:"Kuala Lumpur" created:2018-01-01..2018-01-31
+type searchQuery struct {
+	qualifiers map[string]string
+	terms      []string
+}
+
+// parseQuery splits the search string into the qualifiers and the free text terms.
+// Quoted values are kept as a whole, without the quotes
+func parseQuery(q string) searchQuery {
+	res := searchQuery{qualifiers: make(map[string]string)}
+	for _, field := range splitFields(q) {
+		if i := strings.Index(field, ":"); i > 0 {
+			res.qualifiers[strings.ToLower(field[:i])] = strings.Trim(field[i+1:], `"`)
+			continue
+		}
+		res.terms = append(res.terms, strings.ToLower(strings.Trim(field, `"`)))
+	}
+	return res
+}
+
+// splitFields splits the string on the spaces that are not within quotes
+func splitFields(s string) []string {
+	var quoted bool
+	return strings.FieldsFunc(s, func(r rune) bool {
+		if r == '"' {
+			quoted = !quoted
+		}
+		return !quoted && unicode.IsSpace(r)
+	})
+}
+
+// matchUser checks if the user satisfies the location and created qualifiers, and contains
+// the terms in the login, name or bio
+func (q searchQuery) matchUser(u github.User) bool {
+	if loc, ok := q.qualifiers["location"]; ok && !containsFold(u.Location, loc) {
+		return false
+	}
+	if expr, ok := q.qualifiers["created"]; ok && !inRange(u.CreatedAt, expr) {
+		return false
+	}
+	return q.matchTerms(u.Login, u.Name, u.Bio)
+}
+
+// matchRepo checks if the repo satisfies the user, language, fork, created and pushed
+// qualifiers, and contains the terms in the name or description
+func (q searchQuery) matchRepo(r github.Repo) bool {
+	if login, ok := q.qualifiers["user"]; ok && !strings.EqualFold(r.Owner.Login, login) {
+		return false
+	}
+	if lang, ok := q.qualifiers["language"]; ok && !strings.EqualFold(r.PrimaryLanguage.Name, lang) {
+		return false
+	}
+	// Forks are left out of the search unless asked for, as Github does
+	switch q.qualifiers["fork"] {
+	case "only":
+		if !r.IsFork {
+			return false
+		}
+	case "true":
+	default:
+		if r.IsFork {
+			return false
+		}
+	}
+	if expr, ok := q.qualifiers["created"]; ok && !inRange(r.CreatedAt, expr) {
+		return false
+	}
+	if expr, ok := q.qualifiers["pushed"]; ok && !inRange(r.PushedAt, expr) {
+		return false
+	}
+	return q.matchTerms(r.Name, r.Description)
+}
+
+func (q searchQuery) matchTerms(fields ...string) bool {
+	for _, term := range q.terms {
+		var found bool
+		for _, field := range fields {
+			if containsFold(field, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// inRange checks if the time falls within the range qualifier, which is one of a..b, a..*,
+// *..b, >a, >=a, <a, <=a or a, where the bounds are dates or RFC3339 timestamps. A date
+// covers the whole day, so a..b includes the users created on b. A range that cannot be
+// parsed matches nothing
+func inRange(t time.Time, expr string) bool {
+	if t.IsZero() {
+		return false
+	}
+	if i := strings.Index(expr, ".."); i >= 0 {
+		from, to := expr[:i], expr[i+2:]
+		if from != "*" {
+			start, _, ok := parseBound(from)
+			if !ok || t.Before(start) {
+				return false
+			}
+		}
+		if to != "*" {
+			_, end, ok := parseBound(to)
+			if !ok || !t.Before(end) {
+				return false
+			}
+		}
+		return true
+	}
+	var op string
+	for _, prefix := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(expr, prefix) {
+			op, expr = prefix, expr[len(prefix):]
+			break
+		}
+	}
+	start, end, ok := parseBound(expr)
+	if !ok {
+		return false
+	}
+	switch op {
+	case ">=":
+		return !t.Before(start)
+	case ">":
+		return !t.Before(end)
+	case "<=":
+		return t.Before(end)
+	case "<":
+		return t.Before(start)
+	default:
+		return !t.Before(start) && t.Before(end)
+	}
+}
+
+// parseBound returns the time span covered by the bound, from the start inclusive to the
+// end exclusive. A date spans the whole day, and a timestamp spans a single second
+func parseBound(s string) (start, end time.Time, ok bool) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, t.AddDate(0, 0, 1), true
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, t.Add(time.Second), true
+	}
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,249 @@
+package githubtest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+)
+
+const (
+	// maxFirst is the maximum number of nodes that can be requested in a page
+	maxFirst = 100
+
+	// maxSearchResults is the maximum number of results the search returns for a query,
+	// although the count reports all the matches
+	maxSearchResults = 1000
+)
+
+// resolve answers the query by the field it selects. Only the fields used by the
+// crawler are supported: search, user.repositories and nodes
+func (s *Server) resolve(q github.GraphQLQuery) (object, []github.GraphQLError) {
+	switch {
+	case strings.Contains(q.Query, "search("):
+		return s.search(q)
+	case strings.Contains(q.Query, "user(login:"):
+		return s.userRepos(q)
+	case strings.Contains(q.Query, "nodes(ids:"):
+		return s.nodes(q)
+	default:
+		return object{}, []github.GraphQLError{{
+			Type:    "UNSUPPORTED",
+			Message: fmt.Sprintf("githubtest: the query %q is not supported", q.OperationName),
+		}}
+	}
+}
+
+// search serves the USER and REPOSITORY searches. Users are ordered by the creation date,
+// and repos by the most stars, so that the pages are stable
+func (s *Server) search(q github.GraphQLQuery) (object, []github.GraphQLError) {
+	query := parseQuery(stringVar(q, "query"))
+	kind := stringVar(q, "type")
+	if kind == "" && strings.Contains(q.Query, "type: REPOSITORY") {
+		kind = "REPOSITORY"
+	}
+	var (
+		nodes    []interface{}
+		countKey = "userCount"
+	)
+	switch kind {
+	case "REPOSITORY":
+		countKey = "repositoryCount"
+		var repos []github.Repo
+		for _, r := range s.fixtures.Repos {
+			if query.matchRepo(r) {
+				repos = append(repos, r)
+			}
+		}
+		sort.SliceStable(repos, func(i, j int) bool {
+			if repos[i].Stargazers.TotalCount != repos[j].Stargazers.TotalCount {
+				return repos[i].Stargazers.TotalCount > repos[j].Stargazers.TotalCount
+			}
+			return repos[i].NameWithOwner < repos[j].NameWithOwner
+		})
+		for _, r := range repos {
+			nodes = append(nodes, r)
+		}
+	default:
+		var users []github.User
+		for _, u := range s.fixtures.Users {
+			if query.matchUser(u) {
+				users = append(users, u)
+			}
+		}
+		sort.SliceStable(users, func(i, j int) bool {
+			if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+				return users[i].CreatedAt.Before(users[j].CreatedAt)
+			}
+			return users[i].Login < users[j].Login
+		})
+		for _, u := range users {
+			nodes = append(nodes, u)
+		}
+	}
+
+	count := len(nodes)
+	if len(nodes) > maxSearchResults {
+		nodes = nodes[:maxSearchResults]
+	}
+	conn, err := paginate(q, "search", nodes)
+	if err != nil {
+		return object{"search": nil}, []github.GraphQLError{*err}
+	}
+	conn[countKey] = count
+	return object{"search": conn}, nil
+}
+
+// userRepos serves the repos owned by the user that are not forks, with the most recently
+// pushed first. A login that is not in the fixtures is not found
+func (s *Server) userRepos(q github.GraphQLQuery) (object, []github.GraphQLError) {
+	login := stringVar(q, "login")
+	found := false
+	for _, u := range s.fixtures.Users {
+		if strings.EqualFold(u.Login, login) {
+			found = true
+			break
+		}
+	}
+	var repos []github.Repo
+	for _, r := range s.fixtures.Repos {
+		if strings.EqualFold(r.Owner.Login, login) {
+			found = true
+			if !r.IsFork {
+				repos = append(repos, r)
+			}
+		}
+	}
+	if !found {
+		return object{"user": nil}, []github.GraphQLError{{
+			Type:    "NOT_FOUND",
+			Message: fmt.Sprintf("Could not resolve to a User with the login of '%s'.", login),
+			Path:    []interface{}{"user"},
+		}}
+	}
+	sort.SliceStable(repos, func(i, j int) bool {
+		return repos[i].PushedAt.After(repos[j].PushedAt)
+	})
+	nodes := make([]interface{}, len(repos))
+	for i, r := range repos {
+		nodes[i] = r
+	}
+	conn, err := paginate(q, "repositories", nodes)
+	if err != nil {
+		return object{"user": nil}, []github.GraphQLError{*err}
+	}
+	conn["totalCount"] = len(repos)
+	return object{"user": object{"repositories": conn}}, nil
+}
+
+// nodes serves the repos by their node id, with a null node and a not found error
+// for each id that is not in the fixtures
+func (s *Server) nodes(q github.GraphQLQuery) (object, []github.GraphQLError) {
+	ids, _ := q.Variables["ids"].([]interface{})
+	if len(ids) > maxFirst {
+		return object{"nodes": nil}, []github.GraphQLError{{
+			Type:    "EXCESSIVE_PAGINATION",
+			Message: fmt.Sprintf("You may not request more than %d node ids, but you requested %d.", maxFirst, len(ids)),
+		}}
+	}
+	var errs []github.GraphQLError
+	nodes := make([]interface{}, len(ids))
+	for i, id := range ids {
+		for _, r := range s.fixtures.Repos {
+			if r.ID == id {
+				nodes[i] = r
+				break
+			}
+		}
+		if nodes[i] == nil {
+			errs = append(errs, github.GraphQLError{
+				Type:    "NOT_FOUND",
+				Message: fmt.Sprintf("Could not resolve to a node with the global id of '%v'", id),
+				Path:    []interface{}{"nodes", i},
+			})
+		}
+	}
+	return object{"nodes": nodes}, errs
+}
+
+// paginate returns the page of the connection after the cursor, with up to first nodes
+func paginate(q github.GraphQLQuery, field string, nodes []interface{}) (object, *github.GraphQLError) {
+	first, ok := q.Variables["first"].(float64)
+	if !ok {
+		return nil, &github.GraphQLError{
+			Type:    "MISSING_PAGINATION_BOUNDARIES",
+			Message: fmt.Sprintf("You must provide a `first` or `last` value to properly paginate the `%s` connection.", field),
+			Path:    []interface{}{field},
+		}
+	}
+	if first < 0 || first > maxFirst {
+		return nil, &github.GraphQLError{
+			Type:    "EXCESSIVE_PAGINATION",
+			Message: fmt.Sprintf("Requesting %d records on the `%s` connection exceeds the `first` limit of %d records.", int(first), field, maxFirst),
+			Path:    []interface{}{field},
+		}
+	}
+	offset := 0
+	if after := stringVar(q, "after"); after != "" {
+		var ok bool
+		if offset, ok = decodeCursor(after); !ok {
+			return nil, &github.GraphQLError{
+				Type:    "INVALID_CURSOR_ARGUMENTS",
+				Message: fmt.Sprintf("`%s` does not appear to be a valid cursor.", after),
+				Path:    []interface{}{field},
+			}
+		}
+	}
+	if offset > len(nodes) {
+		offset = len(nodes)
+	}
+	end := offset + int(first)
+	if end > len(nodes) {
+		end = len(nodes)
+	}
+
+	edges := make([]object, 0, end-offset)
+	for i := offset; i < end; i++ {
+		edges = append(edges, object{
+			"cursor": encodeCursor(i + 1),
+			"node":   nodes[i],
+		})
+	}
+	pageInfo := object{
+		"hasNextPage":     end < len(nodes),
+		"hasPreviousPage": offset > 0,
+		"startCursor":     nil,
+		"endCursor":       nil,
+	}
+	if len(edges) > 0 {
+		pageInfo["startCursor"] = edges[0]["cursor"]
+		pageInfo["endCursor"] = edges[len(edges)-1]["cursor"]
+	}
+	return object{
+		"pageInfo": pageInfo,
+		"edges":    edges,
+	}, nil
+}
+
+// encodeCursor returns the opaque cursor of the node at the position, counted from one,
+// in the same format as Github
+func encodeCursor(pos int) string {
+	return base64.StdEncoding.EncodeToString([]byte("cursor:" + strconv.Itoa(pos)))
+}
+
+func decodeCursor(s string) (int, bool) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || !strings.HasPrefix(string(b), "cursor:") {
+		return 0, false
+	}
+	pos, err := strconv.Atoi(strings.TrimPrefix(string(b), "cursor:"))
+	return pos, err == nil && pos > 0
+}
+
+func stringVar(q github.GraphQLQuery, name string) string {
+	s, _ := q.Variables[name].(string)
+	return s
+}
@@ -0,0 +1,207 @@
+// Package githubtest provides a fake of the Github's GraphQL endpoint, which serves the
+// users and repos from fixtures instead of calling Github. Point the github_uri setting,
+// or the endpoint passed to github.New, to the Endpoint of the server:
+//
+//	fixtures, err := githubtest.LoadFixtures("testdata/fixtures.json")
+//	srv := githubtest.NewServer(fixtures)
+//	defer srv.Close()
+//	api := github.New(srv.Client(), []string{"token"}, srv.Endpoint())
+//
+// Faults can be injected to check how the crawler copes with rate limits and failures
+package githubtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+)
+
+// defaultLimit is the number of points per hour granted to a token, as on Github
+const defaultLimit = 5000
+
+// Fault represents an error response served in place of the next response
+type Fault int
+
+// The faults that can be injected, which mirror the responses of Github
+const (
+	// None lets the call through, so that a fault can be injected in a later call
+	None Fault = iota
+	// RateLimited responds with the RATE_LIMITED error, as when the points run out
+	RateLimited
+	// SecondaryRateLimited responds with the forbidden status and a Retry-After header
+	SecondaryRateLimited
+	// BadGateway responds with the bad gateway status, which Github returns on timeouts
+	BadGateway
+	// InternalServerError responds with the internal server error status
+	InternalServerError
+	// QueryFailed responds with null data and an error in the errors array
+	QueryFailed
+	// BadCredentials responds with the unauthorized status, as for a revoked token
+	BadCredentials
+)
+
+// Server is the fake Github's GraphQL endpoint
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	fixtures  Fixtures
+	faults    []Fault
+	requests  []github.GraphQLQuery
+	rateLimit github.RateLimit
+}
+
+// NewServer starts the server with the fixtures, and a full rate limit that resets in an hour.
+// The caller should call Close when done
+func NewServer(fixtures *Fixtures) *Server {
+	s := &Server{
+		fixtures: *fixtures,
+		rateLimit: github.RateLimit{
+			Limit:     defaultLimit,
+			Remaining: defaultLimit,
+			ResetAt:   time.Now().UTC().Add(time.Hour).Truncate(time.Second),
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", s.serveGraphQL)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Endpoint returns the url of the GraphQL endpoint, in place of https://api.github.com/graphql
+func (s *Server) Endpoint() string {
+	return s.URL + "/graphql"
+}
+
+// Inject queues the faults, which are served in order to the next calls, one each
+func (s *Server) Inject(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, faults...)
+}
+
+// SetRateLimit sets the points left and the time they reset. Once the points run out, the
+// calls are rate limited until the reset
+func (s *Server) SetRateLimit(remaining int, resetAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit.Remaining = remaining
+	s.rateLimit.ResetAt = resetAt.UTC().Truncate(time.Second)
+}
+
+// Requests returns the queries received so far, including the ones that were served a fault
+func (s *Server) Requests() []github.GraphQLQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]github.GraphQLQuery, len(s.requests))
+	copy(res, s.requests)
+	return res
+}
+
+func (s *Server) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, object{"message": "Method Not Allowed"})
+		return
+	}
+	if len(r.Header.Get("Authorization")) <= len("bearer ") {
+		writeJSON(w, http.StatusUnauthorized, badCredentials)
+		return
+	}
+	var q github.GraphQLQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		writeJSON(w, http.StatusBadRequest, object{"message": "Problems parsing JSON"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, q)
+
+	if !time.Now().Before(s.rateLimit.ResetAt) {
+		s.rateLimit.Remaining = s.rateLimit.Limit
+		s.rateLimit.ResetAt = time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	}
+	// Github sets the rate limit headers on every response, including the failed ones
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.rateLimit.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.rateLimit.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.rateLimit.ResetAt.Unix(), 10))
+
+	var fault Fault
+	if len(s.faults) > 0 {
+		fault, s.faults = s.faults[0], s.faults[1:]
+	}
+	if fault != None {
+		writeFault(w, fault)
+		return
+	}
+
+	if s.rateLimit.Remaining <= 0 {
+		writeFault(w, RateLimited)
+		return
+	}
+	s.rateLimit.Remaining--
+
+	data, errs := s.resolve(q)
+	data["rateLimit"] = object{
+		"cost":      1,
+		"limit":     s.rateLimit.Limit,
+		"remaining": s.rateLimit.Remaining,
+		"resetAt":   s.rateLimit.ResetAt,
+	}
+	res := object{"data": data}
+	if len(errs) > 0 {
+		res["errors"] = errs
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// badCredentials is the body returned by Github when the token is invalid
+var badCredentials = object{
+	"message":           "Bad credentials",
+	"documentation_url": "https://docs.github.com/graphql",
+}
+
+func writeFault(w http.ResponseWriter, fault Fault) {
+	switch fault {
+	case RateLimited:
+		writeJSON(w, http.StatusOK, object{
+			"data": nil,
+			"errors": []github.GraphQLError{{
+				Type:    "RATE_LIMITED",
+				Message: "API rate limit exceeded for user ID 1.",
+			}},
+		})
+	case SecondaryRateLimited:
+		w.Header().Set("Retry-After", "1")
+		writeJSON(w, http.StatusForbidden, object{
+			"message":           "You have exceeded a secondary rate limit. Please wait a few minutes before you try again.",
+			"documentation_url": "https://docs.github.com/graphql/overview/resource-limitations",
+		})
+	case BadGateway:
+		writeJSON(w, http.StatusBadGateway, object{"message": "Bad Gateway"})
+	case InternalServerError:
+		writeJSON(w, http.StatusInternalServerError, object{"message": "Server Error"})
+	case QueryFailed:
+		writeJSON(w, http.StatusOK, object{
+			"data": nil,
+			"errors": []github.GraphQLError{{
+				Message: "Something went wrong while executing your query. Please include the request id when reporting this issue.",
+			}},
+		})
+	case BadCredentials:
+		writeJSON(w, http.StatusUnauthorized, badCredentials)
+	}
+}
+
+type object map[string]interface{}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -23,7 +23,8 @@ type loggingMiddleware struct {
 	logger  *logger.Logger
 }
 
-func (m *loggingMiddleware) FetchUsersCursor(ctx context.Context, location, start, end string, limit int) (users []User, err error) {
+func (m *loggingMiddleware) FetchUsersCursor(ctx context.Context, location, start, end, cursor string, limit int, fn UsersFn) (err error) {
+	var count int
 	defer func(s time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("FetchUsersCursor"),
@@ -31,26 +32,88 @@ func (m *loggingMiddleware) FetchUsersCursor(ctx context.Context, location, star
 			zap.String("location", location),
 			zap.String("start", start),
 			zap.String("end", end),
-			zap.Int("limit", limit))
+			zap.String("cursor", cursor),
+			zap.Int("limit", limit),
+			zap.Int("count", count),
+			zap.Any("tokens", m.service.Tokens(ctx)))
 
 		logger.Maybe(L, "fetch users cursor", err)
 	}(time.Now())
 
-	return m.service.FetchUsersCursor(ctx, location, start, end, limit)
+	return m.service.FetchUsersCursor(ctx, location, start, end, cursor, limit, func(users []User, c Cursor) error {
+		count += len(users)
+		return fn(users, c)
+	})
 }
 
-func (m *loggingMiddleware) FetchReposCursor(ctx context.Context, login, start, end string, limit int) (repos []Repo, err error) {
+func (m *loggingMiddleware) FetchReposCursor(ctx context.Context, login, since, cursor string, limit int, fn ReposFn) (err error) {
+	var count int
 	defer func(s time.Time) {
 		L := logger.Wrap(ctx, m.logger,
 			logger.Method("FetchReposCursor"),
 			logger.Duration(s),
 			zap.String("login", login),
-			zap.String("start", start),
-			zap.String("end", end),
-			zap.Int("limit", limit))
+			zap.String("since", since),
+			zap.String("cursor", cursor),
+			zap.Int("limit", limit),
+			zap.Int("count", count),
+			zap.Any("tokens", m.service.Tokens(ctx)))
 
 		logger.Maybe(L, "fetch repos cursor", err)
 	}(time.Now())
 
-	return m.service.FetchReposCursor(ctx, login, start, end, limit)
+	return m.service.FetchReposCursor(ctx, login, since, cursor, limit, func(repos []Repo, c Cursor) error {
+		count += len(repos)
+		return fn(repos, c)
+	})
+}
+
+func (m *loggingMiddleware) FetchReposByID(ctx context.Context, ids []string) (res []Repo, err error) {
+	defer func(s time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("FetchReposByID"),
+			logger.Duration(s),
+			zap.Int("ids", len(ids)),
+			zap.Int("count", len(res)),
+			zap.Any("tokens", m.service.Tokens(ctx)))
+
+		logger.Maybe(L, "fetch repos by id", err)
+	}(time.Now())
+
+	return m.service.FetchReposByID(ctx, ids)
+}
+
+func (m *loggingMiddleware) RateLimit(ctx context.Context) (res RateLimit) {
+	defer func(s time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("RateLimit"),
+			logger.Duration(s))
+
+		L.Info("get rate limit",
+			zap.Int("limit", res.Limit),
+			zap.Int("remaining", res.Remaining),
+			zap.Time("resetAt", res.ResetAt))
+	}(time.Now())
+
+	return m.service.RateLimit(ctx)
+}
+
+func (m *loggingMiddleware) Tokens(ctx context.Context) (res []TokenUsage) {
+	defer func(s time.Time) {
+		L := logger.Wrap(ctx, m.logger,
+			logger.Method("Tokens"),
+			logger.Duration(s))
+
+		for _, t := range res {
+			L.Info("get token usage",
+				zap.String("token", t.ID),
+				zap.Int("calls", t.Calls),
+				zap.Int("limit", t.RateLimit.Limit),
+				zap.Int("remaining", t.RateLimit.Remaining),
+				zap.Time("resetAt", t.RateLimit.ResetAt),
+				zap.Bool("revoked", t.Revoked))
+		}
+	}(time.Now())
+
+	return m.service.Tokens(ctx)
 }
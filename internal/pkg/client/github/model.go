@@ -1,19 +1,30 @@
 package github
 
 import (
+	"context"
 	"errors"
+	"time"
 )
 
+// maxNodes is the maximum number of nodes that can be looked up in a call
+const maxNodes = 100
+
 var (
 	ErrStartFieldRequired = errors.New(`field "start" is required`)
 	ErrEndFieldRequired   = errors.New(`field "end" is required`)
+	ErrLoginFieldRequired = errors.New(`field "login" is required`)
+	ErrInvalidSince       = errors.New(`field "since" must be in the RFC3339 format`)
+	ErrTooManyIDs         = errors.New(`field "ids" exceeds the maximum of 100 nodes`)
 )
 
 // Model represents the api interface for the Github's GraphQL
 type (
 	Model interface {
-		FetchUsers(req FetchUsersRequest) (*FetchUsersResponse, error)
-		FetchRepos(req FetchReposRequest) (*FetchReposResponse, error)
+		FetchUsers(ctx context.Context, req FetchUsersRequest) (*FetchUsersResponse, error)
+		FetchRepos(ctx context.Context, req FetchReposRequest) (*FetchReposResponse, error)
+		FetchReposByID(ctx context.Context, req FetchReposByIDRequest) (*FetchReposByIDResponse, error)
+		RateLimit() RateLimit
+		Tokens() []TokenUsage
 	}
 
 	model struct {
@@ -28,22 +39,42 @@ func NewModel(store Store) Model {
 	}
 }
 
-func (m *model) FetchUsers(req FetchUsersRequest) (*FetchUsersResponse, error) {
+func (m *model) FetchUsers(ctx context.Context, req FetchUsersRequest) (*FetchUsersResponse, error) {
 	if req.Start == "" {
 		return nil, ErrStartFieldRequired
 	}
 	if req.End == "" {
 		return nil, ErrEndFieldRequired
 	}
-	return m.store.FetchUsers(req)
+	return m.store.FetchUsers(ctx, req)
 }
 
-func (m *model) FetchRepos(req FetchReposRequest) (*FetchReposResponse, error) {
-	if req.Start == "" {
-		return nil, ErrStartFieldRequired
+func (m *model) FetchRepos(ctx context.Context, req FetchReposRequest) (*FetchReposResponse, error) {
+	if req.Login == "" {
+		return nil, ErrLoginFieldRequired
 	}
-	if req.End == "" {
-		return nil, ErrEndFieldRequired
+	if req.Since != "" {
+		if _, err := time.Parse(time.RFC3339, req.Since); err != nil {
+			return nil, ErrInvalidSince
+		}
 	}
-	return m.store.FetchRepos(req)
+	return m.store.FetchRepos(ctx, req)
+}
+
+func (m *model) FetchReposByID(ctx context.Context, req FetchReposByIDRequest) (*FetchReposByIDResponse, error) {
+	if len(req.IDs) > maxNodes {
+		return nil, ErrTooManyIDs
+	}
+	if len(req.IDs) == 0 {
+		return &FetchReposByIDResponse{}, nil
+	}
+	return m.store.FetchReposByID(ctx, req)
+}
+
+func (m *model) RateLimit() RateLimit {
+	return m.store.RateLimit()
+}
+
+func (m *model) Tokens() []TokenUsage {
+	return m.store.Tokens()
 }
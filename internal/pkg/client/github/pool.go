@@ -0,0 +1,156 @@
+package github
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLimit is the hourly points of a token, assumed for a token that is rate limited
+// before its rate limit is known
+const defaultLimit = 5000
+
+// TokenUsage represents the usage of a token in the pool. The token is masked,
+// so that it is safe to be logged
+type TokenUsage struct {
+	ID        string    `json:"id"`
+	Calls     int       `json:"calls"`
+	RateLimit RateLimit `json:"rateLimit"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// token holds the access token together with its last known rate limit
+type token struct {
+	value     string
+	calls     int
+	rateLimit RateLimit
+	revoked   bool
+}
+
+// pool spreads the calls across the tokens in a round-robin manner, skipping the tokens
+// that are revoked or have exhausted their budget
+type pool struct {
+	sync.Mutex
+	tokens []*token
+	next   int
+}
+
+// newPool returns a pool of the tokens provided, ignoring empty and duplicate tokens
+func newPool(tokens ...string) *pool {
+	p := new(pool)
+	seen := make(map[string]bool)
+	for _, t := range tokens {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		p.tokens = append(p.tokens, &token{value: t})
+	}
+	return p
+}
+
+// Get returns the next token in the rotation with budget left. When every token is exhausted,
+// the token that resets first is returned. An AuthError is returned if all tokens are revoked
+func (p *pool) Get() (*token, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	var fallback *token
+	for i := 0; i < len(p.tokens); i++ {
+		t := p.tokens[(p.next+i)%len(p.tokens)]
+		if t.revoked {
+			continue
+		}
+		if !t.rateLimit.Exhausted() {
+			p.next = (p.next + i + 1) % len(p.tokens)
+			return t, nil
+		}
+		if fallback == nil || t.rateLimit.ResetAt.Before(fallback.rateLimit.ResetAt) {
+			fallback = t
+		}
+	}
+	if fallback == nil {
+		return nil, &AuthError{Status: http.StatusUnauthorized, Message: "no valid token left in the pool"}
+	}
+	return fallback, nil
+}
+
+// Used records the call made with the token, and the rate limit returned
+func (p *pool) Used(t *token, r RateLimit) {
+	p.Lock()
+	defer p.Unlock()
+
+	t.calls++
+	if r.Known() {
+		t.rateLimit = r
+	}
+}
+
+// Exhaust marks the token as exhausted until the reset, for the calls that are rate limited
+// without the rate limit in the response
+func (p *pool) Exhaust(t *token, resetAt time.Time) {
+	p.Lock()
+	defer p.Unlock()
+
+	if !t.rateLimit.Known() {
+		t.rateLimit.Limit = defaultLimit
+	}
+	t.rateLimit.Remaining = 0
+	t.rateLimit.ResetAt = resetAt
+}
+
+// Revoke takes the token out of the rotation, when it is revoked or expired
+func (p *pool) Revoke(t *token) {
+	p.Lock()
+	defer p.Unlock()
+
+	t.revoked = true
+}
+
+// RateLimit returns the combined budget of the tokens in the rotation. The budget
+// is reset when the first of the exhausted tokens resets. The cost is left out, being
+// the cost of the last query of each token, which does not add up across the tokens
+func (p *pool) RateLimit() RateLimit {
+	p.Lock()
+	defer p.Unlock()
+
+	var res RateLimit
+	for _, t := range p.tokens {
+		if t.revoked || !t.rateLimit.Known() {
+			continue
+		}
+		res.Limit += t.rateLimit.Limit
+		res.Remaining += t.rateLimit.Remaining
+		if res.ResetAt.IsZero() || t.rateLimit.ResetAt.Before(res.ResetAt) {
+			res.ResetAt = t.rateLimit.ResetAt
+		}
+	}
+	return res
+}
+
+// Usage returns the usage of each token in the pool
+func (p *pool) Usage() []TokenUsage {
+	p.Lock()
+	defer p.Unlock()
+
+	res := make([]TokenUsage, len(p.tokens))
+	for i, t := range p.tokens {
+		res[i] = TokenUsage{
+			ID:        mask(t.value),
+			Calls:     t.calls,
+			RateLimit: t.rateLimit,
+			Revoked:   t.revoked,
+		}
+	}
+	return res
+}
+
+// mask hides all but the last four characters of the token
+func mask(s string) string {
+	if len(s) <= 4 {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", 4) + s[len(s)-4:]
+}
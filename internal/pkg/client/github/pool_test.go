@@ -0,0 +1,25 @@
+package github
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPoolRateLimit(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	p := newPool("a", "b", "c", "d")
+	for i, r := range []RateLimit{
+		{Cost: 1, Limit: 5000, Remaining: 4000, ResetAt: now.Add(30 * time.Minute)},
+		{Cost: 3, Limit: 5000, Remaining: 100, ResetAt: now.Add(10 * time.Minute)},
+		{Cost: 2, Limit: 5000, Remaining: 5000, ResetAt: now.Add(5 * time.Minute)},
+	} {
+		p.Used(p.tokens[i], r)
+	}
+	// The revoked tokens and the tokens not used yet are left out
+	p.Revoke(p.tokens[2])
+
+	want := RateLimit{Limit: 10000, Remaining: 4100, ResetAt: now.Add(10 * time.Minute)}
+	if got := p.RateLimit(); got != want {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
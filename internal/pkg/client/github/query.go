@@ -0,0 +1,159 @@
+package github
+
+// The GraphQL documents sent to Github. Values are never formatted into the documents,
+// and are passed as variables instead. When the fields requested change, add a new
+// version rather than modifying the existing one, so that each version can be validated
+// against the schema on its own
+const (
+	// SearchUsersV1 searches the users matching the query, one page at a time
+	SearchUsersV1 = `query SearchUsersV1($query: String!, $first: Int!, $after: String) {
+	rateLimit {
+		cost
+		limit
+		remaining
+		resetAt
+	}
+	search(query: $query, type: USER, first: $first, after: $after) {
+		userCount
+		pageInfo {
+			startCursor
+			endCursor
+			hasNextPage
+			hasPreviousPage
+		}
+		edges {
+			cursor
+			node {
+				... on User {
+					name
+					createdAt
+					updatedAt
+					login
+					bio
+					location
+					email
+					company
+					avatarUrl
+					websiteUrl
+					repositories(last: 0) {
+						totalCount
+					}
+					gists(last: 0) {
+						totalCount
+					}
+					followers(last: 0) {
+						totalCount
+					}
+					following(last: 0) {
+						totalCount
+					}
+				}
+			}
+		}
+	}
+}`
+
+	// UserReposV1 pages through the repos owned by the user, with the most recently pushed first
+	UserReposV1 = `query UserReposV1($login: String!, $first: Int!, $after: String) {
+	rateLimit {
+		cost
+		limit
+		remaining
+		resetAt
+	}
+	user(login: $login) {
+		repositories(first: $first, after: $after, isFork: false, ownerAffiliations: [OWNER], orderBy: {field: PUSHED_AT, direction: DESC}) {
+			totalCount
+			pageInfo {
+				hasNextPage
+				startCursor
+				endCursor
+				hasPreviousPage
+			}
+			edges {
+				cursor
+				node {
+					...RepoFieldsV1
+				}
+			}
+		}
+	}
+}
+` + RepoFieldsV1
+
+	// ReposByIDV1 looks up the repos by their node id, to refresh them
+	ReposByIDV1 = `query ReposByIDV1($ids: [ID!]!) {
+	rateLimit {
+		cost
+		limit
+		remaining
+		resetAt
+	}
+	nodes(ids: $ids) {
+		...RepoFieldsV1
+	}
+}
+` + RepoFieldsV1
+
+	// RepoFieldsV1 is the fragment of the repo fields shared by the repo queries
+	RepoFieldsV1 = `fragment RepoFieldsV1 on Repository {
+	id
+	name
+	createdAt
+	updatedAt
+	pushedAt
+	description
+	homepageUrl
+	forkCount
+	isFork
+	isArchived
+	diskUsage
+	nameWithOwner
+	primaryLanguage {
+		name
+		color
+	}
+	licenseInfo {
+		key
+		name
+		spdxId
+	}
+	repositoryTopics(first: 20) {
+		nodes {
+			topic {
+				name
+			}
+		}
+	}
+	languages(first: 30, orderBy: {field: SIZE, direction: DESC}) {
+		totalCount
+		totalSize
+		edges {
+			size
+			node {
+				name
+				color
+			}
+		}
+	}
+	owner {
+		login
+		avatarUrl
+	}
+	stargazers(last: 0) {
+		totalCount
+	}
+	watchers(last: 0) {
+		totalCount
+	}
+	url
+}`
+)
+
+// Documents holds the GraphQL documents in use by their operation name, so that they
+// can be validated against the copy of Github's schema in testdata/schema.graphql
+var Documents = map[string]string{
+	"SearchUsersV1": SearchUsersV1,
+	"UserReposV1":   UserReposV1,
+	"ReposByIDV1":   ReposByIDV1,
+}
@@ -0,0 +1,63 @@
+package github
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func loadSchema(t *testing.T) *ast.Schema {
+	t.Helper()
+	b, err := ioutil.ReadFile("testdata/schema.graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := gqlparser.LoadSchema(&ast.Source{
+		Name:  "schema.graphql",
+		Input: string(b),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestDocuments(t *testing.T) {
+	schema := loadSchema(t)
+	if len(Documents) == 0 {
+		t.Fatal("want documents, got none")
+	}
+	for name, document := range Documents {
+		t.Run(name, func(t *testing.T) {
+			doc, errs := gqlparser.LoadQuery(schema, document)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			if len(doc.Operations) != 1 {
+				t.Fatalf("want 1 operation, got %d", len(doc.Operations))
+			}
+			if got := doc.Operations[0].Name; got != name {
+				t.Fatalf("want operation %q, got %q", name, got)
+			}
+		})
+	}
+}
+
+func TestDocumentsInvalid(t *testing.T) {
+	schema := loadSchema(t)
+	tests := map[string]string{
+		"unknown field":    `query Q { rateLimit { cost unknown } }`,
+		"missing argument": `query Q { user { login } }`,
+		"wrong variable":   `query Q($first: String) { search(query: "", type: USER, first: $first) { userCount } }`,
+		"wrong fragment":   `query Q { user(login: "") { ...F } } fragment F on Repository { name }`,
+	}
+	for name, document := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, errs := gqlparser.LoadQuery(schema, document); len(errs) == 0 {
+				t.Fatal("want validation errors, got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package github
+
+import (
+	"context"
+	"time"
+)
+
+// minRemaining is the number of points below which the client will wait
+// for the rate limit to reset before making the next call
+const minRemaining = 50
+
+// RateLimit represents the rate limit information returned by the GraphQL API
+type RateLimit struct {
+	Cost      int       `json:"cost,omitempty"`
+	Limit     int       `json:"limit,omitempty"`
+	Remaining int       `json:"remaining,omitempty"`
+	ResetAt   time.Time `json:"resetAt,omitempty"`
+}
+
+// Known returns true if the rate limit has been populated by at least one call
+func (r RateLimit) Known() bool {
+	return r.Limit > 0
+}
+
+// Exhausted returns true if the remaining points fall below the minimum threshold
+// and the rate limit has not been reset yet
+func (r RateLimit) Exhausted() bool {
+	return r.Known() && r.Remaining < minRemaining && time.Now().Before(r.ResetAt)
+}
+
+// waitForReset blocks until the rate limit is reset, or the context is cancelled
+func waitForReset(ctx context.Context, r RateLimit) error {
+	if !r.Exhausted() {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(r.ResetAt)):
+		return nil
+	}
+}
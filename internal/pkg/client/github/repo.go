@@ -9,54 +9,105 @@ import (
 
 // Repo represents the repository structure
 type Repo struct {
-	Name          string     `json:"name,omitempty" bson:"name,omitempty"`
-	CreatedAt     time.Time  `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
-	UpdatedAt     time.Time  `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
-	Description   string     `json:"description,omitempty" bson:"description,omitempty"`
-	Languages     Language   `json:"languages,omitempty" bson:"languages,omitempty"`
-	HomepageURL   string     `json:"homepageUrl,omitempty" bson:"homepageUrl,omitempty"`
-	ForkCount     int64      `json:"forkCount,omitempty" bson:"forks,omitempty"`
-	IsFork        bool       `json:"isFork,omitempty" bson:"isFork,omitempty"`
-	NameWithOwner string     `json:"nameWithOwner,omitempty" bson:"nameWithOwner,omitempty"`
-	Owner         Owner      `json:"owner,omitempty" bson:"owner,omitempty"`
-	Stargazers    Stargazers `json:"stargazers,omitempty" bson:"stargazers,omitempty"`
-	Watchers      Watchers   `json:"watchers,omitempty" bson:"watchers,omitempty"`
-	URL           string     `json:"url,omitempty" bson:"url,omitempty"`
+	ID               string           `json:"id,omitempty" bson:"nodeId,omitempty"`
+	Name             string           `json:"name,omitempty" bson:"name,omitempty"`
+	CreatedAt        time.Time        `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	UpdatedAt        time.Time        `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	PushedAt         time.Time        `json:"pushedAt,omitempty" bson:"pushedAt,omitempty"`
+	Description      string           `json:"description,omitempty" bson:"description,omitempty"`
+	Languages        Language         `json:"languages,omitempty" bson:"languages,omitempty"`
+	PrimaryLanguage  LanguageNode     `json:"primaryLanguage,omitempty" bson:"primaryLanguage,omitempty"`
+	LicenseInfo      License          `json:"licenseInfo,omitempty" bson:"licenseInfo,omitempty"`
+	RepositoryTopics RepositoryTopics `json:"repositoryTopics,omitempty" bson:"repositoryTopics,omitempty"`
+	HomepageURL      string           `json:"homepageUrl,omitempty" bson:"homepageUrl,omitempty"`
+	ForkCount        int64            `json:"forkCount,omitempty" bson:"forks,omitempty"`
+	IsFork           bool             `json:"isFork,omitempty" bson:"isFork,omitempty"`
+	IsArchived       bool             `json:"isArchived,omitempty" bson:"isArchived,omitempty"`
+	DiskUsage        int64            `json:"diskUsage,omitempty" bson:"diskUsage,omitempty"`
+	NameWithOwner    string           `json:"nameWithOwner,omitempty" bson:"nameWithOwner,omitempty"`
+	Owner            Owner            `json:"owner,omitempty" bson:"owner,omitempty"`
+	Stargazers       Stargazers       `json:"stargazers,omitempty" bson:"stargazers,omitempty"`
+	Watchers         Watchers         `json:"watchers,omitempty" bson:"watchers,omitempty"`
+	URL              string           `json:"url,omitempty" bson:"url,omitempty"`
 }
 
 // BSON returns the repo as bson object
 func (r Repo) BSON() bson.M {
 	var languages []string
+	var languageBytes []bson.M
 	for _, lang := range r.Languages.Edges {
 		languages = append(languages, lang.Node.Name)
+		languageBytes = append(languageBytes, bson.M{
+			"name":  lang.Node.Name,
+			"bytes": lang.Size,
+		})
 	}
-	return bson.M{
-		"name":          r.Name,
-		"createdAt":     r.CreatedAt.UTC().Format(time.RFC3339),
-		"updatedAt":     r.UpdatedAt.UTC().Format(time.RFC3339),
-		"fetchedAt":     moment.NewUTCDate(),
-		"description":   r.Description,
-		"languages":     languages,
-		"homepageUrl":   r.HomepageURL,
-		"isFork":        r.IsFork,
-		"forks":         r.ForkCount,
-		"nameWithOwner": r.NameWithOwner,
-		"stargazers":    r.Stargazers.TotalCount,
-		"watchers":      r.Watchers.TotalCount,
-		"login":         r.Owner.Login,
-		"avatarUrl":     r.Owner.AvatarURL,
-		"url":           r.URL,
+	var topics []string
+	for _, node := range r.RepositoryTopics.Nodes {
+		topics = append(topics, node.Topic.Name)
 	}
+	m := bson.M{
+		"nodeId":              r.ID,
+		"name":                r.Name,
+		"createdAt":           r.CreatedAt.UTC().Format(time.RFC3339),
+		"updatedAt":           r.UpdatedAt.UTC().Format(time.RFC3339),
+		"pushedAt":            r.PushedAt.UTC().Format(time.RFC3339),
+		"fetchedAt":           moment.NewUTCDate(),
+		"countersRefreshedAt": moment.NewUTCDate(),
+		"description":         r.Description,
+		"languages":           languages,
+		"languageBytes":       languageBytes,
+		"primaryLanguage":     r.PrimaryLanguage.Name,
+		"license":             r.LicenseInfo.ID(),
+		"topics":              topics,
+		"homepageUrl":         r.HomepageURL,
+		"isFork":              r.IsFork,
+		"isArchived":          r.IsArchived,
+		"diskUsage":           r.DiskUsage,
+		"forks":               r.ForkCount,
+		"nameWithOwner":       r.NameWithOwner,
+		"stargazers":          r.Stargazers.TotalCount,
+		"watchers":            r.Watchers.TotalCount,
+		"login":               r.Owner.Login,
+		"avatarUrl":           r.Owner.AvatarURL,
+		"url":                 r.URL,
+	}
+
+	// A repo that has never been pushed to has no push date
+	if r.PushedAt.IsZero() {
+		delete(m, "pushedAt")
+	}
+	return m
+}
+
+// CountersBSON returns the fields of the repo that change over time, such as the stars and forks
+func (r Repo) CountersBSON() bson.M {
+	m := bson.M{
+		"updatedAt":           r.UpdatedAt.UTC().Format(time.RFC3339),
+		"pushedAt":            r.PushedAt.UTC().Format(time.RFC3339),
+		"isArchived":          r.IsArchived,
+		"diskUsage":           r.DiskUsage,
+		"forks":               r.ForkCount,
+		"stargazers":          r.Stargazers.TotalCount,
+		"watchers":            r.Watchers.TotalCount,
+		"countersRefreshedAt": moment.NewUTCDate(),
+	}
+	if r.PushedAt.IsZero() {
+		delete(m, "pushedAt")
+	}
+	return m
 }
 
 // Language represents the language node of the repo
 type Language struct {
 	TotalCount int64          `json:"totalCount,omitempty"`
+	TotalSize  int64          `json:"totalSize,omitempty"`
 	Edges      []LanguageEdge `json:"edges,omitempty"`
 }
 
-// LanguageEdge represents the language edge of the repo
+// LanguageEdge represents the language edge of the repo, with the size in bytes
 type LanguageEdge struct {
+	Size int64        `json:"size,omitempty"`
 	Node LanguageNode `json:"node,omitempty"`
 }
 
@@ -66,6 +117,36 @@ type LanguageNode struct {
 	Color string `json:"color,omitempty"`
 }
 
+// License represents the license of the repo
+type License struct {
+	Key    string `json:"key,omitempty"`
+	Name   string `json:"name,omitempty"`
+	SpdxID string `json:"spdxId,omitempty"`
+}
+
+// ID returns the SPDX identifier of the license, or the key if the license has none
+func (l License) ID() string {
+	if l.SpdxID != "" {
+		return l.SpdxID
+	}
+	return l.Key
+}
+
+// RepositoryTopics represents the topics of the repo
+type RepositoryTopics struct {
+	Nodes []RepositoryTopic `json:"nodes,omitempty"`
+}
+
+// RepositoryTopic represents the topic node of the repo
+type RepositoryTopic struct {
+	Topic Topic `json:"topic,omitempty"`
+}
+
+// Topic represents the topic
+type Topic struct {
+	Name string `json:"name,omitempty"`
+}
+
 // Owner represents the repo's owner
 type Owner struct {
 	Login     string `json:"login,omitempty"`
@@ -2,11 +2,27 @@ package github
 
 import (
 	"fmt"
+	"strings"
 )
 
 // GraphQLQuery represents the structure for the Github's GraphQL API calls
 type GraphQLQuery struct {
-	Query string `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// searchVariables returns the variables for the search queries. The cursor is
+// left out for the first page, since the after argument is nullable
+func searchVariables(query string, first int, after string) map[string]interface{} {
+	vars := map[string]interface{}{
+		"query": query,
+		"first": first,
+	}
+	if after != "" {
+		vars["after"] = after
+	}
+	return vars
 }
 
 // PageInfo represents the pagination structure from Github
@@ -26,65 +42,30 @@ type FetchUsersRequest struct {
 	Limit    int
 }
 
-func (f FetchUsersRequest) String() string {
-	var cursor string
-	if f.Cursor == "" {
-		cursor = ""
-	} else {
-		// String must be quoted for the graphql api to work
-		cursor = fmt.Sprintf(`"%s"`, f.Cursor)
+// Query returns the search query for the users
+func (f FetchUsersRequest) Query() string {
+	return fmt.Sprintf("location:%s created:%s..%s", qualifier(f.Location), f.Start, f.End)
+}
+
+// GraphQLQuery returns the query for the users, with the values passed as variables
+func (f FetchUsersRequest) GraphQLQuery() GraphQLQuery {
+	return GraphQLQuery{
+		OperationName: "SearchUsersV1",
+		Query:         SearchUsersV1,
+		Variables:     searchVariables(f.Query(), f.Limit, f.Cursor),
 	}
-	return fmt.Sprintf(`
-		query {
-			search(query: "location:%s created:%s..%s", type: USER, first: %d, after: %s) {
-				userCount,
-				pageInfo {
-					startCursor,
-					endCursor,
-					hasNextPage,
-					hasPreviousPage,
-				},
-				edges {
-					cursor,
-					node {
-						...on User {
-							name,
-							createdAt,
-							updatedAt,
-							login,
-							bio,
-							location,
-							email,
-							company,
-							avatarUrl,
-							websiteUrl,
-							repositories(last: 0) {
-								totalCount
-							},
-							gists(last: 0) {
-								totalCount
-							},
-							followers(last: 0) {
-								totalCount
-							},
-							following(last: 0) {
-								totalCount
-							},
-						}
-					}
-				}
-			}
-		}`, f.Location, f.Start, f.End, f.Limit, cursor)
 }
 
 // FetchUsersResponse represents the GraphQL's user data structure
 type FetchUsersResponse struct {
-	Data UserData `json:"data,omitempty"`
+	Data   UserData       `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
 }
 
 // UserData represents the GraphQL's user data structure
 type UserData struct {
-	Search SearchUser `json:"search,omitempty"`
+	RateLimit RateLimit  `json:"rateLimit,omitempty"`
+	Search    SearchUser `json:"search,omitempty"`
 }
 
 // SearchUser represents the GraphQL's search user data structure
@@ -100,85 +81,60 @@ type UserEdge struct {
 	Node   User   `json:"node,omitempty"`
 }
 
-// FetchReposRequest represents the request for the GraphQL service
+// FetchReposRequest represents the request for the repos of the user, ordered by the push date
 type FetchReposRequest struct {
 	Login  string
-	Start  string
-	End    string
+	Since  string
 	Cursor string
 	Limit  int
 }
 
-func (f FetchReposRequest) String() string {
-	var cursor string
-	if f.Cursor == "" {
-		cursor = ""
-	} else {
-		cursor = fmt.Sprintf(`"%s"`, f.Cursor)
+// Query returns the description of the repos requested, which is stored with the checkpoint
+func (f FetchReposRequest) Query() string {
+	if f.Since == "" {
+		return fmt.Sprintf("user:%s", f.Login)
+	}
+	return fmt.Sprintf("user:%s pushed:>%s", f.Login, f.Since)
+}
+
+// GraphQLQuery returns the query for the repos, with the values passed as variables
+func (f FetchReposRequest) GraphQLQuery() GraphQLQuery {
+	vars := map[string]interface{}{
+		"login": f.Login,
+		"first": f.Limit,
+	}
+	if f.Cursor != "" {
+		vars["after"] = f.Cursor
+	}
+	return GraphQLQuery{
+		OperationName: "UserReposV1",
+		Query:         UserReposV1,
+		Variables:     vars,
 	}
-	return fmt.Sprintf(`query {
-		search(query: "user:%s created:%s..%s", type: REPOSITORY, first: %d, after: %s) {
-			repositoryCount,
-			pageInfo {
-				hasNextPage,
-				startCursor,
-				endCursor,
-				hasPreviousPage,
-			},
-			edges {
-				cursor,
-				node {
-					...on Repository {
-						name,
-						createdAt,
-						updatedAt,
-						description,
-						homepageUrl,
-						forkCount,
-						isFork,
-						nameWithOwner,
-						languages (last: 30) {
-							totalCount,
-							edges {
-								node {
-									name,
-									color
-								}
-							}
-						},
-						owner {
-							login,
-							avatarUrl
-						},
-						stargazers (last: 0) {
-							totalCount
-						},
-						watchers (last: 0) {
-							totalCount
-						},
-						url
-					}
-				}
-			}
-		}
-	}`, f.Login, f.Start, f.End, f.Limit, cursor)
 }
 
 // FetchReposResponse represents the GraphQL's repo data structure
 type FetchReposResponse struct {
-	Data RepoData `json:"data,omitempty"`
+	Data   RepoData       `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
 }
 
 // RepoData represents the GraphQL's repo data structure
 type RepoData struct {
-	Search SearchRepo `json:"search,omitempty"`
+	RateLimit RateLimit `json:"rateLimit,omitempty"`
+	User      RepoOwner `json:"user,omitempty"`
+}
+
+// RepoOwner represents the GraphQL's user with the repositories connection
+type RepoOwner struct {
+	Repositories RepoConnection `json:"repositories,omitempty"`
 }
 
-// SearchRepo represents the GraphQL's search repo data structure
-type SearchRepo struct {
-	RepositoryCount int64      `json:"repositoryCount,omitempty"`
-	PageInfo        PageInfo   `json:"pageInfo,omitempty"`
-	Edges           []RepoEdge `json:"edges,omitempty"`
+// RepoConnection represents the GraphQL's repositories connection
+type RepoConnection struct {
+	TotalCount int64      `json:"totalCount,omitempty"`
+	PageInfo   PageInfo   `json:"pageInfo,omitempty"`
+	Edges      []RepoEdge `json:"edges,omitempty"`
 }
 
 // RepoEdge represents the GraphQL's repo edge data structure
@@ -186,3 +142,42 @@ type RepoEdge struct {
 	Cursor string `json:"cursor,omitempty"`
 	Node   Repo   `json:"node,omitempty"`
 }
+
+// FetchReposByIDRequest represents the request for the repos by their node id
+type FetchReposByIDRequest struct {
+	IDs []string
+}
+
+// GraphQLQuery returns the query for the repos, with the ids passed as variables
+func (f FetchReposByIDRequest) GraphQLQuery() GraphQLQuery {
+	return GraphQLQuery{
+		OperationName: "ReposByIDV1",
+		Query:         ReposByIDV1,
+		Variables: map[string]interface{}{
+			"ids": f.IDs,
+		},
+	}
+}
+
+// FetchReposByIDResponse represents the GraphQL's nodes data structure
+type FetchReposByIDResponse struct {
+	Data   NodesData      `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// NodesData represents the GraphQL's nodes data structure. The node is null
+// when the repo no longer exists
+type NodesData struct {
+	RateLimit RateLimit `json:"rateLimit,omitempty"`
+	Nodes     []*Repo   `json:"nodes,omitempty"`
+}
+
+// qualifier quotes the value of a search qualifier, so that values with spaces such as
+// "Kuala Lumpur" are searched as a whole. Quotes are not allowed within the value, and are removed
+func qualifier(value string) string {
+	value = strings.Replace(value, `"`, "", -1)
+	if strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
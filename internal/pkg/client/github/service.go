@@ -2,72 +2,232 @@ package github
 
 import (
 	"context"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/logger"
+	"go.uber.org/zap"
 )
 
+// maxSearchResults is the maximum number of results the search returns for a query
+const maxSearchResults = 1000
+
 type (
 	// Service represents the Github Service
 	Service interface {
-		FetchUsersCursor(ctx context.Context, location, start, end string, limit int) (users []User, err error)
-		FetchReposCursor(ctx context.Context, login, start, end string, limit int) (repos []Repo, err error)
+		FetchUsersCursor(ctx context.Context, location, start, end, cursor string, limit int, fn UsersFn) error
+		FetchReposCursor(ctx context.Context, login, since, cursor string, limit int, fn ReposFn) error
+		FetchReposByID(ctx context.Context, ids []string) ([]Repo, error)
+		RateLimit(ctx context.Context) RateLimit
+		Tokens(ctx context.Context) []TokenUsage
 	}
 
+	// UsersFn is called with each page of users as soon as it arrives
+	UsersFn func(users []User, c Cursor) error
+
+	// ReposFn is called with each page of repos as soon as it arrives
+	ReposFn func(repos []Repo, c Cursor) error
+
 	service struct {
 		model Model
 	}
 )
 
+// Cursor represents the position of the search after a page is fetched,
+// which can be stored to resume the search later
+type Cursor struct {
+	Query string
+	Start string
+	End   string
+	After string
+}
+
 // NewService returns a new service
 func NewService(model Model) Service {
 	return &service{model}
 }
 
-func (s *service) FetchUsersCursor(ctx context.Context, location, start, end string, limit int) ([]User, error) {
-	var users []User
-
-	cursor := ""
-	hasNextPage := true
-	for hasNextPage {
-		res, err := s.model.FetchUsers(FetchUsersRequest{
-			Location: location,
-			Start:    start,
-			End:      end,
-			Cursor:   cursor,
-			Limit:    limit,
-		})
-		if err != nil {
-			return nil, err
+// FetchUsersCursor pages through the users in the location, starting after the given cursor.
+// Since the search only returns the first 1000 results, the window is split in half recursively
+// until each part fits. A window that is resumed from a cursor is assumed to fit already.
+// If a page fails, a PartialError holding the last good cursor is returned
+func (s *service) FetchUsersCursor(ctx context.Context, location, start, end, cursor string, limit int, fn UsersFn) error {
+	req := FetchUsersRequest{
+		Location: location,
+		Start:    start,
+		End:      end,
+		Cursor:   cursor,
+		Limit:    limit,
+	}
+	if cursor != "" {
+		return s.fetchUsersPages(ctx, req, nil, fn)
+	}
+	return s.fetchUsersWindow(ctx, req, fn)
+}
+
+// fetchUsersWindow reads the user count from the first page, and splits the window
+// if there are more users than the search can return
+func (s *service) fetchUsersWindow(ctx context.Context, req FetchUsersRequest, fn UsersFn) error {
+	res, err := s.fetchUsers(ctx, req)
+	if err != nil {
+		return err
+	}
+	count := res.Data.Search.UserCount
+	if count > maxSearchResults {
+		mid, next, ok := splitWindow(req.Start, req.End)
+		if ok {
+			left, right := req, req
+			left.End, right.Start = mid, next
+			if err := s.fetchUsersWindow(ctx, left, fn); err != nil {
+				return err
+			}
+			return s.fetchUsersWindow(ctx, right, fn)
 		}
-		hasNextPage = res.Data.Search.PageInfo.HasNextPage
-		cursor = res.Data.Search.PageInfo.EndCursor
-		for _, edge := range res.Data.Search.Edges {
-			users = append(users, edge.Node)
+		L := logger.Wrap(ctx, zap.L(), logger.Method("FetchUsersCursor"))
+		L.Warn("search window exceeds the result limit",
+			zap.String("query", req.Query()),
+			zap.Int64("count", count),
+			zap.Int("max", maxSearchResults))
+	}
+	return s.fetchUsersPages(ctx, req, res, fn)
+}
+
+// fetchUsersPages pages through the window, starting from the first page if it is already fetched
+func (s *service) fetchUsersPages(ctx context.Context, req FetchUsersRequest, res *FetchUsersResponse, fn UsersFn) error {
+	for {
+		if res == nil {
+			var err error
+			if res, err = s.fetchUsers(ctx, req); err != nil {
+				return err
+			}
+		}
+		pageInfo := res.Data.Search.PageInfo
+		req.Cursor = pageInfo.EndCursor
+
+		users := make([]User, len(res.Data.Search.Edges))
+		for i, edge := range res.Data.Search.Edges {
+			users[i] = edge.Node
+		}
+		if err := fn(users, Cursor{
+			Query: req.Query(),
+			Start: req.Start,
+			End:   req.End,
+			After: req.Cursor,
+		}); err != nil {
+			return err
 		}
+		if !pageInfo.HasNextPage {
+			return nil
+		}
+		res = nil
 	}
-	return users, nil
 }
 
-func (s *service) FetchReposCursor(ctx context.Context, login, start, end string, limit int) ([]Repo, error) {
-	var repos []Repo
-	cursor := ""
-	hasNextPage := true
+// fetchUsers waits for the rate limit to reset when the budget runs low, before fetching the page
+func (s *service) fetchUsers(ctx context.Context, req FetchUsersRequest) (*FetchUsersResponse, error) {
+	if err := waitForReset(ctx, s.model.RateLimit()); err != nil {
+		return nil, &PartialError{Cursor: req.Cursor, Err: err}
+	}
+	res, err := s.model.FetchUsers(ctx, req)
+	if err != nil {
+		return nil, &PartialError{Cursor: req.Cursor, Err: err}
+	}
+	return res, nil
+}
 
-	for hasNextPage {
-		res, err := s.model.FetchRepos(FetchReposRequest{
+// FetchReposCursor pages through the repos of the user, with the most recently pushed first,
+// starting after the given cursor. Since repos pushed before the last run have not changed since,
+// the pagination stops at the first repo pushed at or before the since date, in RFC3339 format.
+// If a page fails, a PartialError holding the last good cursor is returned
+func (s *service) FetchReposCursor(ctx context.Context, login, since, cursor string, limit int, fn ReposFn) error {
+	var sinceTime time.Time
+	if since != "" {
+		var err error
+		if sinceTime, err = time.Parse(time.RFC3339, since); err != nil {
+			return ErrInvalidSince
+		}
+	}
+	for {
+		if err := waitForReset(ctx, s.model.RateLimit()); err != nil {
+			return &PartialError{Cursor: cursor, Err: err}
+		}
+		req := FetchReposRequest{
 			Login:  login,
-			Start:  start,
-			End:    end,
+			Since:  since,
 			Cursor: cursor,
 			Limit:  limit,
-		})
+		}
+		res, err := s.model.FetchRepos(ctx, req)
 		if err != nil {
-			break
+			return &PartialError{Cursor: cursor, Err: err}
 		}
-		hasNextPage = res.Data.Search.PageInfo.HasNextPage
-		cursor = res.Data.Search.PageInfo.EndCursor
-		for _, edge := range res.Data.Search.Edges {
+		conn := res.Data.User.Repositories
+		cursor = conn.PageInfo.EndCursor
+
+		done := !conn.PageInfo.HasNextPage
+		var repos []Repo
+		for _, edge := range conn.Edges {
+			if !sinceTime.IsZero() && !edge.Node.PushedAt.After(sinceTime) {
+				done = true
+				break
+			}
 			repos = append(repos, edge.Node)
 		}
+		if err := fn(repos, Cursor{
+			Query: req.Query(),
+			Start: since,
+			After: cursor,
+		}); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
 	}
+}
 
+// FetchReposByID looks up the repos by their node id. The repos that no longer exist are left out
+func (s *service) FetchReposByID(ctx context.Context, ids []string) ([]Repo, error) {
+	if err := waitForReset(ctx, s.model.RateLimit()); err != nil {
+		return nil, err
+	}
+	res, err := s.model.FetchReposByID(ctx, FetchReposByIDRequest{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	var repos []Repo
+	for _, node := range res.Data.Nodes {
+		if node != nil {
+			repos = append(repos, *node)
+		}
+	}
 	return repos, nil
 }
+
+// RateLimit returns the remaining budget, so that callers can decide how much work to start
+func (s *service) RateLimit(ctx context.Context) RateLimit {
+	return s.model.RateLimit()
+}
+
+// Tokens returns the usage of each token, such as the calls made and the remaining budget
+func (s *service) Tokens(ctx context.Context) []TokenUsage {
+	return s.model.Tokens()
+}
+
+// splitWindow splits the date window in half, returning the end of the first half and the start
+// of the second half. Since the dates are inclusive, a window of a single day cannot be split
+func splitWindow(start, end string) (mid, next string, ok bool) {
+	t1, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return "", "", false
+	}
+	t2, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return "", "", false
+	}
+	days := int(t2.Sub(t1).Hours() / 24)
+	if days < 1 {
+		return "", "", false
+	}
+	m := t1.AddDate(0, 0, (days-1)/2)
+	return m.Format("2006-01-02"), m.AddDate(0, 0, 1).Format("2006-01-02"), true
+}
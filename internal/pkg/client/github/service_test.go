@@ -0,0 +1,244 @@
+package github_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github/githubtest"
+)
+
+// newUsers returns n users in Malaysia, spread evenly across the days from the start
+func newUsers(n, days int, start string) []github.User {
+	t, _ := time.Parse("2006-01-02", start)
+	users := make([]github.User, n)
+	for i := range users {
+		users[i] = github.User{
+			Login:     fmt.Sprintf("user%04d", i),
+			Location:  "Kuala Lumpur, Malaysia",
+			CreatedAt: t.AddDate(0, 0, i%days).Add(time.Duration(i) * time.Second),
+		}
+	}
+	return users
+}
+
+func newServer(t *testing.T, fixtures *githubtest.Fixtures) (*githubtest.Server, github.Service) {
+	t.Helper()
+	srv := githubtest.NewServer(fixtures)
+	t.Cleanup(srv.Close)
+	return srv, github.New(srv.Client(), []string{"token"}, srv.Endpoint())
+}
+
+// fetchUsers collects the logins and the cursors of the pages
+func fetchUsers(api github.Service, start, end, cursor string, limit int) ([]string, []github.Cursor, error) {
+	var (
+		logins  []string
+		cursors []github.Cursor
+	)
+	err := api.FetchUsersCursor(context.Background(), "Malaysia", start, end, cursor, limit, func(users []github.User, c github.Cursor) error {
+		for _, u := range users {
+			logins = append(logins, u.Login)
+		}
+		cursors = append(cursors, c)
+		return nil
+	})
+	return logins, cursors, err
+}
+
+func TestFetchUsersCursor(t *testing.T) {
+	_, api := newServer(t, &githubtest.Fixtures{Users: newUsers(5, 5, "2018-01-01")})
+
+	logins, cursors, err := fetchUsers(api, "2018-01-01", "2018-01-31", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"user0000", "user0001", "user0002", "user0003", "user0004"}; !reflect.DeepEqual(logins, want) {
+		t.Fatalf("want %v, got %v", want, logins)
+	}
+	if len(cursors) != 3 {
+		t.Fatalf("want 3 pages, got %d", len(cursors))
+	}
+	if c := cursors[0]; c.Start != "2018-01-01" || c.End != "2018-01-31" || c.After == "" ||
+		c.Query != `location:Malaysia created:2018-01-01..2018-01-31` {
+		t.Fatalf("want the cursor of the window, got %+v", c)
+	}
+
+	// Resuming from the cursor of the first page skips the users fetched already
+	logins, _, err = fetchUsers(api, "2018-01-01", "2018-01-31", cursors[0].After, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"user0002", "user0003", "user0004"}; !reflect.DeepEqual(logins, want) {
+		t.Fatalf("want %v, got %v", want, logins)
+	}
+}
+
+func TestFetchUsersCursorSplit(t *testing.T) {
+	srv, api := newServer(t, &githubtest.Fixtures{Users: newUsers(1500, 10, "2018-01-01")})
+
+	logins, cursors, err := fetchUsers(api, "2018-01-01", "2018-01-10", "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, login := range logins {
+		if seen[login] {
+			t.Fatalf("want each user once, got %s again", login)
+		}
+		seen[login] = true
+	}
+	if len(seen) != 1500 {
+		t.Fatalf("want all 1500 users past the limit of the search, got %d", len(seen))
+	}
+
+	// The window is split in half, and each half fits within the limit
+	windows := make(map[string]int)
+	for _, c := range cursors {
+		windows[c.Start+".."+c.End]++
+	}
+	if want := map[string]int{"2018-01-01..2018-01-05": 8, "2018-01-06..2018-01-10": 8}; !reflect.DeepEqual(windows, want) {
+		t.Fatalf("want the pages of each half %v, got %v", want, windows)
+	}
+	// The first page of the whole window is only fetched for the count
+	if n := len(srv.Requests()); n != 17 {
+		t.Fatalf("want 17 requests, got %d", n)
+	}
+}
+
+func TestFetchUsersCursorPartial(t *testing.T) {
+	srv, api := newServer(t, &githubtest.Fixtures{Users: newUsers(5, 5, "2018-01-01")})
+	srv.Inject(githubtest.None, githubtest.InternalServerError)
+
+	logins, cursors, err := fetchUsers(api, "2018-01-01", "2018-01-31", "", 2)
+	perr, ok := err.(*github.PartialError)
+	if !ok {
+		t.Fatalf("want a partial error, got %v", err)
+	}
+	if len(cursors) != 1 || perr.Cursor != cursors[0].After {
+		t.Fatalf("want the cursor of the last page fetched %+v, got %q", cursors, perr.Cursor)
+	}
+	if terr, ok := perr.Err.(*github.TransportError); !ok || terr.Status != 500 {
+		t.Fatalf("want the server error, got %v", perr.Err)
+	}
+
+	// Resuming from the cursor of the error fetches the rest of the users
+	rest, _, err := fetchUsers(api, "2018-01-01", "2018-01-31", perr.Cursor, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := append(logins, rest...); len(got) != 5 || got[2] != "user0002" {
+		t.Fatalf("want all users once, got %v", got)
+	}
+}
+
+func TestFetchUsersCursorRevoked(t *testing.T) {
+	srv := githubtest.NewServer(&githubtest.Fixtures{Users: newUsers(5, 5, "2018-01-01")})
+	defer srv.Close()
+	api := github.New(srv.Client(), []string{"revoked-aaaa", "valid-bbbb"}, srv.Endpoint())
+
+	// The token rejected as bad credentials is taken out of the pool, and the call is made
+	// again with the next token
+	srv.Inject(githubtest.BadCredentials)
+	logins, _, err := fetchUsers(api, "2018-01-01", "2018-01-31", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logins) != 5 {
+		t.Fatalf("want 5 users, got %v", logins)
+	}
+	tokens := api.Tokens(context.Background())
+	if !tokens[0].Revoked || tokens[0].Calls != 0 || tokens[1].Revoked || tokens[1].Calls != 1 {
+		t.Fatalf("want the first token revoked and the call made with the second, got %+v", tokens)
+	}
+
+	// Once every token is revoked, the pagination stops
+	srv.Inject(githubtest.BadCredentials)
+	_, _, err = fetchUsers(api, "2018-01-01", "2018-01-31", "", 10)
+	perr, ok := err.(*github.PartialError)
+	if !ok {
+		t.Fatalf("want a partial error, got %v", err)
+	}
+	if aerr, ok := perr.Err.(*github.AuthError); !ok || aerr.Status != 401 {
+		t.Fatalf("want an auth error, got %v", perr.Err)
+	}
+}
+
+// fetchRepos collects the names and the cursors of the pages
+func fetchRepos(api github.Service, login, since, cursor string, limit int) ([]string, []github.Cursor, error) {
+	var (
+		names   []string
+		cursors []github.Cursor
+	)
+	err := api.FetchReposCursor(context.Background(), login, since, cursor, limit, func(repos []github.Repo, c github.Cursor) error {
+		for _, r := range repos {
+			names = append(names, strings.TrimPrefix(r.NameWithOwner, login+"/"))
+		}
+		cursors = append(cursors, c)
+		return nil
+	})
+	return names, cursors, err
+}
+
+func loadFixtures(t *testing.T) *githubtest.Fixtures {
+	t.Helper()
+	fixtures, err := githubtest.LoadFixtures("githubtest/testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixtures
+}
+
+func TestFetchReposCursor(t *testing.T) {
+	srv, api := newServer(t, loadFixtures(t))
+
+	// The forks are left out, and the most recently pushed come first
+	names, cursors, err := fetchRepos(api, "alextanhongpin", "", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go-github-scraper", "go-rate", "recsys", "dotfiles"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("want %v, got %v", want, names)
+	}
+	if len(cursors) != 2 {
+		t.Fatalf("want 2 pages, got %d", len(cursors))
+	}
+
+	// The pagination stops at the first repo pushed before the last run
+	before := len(srv.Requests())
+	names, _, err = fetchRepos(api, "alextanhongpin", "2018-06-01T00:00:00Z", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go-github-scraper", "go-rate"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("want %v, got %v", want, names)
+	}
+	if n := len(srv.Requests()) - before; n != 3 {
+		t.Fatalf("want 3 requests, got %d", n)
+	}
+}
+
+func TestFetchReposCursorPartial(t *testing.T) {
+	srv, api := newServer(t, loadFixtures(t))
+	srv.Inject(githubtest.None, githubtest.QueryFailed)
+
+	names, cursors, err := fetchRepos(api, "alextanhongpin", "", "", 1)
+	perr, ok := err.(*github.PartialError)
+	if !ok {
+		t.Fatalf("want a partial error, got %v", err)
+	}
+	if len(cursors) != 1 || perr.Cursor != cursors[0].After {
+		t.Fatalf("want the cursor of the last page fetched %+v, got %q", cursors, perr.Cursor)
+	}
+
+	rest, _, err := fetchRepos(api, "alextanhongpin", "", perr.Cursor, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go-github-scraper", "go-rate", "recsys", "dotfiles"}; !reflect.DeepEqual(append(names, rest...), want) {
+		t.Fatalf("want %v, got %v", want, append(names, rest...))
+	}
+}
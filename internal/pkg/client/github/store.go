@@ -2,81 +2,239 @@ package github
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxRetries     = 5
+	initialBackoff = time.Second
+	maxBackoff     = time.Minute
 )
 
 // Store represents the interface for the Github Service
 type (
 	Store interface {
-		FetchUsers(req FetchUsersRequest) (*FetchUsersResponse, error)
-		FetchRepos(req FetchReposRequest) (*FetchReposResponse, error)
+		FetchUsers(ctx context.Context, req FetchUsersRequest) (*FetchUsersResponse, error)
+		FetchRepos(ctx context.Context, req FetchReposRequest) (*FetchReposResponse, error)
+		FetchReposByID(ctx context.Context, req FetchReposByIDRequest) (*FetchReposByIDResponse, error)
+		RateLimit() RateLimit
+		Tokens() []TokenUsage
 	}
 
 	// store holds the store configuration
 	store struct {
 		client   *http.Client
-		token    string
+		tokens   *pool
 		endpoint string
 	}
 )
 
-// NewStore returns a new store
-func NewStore(client *http.Client, token, endpoint string) Store {
+// NewStore returns a new store that spreads the calls across the tokens
+func NewStore(client *http.Client, tokens []string, endpoint string) Store {
 	return &store{
 		client:   client,
-		token:    token,
+		tokens:   newPool(tokens...),
 		endpoint: endpoint,
 	}
 }
 
-func (s *store) FetchUsers(req FetchUsersRequest) (*FetchUsersResponse, error) {
-	jsonBytes, err := json.Marshal(GraphQLQuery{req.String()})
+func (s *store) FetchUsers(ctx context.Context, req FetchUsersRequest) (*FetchUsersResponse, error) {
+	jsonBytes, err := json.Marshal(req.GraphQLQuery())
 	if err != nil {
 		return nil, err
 	}
 
-	jsonResp, err := graphqlService(s.client, s.token, s.endpoint, jsonBytes)
+	jsonResp, header, t, err := s.call(ctx, jsonBytes)
 	if err != nil {
 		return nil, err
 	}
 	var resp FetchUsersResponse
 	if err := json.Unmarshal(jsonResp, &resp); err != nil {
+		return nil, &TransportError{Status: http.StatusOK, Body: truncate(string(jsonResp)), Err: err}
+	}
+	s.tokens.Used(t, resp.Data.RateLimit)
+	if err := newQueryError(resp.Errors, resp.Data.RateLimit); err != nil {
+		s.exhaust(t, header, err)
 		return nil, err
 	}
 	return &resp, nil
 }
 
-func (s *store) FetchRepos(req FetchReposRequest) (*FetchReposResponse, error) {
-	jsonBytes, err := json.Marshal(GraphQLQuery{req.String()})
+func (s *store) FetchRepos(ctx context.Context, req FetchReposRequest) (*FetchReposResponse, error) {
+	jsonBytes, err := json.Marshal(req.GraphQLQuery())
 	if err != nil {
 		return nil, err
 	}
 
-	jsonResp, err := graphqlService(s.client, s.token, s.endpoint, jsonBytes)
+	jsonResp, header, t, err := s.call(ctx, jsonBytes)
 	if err != nil {
 		return nil, err
 	}
 
 	var resp FetchReposResponse
 	if err := json.Unmarshal(jsonResp, &resp); err != nil {
+		return nil, &TransportError{Status: http.StatusOK, Body: truncate(string(jsonResp)), Err: err}
+	}
+	s.tokens.Used(t, resp.Data.RateLimit)
+	if err := newQueryError(resp.Errors, resp.Data.RateLimit); err != nil {
+		s.exhaust(t, header, err)
 		return nil, err
 	}
 	return &resp, nil
 }
 
-func graphqlService(client *http.Client, token, endpoint string, body []byte) ([]byte, error) {
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(body))
-	req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+func (s *store) FetchReposByID(ctx context.Context, req FetchReposByIDRequest) (*FetchReposByIDResponse, error) {
+	jsonBytes, err := json.Marshal(req.GraphQLQuery())
 	if err != nil {
 		return nil, err
 	}
-	resp, err := client.Do(req)
+
+	jsonResp, header, t, err := s.call(ctx, jsonBytes)
 	if err != nil {
 		return nil, err
 	}
+
+	var resp FetchReposByIDResponse
+	if err := json.Unmarshal(jsonResp, &resp); err != nil {
+		return nil, &TransportError{Status: http.StatusOK, Body: truncate(string(jsonResp)), Err: err}
+	}
+	s.tokens.Used(t, resp.Data.RateLimit)
+
+	// The repos that no longer exist are returned as null nodes, together with a not found error each
+	if err := newQueryError(withoutNotFound(resp.Errors), resp.Data.RateLimit); err != nil {
+		s.exhaust(t, header, err)
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RateLimit returns the combined rate limit of the tokens in the pool
+func (s *store) RateLimit() RateLimit {
+	return s.tokens.RateLimit()
+}
+
+// Tokens returns the usage of each token in the pool
+func (s *store) Tokens() []TokenUsage {
+	return s.tokens.Usage()
+}
+
+// call makes the call with the next token in the pool. A token that is rejected as bad
+// credentials is taken out of the pool, and the call is made again with the next token
+func (s *store) call(ctx context.Context, body []byte) ([]byte, http.Header, *token, error) {
+	for {
+		t, err := s.tokens.Get()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		header, data, err := graphqlService(ctx, s.client, t.value, s.endpoint, body)
+		if aerr, ok := err.(*AuthError); ok && aerr.Status == http.StatusUnauthorized {
+			s.tokens.Revoke(t)
+			continue
+		}
+		s.exhaust(t, header, err)
+		return data, header, t, err
+	}
+}
+
+// exhaust keeps the token out of the rotation until the reset when the call is rate limited.
+// The rate limit is null in the response then, so the reset is taken from the headers
+func (s *store) exhaust(t *token, header http.Header, err error) {
+	rerr, ok := err.(*RateLimitError)
+	if !ok {
+		return
+	}
+	if rerr.ResetAt.IsZero() {
+		rerr.ResetAt = resetAt(header)
+	}
+	s.tokens.Exhaust(t, rerr.ResetAt)
+}
+
+// graphqlService makes the call to the GraphQL endpoint, and retries with exponential
+// backoff when Github responds with a secondary rate limit or a bad gateway. The wait
+// between the retries ends early when the context is cancelled
+func graphqlService(ctx context.Context, client *http.Client, token, endpoint string, body []byte) (http.Header, []byte, error) {
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		status, header, data, err := graphqlCall(ctx, client, token, endpoint, body)
+		if err != nil {
+			return header, nil, &TransportError{Status: status, Err: err}
+		}
+		if !shouldRetry(status, data) || attempt == maxRetries {
+			if status != http.StatusOK {
+				return header, nil, newStatusError(status, data)
+			}
+			return header, data, nil
+		}
+		select {
+		case <-ctx.Done():
+			return header, nil, &TransportError{Status: status, Err: ctx.Err()}
+		case <-time.After(retryAfter(header, backoff)):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func graphqlCall(ctx context.Context, client *http.Client, token, endpoint string, body []byte) (int, http.Header, []byte, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
 	defer resp.Body.Close()
-	return ioutil.ReadAll(resp.Body)
+	data, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header, data, err
+}
+
+// shouldRetry checks if the response is a bad gateway or a secondary rate limit,
+// which Github returns as a forbidden status with the reason in the message
+func shouldRetry(status int, body []byte) bool {
+	switch status {
+	case http.StatusBadGateway:
+		return true
+	case http.StatusForbidden:
+		msg := strings.ToLower(string(body))
+		return strings.Contains(msg, "secondary rate limit") || strings.Contains(msg, "abuse")
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the duration specified by the Retry-After header, or the backoff if not present
+func retryAfter(header http.Header, backoff time.Duration) time.Duration {
+	if secs, err := strconv.Atoi(header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return backoff
+}
+
+// resetAt returns the time the rate limit resets, from the Retry-After seconds or the
+// X-RateLimit-Reset epoch seconds. Without either, the token is rested for the hour
+// that the primary rate limit spans
+func resetAt(header http.Header) time.Time {
+	now := time.Now()
+	if secs, err := strconv.Atoi(header.Get("Retry-After")); err == nil && secs > 0 {
+		return now.Add(time.Duration(secs) * time.Second)
+	}
+	if epoch, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if t := time.Unix(epoch, 0); t.After(now) {
+			return t
+		}
+	}
+	return now.Add(time.Hour)
 }
@@ -19,25 +19,105 @@ type tracingMiddleware struct {
 	service Service
 }
 
-func (m *tracingMiddleware) FetchUsersCursor(ctx context.Context, location, start, end string, limit int) (users []User, err error) {
+func (m *tracingMiddleware) FetchUsersCursor(ctx context.Context, location, start, end, cursor string, limit int, fn UsersFn) error {
 	ctx, span := trace.StartSpan(ctx, "FetchUsersCursor")
 	defer span.End()
 
 	span.AddAttributes(
 		trace.StringAttribute("start", start),
 		trace.StringAttribute("end", end),
+		trace.StringAttribute("after", cursor),
 		trace.Int64Attribute("limit", int64(limit)))
 
-	return m.service.FetchUsersCursor(ctx, location, start, end, limit)
+	err := m.service.FetchUsersCursor(ctx, location, start, end, cursor, limit, fn)
+	setStatus(span, err)
+	addTokenAttributes(span, m.service.Tokens(ctx))
+	return err
 }
-func (m *tracingMiddleware) FetchReposCursor(ctx context.Context, login, start, end string, limit int) (repos []Repo, err error) {
+
+func (m *tracingMiddleware) FetchReposCursor(ctx context.Context, login, since, cursor string, limit int, fn ReposFn) error {
 	ctx, span := trace.StartSpan(ctx, "FetchReposCursor")
 	defer span.End()
 
 	span.AddAttributes(
-		trace.StringAttribute("start", start),
-		trace.StringAttribute("end", end),
+		trace.StringAttribute("since", since),
+		trace.StringAttribute("after", cursor),
 		trace.Int64Attribute("limit", int64(limit)))
 
-	return m.service.FetchReposCursor(ctx, login, start, end, limit)
+	err := m.service.FetchReposCursor(ctx, login, since, cursor, limit, fn)
+	setStatus(span, err)
+	addTokenAttributes(span, m.service.Tokens(ctx))
+	return err
+}
+
+func (m *tracingMiddleware) FetchReposByID(ctx context.Context, ids []string) ([]Repo, error) {
+	ctx, span := trace.StartSpan(ctx, "FetchReposByID")
+	defer span.End()
+
+	span.AddAttributes(trace.Int64Attribute("ids", int64(len(ids))))
+
+	res, err := m.service.FetchReposByID(ctx, ids)
+	setStatus(span, err)
+	addTokenAttributes(span, m.service.Tokens(ctx))
+	return res, err
+}
+
+func (m *tracingMiddleware) RateLimit(ctx context.Context) RateLimit {
+	ctx, span := trace.StartSpan(ctx, "RateLimit")
+	defer span.End()
+
+	res := m.service.RateLimit(ctx)
+
+	span.AddAttributes(
+		trace.Int64Attribute("limit", int64(res.Limit)),
+		trace.Int64Attribute("remaining", int64(res.Remaining)))
+
+	return res
+}
+
+func (m *tracingMiddleware) Tokens(ctx context.Context) []TokenUsage {
+	ctx, span := trace.StartSpan(ctx, "Tokens")
+	defer span.End()
+
+	res := m.service.Tokens(ctx)
+	addTokenAttributes(span, res)
+
+	return res
+}
+
+// addTokenAttributes records the usage of each token on the span
+func addTokenAttributes(span *trace.Span, tokens []TokenUsage) {
+	for _, t := range tokens {
+		span.AddAttributes(
+			trace.Int64Attribute("token."+t.ID+".calls", int64(t.Calls)),
+			trace.Int64Attribute("token."+t.ID+".remaining", int64(t.RateLimit.Remaining)),
+			trace.BoolAttribute("token."+t.ID+".revoked", t.Revoked))
+	}
+}
+
+// setStatus records the error on the span, with the status code that matches the error type
+func setStatus(span *trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	if perr, ok := err.(*PartialError); ok {
+		span.AddAttributes(trace.StringAttribute("cursor", perr.Cursor))
+		err = perr.Err
+	}
+	var code int32
+	switch err.(type) {
+	case *AuthError:
+		code = trace.StatusCodeUnauthenticated
+	case *RateLimitError:
+		code = trace.StatusCodeResourceExhausted
+	case *NotFoundError:
+		code = trace.StatusCodeNotFound
+	case *QueryError:
+		code = trace.StatusCodeInvalidArgument
+	case *TransportError:
+		code = trace.StatusCodeUnavailable
+	default:
+		code = trace.StatusCodeUnknown
+	}
+	span.SetStatus(trace.Status{Code: code, Message: err.Error()})
 }
@@ -2,6 +2,8 @@ package cronjob
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/robfig/cron"
 	"go.uber.org/zap"
@@ -13,6 +15,26 @@ type (
 		Do(ctx context.Context)
 	}
 
+	// Locker holds the lock of a job while it runs, so that the job runs on one instance at a time.
+	// The context returned is cancelled when the lock is lost, and unlock releases the lock
+	Locker interface {
+		Lock(ctx context.Context, name string) (lctx context.Context, unlock func(), err error)
+	}
+
+	// Recorder keeps the outcome of each run of the jobs
+	Recorder interface {
+		Record(ctx context.Context, res Result)
+	}
+
+	// Result is the outcome of a run of the job, with the outcome of each task run with Parallel
+	Result struct {
+		Name      string
+		StartedAt time.Time
+		EndedAt   time.Time
+		Err       error
+		Tasks     []TaskResult
+	}
+
 	// Config represents the cronjob config
 	Config struct {
 		Name        string
@@ -21,6 +43,12 @@ type (
 		CronTab     string
 		Trigger     bool
 		Fn          func(ctx context.Context) error
+
+		// Locker is optional, the job runs without a lock when it is not set
+		Locker Locker
+
+		// Recorder is optional, the runs are only logged when it is not set
+		Recorder Recorder
 	}
 )
 
@@ -28,7 +56,7 @@ type (
 func (cfg *Config) Do(ctx context.Context) {
 
 	if cfg.Trigger {
-		go cfg.Fn(ctx)
+		go cfg.run(ctx)
 	}
 
 	if !cfg.Start {
@@ -36,7 +64,7 @@ func (cfg *Config) Do(ctx context.Context) {
 	}
 	c := cron.New()
 	c.AddFunc(cfg.CronTab, func() {
-		cfg.Fn(ctx)
+		cfg.run(ctx)
 	})
 	c.Start()
 	zap.L().Info("started cron",
@@ -44,6 +72,47 @@ func (cfg *Config) Do(ctx context.Context) {
 		zap.String("tab", cfg.CronTab))
 }
 
+// run calls the job while holding the lock, and records the outcome. The run is skipped,
+// and not recorded, when the lock is held by another instance, or by the previous run that
+// has not finished. A panic in the job is recorded as the error of the run
+func (cfg *Config) run(ctx context.Context) (err error) {
+	if cfg.Locker != nil {
+		lctx, unlock, err := cfg.Locker.Lock(ctx, cfg.Name)
+		if err != nil {
+			zap.L().Info("skipped cron",
+				zap.String("name", cfg.Name),
+				zap.Error(err))
+			return err
+		}
+		defer unlock()
+		ctx = lctx
+	}
+
+	log := new(taskLog)
+	ctx = context.WithValue(ctx, taskLogKey{}, log)
+	defer func(start time.Time) {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil {
+			zap.L().Warn("cron failed",
+				zap.String("name", cfg.Name),
+				zap.Error(err))
+		}
+		if cfg.Recorder != nil {
+			cfg.Recorder.Record(ctx, Result{
+				Name:      cfg.Name,
+				StartedAt: start,
+				EndedAt:   time.Now(),
+				Err:       err,
+				Tasks:     log.list(),
+			})
+		}
+	}(time.Now())
+
+	return cfg.Fn(ctx)
+}
+
 // Exec runs a list of cronjobs
 func Exec(ctx context.Context, jobs ...CronJob) {
 	for _, j := range jobs {
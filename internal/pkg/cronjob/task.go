@@ -0,0 +1,85 @@
+package cronjob
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/null"
+)
+
+type taskLogKey struct{}
+
+// Task is a named part of a job, whose outcome is recorded with the run of the job
+type Task struct {
+	Name string
+	Fn   null.Fn
+}
+
+// TaskResult is the outcome of a task
+type TaskResult struct {
+	Name      string
+	StartedAt time.Time
+	EndedAt   time.Time
+	Err       error
+}
+
+// taskLog collects the outcome of the tasks during a run
+type taskLog struct {
+	sync.Mutex
+	results []TaskResult
+}
+
+func (l *taskLog) add(res TaskResult) {
+	l.Lock()
+	defer l.Unlock()
+	l.results = append(l.results, res)
+}
+
+func (l *taskLog) list() []TaskResult {
+	l.Lock()
+	defer l.Unlock()
+	res := make([]TaskResult, len(l.results))
+	copy(res, l.results)
+	return res
+}
+
+// Parallel runs the tasks concurrently and waits for all of them. The outcome of each task
+// is recorded with the run of the job in the context. The error returned counts the tasks
+// that failed, with the error of the first one
+func Parallel(ctx context.Context, tasks ...Task) error {
+	log, _ := ctx.Value(taskLogKey{}).(*taskLog)
+	results := make([]TaskResult, len(tasks))
+
+	var wg sync.WaitGroup
+	wg.Add(len(tasks))
+	for i, task := range tasks {
+		go func(i int, task Task) {
+			defer wg.Done()
+			start := time.Now()
+			err := task.Fn()
+			results[i] = TaskResult{
+				Name:      task.Name,
+				StartedAt: start,
+				EndedAt:   time.Now(),
+				Err:       err,
+			}
+		}(i, task)
+	}
+	wg.Wait()
+
+	var failed []TaskResult
+	for _, res := range results {
+		if log != nil {
+			log.add(res)
+		}
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d tasks failed, %s: %s", len(failed), len(tasks), failed[0].Name, failed[0].Err.Error())
+}
@@ -1,8 +1,13 @@
 package database
 
 const (
-	Stats    = "stats"
-	Profiles = "profiles"
-	Repos    = "repos"
-	Users    = "users"
+	Stats        = "stats"
+	StatsHistory = "stats_history"
+	Profiles     = "profiles"
+	Repos        = "repos"
+	SimilarRepos = "similar_repos"
+	Users        = "users"
+	Checkpoints  = "checkpoints"
+	Locks        = "locks"
+	JobRuns      = "job_runs"
 )
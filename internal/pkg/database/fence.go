@@ -0,0 +1,18 @@
+package database
+
+import (
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ByFence returns the condition of a write under the fence, for the $or of the query of an
+// upsert. The document is only matched when it was last written under another lease, or
+// under a token that is not newer. A stale write matches nothing and fails on the unique
+// index as it inserts instead, which the stores report as fence.ErrStale
+func ByFence(f fence.Fence) []bson.M {
+	return []bson.M{
+		{"fence.name": bson.M{"$ne": f.Name}},
+		{"fence.token": bson.M{"$lte": f.Token}},
+	}
+}
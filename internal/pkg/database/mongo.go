@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// timeout is the time given to each call to the mongo db, the same as the dial timeout of
+// the mgo session
+const timeout = time.Minute * 1
+
+// Mongo holds the client of the official mongo driver, go.mongodb.org/mongo-driver
+type Mongo struct {
+	Client *mongo.Client
+	Name   string
+}
+
+// Close disconnects the client
+func (db *Mongo) Close() {
+	ctx, cancel := Context()
+	defer cancel()
+	db.Client.Disconnect(ctx)
+}
+
+// Collection returns the collection of the db. Unlike with mgo, the client is safe for
+// concurrent use, and there is no session to copy
+func (db *Mongo) Collection(name string) *mongo.Collection {
+	return db.Client.Database(db.Name).Collection(name)
+}
+
+// Context returns the context of a call to the db, which times out
+func Context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// Sort returns the sort document of the fields, in the format of the Sort of mgo, where a
+// field prefixed with - is sorted in descending order
+func Sort(fields ...string) bson.D {
+	var sort bson.D
+	for _, field := range fields {
+		if strings.HasPrefix(field, "-") {
+			sort = append(sort, bson.E{Key: field[1:], Value: -1})
+		} else {
+			sort = append(sort, bson.E{Key: field, Value: 1})
+		}
+	}
+	return sort
+}
+
+// Text returns the keys of a text index over the fields
+func Text(fields ...string) bson.D {
+	keys := make(bson.D, len(fields))
+	for i, field := range fields {
+		keys[i] = bson.E{Key: field, Value: "text"}
+	}
+	return keys
+}
+
+// NewMongo connects to the mongo db with the official driver. The address is the host of
+// the server, as for New, or a connection string
+func NewMongo(addr, username, password, database, auth string) (*Mongo, error) {
+	uri := addr
+	if !strings.Contains(uri, "://") {
+		uri = "mongodb://" + uri
+	}
+	opts := options.Client().ApplyURI(uri).SetConnectTimeout(timeout)
+	if username != "" {
+		opts.SetAuth(options.Credential{
+			Username:   username,
+			Password:   password,
+			AuthSource: auth,
+		})
+	}
+
+	ctx, cancel := Context()
+	defer cancel()
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	return &Mongo{
+		Client: client,
+		Name:   database,
+	}, nil
+}
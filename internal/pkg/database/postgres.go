@@ -0,0 +1,308 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/fence"
+)
+
+// Postgres holds the pool of connections to the postgres db. Each collection of the mongo db
+// is a table of jsonb documents, keyed by the field the collection is unique by, and the
+// documents have the fields of the mongo documents
+type Postgres struct {
+	DB *sql.DB
+}
+
+// NewPostgres connects to the postgres db at the url, with the driver registered as postgres,
+// e.g. by a blank import of github.com/lib/pq
+func NewPostgres(url string) (*Postgres, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := Context()
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Postgres{DB: db}, nil
+}
+
+// Close closes the connections of the pool
+func (db *Postgres) Close() {
+	db.DB.Close()
+}
+
+// CreateTable creates the table of the documents if it does not exist, with the indexes on
+// the expressions of the documents
+func (db *Postgres) CreateTable(table string, indexes ...string) error {
+	if _, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (id text PRIMARY KEY, doc jsonb NOT NULL)`, table)); err != nil {
+		return err
+	}
+	for i, index := range indexes {
+		if _, err := db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_%d ON %s %s`, table, i, table, index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exec runs the statement, which times out as the calls to the mongo db do
+func (db *Postgres) Exec(query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := Context()
+	defer cancel()
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+// Tx runs fn in a transaction, which is rolled back when fn fails
+func (db *Postgres) Tx(fn func(tx *sql.Tx) error) error {
+	ctx, cancel := Context()
+	defer cancel()
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Rows calls fn with each row returned by the query
+func (db *Postgres) Rows(fn func(rows *sql.Rows) error, query string, args ...interface{}) error {
+	ctx, cancel := Context()
+	defer cancel()
+
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Find decodes the documents returned by the query, in the first column, into res, which
+// points to a slice. The slice is left as it is when there are none
+func (db *Postgres) Find(res interface{}, query string, args ...interface{}) error {
+	var docs []string
+	if err := db.Rows(func(rows *sql.Rows) error {
+		var doc string
+		if err := rows.Scan(&doc); err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		return nil
+	}, query, args...); err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	return json.Unmarshal([]byte("["+strings.Join(docs, ",")+"]"), res)
+}
+
+// FindOne decodes the document of the first row returned by the query into res, or returns
+// apperr.ErrNotFound when there is none
+func (db *Postgres) FindOne(res interface{}, query string, args ...interface{}) error {
+	ctx, cancel := Context()
+	defer cancel()
+
+	var doc string
+	err := db.DB.QueryRowContext(ctx, query, args...).Scan(&doc)
+	if err == sql.ErrNoRows {
+		return apperr.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(doc), res)
+}
+
+// Count returns the count of the rows, for a query that selects count(*)
+func (db *Postgres) Count(query string, args ...interface{}) (int, error) {
+	ctx, cancel := Context()
+	defer cancel()
+
+	var n int
+	err := db.DB.QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+// Doc is a document to upsert by its id. Set holds the fields set on the document, as with
+// $set, and SetOnInsert the fields only set when it is inserted. AddToSet holds the values
+// added to the array fields, unless the arrays have them already
+type Doc struct {
+	ID          string
+	Set         map[string]interface{}
+	SetOnInsert map[string]interface{}
+	AddToSet    map[string]interface{}
+}
+
+// Upsert upserts the documents in a single transaction. Under a lease, the document last
+// written under a newer token of the lease is kept, and fence.ErrStale is returned instead
+func (db *Postgres) Upsert(table string, f fence.Fence, docs ...Doc) error {
+	return db.Tx(func(tx *sql.Tx) error {
+		for _, doc := range docs {
+			query, args, err := upsertQuery(table, f, doc)
+			if err != nil {
+				return err
+			}
+			res, err := tx.Exec(query, args...)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return err
+			} else if n == 0 {
+				return fence.ErrStale
+			}
+		}
+		return nil
+	})
+}
+
+// upsertQuery returns the statement of the upsert of the document. On conflict, the fields
+// set replace the fields of the document in the table, and the others are kept
+func upsertQuery(table string, f fence.Fence, doc Doc) (string, []interface{}, error) {
+	set := make(map[string]interface{}, len(doc.Set)+1)
+	for field, value := range doc.Set {
+		set[field] = value
+	}
+	if !f.IsZero() {
+		set["fence"] = f
+	}
+	insert := make(map[string]interface{}, len(set)+len(doc.SetOnInsert)+len(doc.AddToSet))
+	for field, value := range doc.SetOnInsert {
+		insert[field] = value
+	}
+	for field, value := range set {
+		insert[field] = value
+	}
+
+	var w Where
+	id := w.Arg(doc.ID)
+	update, err := w.JSONArg(set)
+	if err != nil {
+		return "", nil, err
+	}
+	update = "t.doc || " + update
+	for field, value := range doc.AddToSet {
+		insert[field] = []interface{}{value}
+		values, err := w.JSONArg([]interface{}{value})
+		if err != nil {
+			return "", nil, err
+		}
+		arr := JSONArray("t.doc->'" + field + "'")
+		update += fmt.Sprintf(` || jsonb_build_object('%s', CASE WHEN %s @> %s THEN %s ELSE %s || %s END)`,
+			field, arr, values, arr, arr, values)
+	}
+	values, err := w.JSONArg(insert)
+	if err != nil {
+		return "", nil, err
+	}
+	w.ByFence(f, "t.doc")
+
+	query := fmt.Sprintf(`INSERT INTO %s AS t (id, doc) VALUES (%s, %s) ON CONFLICT (id) DO UPDATE SET doc = %s%s`,
+		table, id, values, update, w.String())
+	return query, w.Args, nil
+}
+
+// Where builds the conditions of a query, with the arguments numbered in the order they
+// are added
+type Where struct {
+	conds []string
+	Args  []interface{}
+}
+
+// ByFence adds the condition of a write under the fence on the document column, as ByFence
+// does for mongo. The document is only matched when it was last written under another lease,
+// or under a token that is not newer. Writes made without a lease match every document
+func (w *Where) ByFence(f fence.Fence, doc string) {
+	if f.IsZero() {
+		return
+	}
+	w.Add(`COALESCE(`+doc+`->'fence'->>'name', '') <> %s OR (`+doc+`->'fence'->>'token')::bigint <= %s`, f.Name, f.Token)
+}
+
+// Add adds the condition, where the verbs are replaced by the placeholders of the args in
+// order, e.g. %s or %[1]s for the first
+func (w *Where) Add(cond string, args ...interface{}) {
+	placeholders := make([]interface{}, len(args))
+	for i, arg := range args {
+		placeholders[i] = w.Arg(arg)
+	}
+	w.conds = append(w.conds, "("+fmt.Sprintf(cond, placeholders...)+")")
+}
+
+// Arg adds the arg and returns its placeholder, for the parts of the query other than the
+// conditions
+func (w *Where) Arg(arg interface{}) string {
+	w.Args = append(w.Args, arg)
+	return "$" + strconv.Itoa(len(w.Args))
+}
+
+// JSONArg adds the value encoded as a jsonb arg, and returns its placeholder
+func (w *Where) JSONArg(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return w.Arg(string(b)) + "::jsonb", nil
+}
+
+// String returns the where clause of the conditions, or nothing without conditions
+func (w *Where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// JSONInt returns the expression of the integer field of the document, which is zero when
+// the field is missing, as the counters written by the mongo stores always have a value
+func JSONInt(field string) string {
+	return fmt.Sprintf(`COALESCE((doc->>'%s')::bigint, 0)`, field)
+}
+
+// JSONText returns the expression of the text field of the document, which is null when
+// the field is missing or empty. It compares by bytes, as strings do in mongo
+func JSONText(field string) string {
+	return fmt.Sprintf(`NULLIF(doc->>'%s', '') COLLATE "C"`, field)
+}
+
+// JSONArray returns the expression of the array value, or of an empty array when the value
+// is missing or null
+func JSONArray(value string) string {
+	return fmt.Sprintf(`CASE jsonb_typeof(%[1]s) WHEN 'array' THEN %[1]s ELSE '[]'::jsonb END`, value)
+}
+
+// OrderBy returns the order of the expression, with the missing values first in ascending
+// order and last in descending order, as in mongo
+func OrderBy(expr string, desc bool) string {
+	if desc {
+		return expr + " DESC NULLS LAST"
+	}
+	return expr + " ASC NULLS FIRST"
+}
+
+// Limit returns the limit clause, or nothing for a limit of zero
+func Limit(limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	return " LIMIT " + strconv.Itoa(limit)
+}
@@ -3,30 +3,40 @@ package encoder
 import (
 	"encoding/json"
 	"net/http"
-)
 
-// ErrorJSON represents the error payload in json format
-type ErrorJSON struct {
-	Message string `json:"message,omitempty"`
-	Code    int    `json:"code,omitempty"`
-}
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/logger"
+)
 
 // SuccessJSON represents the success payload in json format
 type SuccessJSON struct {
 	Data interface{} `json:"data,omitempty"`
 }
 
-// JSON returns the encoded response as json
-func JSON(w http.ResponseWriter, err error, v interface{}) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+// JSON returns the encoded response as json, or the error as problem+json with the status
+// code mapped from the error
+func JSON(w http.ResponseWriter, r *http.Request, err error, v interface{}) {
 	if err != nil {
-		errJSON := ErrorJSON{
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
+		status := Status(err)
+		p := Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   err.Error(),
+			Instance: r.URL.RequestURI(),
 		}
-		json.NewEncoder(w).Encode(errJSON)
+		// Internal errors may carry details of the store, which should not be exposed
+		if status == http.StatusInternalServerError {
+			p.Detail = ""
+		}
+		if id, ok := r.Context().Value(logger.RequestID).(string); ok {
+			p.RequestID = id
+		}
+		w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(p)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(SuccessJSON{v}); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
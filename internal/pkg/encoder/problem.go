@@ -0,0 +1,45 @@
+package encoder
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/apperr"
+
+	mgo "gopkg.in/mgo.v2"
+)
+
+// Problem represents the error payload in the RFC 7807 problem details format, with
+// the id of the request to correlate the error with the logs
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Status maps the error to the http status code
+func Status(err error) int {
+	switch {
+	case apperr.IsNotFound(err), err == mgo.ErrNotFound:
+		return http.StatusNotFound
+	case apperr.IsValidation(err):
+		return http.StatusUnprocessableEntity
+	case isTimeout(err):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// isTimeout returns true if the deadline is exceeded, or the call to the store timed out
+func isTimeout(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	nerr, ok := err.(net.Error)
+	return ok && nerr.Timeout()
+}
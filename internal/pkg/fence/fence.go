@@ -0,0 +1,43 @@
+// Package fence carries the fencing token of the lease held by a job through the context,
+// so that the stores can reject the writes of a holder whose lease has been taken over
+package fence
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStale is returned by the stores when the write carries an older token than the last
+// write under the same lease, which means that the lease has been taken over since
+var ErrStale = errors.New("fence: the lease has been taken over by a newer holder")
+
+// Fence identifies the lease a write is made under, by the name of the lease and the token
+// it was acquired with. The tokens of the same lease only increase
+type Fence struct {
+	Name  string `json:"name" bson:"name"`
+	Token int64  `json:"token" bson:"token"`
+}
+
+// IsZero returns true for the writes made without a lease, which are never rejected
+func (f Fence) IsZero() bool {
+	return f.Name == "" && f.Token == 0
+}
+
+// Stale returns true if the write is made under an older token of the same lease than the
+// last write. The tokens of different leases are not comparable
+func (f Fence) Stale(last Fence) bool {
+	return !f.IsZero() && f.Name == last.Name && f.Token < last.Token
+}
+
+type contextKey struct{}
+
+// NewContext returns the context of the writes made under the lease
+func NewContext(ctx context.Context, f Fence) context.Context {
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+// FromContext returns the fence of the context, or the zero fence outside of a lease
+func FromContext(ctx context.Context) Fence {
+	f, _ := ctx.Value(contextKey{}).(Fence)
+	return f
+}
@@ -0,0 +1,53 @@
+// Package kv defines the buckets of an embedded key value store, which the memory stores of
+// the services write through to, so that the records outlive the application
+package kv
+
+import "gopkg.in/mgo.v2/bson"
+
+// Bucket holds the records of a store, encoded with bson, by their key
+type Bucket interface {
+	// ForEach calls fn with each record of the bucket, in the order of the keys
+	ForEach(fn func(key string, value []byte) error) error
+
+	// Put saves the records in a single transaction
+	Put(records map[string][]byte) error
+
+	// Delete removes the records of the keys, if they exist
+	Delete(keys ...string) error
+
+	// Clear removes all the records of the bucket
+	Clear() error
+}
+
+// Save encodes the records and puts them in the bucket. A nil bucket saves nothing, so
+// that the stores kept only in memory can call it as well
+func Save(b Bucket, records map[string]interface{}) error {
+	if b == nil || len(records) == 0 {
+		return nil
+	}
+	values := make(map[string][]byte, len(records))
+	for key, record := range records {
+		value, err := bson.Marshal(record)
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+	return b.Put(values)
+}
+
+// Delete removes the records of the keys from the bucket. A nil bucket deletes nothing
+func Delete(b Bucket, keys ...string) error {
+	if b == nil || len(keys) == 0 {
+		return nil
+	}
+	return b.Delete(keys...)
+}
+
+// Clear removes all the records of the bucket. A nil bucket clears nothing
+func Clear(b Bucket) error {
+	if b == nil {
+		return nil
+	}
+	return b.Clear()
+}
@@ -11,3 +11,8 @@ func NewUTCDate() string {
 func NewCurrentFormattedDate() string {
 	return time.Now().Format("2006-01-02")
 }
+
+// NewUTCFormattedDate returns a new string date in the format YYYY-MM-DD in UTC
+func NewUTCFormattedDate() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
@@ -0,0 +1,115 @@
+// Package order sorts the records of the stores kept in memory the way mongo sorts the
+// documents, so that they return the same results as the mongo stores
+package order
+
+import (
+	"sort"
+	"strings"
+)
+
+// Compare returns -1, 0 or 1 when a is less than, equal to or greater than b. Numbers are
+// compared by their value whatever their type, since the values of the cursors decoded
+// from json are float64. As in mongo, nil sorts first, then the numbers, then the strings
+func Compare(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return sign(float64(ra - rb))
+	}
+	switch ra {
+	case 1:
+		return sign(number(a) - number(b))
+	case 2:
+		return strings.Compare(a.(string), b.(string))
+	}
+	return 0
+}
+
+// Less returns the less function for sort.Slice, sorting by the keys in turn. A key is the
+// name of a field, prefixed with "-" to sort in descending order. value returns the value
+// of the field for the element at the index
+func Less(keys []string, value func(i int, field string) interface{}) func(i, j int) bool {
+	return func(i, j int) bool {
+		for _, key := range keys {
+			field, desc := key, false
+			if strings.HasPrefix(key, "-") {
+				field, desc = key[1:], true
+			}
+			c := Compare(value(i, field), value(j, field))
+			if c == 0 {
+				continue
+			}
+			if desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	}
+}
+
+// Counts sorts the keys by their count in descending order, and by the key when the counts
+// are the same, then returns the first n keys, or all keys when n is zero
+func Counts(count map[string]int, n int) []string {
+	keys := make([]string, 0, len(count))
+	for k := range count {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if count[keys[i]] != count[keys[j]] {
+			return count[keys[i]] > count[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// Missing reports whether the field with the value is missing from the record, the way the
+// stores written by the mongo stores see it. The counters are always written, so a number
+// is never missing, even when it is zero, while the dates and the other strings are left
+// out when they are empty
+func Missing(v interface{}) bool {
+	switch v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	}
+	return false
+}
+
+func rank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case int, int64, float64:
+		return 1
+	case string:
+		return 2
+	}
+	return 3
+}
+
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package recsys
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Fixture represents a labelled set of profiles for the offline evaluation. Relevant holds
+// the ids of the profiles that are known to be similar, by the id of the profile
+type Fixture struct {
+	Profiles []Profile           `json:"profiles"`
+	Relevant map[string][]string `json:"relevant"`
+}
+
+// LoadFixture decodes the fixture in the json format
+func LoadFixture(r io.Reader) (*Fixture, error) {
+	var f Fixture
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// PrecisionAtK returns the fraction of the top k recommendations that are relevant
+func PrecisionAtK(recommended, relevant []string, k int) float64 {
+	if k <= 0 {
+		return 0
+	}
+	if len(recommended) > k {
+		recommended = recommended[:k]
+	}
+	set := make(map[string]bool, len(relevant))
+	for _, id := range relevant {
+		set[id] = true
+	}
+	var hits int
+	for _, id := range recommended {
+		if set[id] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(k)
+}
+
+// Evaluate fits the recommender on the fixture, and returns the mean precision at k of the
+// recommendations for the profiles that are labelled
+func Evaluate(r Recommender, f *Fixture, k int) float64 {
+	r.Fit(f.Profiles)
+
+	var sum float64
+	var n int
+	for _, p := range f.Profiles {
+		relevant, ok := f.Relevant[p.ID]
+		if !ok {
+			continue
+		}
+		matches := TopK(r, p, f.Profiles, k)
+		ids := make([]string, len(matches))
+		for i, m := range matches {
+			ids[i] = m.ID
+		}
+		sum += PrecisionAtK(ids, relevant, k)
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
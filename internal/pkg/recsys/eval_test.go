@@ -0,0 +1,70 @@
+package recsys
+
+import (
+	"os"
+	"testing"
+)
+
+// minPrecision is the precision at 3 that the default weights must reach on the fixture,
+// which labels the users of the same community as relevant to each other
+const minPrecision = 0.9
+
+func loadFixture(t *testing.T) *Fixture {
+	t.Helper()
+	f, err := os.Open("testdata/fixture.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fixture, err := LoadFixture(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixture
+}
+
+func TestEvaluateDefaultWeights(t *testing.T) {
+	f := loadFixture(t)
+	if len(f.Relevant) == 0 {
+		t.Fatal("want labelled profiles, got none")
+	}
+
+	got := Evaluate(NewTFIDF(DefaultWeights), f, 3)
+	if got < minPrecision {
+		t.Fatalf("want precision@3 of at least %.2f, got %.2f", minPrecision, got)
+	}
+
+	// The fixture has a popular user in each community, so weighing the numeric
+	// features as much as the terms recommends the popular users of the others
+	equal := make(Weights, len(DefaultWeights))
+	for feature := range DefaultWeights {
+		equal[feature] = 1
+	}
+	if baseline := Evaluate(NewTFIDF(equal), f, 3); got <= baseline {
+		t.Fatalf("want precision@3 above %.2f of equal weights, got %.2f", baseline, got)
+	}
+}
+
+func TestPrecisionAtK(t *testing.T) {
+	relevant := []string{"a", "b", "c"}
+	tests := []struct {
+		name        string
+		recommended []string
+		k           int
+		want        float64
+	}{
+		{"all relevant", []string{"a", "b"}, 2, 1},
+		{"half relevant", []string{"a", "x"}, 2, 0.5},
+		{"beyond k", []string{"x", "a", "b"}, 1, 0},
+		{"fewer than k", []string{"a"}, 4, 0.25},
+		{"zero k", []string{"a"}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrecisionAtK(tt.recommended, relevant, tt.k); got != tt.want {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package recsys
+
+import "sort"
+
+// Index is an inverted index from the terms to the profiles that use them. It generates
+// the candidates to be scored, so that a profile is only compared with the profiles that
+// share at least one of its terms instead of every profile in the corpus
+type Index struct {
+	profiles []Profile
+	postings map[string][]int
+}
+
+// NewIndex indexes the terms of every field of the profiles
+func NewIndex(profiles []Profile) *Index {
+	idx := &Index{
+		profiles: profiles,
+		postings: make(map[string][]int),
+	}
+	for i, p := range profiles {
+		for field, terms := range p.Terms {
+			for term, count := range terms {
+				if count > 0 {
+					key := postingKey(field, term)
+					idx.postings[key] = append(idx.postings[key], i)
+				}
+			}
+		}
+	}
+	return idx
+}
+
+// Candidates returns up to max profiles that share a term with the profile, excluding the
+// profile itself. The rarest terms are visited first since they are the most specific, and
+// the number of postings visited is bounded so that terms used by almost every profile do
+// not turn the lookup into a scan of the corpus. The profiles sharing more terms come first
+func (idx *Index) Candidates(p Profile, max int) []Profile {
+	var keys []string
+	for field, terms := range p.Terms {
+		for term, count := range terms {
+			if count > 0 {
+				keys = append(keys, postingKey(field, term))
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return len(idx.postings[keys[i]]) < len(idx.postings[keys[j]])
+	})
+
+	budget := 10 * max
+	shared := make(map[int]int)
+visit:
+	for _, key := range keys {
+		for _, i := range idx.postings[key] {
+			if budget == 0 {
+				break visit
+			}
+			budget--
+			if idx.profiles[i].ID != p.ID {
+				shared[i]++
+			}
+		}
+	}
+
+	ids := make([]int, 0, len(shared))
+	for i := range shared {
+		ids = append(ids, i)
+	}
+	sort.Slice(ids, func(a, b int) bool {
+		if shared[ids[a]] != shared[ids[b]] {
+			return shared[ids[a]] > shared[ids[b]]
+		}
+		return ids[a] < ids[b]
+	})
+	if len(ids) > max {
+		ids = ids[:max]
+	}
+
+	res := make([]Profile, len(ids))
+	for i, id := range ids {
+		res[i] = idx.profiles[id]
+	}
+	return res
+}
+
+func postingKey(field, term string) string {
+	return field + "\x00" + term
+}
@@ -0,0 +1,69 @@
+// Package recsys recommends similar users based on the terms they use, such as their
+// languages and keywords, and their numeric features, such as the follower count
+package recsys
+
+import "sort"
+
+type (
+	// Profile represents the features of a user. Terms holds the term counts by field,
+	// and Numeric holds the raw counts by feature
+	Profile struct {
+		ID      string                        `json:"id"`
+		Terms   map[string]map[string]float64 `json:"terms,omitempty"`
+		Numeric map[string]float64            `json:"numeric,omitempty"`
+	}
+
+	// Match represents a profile recommended, and the similarity score
+	Match struct {
+		ID    string  `json:"id"`
+		Score float64 `json:"score"`
+	}
+
+	// Recommender represents a similarity engine. Fit computes the statistics of the
+	// corpus, and must be called before the similarity is computed
+	Recommender interface {
+		Fit(profiles []Profile)
+		Similarity(a, b Profile) float64
+	}
+)
+
+// TopK returns the k candidates that are most similar to the profile, sorted from the
+// most similar. The profile itself is excluded from the candidates
+func TopK(r Recommender, p Profile, candidates []Profile, k int) []Match {
+	matches := make([]Match, 0, len(candidates))
+	for _, c := range candidates {
+		if c.ID == p.ID {
+			continue
+		}
+		matches = append(matches, Match{
+			ID:    c.ID,
+			Score: r.Similarity(p, c),
+		})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// SharedTerms returns the terms found in both profiles by field, sorted by name. It explains
+// why the profiles are similar, e.g. the languages both users write
+func SharedTerms(a, b Profile) map[string][]string {
+	res := make(map[string][]string)
+	for field, terms := range a.Terms {
+		var shared []string
+		for term, count := range terms {
+			if count > 0 && b.Terms[field][term] > 0 {
+				shared = append(shared, term)
+			}
+		}
+		if len(shared) > 0 {
+			sort.Strings(shared)
+			res[field] = shared
+		}
+	}
+	return res
+}
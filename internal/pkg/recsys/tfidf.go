@@ -0,0 +1,168 @@
+package recsys
+
+import "math"
+
+// vector represents the unit tf-idf vector of a term field
+type vector map[string]float64
+
+// TFIDF scores the similarity as the weighted mean of the similarity of each feature.
+// Term fields are compared by the cosine similarity of their tf-idf vectors, so that rare
+// terms count more than the terms everyone uses. Numeric features are log-scaled and
+// normalized by the largest value in the corpus before they are compared, so that the
+// raw counts of popular users do not dominate the score
+type TFIDF struct {
+	weights Weights
+	n       int
+	idf     map[string]map[string]float64
+	maxLog  map[string]float64
+	vectors map[string]map[string]vector
+}
+
+// NewTFIDF returns a new tf-idf recommender with the weights of each feature
+func NewTFIDF(w Weights) *TFIDF {
+	return &TFIDF{weights: w}
+}
+
+// Fit computes the inverse document frequency of the terms and the range of the numeric
+// features, and caches the vectors of the profiles. Similarity is safe for concurrent
+// use once Fit returns
+func (t *TFIDF) Fit(profiles []Profile) {
+	t.n = len(profiles)
+	df := make(map[string]map[string]int)
+	t.maxLog = make(map[string]float64)
+	for _, p := range profiles {
+		for field, terms := range p.Terms {
+			if df[field] == nil {
+				df[field] = make(map[string]int)
+			}
+			for term, count := range terms {
+				if count > 0 {
+					df[field][term]++
+				}
+			}
+		}
+		for feature, v := range p.Numeric {
+			if l := math.Log1p(math.Max(v, 0)); l > t.maxLog[feature] {
+				t.maxLog[feature] = l
+			}
+		}
+	}
+
+	t.idf = make(map[string]map[string]float64)
+	for field, terms := range df {
+		t.idf[field] = make(map[string]float64)
+		for term, count := range terms {
+			t.idf[field][term] = t.smoothIDF(count)
+		}
+	}
+
+	t.vectors = make(map[string]map[string]vector)
+	for _, p := range profiles {
+		vectors := make(map[string]vector)
+		for field := range p.Terms {
+			vectors[field] = t.vectorize(p, field)
+		}
+		t.vectors[p.ID] = vectors
+	}
+}
+
+// Similarity returns the similarity of the profiles in the range [0, 1]. Features that
+// are missing in both profiles are left out of the mean
+func (t *TFIDF) Similarity(a, b Profile) float64 {
+	var score, total float64
+	for feature, w := range t.weights {
+		if w <= 0 {
+			continue
+		}
+		s, ok := t.featureSimilarity(a, b, feature)
+		if !ok {
+			continue
+		}
+		score += w * s
+		total += w
+	}
+	if total == 0 {
+		return 0
+	}
+	return score / total
+}
+
+func (t *TFIDF) featureSimilarity(a, b Profile, feature string) (float64, bool) {
+	_, aTerms := a.Terms[feature]
+	_, bTerms := b.Terms[feature]
+	if aTerms || bTerms {
+		va, vb := t.vector(a, feature), t.vector(b, feature)
+		if len(va) == 0 && len(vb) == 0 {
+			return 0, false
+		}
+		return cosine(va, vb), true
+	}
+
+	x, aNum := a.Numeric[feature]
+	y, bNum := b.Numeric[feature]
+	if !aNum && !bNum {
+		return 0, false
+	}
+	max := t.maxLog[feature]
+	if max == 0 {
+		return 0, false
+	}
+	nx := math.Log1p(math.Max(x, 0)) / max
+	ny := math.Log1p(math.Max(y, 0)) / max
+	return 1 - math.Min(math.Abs(nx-ny), 1), true
+}
+
+// vector returns the cached vector of the profile, or computes it for profiles that are
+// not in the corpus
+func (t *TFIDF) vector(p Profile, field string) vector {
+	if vectors, ok := t.vectors[p.ID]; ok {
+		if v, ok := vectors[field]; ok {
+			return v
+		}
+	}
+	return t.vectorize(p, field)
+}
+
+// vectorize computes the unit tf-idf vector of the field, with the sublinear term frequency
+func (t *TFIDF) vectorize(p Profile, field string) vector {
+	v := make(vector)
+	var norm float64
+	for term, count := range p.Terms[field] {
+		if count <= 0 {
+			continue
+		}
+		idf, ok := t.idf[field][term]
+		if !ok {
+			idf = t.smoothIDF(0)
+		}
+		w := (1 + math.Log(count)) * idf
+		v[term] = w
+		norm += w * w
+	}
+	if norm == 0 {
+		return v
+	}
+	norm = math.Sqrt(norm)
+	for term := range v {
+		v[term] /= norm
+	}
+	return v
+}
+
+// smoothIDF returns the inverse document frequency, smoothed so that terms found in every
+// profile still have a positive weight
+func (t *TFIDF) smoothIDF(df int) float64 {
+	return math.Log(float64(1+t.n)/float64(1+df)) + 1
+}
+
+// cosine returns the cosine similarity of the unit vectors
+func cosine(a, b vector) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var dot float64
+	for term, w := range a {
+		dot += w * b[term]
+	}
+	return dot
+}
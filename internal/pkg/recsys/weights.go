@@ -0,0 +1,54 @@
+package recsys
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Weights represents the weight of each feature in the similarity score. Features without
+// a weight are ignored
+type Weights map[string]float64
+
+// DefaultWeights favours the languages and keywords over the numeric features, so that
+// popular users do not dominate the recommendations
+var DefaultWeights = Weights{
+	"languages":  3,
+	"keywords":   2,
+	"followers":  0.5,
+	"following":  0.25,
+	"repos":      0.5,
+	"gists":      0.25,
+	"stargazers": 0.5,
+	"forks":      0.25,
+	"watchers":   0.25,
+}
+
+// DefaultRepoWeights favours the topics and the keywords of the description, since most
+// repos share one of the few popular languages
+var DefaultRepoWeights = Weights{
+	"topics":    3,
+	"keywords":  2,
+	"languages": 1,
+}
+
+// ParseWeights parses the weights in the format "languages=3,keywords=2,followers=0.5".
+// The default weights provided are returned for an empty string
+func ParseWeights(s string, defaults Weights) (Weights, error) {
+	if strings.TrimSpace(s) == "" {
+		return defaults, nil
+	}
+	w := make(Weights)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("recsys: invalid weight %q", pair)
+		}
+		v, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("recsys: invalid weight %q", pair)
+		}
+		w[strings.TrimSpace(kv[0])] = v
+	}
+	return w, nil
+}
@@ -0,0 +1,49 @@
+// Package region groups the locations that are crawled, such as a country and the cities in it
+package region
+
+import "strings"
+
+// Region represents a group of locations, which are aliases searched as one
+type Region struct {
+	Name      string
+	Locations []string
+}
+
+// Parse parses the regions in the format "malaysia=Malaysia,Kuala Lumpur,KL;singapore=Singapore".
+// A region without the name is named after the first location
+func Parse(s string) []Region {
+	var regions []Region
+	for _, group := range strings.Split(s, ";") {
+		var name string
+		if i := strings.Index(group, "="); i != -1 {
+			name, group = strings.TrimSpace(group[:i]), group[i+1:]
+		}
+
+		var locations []string
+		for _, location := range strings.Split(group, ",") {
+			if location = strings.TrimSpace(location); location != "" {
+				locations = append(locations, location)
+			}
+		}
+		if len(locations) == 0 {
+			continue
+		}
+		if name == "" {
+			name = locations[0]
+		}
+		regions = append(regions, Region{
+			Name:      name,
+			Locations: locations,
+		})
+	}
+	return regions
+}
+
+// Names returns the name of the regions
+func Names(regions []Region) []string {
+	names := make([]string, len(regions))
+	for i, r := range regions {
+		names[i] = r.Name
+	}
+	return names
+}
@@ -1,7 +1,11 @@
 package schema
 
-// Keyword represents the map reduce keyword from MongoDB
+// Keyword represents a keyword of the profile. ID is the stemmed key that the keywords are
+// matched by, and Text the word as written most often in the descriptions. Value is the
+// number of times the keyword appears, and Score its tf-idf against the corpus
 type Keyword struct {
-	ID    string `json:"name,omitempty" bson:"_id"`
-	Value int    `json:"count,omitempty" bson:"value"`
+	ID    string  `json:"key,omitempty" bson:"_id"`
+	Text  string  `json:"name,omitempty" bson:"text,omitempty"`
+	Value int     `json:"count,omitempty" bson:"value"`
+	Score float64 `json:"score,omitempty" bson:"score,omitempty"`
 }
@@ -1,7 +1,8 @@
 package schema
 
-// LanguageCount represents the language and the count
+// LanguageCount represents the language and the count, with the size in bytes when known
 type LanguageCount struct {
 	Name  string `json:"name,omitempty" bson:"name,omitempty"`
 	Count int    `json:"count,omitempty" bson:"count,omitempty"`
+	Bytes int64  `json:"bytes,omitempty" bson:"bytes,omitempty"`
 }
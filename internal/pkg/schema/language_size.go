@@ -0,0 +1,7 @@
+package schema
+
+// LanguageSize represents the size of the language in the repo in bytes
+type LanguageSize struct {
+	Name  string `json:"name,omitempty" bson:"name,omitempty"`
+	Bytes int64  `json:"bytes,omitempty" bson:"bytes,omitempty"`
+}
@@ -2,19 +2,29 @@ package schema
 
 // Repo represents the repository structure
 type Repo struct {
-	Name          string   `json:"name" bson:"name,omitempty"`
-	CreatedAt     string   `json:"createdAt" bson:"createdAt,omitempty"`
-	UpdatedAt     string   `json:"updatedAt" bson:"updatedAt,omitempty"`
-	FetchedAt     string   `json:"fetchedAt" bson:"fetchedAt,omitempty"`
-	Description   string   `json:"description" bson:"description,omitempty"`
-	Languages     []string `json:"languages" bson:"languages,omitempty"`
-	HomepageURL   string   `json:"homepageUrl" bson:"homepageUrl,omitempty"`
-	Forks         int64    `json:"forks" bson:"forks,omitempty"`
-	IsFork        bool     `json:"isFork" bson:"isFork,omitempty"`
-	NameWithOwner string   `json:"nameWithOwner" bson:"nameWithOwner,omitempty"`
-	Login         string   `json:"login" bson:"login,omitempty"`
-	AvatarURL     string   `json:"avatarUrl" bson:"avatarUrl,omitempty"`
-	Stargazers    int64    `json:"stargazers" bson:"stargazers,omitempty"`
-	Watchers      int64    `json:"watchers" bson:"watchers,omitempty"`
-	URL           string   `json:"url" bson:"url,omitempty"`
+	NodeID              string         `json:"nodeId,omitempty" bson:"nodeId,omitempty"`
+	Name                string         `json:"name" bson:"name,omitempty"`
+	CreatedAt           string         `json:"createdAt" bson:"createdAt,omitempty"`
+	UpdatedAt           string         `json:"updatedAt" bson:"updatedAt,omitempty"`
+	PushedAt            string         `json:"pushedAt,omitempty" bson:"pushedAt,omitempty"`
+	FetchedAt           string         `json:"fetchedAt" bson:"fetchedAt,omitempty"`
+	CountersRefreshedAt string         `json:"countersRefreshedAt,omitempty" bson:"countersRefreshedAt,omitempty"`
+	Description         string         `json:"description" bson:"description,omitempty"`
+	Languages           []string       `json:"languages" bson:"languages,omitempty"`
+	LanguageBytes       []LanguageSize `json:"languageBytes,omitempty" bson:"languageBytes,omitempty"`
+	PrimaryLanguage     string         `json:"primaryLanguage,omitempty" bson:"primaryLanguage,omitempty"`
+	License             string         `json:"license,omitempty" bson:"license,omitempty"`
+	Topics              []string       `json:"topics,omitempty" bson:"topics,omitempty"`
+	HomepageURL         string         `json:"homepageUrl" bson:"homepageUrl,omitempty"`
+	Forks               int64          `json:"forks" bson:"forks,omitempty"`
+	IsFork              bool           `json:"isFork" bson:"isFork,omitempty"`
+	IsArchived          bool           `json:"isArchived" bson:"isArchived,omitempty"`
+	DiskUsage           int64          `json:"diskUsage,omitempty" bson:"diskUsage,omitempty"`
+	NameWithOwner       string         `json:"nameWithOwner" bson:"nameWithOwner,omitempty"`
+	Login               string         `json:"login" bson:"login,omitempty"`
+	AvatarURL           string         `json:"avatarUrl" bson:"avatarUrl,omitempty"`
+	Stargazers          int64          `json:"stargazers" bson:"stargazers,omitempty"`
+	Watchers            int64          `json:"watchers" bson:"watchers,omitempty"`
+	URL                 string         `json:"url" bson:"url,omitempty"`
+	Region              string         `json:"region,omitempty" bson:"region,omitempty"`
 }
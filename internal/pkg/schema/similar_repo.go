@@ -0,0 +1,11 @@
+package schema
+
+// SimilarRepo represents a repo recommended for another repo, with the score of the match
+// and the reasons, which are the terms shared by field, such as keywords or languages
+type SimilarRepo struct {
+	NameWithOwner string              `json:"nameWithOwner" bson:"nameWithOwner"`
+	Description   string              `json:"description,omitempty" bson:"description,omitempty"`
+	Stargazers    int64               `json:"stargazers,omitempty" bson:"stargazers,omitempty"`
+	Score         float64             `json:"score" bson:"score"`
+	Reasons       map[string][]string `json:"reasons,omitempty" bson:"reasons,omitempty"`
+}
@@ -2,25 +2,34 @@ package main
 
 import (
 	"context"
+	"flag"
 	stdlog "log"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
-	"sync"
+	"strings"
 	"time"
 
+	"github.com/alextanhongpin/go-github-scraper/internal/app/checkpointsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/jobsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/locksvc"
 	"github.com/alextanhongpin/go-github-scraper/internal/app/mediatorsvc"
 	"github.com/alextanhongpin/go-github-scraper/internal/app/reposvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/searchsvc"
 	"github.com/alextanhongpin/go-github-scraper/internal/app/statsvc"
+	"github.com/alextanhongpin/go-github-scraper/internal/app/storage"
+	_ "github.com/alextanhongpin/go-github-scraper/internal/app/storage/bolt"     // Registers the bolt driver
+	_ "github.com/alextanhongpin/go-github-scraper/internal/app/storage/postgres" // Registers the postgres driver
 	"github.com/alextanhongpin/go-github-scraper/internal/app/transport"
 	"github.com/alextanhongpin/go-github-scraper/internal/app/usersvc"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/client/github"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/cronjob"
-	"github.com/alextanhongpin/go-github-scraper/internal/pkg/database"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/logger"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/null"
 	"github.com/alextanhongpin/go-github-scraper/internal/pkg/profiler"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/recsys"
+	"github.com/alextanhongpin/go-github-scraper/internal/pkg/region"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/rs/cors"
@@ -29,33 +38,50 @@ import (
 	"go.opencensus.io/trace"
 )
 
+// demo runs the application without Mongo and Github, on sample data kept in memory
+var demo = flag.Bool("demo", false, "serve sample data kept in memory, without mongo and github")
+
 func init() {
 	viper.AutomaticEnv()
 	viper.SetDefault("version", "0.0.1")                             // The application version, normally the git hash
 	viper.SetDefault("crontab_user_tab", "*/20 * * * * *")           // The crontab for user, running every 20 seconds
 	viper.SetDefault("reset_repo", false)                            // Whether to fetch it from scratch or not
 	viper.SetDefault("crontab_repo_tab", "0 * * * * *")              // The crontab for repo, running every minute
+	viper.SetDefault("crontab_refresh_tab", "0 */10 * * * *")        // The crontab for refreshing the repo counters, running every ten minutes
 	viper.SetDefault("crontab_stat_tab", "0 10 0 * * *")             // The crontab for stat, running ten minutes after midnight
 	viper.SetDefault("crontab_profile_tab", "@midnight")             // The crontab for profile, running at midnight
 	viper.SetDefault("crontab_match_tab", "0 15 0 * * *")            // The crontab for matching, running fifteen minutes after midnight
+	viper.SetDefault("crontab_similar_tab", "0 30 0 * * *")          // The crontab for similar repos, running thirty minutes after midnight
 	viper.SetDefault("crontab_user_enable", false)                   // The enable state of the crontab for user
 	viper.SetDefault("crontab_repo_enable", false)                   // The enable state of the crontab for repo
+	viper.SetDefault("crontab_refresh_enable", false)                // The enable state of the crontab for refreshing the repo counters
 	viper.SetDefault("crontab_stat_enable", false)                   // The enable state of the crontab for stat
 	viper.SetDefault("crontab_profile_enable", false)                // The enable state of the crontab for profile
 	viper.SetDefault("crontab_match_enable", false)                  // The enable state of the crontab for profile
+	viper.SetDefault("crontab_similar_enable", false)                // The enable state of the crontab for similar repos
 	viper.SetDefault("crontab_user_trigger", false)                  // Will run once if set to true
 	viper.SetDefault("crontab_repo_trigger", false)                  // Will run once if set to true
+	viper.SetDefault("crontab_refresh_trigger", false)               // Will run once if set to true
 	viper.SetDefault("crontab_stat_trigger", false)                  // Will run once if set to true
 	viper.SetDefault("crontab_profile_trigger", false)               // Will run once if set to true
 	viper.SetDefault("crontab_match_trigger", false)                 // Will run once if set to true
+	viper.SetDefault("crontab_similar_trigger", false)               // Will run once if set to true
+	viper.SetDefault("db_driver", "mgo")                             // The storage driver of the services
 	viper.SetDefault("db_user", "root")                              // The username of the database
 	viper.SetDefault("db_pass", "example")                           // The password of the database
 	viper.SetDefault("db_name", "scraper")                           // The name of the database
 	viper.SetDefault("db_auth", "admin")                             // The name of the auth database
 	viper.SetDefault("db_host", "mongodb://localhost:27017")         // The URI of the database
-	viper.SetDefault("github_location", "Malaysia")                  // The default country to scrape data from
+	viper.SetDefault("lock_holder", "")                              // The name of the instance in the job locks, defaults to the hostname and pid
+	viper.SetDefault("lock_ttl", 60)                                 // The duration in seconds of the job locks, which are renewed while the job runs
+	viper.SetDefault("job_failure_threshold", 3)                     // The number of consecutive failed runs after which the health of a job is degraded
+	viper.SetDefault("github_location", "Malaysia")                  // The default country to scrape data from, when no regions are configured
+	viper.SetDefault("github_regions", "")                           // The regions to scrape data from, e.g. malaysia=Malaysia,Kuala Lumpur;singapore=Singapore
 	viper.SetDefault("github_token", "")                             // The Github's access token used to make call to the GraphQL Endpoint
+	viper.SetDefault("github_tokens", "")                            // Additional comma-separated access tokens, the calls are spread across all tokens
 	viper.SetDefault("github_uri", "https://api.github.com/graphql") // The Github's GraphQL Endpoint
+	viper.SetDefault("recsys_weights", "")                           // The weight of each feature for the matches, e.g. languages=3,keywords=2,followers=0.5
+	viper.SetDefault("recsys_repo_weights", "")                      // The weight of each feature for the similar repos, e.g. topics=3,keywords=2,languages=1
 	viper.SetDefault("port", ":8080")                                // The TCP port of the application
 	viper.SetDefault("pprof_port", ":6060")                          // The TCP port of for the http profiling
 	viper.SetDefault("pprof_enable", false)                          // Toggle flag for pprof
@@ -65,13 +91,21 @@ func init() {
 	viper.SetDefault("graceful_timeout", 15)                         // The duration for which the server gracefully wait for existing connections to finish
 	viper.SetDefault("trace_endpoint", "http://localhost:14268")     // The endpoint of the jaeger image
 	viper.SetDefault("trace_service", "go-scraper")                  // The name of the service that appears in the dashboard
-
-	if viper.GetString("github_token") == "" {
-		panic("github_token environment variable is missing")
-	}
 }
 
 func main() {
+	flag.Parse()
+	if *demo {
+		viper.Set("db_driver", storage.Memory)
+	} else if viper.GetString("github_token") == "" && viper.GetString("github_tokens") == "" {
+		panic("github_token or github_tokens environment variable is missing")
+	}
+	// A job is degraded after the threshold of consecutive failures, which takes at least one,
+	// and at most the number of recent runs that the health is computed from
+	if n := viper.GetInt("job_failure_threshold"); n < 1 || n > jobsvc.MaxHealthRuns {
+		stdlog.Fatalf("job_failure_threshold must be between 1 and %d", jobsvc.MaxHealthRuns)
+	}
+
 	// Create global context for cancellation
 	ctx := context.Background()
 
@@ -106,31 +140,50 @@ func main() {
 	l := logger.New()
 	defer l.Sync()
 
-	// Setup database
-	db := database.New(
-		viper.GetString("db_host"),
-		viper.GetString("db_user"),
-		viper.GetString("db_pass"),
-		viper.GetString("db_name"),
-		viper.GetString("db_auth"))
-	defer db.Close()
+	// Setup the stores with the storage driver
+	stores, err := storage.Open(viper.GetString("db_driver"), storage.Config{
+		Host:     viper.GetString("db_host"),
+		User:     viper.GetString("db_user"),
+		Password: viper.GetString("db_pass"),
+		Name:     viper.GetString("db_name"),
+		Auth:     viper.GetString("db_auth"),
+	})
+	if err != nil {
+		stdlog.Fatal(err)
+	}
+	defer stores.Close()
+
+	// Setup the weights of the features used to match the users
+	weights, err := recsys.ParseWeights(viper.GetString("recsys_weights"), recsys.DefaultWeights)
+	if err != nil {
+		stdlog.Fatal(err)
+	}
+	repoWeights, err := recsys.ParseWeights(viper.GetString("recsys_repo_weights"), recsys.DefaultRepoWeights)
+	if err != nil {
+		stdlog.Fatal(err)
+	}
 
 	// Setup services
 	m := mediatorsvc.Mediator{
-		Stat: statsvc.New(db,
+		Checkpoint: checkpointsvc.NewWithStore(stores.Checkpoint,
+			checkpointsvc.Logging(l.Named("checkpointsvc")),
+			checkpointsvc.Tracing()),
+		Stat: statsvc.NewWithStore(stores.Stat,
 			statsvc.Logging(l.Named("statsvc")),
 			statsvc.Tracing()),
 		Github: github.New(httpClient,
-			viper.GetString("github_token"),
+			append(strings.Split(viper.GetString("github_tokens"), ","), viper.GetString("github_token")),
 			viper.GetString("github_uri"),
 			github.Logging(l.Named("github")),
 			github.Tracing()),
-		Repo: reposvc.New(db,
+		Repo: reposvc.NewWithStore(stores.Repo,
 			reposvc.Logging(l.Named("reposvc")),
 			reposvc.Tracing()),
-		User: usersvc.New(db,
+		User: usersvc.NewWithStore(stores.User,
 			usersvc.Logging(l.Named("usersvc")),
 			usersvc.Tracing()),
+		Recommender:     recsys.NewTFIDF(weights),
+		RepoRecommender: recsys.NewTFIDF(repoWeights),
 	}
 
 	// Setup mediator services, which is basically an orchestration of multiple services
@@ -139,25 +192,108 @@ func main() {
 		mediatorsvc.Logging(l.Named("mediatorsvc")),
 		mediatorsvc.Tracing())
 
+	// Setup the regions to crawl, each with the locations that are searched as one
+	regions := region.Parse(viper.GetString("github_regions"))
+	if len(regions) == 0 {
+		regions = region.Parse(viper.GetString("github_location"))
+	}
+
+	// Compute the stats for each region and across all regions
+	buildStats := func(ctx context.Context) error {
+		ctx = logger.WrapContextWithRequestID(ctx)
+		defaultLimit := 20
+		min := 3
+		max := 100
+
+		// An empty region computes the stats across all regions. Each stat is a task of its own,
+		// so that the ones that fail are recorded with the run
+		var tasks []cronjob.Task
+		for _, r := range append([]string{""}, region.Names(regions)...) {
+			r := r
+			name := r
+			if name == "" {
+				name = "all"
+			}
+			task := func(stat string, fn null.Fn) cronjob.Task {
+				return cronjob.Task{Name: name + "/" + stat, Fn: fn}
+			}
+			tasks = append(tasks,
+				task(statsvc.EnumUserCount, func() error { return msvc.UpdateUserCount(ctx, r) }),
+				task(statsvc.EnumRepoCount, func() error { return msvc.UpdateRepoCount(ctx, r) }),
+				task(statsvc.EnumReposMostRecent, func() error { return msvc.UpdateReposMostRecent(ctx, r, defaultLimit) }),
+				task(statsvc.EnumRepoCountByUser, func() error { return msvc.UpdateRepoCountByUser(ctx, r, defaultLimit) }),
+				task(statsvc.EnumReposMostStars, func() error { return msvc.UpdateReposMostStars(ctx, r, defaultLimit) }),
+				task(statsvc.EnumReposMostForks, func() error { return msvc.UpdateReposMostForks(ctx, r, defaultLimit) }),
+				task(statsvc.EnumMostPopularLanguage, func() error { return msvc.UpdateLanguagesMostPopular(ctx, r, defaultLimit) }),
+				task(statsvc.EnumMostRecentReposByLanguage, func() error { return msvc.UpdateMostRecentReposByLanguage(ctx, r, defaultLimit) }),
+				task(statsvc.EnumReposByLanguage, func() error { return msvc.UpdateReposByLanguage(ctx, r, defaultLimit) }),
+				task(statsvc.EnumCompanyCount, func() error { return msvc.UpdateCompanyCount(ctx, r) }),
+				task(statsvc.EnumUsersByCompany, func() error { return msvc.UpdateUsersByCompany(ctx, r, min, max) }),
+			)
+		}
+		return cronjob.Parallel(ctx, tasks...)
+	}
+
+	// In the demo mode, the stores are seeded with the sample data, and everything that is
+	// computed from it is computed once before serving
+	if *demo {
+		if err := storage.Seed(stores, regions[0]); err != nil {
+			stdlog.Fatal(err)
+		}
+		numWorkers := 4
+		for _, fn := range []func(context.Context) error{
+			func(ctx context.Context) error { return msvc.UpdateProfile(ctx, numWorkers) },
+			func(ctx context.Context) error { return msvc.UpdateMatches(ctx, numWorkers) },
+			func(ctx context.Context) error { return msvc.UpdateSimilarRepos(ctx, numWorkers) },
+			buildStats,
+		} {
+			if err := fn(ctx); err != nil {
+				stdlog.Fatal(err)
+			}
+		}
+	}
+
+	// Setup the locks of the jobs, so that each job runs on one replica at a time
+	locks := locksvc.NewWithStore(stores.Lock,
+		locksvc.Logging(l.Named("locksvc")),
+		locksvc.Tracing())
+	holder := viper.GetString("lock_holder")
+	if holder == "" {
+		holder = locksvc.DefaultHolder()
+	}
+	locker := locksvc.NewLocker(locks, holder, time.Second*viper.GetDuration("lock_ttl"))
+
+	// Setup the history of the runs of the jobs
+	runs := jobsvc.NewWithStore(stores.Job,
+		jobsvc.Logging(l.Named("jobsvc")),
+		jobsvc.Tracing())
+	recorder := jobsvc.NewRecorder(runs, holder)
+
 	// Setup cronjob
-	cronjob.Exec(ctx,
-		&cronjob.Config{
+	jobs := []*cronjob.Config{
+		{
 			Name:        "Fetch Users",
-			Description: "Fetch the Github users data periodically based on location and created date, which is stored as delta timestamp",
+			Description: "Fetch the Github users data periodically for each region based on location and created date, which is stored as delta timestamp",
 			Start:       viper.GetBool("crontab_user_enable"),
 			CronTab:     viper.GetString("crontab_user_tab"),
 			Trigger:     viper.GetBool("crontab_user_trigger"),
 			Fn: func(ctx context.Context) error {
 				ctx = logger.WrapContextWithRequestID(ctx)
-				location := "Malaysia"
 				months := 6
 				perPage := 30
-				return msvc.FetchUsers(ctx, location, months, perPage)
+
+				var lastErr error
+				for _, r := range regions {
+					if err := msvc.FetchUsers(ctx, r, months, perPage); err != nil {
+						lastErr = err
+					}
+				}
+				return lastErr
 			},
 		},
-		&cronjob.Config{
+		{
 			Name:        "Fetch Repos",
-			Description: "Fetch the Github user's repos periodically based on the last fetched date",
+			Description: "Fetch the Github user's repos periodically based on the last fetched date, until the repos pushed before the last run",
 			Start:       viper.GetBool("crontab_repo_enable"),
 			CronTab:     viper.GetString("crontab_repo_tab"),
 			Trigger:     viper.GetBool("crontab_repo_trigger"),
@@ -168,7 +304,19 @@ func main() {
 				return msvc.FetchRepos(ctx, userPerPage, repoPerPage, viper.GetBool("reset_repo"))
 			},
 		},
-		&cronjob.Config{
+		{
+			Name:        "Refresh Repos",
+			Description: "Refresh the stars, forks and watchers of the repos with the least recently refreshed counters",
+			Start:       viper.GetBool("crontab_refresh_enable"),
+			CronTab:     viper.GetString("crontab_refresh_tab"),
+			Trigger:     viper.GetBool("crontab_refresh_trigger"),
+			Fn: func(ctx context.Context) error {
+				ctx = logger.WrapContextWithRequestID(ctx)
+				perPage := 100
+				return msvc.RefreshRepos(ctx, perPage)
+			},
+		},
+		{
 			Name:        "Update Profile",
 			Description: "Compute the new user profile based on the repos that are scraped daily",
 			Start:       viper.GetBool("crontab_profile_enable"),
@@ -180,46 +328,15 @@ func main() {
 				return msvc.UpdateProfile(ctx, numWorkers)
 			},
 		},
-		&cronjob.Config{
+		{
 			Name:        "Build Stats",
-			Description: "Compute the Github's analytic data of users in Malaysia based on the new repos that are scraped daily",
+			Description: "Compute the Github's analytic data of users for each region and across all regions based on the new repos that are scraped daily",
 			Start:       viper.GetBool("crontab_stat_enable"),
 			CronTab:     viper.GetString("crontab_stat_tab"),
 			Trigger:     viper.GetBool("crontab_stat_trigger"),
-			Fn: func(ctx context.Context) error {
-				ctx = logger.WrapContextWithRequestID(ctx)
-				defaultLimit := 20
-				min := 3
-				max := 100
-
-				nullFns := []null.Fn{
-					func() error { return msvc.UpdateUserCount(ctx) },
-					func() error { return msvc.UpdateRepoCount(ctx) },
-					func() error { return msvc.UpdateReposMostRecent(ctx, defaultLimit) },
-					func() error { return msvc.UpdateRepoCountByUser(ctx, defaultLimit) },
-					func() error { return msvc.UpdateReposMostStars(ctx, defaultLimit) },
-					func() error { return msvc.UpdateReposMostForks(ctx, defaultLimit) },
-					func() error { return msvc.UpdateLanguagesMostPopular(ctx, defaultLimit) },
-					func() error { return msvc.UpdateMostRecentReposByLanguage(ctx, defaultLimit) },
-					func() error { return msvc.UpdateReposByLanguage(ctx, defaultLimit) },
-					func() error { return msvc.UpdateCompanyCount(ctx) },
-					func() error { return msvc.UpdateUsersByCompany(ctx, min, max) },
-				}
-				var wg sync.WaitGroup
-				wg.Add(len(nullFns))
-
-				for _, fn := range nullFns {
-					go func(f null.Fn) {
-						defer wg.Done()
-						f()
-					}(fn)
-				}
-
-				wg.Wait()
-				return nil
-			},
+			Fn:          buildStats,
 		},
-		&cronjob.Config{
+		{
 			Name:        "Update Matches",
 			Description: "Compute the new user recommendations based on the new repos pulled",
 			Start:       viper.GetBool("crontab_match_enable"),
@@ -227,10 +344,28 @@ func main() {
 			Trigger:     viper.GetBool("crontab_match_trigger"),
 			Fn: func(ctx context.Context) error {
 				ctx = logger.WrapContextWithRequestID(ctx)
-				return msvc.UpdateMatches(ctx)
+				numWorkers := 4
+				return msvc.UpdateMatches(ctx, numWorkers)
 			},
 		},
-	)
+		{
+			Name:        "Update Similar Repos",
+			Description: "Compute the similar repos based on the description keywords, topics and languages",
+			Start:       viper.GetBool("crontab_similar_enable"),
+			CronTab:     viper.GetString("crontab_similar_tab"),
+			Trigger:     viper.GetBool("crontab_similar_trigger"),
+			Fn: func(ctx context.Context) error {
+				ctx = logger.WrapContextWithRequestID(ctx)
+				numWorkers := 4
+				return msvc.UpdateSimilarRepos(ctx, numWorkers)
+			},
+		},
+	}
+	for _, job := range jobs {
+		job.Locker = locker
+		job.Recorder = recorder
+		job.Do(ctx)
+	}
 
 	// Setup router
 	r := httprouter.New()
@@ -240,11 +375,15 @@ func main() {
 	tr.Init(
 		transport.NewUserEndpoints(m.User),
 		transport.NewStatEndpoints(m.Stat),
+		transport.NewJobEndpoints(jobs, locks, runs, viper.GetInt("job_failure_threshold")),
 		transport.NewRepoEndpoints(m.Repo),
+		transport.NewSearchEndpoints(searchsvc.NewWithSearcher(stores.Searcher,
+			searchsvc.Logging(l.Named("searchsvc")),
+			searchsvc.Tracing())),
 	)
 
-	// Add cors support
-	handler := cors.Default().Handler(r)
+	// Add cors support, and assign a request id to every request
+	handler := cors.Default().Handler(transport.RequestID(r))
 
 	// a http.Server with pre-configured timeouts to avoid Slowloris attack
 	srv := &http.Server{